@@ -8,6 +8,8 @@ import (
 	"golang.org/x/crypto/bcrypt"
 	"hired-valley-backend/config"
 	"hired-valley-backend/models/users"
+	"hired-valley-backend/services"
+	"log"
 	"net/http"
 	"os"
 	"strings"
@@ -38,6 +40,7 @@ func Register(w http.ResponseWriter, r *http.Request) {
 	}
 	user.Password = string(hashedPassword)
 	user.Provider = "local"
+	user.EmailVerifiedAt = nil // Адрес подтверждается письмом
 
 	// Создаем запись в базе данных
 	if err := config.DB.Create(&user).Error; err != nil {
@@ -47,6 +50,10 @@ func Register(w http.ResponseWriter, r *http.Request) {
 
 	fmt.Printf("User registered with ID: %d\n", user.ID)
 
+	if err := services.SendEmailVerification(&user); err != nil {
+		log.Printf("Ошибка отправки подтверждения email пользователю %d: %v", user.ID, err)
+	}
+
 	// Генерируем токен
 	tokenString, err := generateToken(user.ID, user.Email, user.Role)
 	if err != nil {
@@ -167,7 +174,8 @@ func GetProfile(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var user users.User
-	if err := config.DB.Where("email = ? AND provider = ?", claims.Email, "local").First(&user).Error; err != nil {
+	if err := config.DB.Preload("Certificates", "revoked_at IS NULL").
+		Where("email = ? AND provider = ?", claims.Email, "local").First(&user).Error; err != nil {
 		http.Error(w, "User not found", http.StatusUnauthorized)
 		return
 	}
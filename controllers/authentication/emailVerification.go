@@ -0,0 +1,64 @@
+package authentication
+
+import (
+	"encoding/json"
+	"errors"
+	"hired-valley-backend/services"
+	"net/http"
+)
+
+// SendEmailVerificationHandler - POST: письмо со ссылкой подтверждения email текущего пользователя
+func SendEmailVerificationHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user, err := ValidateToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	if user.EmailVerifiedAt != nil {
+		http.Error(w, "Email is already verified", http.StatusConflict)
+		return
+	}
+
+	emailSent := services.SendEmailVerification(user) == nil
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"email":      user.Email,
+		"email_sent": emailSent,
+	})
+}
+
+// ConfirmEmailVerificationHandler - GET/POST: подтверждение email по ссылке из письма (?token=)
+func ConfirmEmailVerificationHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		http.Error(w, "Verification token is required", http.StatusBadRequest)
+		return
+	}
+
+	user, err := services.ConfirmEmailVerification(token)
+	if errors.Is(err, services.ErrVerificationInvalid) {
+		http.Error(w, "Verification link is invalid or has expired", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to verify email", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"email":             user.Email,
+		"email_verified_at": user.EmailVerifiedAt,
+	})
+}
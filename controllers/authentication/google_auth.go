@@ -15,6 +15,7 @@ import (
 	"net/http"
 	"os"
 	"strconv"
+	"time"
 )
 
 var (
@@ -126,9 +127,18 @@ func HandleGoogleCallback(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Google сообщает, подтвержден ли адрес
+	emailVerified, _ := userInfo["verified_email"].(bool)
+
 	// Проверка, существует ли пользователь с таким email
 	var user users.User
-	if err := config.DB.Where("email = ?", email).First(&user).Error; err != nil {
+	if err := config.DB.Where("email = ?", email).First(&user).Error; err == nil {
+		if emailVerified && user.EmailVerifiedAt == nil {
+			now := time.Now()
+			user.EmailVerifiedAt = &now
+			config.DB.Model(&user).Update("email_verified_at", now)
+		}
+	} else {
 		// Если пользователь не найден, создаем нового
 		if err == gorm.ErrRecordNotFound {
 			log.Printf("Пользователь с email %s не найден, создаем нового", email)
@@ -138,6 +148,10 @@ func HandleGoogleCallback(w http.ResponseWriter, r *http.Request) {
 				Provider:    "google",
 				AccessToken: token.AccessToken,
 			}
+			if emailVerified {
+				now := time.Now()
+				user.EmailVerifiedAt = &now
+			}
 			if err := config.DB.Create(&user).Error; err != nil {
 				log.Printf("Ошибка при создании пользователя: %v", err)
 				http.Error(w, "Ошибка создания пользователя", http.StatusInternalServerError)
@@ -272,3 +286,17 @@ func ValidateGoogleToken(r *http.Request) (*users.GoogleUser, error) {
 
 	return &googleUser, nil
 }
+
+// ValidateGoogleUser - пользователь приложения по Google OAuth токену
+func ValidateGoogleUser(r *http.Request) (*users.User, error) {
+	googleUser, err := ValidateGoogleToken(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var user users.User
+	if err := config.DB.First(&user, googleUser.UserID).Error; err != nil {
+		return nil, errors.New("user not found in the database")
+	}
+	return &user, nil
+}
@@ -2,12 +2,16 @@ package careers
 
 import (
 	"encoding/json"
+	"gorm.io/gorm"
 	"hired-valley-backend/config"
 	"hired-valley-backend/controllers/authentication"
 	"hired-valley-backend/models/career"
+	"hired-valley-backend/models/users"
 	"hired-valley-backend/services"
 	"net/http"
 	"os"
+	"strconv"
+	"time"
 )
 
 type CareerPlanRequest struct {
@@ -67,3 +71,114 @@ func GenerateCareerPlanHandler(w http.ResponseWriter, r *http.Request) {
 		"steps":   plan,
 	})
 }
+
+// CareerPlanProgressHandler - шаги карьерного плана и процент выполнения
+func CareerPlanProgressHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	claims, err := authentication.ValidateToken(r)
+	if err != nil {
+		http.Error(w, "Unauthorized: "+err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	planID, err := strconv.Atoi(r.URL.Query().Get("plan_id"))
+	if err != nil || planID <= 0 {
+		http.Error(w, "Invalid plan ID", http.StatusBadRequest)
+		return
+	}
+
+	var plan career.PlanCareer
+	if err := config.DB.Preload("PlanSteps", func(db *gorm.DB) *gorm.DB {
+		return db.Order("due_date ASC NULLS LAST, id ASC")
+	}).Where("id = ? AND user_id = ?", planID, claims.ID).First(&plan).Error; err != nil {
+		http.Error(w, "Career plan not found", http.StatusNotFound)
+		return
+	}
+
+	completed := 0
+	for _, step := range plan.PlanSteps {
+		if step.Completed {
+			completed++
+		}
+	}
+	percent := 0.0
+	if len(plan.PlanSteps) > 0 {
+		percent = float64(completed) * 100 / float64(len(plan.PlanSteps))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"plan":               plan,
+		"total_steps":        len(plan.PlanSteps),
+		"completed_steps":    completed,
+		"completion_percent": percent,
+	})
+}
+
+// UpdatePlanStepHandler - отметка шага карьерного плана выполненным
+func UpdatePlanStepHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	claims, err := authentication.ValidateToken(r)
+	if err != nil {
+		http.Error(w, "Unauthorized: "+err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	stepID, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil || stepID <= 0 {
+		http.Error(w, "Invalid step ID", http.StatusBadRequest)
+		return
+	}
+
+	var step career.PlanStep
+	if err := config.DB.Joins("JOIN plan_careers ON plan_careers.id = plan_steps.plan_id").
+		Where("plan_steps.id = ? AND plan_careers.user_id = ?", stepID, claims.ID).
+		First(&step).Error; err != nil {
+		http.Error(w, "Plan step not found", http.StatusNotFound)
+		return
+	}
+
+	var input struct {
+		Completed bool `json:"completed"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	step.Completed = input.Completed
+	step.CompletedAt = nil
+	if step.Completed {
+		now := time.Now()
+		step.CompletedAt = &now
+	}
+
+	// Шаг из сессии с ментором синхронизируется с исходной задачей
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&step).Error; err != nil {
+			return err
+		}
+		if step.ActionItemID == nil {
+			return nil
+		}
+		return tx.Model(&users.ActionItem{}).Where("id = ?", *step.ActionItemID).Updates(map[string]interface{}{
+			"completed":    step.Completed,
+			"completed_at": step.CompletedAt,
+		}).Error
+	})
+	if err != nil {
+		http.Error(w, "Failed to update plan step", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(step)
+}
@@ -1,27 +1,46 @@
 package contentsControl
 
 import (
-	"context"
 	"encoding/json"
-	"fmt"
-	"golang.org/x/oauth2"
-	"google.golang.org/api/option"
-	"google.golang.org/api/youtube/v3"
+	"errors"
 	"hired-valley-backend/config"
 	"hired-valley-backend/controllers/authentication"
+	"hired-valley-backend/models/analytics"
 	"hired-valley-backend/models/content"
-	"mime/multipart"
+	"hired-valley-backend/models/courses/videos"
+	"hired-valley-backend/models/users"
+	"hired-valley-backend/services"
 	"net/http"
 	"strconv"
 	"strings"
 )
 
-// UploadContent - загрузка контента на YouTube и сохранение записи в базе данных
+// UploadContent - загрузка видео контента (поле host: youtube или self); публикация на площадку
+// и создание записи идут в фоне. Для YouTube нужен Google OAuth токен или подключенный канал
 func UploadContent(w http.ResponseWriter, r *http.Request) {
-	// Проверяем Google OAuth токен
-	claims, err := authentication.ValidateGoogleToken(r)
-	if err != nil {
-		http.Error(w, "Unauthorized: "+err.Error(), http.StatusUnauthorized)
+	var authorID uint
+	var googleEmail, googleToken string
+	if user, err := authentication.ValidateToken(r); err == nil {
+		authorID = user.ID
+		var account users.YoutubeUser
+		if config.DB.Where("user_id = ?", user.ID).First(&account).Error == nil {
+			googleEmail = account.Email
+		}
+	} else {
+		claims, err := authentication.ValidateGoogleToken(r)
+		if err != nil {
+			http.Error(w, "Unauthorized: "+err.Error(), http.StatusUnauthorized)
+			return
+		}
+		authorID, googleEmail, googleToken = claims.UserID, claims.Email, claims.AccessToken
+	}
+
+	host := r.FormValue("host")
+	if host == "" {
+		host = services.DefaultVideoHost()
+	}
+	if host == videos.HostYouTube && googleEmail == "" && googleToken == "" {
+		http.Error(w, "YouTube hosting requires a Google token or a connected YouTube channel", http.StatusBadRequest)
 		return
 	}
 
@@ -50,78 +69,38 @@ func UploadContent(w http.ResponseWriter, r *http.Request) {
 	}
 	defer file.Close()
 
-	// Загрузка видео на YouTube
-	videoID, err := uploadVideoToYouTube(file, header.Filename, claims.AccessToken, title, description)
-	if err != nil {
-		http.Error(w, "Failed to upload video to YouTube: "+err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	// Проверяем, что videoID не пустой
-	if videoID == "" {
-		http.Error(w, "YouTube API returned an empty video ID", http.StatusInternalServerError)
-		return
-	}
-
-	// Создаем запись контента
-	content := content.Content{
+	// Файл проверяется и публикуется фоновым обработчиком, запись контента создается после публикации
+	video := videos.Video{
 		Title:       title,
 		Description: description,
+		UploadedBy:  authorID,
+		TargetType:  videos.TargetContent,
+		Host:        host,
 		Category:    category,
 		Tags:        tags,
-		VideoLink:   fmt.Sprintf("https://www.youtube.com/watch?v=%s", videoID),
-		YouTubeID:   videoID,
-		AuthorID:    claims.UserID,
-	}
-
-	// Сохраняем запись в базе данных
-	if err := config.DB.Create(&content).Error; err != nil {
-		http.Error(w, "Failed to save content: "+err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	// Успешный ответ
+		FileName:    header.Filename,
+		SizeBytes:   header.Size,
+		GoogleEmail: googleEmail,
+		GoogleToken: googleToken,
+	}
+	if err := services.StageVideoFile(&video, file); err != nil {
+		switch {
+		case errors.Is(err, services.ErrUnknownVideoHost):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, services.ErrUnsupportedFileType):
+			http.Error(w, err.Error(), http.StatusUnsupportedMediaType)
+		case errors.Is(err, services.ErrUploadTooLarge):
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		default:
+			http.Error(w, "Failed to process video upload: "+err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	// Успешный ответ: клиент опрашивает /videos/uploads?id= до статуса ready, в нем будет content_id
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(content)
-}
-
-func uploadVideoToYouTube(file multipart.File, fileName string, accessToken string, title string, description string) (string, error) {
-	ctx := context.Background()
-
-	// Создаем токен OAuth вручную
-	token := &oauth2.Token{
-		AccessToken: accessToken,
-	}
-
-	// Создаем источник токена
-	tokenSource := authentication.GoogleOauthConfig.TokenSource(ctx, token)
-
-	// Создаем YouTube сервис
-	service, err := youtube.NewService(ctx, option.WithTokenSource(tokenSource))
-	if err != nil {
-		return "", fmt.Errorf("failed to create YouTube service: %v", err)
-	}
-
-	// Подготавливаем метаданные видео
-	video := &youtube.Video{
-		Snippet: &youtube.VideoSnippet{
-			Title:       title,
-			Description: description,
-		},
-		Status: &youtube.VideoStatus{
-			PrivacyStatus: "unlisted",
-		},
-	}
-
-	// Загружаем видео
-	call := service.Videos.Insert([]string{"snippet", "status"}, video)
-	response, err := call.Media(file).Do()
-	if err != nil {
-		return "", fmt.Errorf("failed to upload video: %v", err)
-	}
-
-	// Возвращаем ID видео
-	return response.Id, nil
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(video)
 }
 
 // ListContent - получение списка контента
@@ -142,6 +121,9 @@ func ListContent(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Failed to fetch content", http.StatusInternalServerError)
 		return
 	}
+	for i := range contents {
+		contents[i].VideoLink = services.SignVideoLink(contents[i].VideoLink)
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(contents)
@@ -167,6 +149,14 @@ func GetContentByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Просмотр учитывается в аналитике автора
+	var viewerID uint
+	if viewer, err := authentication.ValidateToken(r); err == nil {
+		viewerID = viewer.ID
+	}
+	services.RecordView(analytics.ItemContent, content.ID, viewerID)
+	content.VideoLink = services.SignVideoLink(content.VideoLink)
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(content)
 }
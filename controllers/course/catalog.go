@@ -0,0 +1,164 @@
+package course
+
+import (
+	"encoding/json"
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"hired-valley-backend/config"
+	"hired-valley-backend/models/courses"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultCatalogLimit = 20
+	maxCatalogLimit     = 100
+)
+
+// catalogCourse - курс в каталоге с количеством записавшихся
+type catalogCourse struct {
+	courses.Course
+	EnrollmentsCount int64 `json:"enrollments_count"`
+}
+
+// CourseCatalogHandler - публичный каталог курсов: поиск, фильтры, сортировка и пагинация
+func CourseCatalogHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	params := r.URL.Query()
+	query := config.DB.Model(&courses.Course{}).Where("courses.status = ?", courses.CourseStatusPublished)
+
+	// Полнотекстовый поиск по названию и описанию
+	text := strings.TrimSpace(params.Get("q"))
+	searchVector := "to_tsvector('simple', coalesce(courses.title, '') || ' ' || coalesce(courses.description, ''))"
+	if text != "" {
+		query = query.Where(searchVector+" @@ plainto_tsquery('simple', ?)", text)
+	}
+
+	// Теги передаются через запятую: tags=go,backend
+	if tags := splitTags(params.Get("tags")); len(tags) > 0 {
+		query = query.Where("courses.tags && ?", pq.StringArray(tags))
+	}
+
+	if value := params.Get("instructor_id"); value != "" {
+		instructorID, err := strconv.Atoi(value)
+		if err != nil || instructorID <= 0 {
+			http.Error(w, "Invalid instructor ID", http.StatusBadRequest)
+			return
+		}
+		query = query.Where("courses.instructor_id = ?", instructorID)
+	}
+
+	if value := params.Get("min_price"); value != "" {
+		minPrice, err := strconv.ParseFloat(value, 64)
+		if err != nil || minPrice < 0 {
+			http.Error(w, "Invalid min_price", http.StatusBadRequest)
+			return
+		}
+		query = query.Where("courses.price >= ?", minPrice)
+	}
+	if value := params.Get("max_price"); value != "" {
+		maxPrice, err := strconv.ParseFloat(value, 64)
+		if err != nil || maxPrice < 0 {
+			http.Error(w, "Invalid max_price", http.StatusBadRequest)
+			return
+		}
+		query = query.Where("courses.price <= ?", maxPrice)
+	}
+	if params.Get("free") == "true" {
+		query = query.Where("courses.price = 0")
+	}
+	if value := params.Get("min_rating"); value != "" {
+		minRating, err := strconv.ParseFloat(value, 64)
+		if err != nil || minRating < 0 || minRating > 5 {
+			http.Error(w, "Invalid min_rating", http.StatusBadRequest)
+			return
+		}
+		query = query.Where("courses.average_rating >= ?", minRating)
+	}
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		http.Error(w, "Failed to list courses", http.StatusInternalServerError)
+		return
+	}
+
+	page, limit := 1, defaultCatalogLimit
+	if value := params.Get("page"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			http.Error(w, "Invalid page", http.StatusBadRequest)
+			return
+		}
+		page = parsed
+	}
+	if value := params.Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		if parsed > maxCatalogLimit {
+			parsed = maxCatalogLimit
+		}
+		limit = parsed
+	}
+
+	enrollments := "(SELECT COUNT(*) FROM enrollments e WHERE e.course_id = courses.id)"
+	query = query.Select("courses.*, " + enrollments + " AS enrollments_count")
+
+	switch params.Get("sort") {
+	case "", "newest":
+		query = query.Order("courses.created_at DESC")
+	case "price_asc":
+		query = query.Order("courses.price ASC")
+	case "price_desc":
+		query = query.Order("courses.price DESC")
+	case "popular":
+		query = query.Order("enrollments_count DESC")
+	case "rating":
+		query = query.Order("courses.average_rating DESC, courses.reviews_count DESC")
+	case "relevance":
+		if text == "" {
+			http.Error(w, "Sort by relevance requires q", http.StatusBadRequest)
+			return
+		}
+		query = query.Order(clause.Expr{SQL: "ts_rank(" + searchVector + ", plainto_tsquery('simple', ?)) DESC", Vars: []interface{}{text}})
+	default:
+		http.Error(w, "Invalid sort. Use newest, price_asc, price_desc, popular, rating or relevance", http.StatusBadRequest)
+		return
+	}
+
+	var items []catalogCourse
+	if err := query.Order("courses.id DESC").Offset((page - 1) * limit).Limit(limit).Scan(&items).Error; err != nil {
+		http.Error(w, "Failed to list courses", http.StatusInternalServerError)
+		return
+	}
+	if items == nil {
+		items = []catalogCourse{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"courses": items,
+		"total":   total,
+		"page":    page,
+		"limit":   limit,
+	})
+}
+
+// splitTags - разбор списка тегов, переданных через запятую
+func splitTags(value string) []string {
+	var tags []string
+	for _, tag := range strings.Split(value, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
@@ -0,0 +1,189 @@
+package course
+
+import (
+	"encoding/json"
+	"fmt"
+	"hired-valley-backend/config"
+	"hired-valley-backend/controllers/authentication"
+	"hired-valley-backend/models/courses"
+	"hired-valley-backend/models/users"
+	"hired-valley-backend/services"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// issueCertificate - выдача сертификата после завершения урока, если курс пройден целиком
+func issueCertificate(userID, courseID uint) *users.Certificate {
+	var course courses.Course
+	if err := config.DB.First(&course, courseID).Error; err != nil {
+		return nil
+	}
+	certificate, err := services.IssueCertificateIfCompleted(userID, course)
+	if err != nil {
+		log.Printf("Ошибка выдачи сертификата пользователю %d за курс %d: %v", userID, courseID, err)
+		return nil
+	}
+	return certificate
+}
+
+// findCertificate - сертификат по публичному идентификатору; при ошибке ответ уже отправлен
+func findCertificate(w http.ResponseWriter, r *http.Request) (users.Certificate, bool) {
+	var certificate users.Certificate
+	verificationID := strings.ToUpper(strings.TrimSpace(r.URL.Query().Get("id")))
+	if verificationID == "" {
+		http.Error(w, "Verification ID is required", http.StatusBadRequest)
+		return certificate, false
+	}
+	if err := config.DB.Where("verification_id = ?", verificationID).First(&certificate).Error; err != nil {
+		http.Error(w, "Certificate not found", http.StatusNotFound)
+		return certificate, false
+	}
+	return certificate, true
+}
+
+// MyCertificatesHandler - сертификаты текущего пользователя, включая отозванные
+func MyCertificatesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user, err := authentication.ValidateToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	var certificates []users.Certificate
+	if err := config.DB.Where("user_id = ?", user.ID).Order("issued_at DESC").Find(&certificates).Error; err != nil {
+		http.Error(w, "Failed to list certificates", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(certificates)
+}
+
+// UserCertificatesHandler - действующие сертификаты пользователя для публичного профиля
+func UserCertificatesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := strconv.Atoi(r.URL.Query().Get("user_id"))
+	if err != nil || userID <= 0 {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	var user users.User
+	if err := config.DB.First(&user, userID).Error; err != nil || user.Visibility == "private" {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	var certificates []users.Certificate
+	if err := config.DB.Where("user_id = ? AND revoked_at IS NULL", user.ID).Order("issued_at DESC").Find(&certificates).Error; err != nil {
+		http.Error(w, "Failed to list certificates", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(certificates)
+}
+
+// VerifyCertificateHandler - публичная проверка подлинности сертификата
+func VerifyCertificateHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	certificate, ok := findCertificate(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"valid":       certificate.RevokedAt == nil,
+		"certificate": certificate,
+	})
+}
+
+// CertificatePDFHandler - PDF сертификата для скачивания и публикации
+func CertificatePDFHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	certificate, ok := findCertificate(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=\"certificate-%s.pdf\"", certificate.VerificationID))
+	w.Write(services.RenderCertificatePDF(certificate))
+}
+
+// RevokeCertificateHandler - отзыв сертификата инструктором курса или администратором
+func RevokeCertificateHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user, err := authentication.ValidateToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	certificate, ok := findCertificate(w, r)
+	if !ok {
+		return
+	}
+
+	if user.Role != "admin" {
+		var course courses.Course
+		if err := config.DB.Unscoped().First(&course, certificate.CourseID).Error; err != nil || course.InstructorID != user.ID {
+			http.Error(w, "Permission denied", http.StatusForbidden)
+			return
+		}
+	}
+	if certificate.RevokedAt != nil {
+		http.Error(w, "Certificate has already been revoked", http.StatusConflict)
+		return
+	}
+
+	var input struct {
+		Reason string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil || strings.TrimSpace(input.Reason) == "" {
+		http.Error(w, "Reason is required", http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now()
+	certificate.RevokedAt = &now
+	certificate.RevokedByID = &user.ID
+	certificate.RevocationReason = strings.TrimSpace(input.Reason)
+	if err := config.DB.Save(&certificate).Error; err != nil {
+		http.Error(w, "Failed to revoke certificate", http.StatusInternalServerError)
+		return
+	}
+
+	config.DB.Create(&users.NotificationMentor{
+		UserID:  certificate.UserID,
+		Message: fmt.Sprintf("Your certificate for \"%s\" was revoked: %s", certificate.CourseTitle, certificate.RevocationReason),
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(certificate)
+}
@@ -0,0 +1,295 @@
+package course
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Поддерживаемое подмножество манифестов IMS Common Cartridge 1.1-1.3 и SCORM 1.2/2004:
+// дерево organization/item задает разделы и уроки, ресурсы webcontent - HTML-текст урока,
+// ресурсы imswl_xmlv1p1 (веб-ссылка) - ссылку на видео. Тесты (QTI) и SCO-runtime не переносятся.
+const (
+	manifestFileName = "imsmanifest.xml"
+	webLinkType      = "imswl_xmlv1p1"
+)
+
+type manifest struct {
+	XMLName       xml.Name              `xml:"manifest"`
+	Identifier    string                `xml:"identifier,attr"`
+	Xmlns         string                `xml:"xmlns,attr,omitempty"`
+	Metadata      *manifestMetadata     `xml:"metadata"`
+	Organizations manifestOrganizations `xml:"organizations"`
+	Resources     manifestResourceList  `xml:"resources"`
+}
+
+type manifestMetadata struct {
+	Schema        string `xml:"schema"`
+	SchemaVersion string `xml:"schemaversion"`
+}
+
+type manifestOrganizations struct {
+	Items []manifestOrganization `xml:"organization"`
+}
+
+type manifestOrganization struct {
+	Identifier string         `xml:"identifier,attr"`
+	Structure  string         `xml:"structure,attr,omitempty"`
+	Title      string         `xml:"title,omitempty"`
+	Items      []manifestItem `xml:"item"`
+}
+
+type manifestItem struct {
+	Identifier    string         `xml:"identifier,attr"`
+	IdentifierRef string         `xml:"identifierref,attr,omitempty"`
+	Title         string         `xml:"title"`
+	Items         []manifestItem `xml:"item"`
+}
+
+type manifestResourceList struct {
+	Items []manifestResource `xml:"resource"`
+}
+
+type manifestResource struct {
+	Identifier string         `xml:"identifier,attr"`
+	Type       string         `xml:"type,attr"`
+	Href       string         `xml:"href,attr,omitempty"`
+	Files      []manifestFile `xml:"file"`
+}
+
+type manifestFile struct {
+	Href string `xml:"href,attr"`
+}
+
+// webLink - ресурс IMS CC с внешней ссылкой
+type webLink struct {
+	XMLName xml.Name `xml:"webLink"`
+	Xmlns   string   `xml:"xmlns,attr,omitempty"`
+	Title   string   `xml:"title"`
+	URL     struct {
+		Href string `xml:"href,attr"`
+	} `xml:"url"`
+}
+
+// writeManifest - imsmanifest.xml (IMS CC 1.1) с HTML-файлами уроков и веб-ссылками на видео
+func writeManifest(archive *zip.Writer, pkg coursePackage) error {
+	doc := manifest{
+		Identifier: "hired-valley-course",
+		Xmlns:      "http://www.imsglobal.org/xsd/imsccv1p1/imscp_v1p1",
+		Metadata:   &manifestMetadata{Schema: "IMS Common Cartridge", SchemaVersion: "1.1.0"},
+	}
+	root := manifestItem{Identifier: "root"}
+
+	addLesson := func(parent *manifestItem, lesson packageLesson) error {
+		id := sanitizeIdentifier(lesson.Key)
+		href := "lessons/" + id + ".html"
+		page := fmt.Sprintf("<!DOCTYPE html>\n<html><head><meta charset=\"utf-8\"><title>%s</title></head><body>\n%s\n</body></html>\n",
+			html.EscapeString(lesson.Title), lesson.Content)
+		file, err := archive.Create(href)
+		if err != nil {
+			return err
+		}
+		if _, err := file.Write([]byte(page)); err != nil {
+			return err
+		}
+		parent.Items = append(parent.Items, manifestItem{Identifier: "item-" + id, IdentifierRef: "res-" + id, Title: lesson.Title})
+		doc.Resources.Items = append(doc.Resources.Items, manifestResource{
+			Identifier: "res-" + id, Type: "webcontent", Href: href, Files: []manifestFile{{Href: href}},
+		})
+
+		if lesson.VideoURL == "" {
+			return nil
+		}
+		linkHref := "links/" + id + ".xml"
+		link := webLink{Xmlns: "http://www.imsglobal.org/xsd/imsccv1p1/imswl_v1p1", Title: lesson.Title + " (video)"}
+		link.URL.Href = lesson.VideoURL
+		content, err := xml.MarshalIndent(link, "", "  ")
+		if err != nil {
+			return err
+		}
+		if file, err = archive.Create(linkHref); err != nil {
+			return err
+		}
+		if _, err := file.Write(append([]byte(xml.Header), content...)); err != nil {
+			return err
+		}
+		parent.Items = append(parent.Items, manifestItem{Identifier: "item-" + id + "-video", IdentifierRef: "res-" + id + "-video", Title: link.Title})
+		doc.Resources.Items = append(doc.Resources.Items, manifestResource{
+			Identifier: "res-" + id + "-video", Type: webLinkType, Files: []manifestFile{{Href: linkHref}},
+		})
+		return nil
+	}
+
+	for i, module := range pkg.Course.Modules {
+		item := manifestItem{Identifier: fmt.Sprintf("module-%d", i+1), Title: module.Title}
+		for _, lesson := range module.Lessons {
+			if err := addLesson(&item, lesson); err != nil {
+				return err
+			}
+		}
+		root.Items = append(root.Items, item)
+	}
+	for _, lesson := range pkg.Course.Lessons {
+		if err := addLesson(&root, lesson); err != nil {
+			return err
+		}
+	}
+	doc.Organizations.Items = []manifestOrganization{{
+		Identifier: "organization", Structure: "rooted-hierarchy", Title: pkg.Course.Title, Items: []manifestItem{root},
+	}}
+
+	content, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	file, err := archive.Create(manifestFileName)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(append([]byte(xml.Header), content...))
+	return err
+}
+
+// readManifestPackage - пакет курса из манифеста IMS CC или SCORM
+func readManifestPackage(file *zip.File, files map[string]*zip.File) (coursePackage, []packageIssue) {
+	pkg := coursePackage{Format: packageFormat, Version: packageFormatVersion}
+	var issues []packageIssue
+
+	content, err := readZipFile(file)
+	if err != nil {
+		return pkg, []packageIssue{{Path: manifestFileName, Message: err.Error()}}
+	}
+	var doc manifest
+	if err := xml.Unmarshal(content, &doc); err != nil {
+		return pkg, []packageIssue{{Path: manifestFileName, Message: "invalid XML: " + err.Error()}}
+	}
+	if len(doc.Organizations.Items) == 0 {
+		return pkg, []packageIssue{{Path: manifestFileName, Message: "manifest has no organization"}}
+	}
+
+	resources := map[string]manifestResource{}
+	for _, resource := range doc.Resources.Items {
+		resources[resource.Identifier] = resource
+	}
+
+	organization := doc.Organizations.Items[0]
+	pkg.Course.Title = strings.TrimSpace(organization.Title)
+	items := organization.Items
+	// В IMS CC уроки вложены в единственный корневой элемент без ресурса
+	if len(items) == 1 && items[0].IdentifierRef == "" && len(items[0].Items) > 0 {
+		if pkg.Course.Title == "" {
+			pkg.Course.Title = strings.TrimSpace(items[0].Title)
+		}
+		items = items[0].Items
+	}
+	if pkg.Course.Title == "" {
+		pkg.Course.Title = "Imported course"
+	}
+
+	// readLessons - уроки из элементов; веб-ссылка сразу после урока становится его видео
+	readLessons := func(itemPath string, children []manifestItem) []packageLesson {
+		var lessons []packageLesson
+		for i, item := range children {
+			itemPath := fmt.Sprintf("%s.item[%s]", itemPath, item.Identifier)
+			if len(item.Items) > 0 {
+				issues = append(issues, packageIssue{Path: itemPath, Message: fmt.Sprintf("nesting deeper than modules is not supported (item %d)", i+1)})
+				continue
+			}
+			resource, ok := resources[item.IdentifierRef]
+			if !ok {
+				issues = append(issues, packageIssue{Path: itemPath, Message: fmt.Sprintf("unknown resource %q", item.IdentifierRef)})
+				continue
+			}
+
+			if resource.Type == webLinkType {
+				href, err := readWebLink(resource, files)
+				if err != nil {
+					issues = append(issues, packageIssue{Path: itemPath, Message: err.Error()})
+					continue
+				}
+				if len(lessons) > 0 && lessons[len(lessons)-1].VideoURL == "" {
+					lessons[len(lessons)-1].VideoURL = href
+				} else {
+					lessons = append(lessons, packageLesson{Key: item.Identifier, Title: strings.TrimSpace(item.Title), VideoURL: href})
+				}
+				continue
+			}
+
+			body, err := readResourceHTML(resource, files)
+			if err != nil {
+				issues = append(issues, packageIssue{Path: itemPath, Message: err.Error()})
+				continue
+			}
+			lessons = append(lessons, packageLesson{Key: item.Identifier, Title: strings.TrimSpace(item.Title), Content: body})
+		}
+		return lessons
+	}
+
+	for _, item := range items {
+		if len(item.Items) > 0 {
+			pkg.Course.Modules = append(pkg.Course.Modules, packageModule{
+				Title:   item.Title,
+				Lessons: readLessons("organization.item["+item.Identifier+"]", item.Items),
+			})
+			continue
+		}
+		pkg.Course.Lessons = append(pkg.Course.Lessons, readLessons("organization", []manifestItem{item})...)
+	}
+	return pkg, issues
+}
+
+var htmlBody = regexp.MustCompile(`(?is)<body[^>]*>(.*)</body>`)
+
+// readResourceHTML - содержимое body HTML-страницы ресурса
+func readResourceHTML(resource manifestResource, files map[string]*zip.File) (string, error) {
+	href := resource.Href
+	if href == "" && len(resource.Files) > 0 {
+		href = resource.Files[0].Href
+	}
+	if href == "" {
+		return "", fmt.Errorf("resource %q has no file", resource.Identifier)
+	}
+	file, ok := files[path.Clean(href)]
+	if !ok {
+		return "", fmt.Errorf("file %q is missing from the archive", href)
+	}
+	content, err := readZipFile(file)
+	if err != nil {
+		return "", err
+	}
+	if match := htmlBody.FindSubmatch(content); match != nil {
+		content = match[1]
+	}
+	return strings.TrimSpace(string(content)), nil
+}
+
+// readWebLink - адрес из ресурса веб-ссылки IMS CC
+func readWebLink(resource manifestResource, files map[string]*zip.File) (string, error) {
+	if len(resource.Files) == 0 {
+		return "", fmt.Errorf("web link %q has no file", resource.Identifier)
+	}
+	file, ok := files[path.Clean(resource.Files[0].Href)]
+	if !ok {
+		return "", fmt.Errorf("file %q is missing from the archive", resource.Files[0].Href)
+	}
+	content, err := readZipFile(file)
+	if err != nil {
+		return "", err
+	}
+	var link webLink
+	if err := xml.Unmarshal(content, &link); err != nil || link.URL.Href == "" {
+		return "", fmt.Errorf("web link %q has no URL", resource.Identifier)
+	}
+	return link.URL.Href, nil
+}
+
+var identifierChars = regexp.MustCompile(`[^A-Za-z0-9_.-]`)
+
+// sanitizeIdentifier - ключ урока, пригодный для идентификаторов манифеста и имен файлов
+func sanitizeIdentifier(key string) string {
+	return identifierChars.ReplaceAllString(key, "_")
+}
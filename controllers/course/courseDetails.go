@@ -0,0 +1,16 @@
+package course
+
+import (
+	"hired-valley-backend/models/analytics"
+	"hired-valley-backend/models/courses"
+	"hired-valley-backend/services"
+)
+
+// courseService - общие правила доступа к курсам (статусы и версии) для обработчиков пакета
+var courseService = services.NewCourseService(nil)
+
+// DescribeCourse - карточка курса для API: учет просмотра в аналитике инструктора и программа курса
+func DescribeCourse(course *courses.Course, viewerID uint) error {
+	services.RecordView(analytics.ItemCourse, course.ID, viewerID)
+	return loadCurriculum(course, viewerID)
+}
@@ -0,0 +1,596 @@
+package course
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"gorm.io/gorm"
+	"hired-valley-backend/config"
+	"hired-valley-backend/controllers/authentication"
+	"hired-valley-backend/models/courses"
+	"hired-valley-backend/services"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Переносимый формат курса (описание - docs/course-package.md)
+const (
+	packageFormat        = "hired-valley-course"
+	packageFormatVersion = 1
+	packageFileName      = "course.json"
+	maxPackageSize       = 50 << 20
+)
+
+// coursePackage - курс с программой и тестами для экспорта и импорта
+type coursePackage struct {
+	Format     string        `json:"format"`
+	Version    int           `json:"version"`
+	ExportedAt *time.Time    `json:"exported_at,omitempty"`
+	Course     packageCourse `json:"course"`
+}
+
+type packageCourse struct {
+	Title       string          `json:"title"`
+	Description string          `json:"description"`
+	Price       float64         `json:"price"`
+	Tags        []string        `json:"tags"`
+	Modules     []packageModule `json:"modules"`
+	Lessons     []packageLesson `json:"lessons"` // Уроки вне разделов
+}
+
+type packageModule struct {
+	Title   string          `json:"title"`
+	Lessons []packageLesson `json:"lessons"`
+}
+
+// packageLesson - урок; key связывает уроки в пререквизитах и уникален в пределах пакета
+type packageLesson struct {
+	Key           string       `json:"key"`
+	Title         string       `json:"title"`
+	Content       string       `json:"content"`
+	VideoURL      string       `json:"video_url,omitempty"`
+	DripDays      int          `json:"drip_days,omitempty"`
+	Preview       bool         `json:"preview,omitempty"` // Бесплатный ознакомительный урок
+	Prerequisites []string     `json:"prerequisites,omitempty"`
+	Quiz          *packageQuiz `json:"quiz,omitempty"`
+}
+
+// packageQuiz - тест урока вместе с правильными ответами
+type packageQuiz struct {
+	Title               string              `json:"title"`
+	PassingScore        int                 `json:"passing_score"`
+	MaxAttempts         int                 `json:"max_attempts"`
+	QuestionsPerAttempt int                 `json:"questions_per_attempt"`
+	Questions           []quizQuestionInput `json:"questions"`
+}
+
+// packageIssue - ошибка проверки конкретного элемента пакета
+type packageIssue struct {
+	Path    string `json:"path"` // Например, course.modules[0].lessons[2].quiz
+	Message string `json:"message"`
+}
+
+// buildCoursePackage - выгрузка курса со всеми разделами, уроками, пререквизитами и тестами
+func buildCoursePackage(course courses.Course) (coursePackage, error) {
+	now := time.Now()
+	pkg := coursePackage{
+		Format:     packageFormat,
+		Version:    packageFormatVersion,
+		ExportedAt: &now,
+		Course: packageCourse{
+			Title:       course.Title,
+			Description: course.Description,
+			Price:       course.Price,
+			Tags:        course.Tags,
+			Modules:     []packageModule{},
+			Lessons:     []packageLesson{},
+		},
+	}
+
+	var modules []courses.CourseModule
+	if err := config.DB.Where("course_id = ?", course.ID).Order("position, id").Find(&modules).Error; err != nil {
+		return pkg, err
+	}
+	moduleIndex := map[uint]int{}
+	for i, module := range modules {
+		moduleIndex[module.ID] = i
+		pkg.Course.Modules = append(pkg.Course.Modules, packageModule{Title: module.Title, Lessons: []packageLesson{}})
+	}
+
+	lessons, err := orderedLessons(course.ID)
+	if err != nil {
+		return pkg, err
+	}
+	keys := map[uint]string{}
+	for i, lesson := range lessons {
+		keys[lesson.ID] = fmt.Sprintf("lesson-%d", i+1)
+	}
+
+	var links []courses.LessonPrerequisite
+	var quizzes []courses.Quiz
+	if len(lessons) > 0 {
+		lessonIDs := config.DB.Model(&courses.Lesson{}).Select("id").Where("course_id = ?", course.ID)
+		if err := config.DB.Where("lesson_id IN (?)", lessonIDs).Find(&links).Error; err != nil {
+			return pkg, err
+		}
+		if err := config.DB.Preload("Questions", func(db *gorm.DB) *gorm.DB { return db.Order("position") }).
+			Where("lesson_id IN (?)", lessonIDs).Find(&quizzes).Error; err != nil {
+			return pkg, err
+		}
+	}
+	prerequisites := map[uint][]string{}
+	for _, link := range links {
+		prerequisites[link.LessonID] = append(prerequisites[link.LessonID], keys[link.PrerequisiteID])
+	}
+	lessonQuizzes := map[uint]*packageQuiz{}
+	for _, quiz := range quizzes {
+		input := toQuizInput(quiz)
+		for i := range input.Questions {
+			input.Questions[i].ID = 0
+		}
+		lessonQuizzes[quiz.LessonID] = &packageQuiz{
+			Title:               input.Title,
+			PassingScore:        input.PassingScore,
+			MaxAttempts:         input.MaxAttempts,
+			QuestionsPerAttempt: input.QuestionsPerAttempt,
+			Questions:           input.Questions,
+		}
+	}
+
+	for _, lesson := range lessons {
+		item := packageLesson{
+			Key:           keys[lesson.ID],
+			Title:         lesson.Title,
+			Content:       lesson.Content,
+			VideoURL:      lesson.VideoLink,
+			DripDays:      lesson.DripDays,
+			Preview:       lesson.IsPreview,
+			Prerequisites: prerequisites[lesson.ID],
+			Quiz:          lessonQuizzes[lesson.ID],
+		}
+		if services.IsSelfHostedVideoLink(lesson.VideoLink) {
+			item.VideoURL = "" // Файл из собственного хранилища не переносится, видео загружается заново
+		}
+		if lesson.ModuleID != nil {
+			if i, ok := moduleIndex[*lesson.ModuleID]; ok {
+				pkg.Course.Modules[i].Lessons = append(pkg.Course.Modules[i].Lessons, item)
+				continue
+			}
+		}
+		pkg.Course.Lessons = append(pkg.Course.Lessons, item)
+	}
+	return pkg, nil
+}
+
+// validateCoursePackage - проверка пакета перед импортом; возвращает все найденные ошибки
+func validateCoursePackage(pkg *coursePackage) []packageIssue {
+	issues := []packageIssue{}
+	add := func(path, format string, args ...interface{}) {
+		issues = append(issues, packageIssue{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if pkg.Format != packageFormat {
+		add("format", "must be %q", packageFormat)
+	}
+	if pkg.Version != packageFormatVersion {
+		add("version", "unsupported version %d, expected %d", pkg.Version, packageFormatVersion)
+	}
+
+	course := &pkg.Course
+	course.Title = strings.TrimSpace(course.Title)
+	if course.Title == "" {
+		add("course.title", "title is required")
+	}
+	if course.Price < 0 {
+		add("course.price", "price cannot be negative")
+	}
+	if len(splitTags(strings.Join(course.Tags, ","))) == 0 {
+		add("course.tags", "at least one tag is required")
+	}
+
+	// Сначала собираем ключи всех уроков, затем проверяем ссылки на них
+	type lessonRef struct {
+		path   string
+		lesson *packageLesson
+	}
+	var all []lessonRef
+	for i := range course.Modules {
+		module := &course.Modules[i]
+		module.Title = strings.TrimSpace(module.Title)
+		if module.Title == "" {
+			add(fmt.Sprintf("course.modules[%d].title", i), "title is required")
+		}
+		for j := range module.Lessons {
+			all = append(all, lessonRef{fmt.Sprintf("course.modules[%d].lessons[%d]", i, j), &module.Lessons[j]})
+		}
+	}
+	for i := range course.Lessons {
+		all = append(all, lessonRef{fmt.Sprintf("course.lessons[%d]", i), &course.Lessons[i]})
+	}
+
+	keys := map[string]bool{}
+	for i, ref := range all {
+		lesson := ref.lesson
+		lesson.Key = strings.TrimSpace(lesson.Key)
+		if lesson.Key == "" {
+			lesson.Key = fmt.Sprintf("lesson-%d", i+1)
+		}
+		if keys[lesson.Key] {
+			add(ref.path+".key", "duplicate key %q", lesson.Key)
+		}
+		keys[lesson.Key] = true
+	}
+
+	graph := map[string][]string{}
+	for _, ref := range all {
+		lesson := ref.lesson
+		lesson.Title = strings.TrimSpace(lesson.Title)
+		if lesson.Title == "" {
+			add(ref.path+".title", "title is required")
+		}
+		if lesson.DripDays < 0 {
+			add(ref.path+".drip_days", "drip_days cannot be negative")
+		}
+		if lesson.VideoURL != "" {
+			if parsed, err := url.Parse(lesson.VideoURL); err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+				add(ref.path+".video_url", "must be an absolute http(s) URL")
+			}
+		}
+		for _, key := range lesson.Prerequisites {
+			switch {
+			case key == lesson.Key:
+				add(ref.path+".prerequisites", "lesson cannot require itself")
+			case !keys[key]:
+				add(ref.path+".prerequisites", "unknown lesson key %q", key)
+			default:
+				graph[lesson.Key] = append(graph[lesson.Key], key)
+			}
+		}
+		if lesson.Quiz != nil {
+			input := quizInput{
+				Title:               lesson.Quiz.Title,
+				PassingScore:        lesson.Quiz.PassingScore,
+				MaxAttempts:         lesson.Quiz.MaxAttempts,
+				QuestionsPerAttempt: lesson.Quiz.QuestionsPerAttempt,
+				Questions:           lesson.Quiz.Questions,
+			}
+			if err := validateQuiz(&input); err != nil {
+				add(ref.path+".quiz", "%s", err.Error())
+			} else {
+				lesson.Quiz.Title = input.Title
+				lesson.Quiz.PassingScore = input.PassingScore
+				lesson.Quiz.Questions = input.Questions
+			}
+		}
+	}
+
+	for _, ref := range all {
+		if reachesKey(graph, graph[ref.lesson.Key], ref.lesson.Key, map[string]bool{}) {
+			add(ref.path+".prerequisites", "prerequisites form a cycle")
+		}
+	}
+	return issues
+}
+
+// reachesKey - достижим ли урок target по цепочке пререквизитов
+func reachesKey(graph map[string][]string, next []string, target string, seen map[string]bool) bool {
+	for _, key := range next {
+		if key == target {
+			return true
+		}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		if reachesKey(graph, graph[key], target, seen) {
+			return true
+		}
+	}
+	return false
+}
+
+// importCoursePackage - создание черновика курса из проверенного пакета
+func importCoursePackage(pkg coursePackage, instructorID uint) (*courses.Course, error) {
+	course := &courses.Course{
+		Title:        pkg.Course.Title,
+		Description:  pkg.Course.Description,
+		Price:        pkg.Course.Price,
+		InstructorID: instructorID,
+		Tags:         splitTags(strings.Join(pkg.Course.Tags, ",")),
+		Status:       courses.CourseStatusDraft,
+		Version:      1,
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Lessons", "Modules").Create(course).Error; err != nil {
+			return err
+		}
+
+		lessonIDs := map[string]uint{}
+		var created []packageLesson
+		createLessons := func(moduleID *uint, items []packageLesson) error {
+			for i, item := range items {
+				lesson := courses.Lesson{
+					Title:        item.Title,
+					Content:      item.Content,
+					VideoLink:    item.VideoURL,
+					CourseID:     course.ID,
+					ModuleID:     moduleID,
+					Position:     i + 1,
+					DripDays:     item.DripDays,
+					IsPreview:    item.Preview,
+					InstructorID: instructorID,
+				}
+				if err := tx.Create(&lesson).Error; err != nil {
+					return err
+				}
+				lessonIDs[item.Key] = lesson.ID
+				created = append(created, item)
+			}
+			return nil
+		}
+
+		for i, item := range pkg.Course.Modules {
+			module := courses.CourseModule{CourseID: course.ID, Title: item.Title, Position: i + 1}
+			if err := tx.Omit("Lessons").Create(&module).Error; err != nil {
+				return err
+			}
+			if err := createLessons(&module.ID, item.Lessons); err != nil {
+				return err
+			}
+		}
+		if err := createLessons(nil, pkg.Course.Lessons); err != nil {
+			return err
+		}
+
+		for _, item := range created {
+			for _, key := range item.Prerequisites {
+				link := courses.LessonPrerequisite{LessonID: lessonIDs[item.Key], PrerequisiteID: lessonIDs[key]}
+				if err := tx.Create(&link).Error; err != nil {
+					return err
+				}
+			}
+			if item.Quiz == nil {
+				continue
+			}
+			quiz := courses.Quiz{
+				LessonID:            lessonIDs[item.Key],
+				Title:               item.Quiz.Title,
+				PassingScore:        item.Quiz.PassingScore,
+				MaxAttempts:         item.Quiz.MaxAttempts,
+				QuestionsPerAttempt: item.Quiz.QuestionsPerAttempt,
+			}
+			if err := tx.Create(&quiz).Error; err != nil {
+				return err
+			}
+			for i, question := range item.Quiz.Questions {
+				record := courses.QuizQuestion{
+					QuizID:          quiz.ID,
+					Type:            question.Type,
+					Text:            question.Text,
+					Options:         question.Options,
+					CorrectOptions:  question.CorrectOptions,
+					AcceptedAnswers: question.AcceptedAnswers,
+					Points:          question.Points,
+					Explanation:     question.Explanation,
+					Position:        i + 1,
+				}
+				if err := tx.Create(&record).Error; err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return course, nil
+}
+
+// ExportCourseHandler - выгрузка курса инструктором или администратором: format=json (по умолчанию) или zip
+func ExportCourseHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user, err := authentication.ValidateToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	courseID, err := strconv.Atoi(r.URL.Query().Get("course_id"))
+	if err != nil || courseID <= 0 {
+		http.Error(w, "Invalid course ID", http.StatusBadRequest)
+		return
+	}
+	var course courses.Course
+	if err := config.DB.First(&course, courseID).Error; err != nil {
+		http.Error(w, "Course not found", http.StatusNotFound)
+		return
+	}
+	if !services.HasCoursePermission(user, &course, courses.PermissionEditLessons) {
+		http.Error(w, "Permission denied", http.StatusForbidden)
+		return
+	}
+
+	pkg, err := buildCoursePackage(course)
+	if err != nil {
+		http.Error(w, "Failed to export course", http.StatusInternalServerError)
+		return
+	}
+
+	fileName := fmt.Sprintf("course-%d", course.ID)
+	switch r.URL.Query().Get("format") {
+	case "", "json":
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s.json\"", fileName))
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(pkg)
+	case "zip":
+		archive, err := writePackageZip(pkg)
+		if err != nil {
+			http.Error(w, "Failed to export course", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/zip")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s.zip\"", fileName))
+		w.Write(archive)
+	default:
+		http.Error(w, "Invalid format. Use json or zip", http.StatusBadRequest)
+	}
+}
+
+// ImportCourseHandler - импорт курса из JSON, ZIP-пакета или манифеста IMS Common Cartridge / SCORM.
+// Курс создается черновиком текущего ментора; dry_run=true - только проверка.
+func ImportCourseHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user, err := authentication.ValidateToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	if user.Role != "mentor" {
+		http.Error(w, "Only mentors can create courses", http.StatusForbidden)
+		return
+	}
+
+	// Файл передается телом запроса или полем package формы multipart/form-data
+	var body io.Reader = http.MaxBytesReader(w, r.Body, maxPackageSize)
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		r.Body = http.MaxBytesReader(w, r.Body, maxPackageSize)
+		file, _, err := r.FormFile("package")
+		if err != nil {
+			http.Error(w, "Package file is required", http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+		body = file
+	}
+	data, err := io.ReadAll(body)
+	if err != nil {
+		http.Error(w, "Failed to read package", http.StatusBadRequest)
+		return
+	}
+
+	pkg, issues := readCoursePackage(data, splitTags(r.URL.Query().Get("tags")))
+	if len(issues) == 0 {
+		issues = validateCoursePackage(&pkg)
+	}
+	if len(issues) > 0 {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(map[string]interface{}{"errors": issues})
+		return
+	}
+
+	if r.URL.Query().Get("dry_run") == "true" {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"valid": true, "package": pkg})
+		return
+	}
+
+	course, err := importCoursePackage(pkg, user.ID)
+	if err != nil {
+		http.Error(w, "Failed to import course", http.StatusInternalServerError)
+		return
+	}
+	if err := loadCurriculum(course, user.ID); err != nil {
+		http.Error(w, "Failed to load curriculum", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(course)
+}
+
+// readCoursePackage - разбор JSON или ZIP; для ZIP без course.json читается imsmanifest.xml.
+// tags - теги курса, если их нет в самом пакете (манифесты их не содержат)
+func readCoursePackage(data []byte, tags []string) (coursePackage, []packageIssue) {
+	var pkg coursePackage
+	if !bytes.HasPrefix(data, []byte("PK")) {
+		if err := json.Unmarshal(data, &pkg); err != nil {
+			return pkg, []packageIssue{{Path: "", Message: "invalid JSON: " + err.Error()}}
+		}
+		return withDefaultTags(pkg, tags), nil
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return pkg, []packageIssue{{Path: "", Message: "invalid ZIP archive: " + err.Error()}}
+	}
+	files := map[string]*zip.File{}
+	for _, file := range archive.File {
+		files[strings.TrimPrefix(file.Name, "./")] = file
+	}
+
+	if file, ok := files[packageFileName]; ok {
+		content, err := readZipFile(file)
+		if err != nil {
+			return pkg, []packageIssue{{Path: packageFileName, Message: err.Error()}}
+		}
+		if err := json.Unmarshal(content, &pkg); err != nil {
+			return pkg, []packageIssue{{Path: packageFileName, Message: "invalid JSON: " + err.Error()}}
+		}
+		return withDefaultTags(pkg, tags), nil
+	}
+	if file, ok := files[manifestFileName]; ok {
+		pkg, issues := readManifestPackage(file, files)
+		return withDefaultTags(pkg, tags), issues
+	}
+	return pkg, []packageIssue{{Path: "", Message: "archive must contain " + packageFileName + " or " + manifestFileName}}
+}
+
+func withDefaultTags(pkg coursePackage, tags []string) coursePackage {
+	if len(pkg.Course.Tags) == 0 {
+		pkg.Course.Tags = tags
+	}
+	return pkg
+}
+
+// readZipFile - содержимое файла архива
+func readZipFile(file *zip.File) ([]byte, error) {
+	reader, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return io.ReadAll(io.LimitReader(reader, maxPackageSize))
+}
+
+// writePackageZip - ZIP с course.json и манифестом IMS Common Cartridge для других LMS
+func writePackageZip(pkg coursePackage) ([]byte, error) {
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+
+	file, err := archive.Create(packageFileName)
+	if err != nil {
+		return nil, err
+	}
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(pkg); err != nil {
+		return nil, err
+	}
+
+	if err := writeManifest(archive, pkg); err != nil {
+		return nil, err
+	}
+	if err := archive.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
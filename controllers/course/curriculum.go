@@ -0,0 +1,629 @@
+package course
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"hired-valley-backend/config"
+	"hired-valley-backend/controllers/authentication"
+	"hired-valley-backend/models/courses"
+	"hired-valley-backend/models/users"
+	"hired-valley-backend/services"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// lessonGate - расписание и пререквизиты уроков курса для конкретного пользователя
+type lessonGate struct {
+	instructor    bool
+	preview       bool       // Ознакомительные уроки открыты без записи (только у опубликованного курса)
+	enrolledAt    *time.Time // nil - пользователь не записан на курс
+	completed     map[uint]bool
+	prerequisites map[uint][]uint
+}
+
+// newLessonGate - загрузка записи на курс, пройденных уроков и пререквизитов
+func newLessonGate(userID uint, course courses.Course) (*lessonGate, error) {
+	gate := &lessonGate{
+		instructor:    services.IsCourseTeamMember(userID, &course),
+		preview:       course.Status == courses.CourseStatusPublished,
+		completed:     map[uint]bool{},
+		prerequisites: map[uint][]uint{},
+	}
+
+	var links []courses.LessonPrerequisite
+	if err := config.DB.Where("lesson_id IN (?)", config.DB.Model(&courses.Lesson{}).Select("id").Where("course_id = ?", course.ID)).
+		Find(&links).Error; err != nil {
+		return nil, err
+	}
+	for _, link := range links {
+		gate.prerequisites[link.LessonID] = append(gate.prerequisites[link.LessonID], link.PrerequisiteID)
+	}
+
+	if userID == 0 || gate.instructor {
+		return gate, nil
+	}
+
+	var enrollment courses.Enrollment
+	if err := config.DB.Where("user_id = ? AND course_id = ?", userID, course.ID).First(&enrollment).Error; err == nil {
+		gate.enrolledAt = &enrollment.CreatedAt
+	}
+
+	var completed []uint
+	if err := config.DB.Model(&courses.Progress{}).Where("user_id = ? AND course_id = ? AND completed = ?", userID, course.ID, true).
+		Pluck("lesson_id", &completed).Error; err != nil {
+		return nil, err
+	}
+	for _, id := range completed {
+		gate.completed[id] = true
+	}
+	return gate, nil
+}
+
+// check - заполняет Locked/UnlockAt/PrerequisiteIDs урока и возвращает причину блокировки ("" - урок открыт)
+func (g *lessonGate) check(lesson *courses.Lesson) string {
+	lesson.PrerequisiteIDs = g.prerequisites[lesson.ID]
+	if g.instructor {
+		return ""
+	}
+	if lesson.IsPreview && (g.preview || g.enrolledAt != nil) {
+		return ""
+	}
+	if g.enrolledAt == nil {
+		lesson.Locked = true
+		return "Enroll in the course to access its lessons"
+	}
+
+	if lesson.DripDays > 0 {
+		unlockAt := g.enrolledAt.AddDate(0, 0, lesson.DripDays)
+		if time.Now().Before(unlockAt) {
+			lesson.Locked = true
+			lesson.UnlockAt = &unlockAt
+			return fmt.Sprintf("Lesson unlocks at %s", unlockAt.Format(time.RFC3339))
+		}
+	}
+
+	for _, id := range lesson.PrerequisiteIDs {
+		if !g.completed[id] {
+			lesson.Locked = true
+			return "Complete the prerequisite lessons first"
+		}
+	}
+	return ""
+}
+
+// hideLocked - скрытие содержимого недоступных уроков; видео доступных из собственного хранилища
+// получают подписанные ссылки
+func (g *lessonGate) hideLocked(lessons []courses.Lesson) {
+	for i := range lessons {
+		if g.check(&lessons[i]) != "" {
+			lessons[i].Content = ""
+			lessons[i].VideoLink = ""
+			continue
+		}
+		lessons[i].VideoLink = services.SignVideoLink(lessons[i].VideoLink)
+	}
+}
+
+// loadCurriculum - программа курса: разделы с уроками по порядку и уроки вне разделов
+func loadCurriculum(course *courses.Course, userID uint) error {
+	gate, err := newLessonGate(userID, *course)
+	if err != nil {
+		return err
+	}
+
+	if err := config.DB.Where("course_id = ?", course.ID).Order("position, id").
+		Preload("Lessons", func(db *gorm.DB) *gorm.DB { return db.Order("position, id") }).
+		Find(&course.Modules).Error; err != nil {
+		return err
+	}
+	for i := range course.Modules {
+		gate.hideLocked(course.Modules[i].Lessons)
+	}
+
+	if err := config.DB.Where("course_id = ? AND module_id IS NULL", course.ID).Order("position, id").
+		Find(&course.Lessons).Error; err != nil {
+		return err
+	}
+	gate.hideLocked(course.Lessons)
+	return nil
+}
+
+// teamCourse - курс, в котором у текущего пользователя есть право permission; при ошибке ответ уже отправлен
+func teamCourse(w http.ResponseWriter, user *users.User, courseIDStr string, permission string) (courses.Course, bool) {
+	var course courses.Course
+	courseID, err := strconv.Atoi(courseIDStr)
+	if err != nil || courseID <= 0 {
+		http.Error(w, "Invalid course ID", http.StatusBadRequest)
+		return course, false
+	}
+	if err := config.DB.First(&course, courseID).Error; err != nil {
+		http.Error(w, "Course not found", http.StatusNotFound)
+		return course, false
+	}
+	if !services.HasCoursePermission(user, &course, permission) {
+		http.Error(w, "Permission denied", http.StatusForbidden)
+		return course, false
+	}
+	return course, true
+}
+
+// editableCourse - черновик курса, программу которого может менять текущий пользователь; опубликованные
+// версии меняются только через черновик новой версии. При ошибке ответ уже отправлен
+func editableCourse(w http.ResponseWriter, user *users.User, courseIDStr string) (courses.Course, bool) {
+	course, ok := teamCourse(w, user, courseIDStr, courses.PermissionEditLessons)
+	if !ok {
+		return course, false
+	}
+	if course.Status != courses.CourseStatusDraft {
+		http.Error(w, "Only drafts can be edited, create a draft version of the course", http.StatusConflict)
+		return course, false
+	}
+	return course, true
+}
+
+// sectionLessons - уроки раздела курса; moduleID nil - уроки вне разделов
+func sectionLessons(db *gorm.DB, courseID uint, moduleID *uint) *gorm.DB {
+	query := db.Model(&courses.Lesson{}).Where("course_id = ?", courseID)
+	if moduleID == nil {
+		return query.Where("module_id IS NULL")
+	}
+	return query.Where("module_id = ?", *moduleID)
+}
+
+// nextLessonPosition - позиция для нового урока в конце раздела
+func nextLessonPosition(courseID uint, moduleID *uint) int {
+	var maxPosition int
+	sectionLessons(config.DB, courseID, moduleID).Select("COALESCE(MAX(position), 0)").Scan(&maxPosition)
+	return maxPosition + 1
+}
+
+// renumberLessons - сплошная нумерация уроков раздела с позиции first в текущем порядке, кроме уроков skip
+func renumberLessons(tx *gorm.DB, courseID uint, moduleID *uint, first int, skip []uint) error {
+	query := sectionLessons(tx, courseID, moduleID)
+	if len(skip) > 0 {
+		query = query.Where("id NOT IN ?", skip)
+	}
+	var ids []uint
+	if err := query.Order("position, id").Pluck("id", &ids).Error; err != nil {
+		return err
+	}
+	for i, id := range ids {
+		if err := tx.Model(&courses.Lesson{}).Where("id = ?", id).Update("position", first+i).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateLessonModule - раздел урока должен принадлежать тому же курсу
+func validateLessonModule(lesson courses.Lesson) error {
+	if lesson.ModuleID == nil {
+		return nil
+	}
+	var module courses.CourseModule
+	if err := config.DB.First(&module, *lesson.ModuleID).Error; err != nil || module.CourseID != lesson.CourseID {
+		return fmt.Errorf("module does not belong to the course")
+	}
+	return nil
+}
+
+// CourseModulesHandler - GET: программа курса, POST: создание раздела
+func CourseModulesHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		courseID, err := strconv.Atoi(r.URL.Query().Get("course_id"))
+		if err != nil || courseID <= 0 {
+			http.Error(w, "Invalid course ID", http.StatusBadRequest)
+			return
+		}
+		var viewer *users.User
+		var viewerID uint
+		if user, err := authentication.ValidateToken(r); err == nil {
+			viewer, viewerID = user, user.ID
+		}
+		course, err := courseService.Get(viewer, uint(courseID))
+		if err != nil {
+			http.Error(w, "Course not found", http.StatusNotFound)
+			return
+		}
+		if err := loadCurriculum(course, viewerID); err != nil {
+			http.Error(w, "Failed to load curriculum", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"modules": course.Modules,
+			"lessons": course.Lessons,
+		})
+
+	case http.MethodPost:
+		user, err := authentication.ValidateToken(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		course, ok := editableCourse(w, user, r.URL.Query().Get("course_id"))
+		if !ok {
+			return
+		}
+
+		var module courses.CourseModule
+		if err := json.NewDecoder(r.Body).Decode(&module); err != nil {
+			http.Error(w, "Invalid input", http.StatusBadRequest)
+			return
+		}
+		module.Title = strings.TrimSpace(module.Title)
+		if module.Title == "" {
+			http.Error(w, "Title is required", http.StatusBadRequest)
+			return
+		}
+
+		var maxPosition int
+		config.DB.Model(&courses.CourseModule{}).Where("course_id = ?", course.ID).Select("COALESCE(MAX(position), 0)").Scan(&maxPosition)
+		module.ID = 0
+		module.CourseID = course.ID
+		module.Position = maxPosition + 1
+		module.Lessons = nil
+		if err := config.DB.Create(&module).Error; err != nil {
+			http.Error(w, "Failed to create module", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(module)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// CourseModuleHandler - PUT: переименование раздела, DELETE: удаление (уроки остаются вне разделов)
+func CourseModuleHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut && r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user, err := authentication.ValidateToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	moduleID, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil || moduleID <= 0 {
+		http.Error(w, "Invalid module ID", http.StatusBadRequest)
+		return
+	}
+
+	var module courses.CourseModule
+	if err := config.DB.First(&module, moduleID).Error; err != nil {
+		http.Error(w, "Module not found", http.StatusNotFound)
+		return
+	}
+	if _, ok := editableCourse(w, user, strconv.Itoa(int(module.CourseID))); !ok {
+		return
+	}
+
+	if r.Method == http.MethodDelete {
+		err := config.DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(&courses.Lesson{}).Where("module_id = ?", module.ID).Update("module_id", nil).Error; err != nil {
+				return err
+			}
+			return tx.Delete(&module).Error
+		})
+		if err != nil {
+			http.Error(w, "Failed to delete module", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	var input struct {
+		Title string `json:"title"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil || strings.TrimSpace(input.Title) == "" {
+		http.Error(w, "Title is required", http.StatusBadRequest)
+		return
+	}
+	module.Title = strings.TrimSpace(input.Title)
+	if err := config.DB.Save(&module).Error; err != nil {
+		http.Error(w, "Failed to update module", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(module)
+}
+
+// ReorderModulesHandler - новый порядок разделов курса (перетаскивание)
+func ReorderModulesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user, err := authentication.ValidateToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	course, ok := editableCourse(w, user, r.URL.Query().Get("course_id"))
+	if !ok {
+		return
+	}
+
+	var input struct {
+		ModuleIDs []uint `json:"module_ids"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+
+	// Список сверяется с разделами курса в той же транзакции, чтобы все разделы получили новые позиции
+	errIncomplete := errors.New("module_ids must list every module of the course exactly once")
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		var existing []uint
+		if err := tx.Model(&courses.CourseModule{}).Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("course_id = ?", course.ID).Pluck("id", &existing).Error; err != nil {
+			return err
+		}
+		if !sameIDs(existing, input.ModuleIDs) {
+			return errIncomplete
+		}
+		for i, id := range input.ModuleIDs {
+			if err := tx.Model(&courses.CourseModule{}).Where("id = ?", id).Update("position", i+1).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if errors.Is(err, errIncomplete) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to reorder modules", http.StatusInternalServerError)
+		return
+	}
+
+	if err := loadCurriculum(&course, user.ID); err != nil {
+		http.Error(w, "Failed to load curriculum", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"modules": course.Modules,
+		"lessons": course.Lessons,
+	})
+}
+
+// ReorderLessonsHandler - порядок уроков внутри раздела; уроки из других разделов переносятся в него
+func ReorderLessonsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user, err := authentication.ValidateToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	course, ok := editableCourse(w, user, r.URL.Query().Get("course_id"))
+	if !ok {
+		return
+	}
+
+	var input struct {
+		ModuleID  *uint  `json:"module_id"` // nil - уроки вне разделов
+		LessonIDs []uint `json:"lesson_ids"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	if err := validateLessonModule(courses.Lesson{CourseID: course.ID, ModuleID: input.ModuleID}); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var count int64
+	config.DB.Model(&courses.Lesson{}).Where("course_id = ? AND id IN ?", course.ID, input.LessonIDs).Count(&count)
+	if len(input.LessonIDs) == 0 || int(count) != len(input.LessonIDs) || !uniqueIDs(input.LessonIDs) {
+		http.Error(w, "lesson_ids must be unique lessons of the course", http.StatusBadRequest)
+		return
+	}
+
+	// Остальные уроки раздела идут следом за перечисленными, а разделы, из которых уроки перенесены,
+	// нумеруются заново, чтобы позиции не совпадали и не оставалось пропусков
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		var moved []courses.Lesson
+		if err := tx.Select("id, module_id").Where("id IN ?", input.LessonIDs).Find(&moved).Error; err != nil {
+			return err
+		}
+		for i, id := range input.LessonIDs {
+			if err := tx.Model(&courses.Lesson{}).Where("id = ?", id).
+				Updates(map[string]interface{}{"module_id": input.ModuleID, "position": i + 1}).Error; err != nil {
+				return err
+			}
+		}
+		if err := renumberLessons(tx, course.ID, input.ModuleID, len(input.LessonIDs)+1, input.LessonIDs); err != nil {
+			return err
+		}
+
+		renumbered := map[uint]bool{}
+		if input.ModuleID != nil {
+			renumbered[*input.ModuleID] = true
+		}
+		outsideModules := input.ModuleID == nil
+		for _, lesson := range moved {
+			if lesson.ModuleID == nil {
+				if outsideModules {
+					continue
+				}
+				outsideModules = true
+			} else {
+				if renumbered[*lesson.ModuleID] {
+					continue
+				}
+				renumbered[*lesson.ModuleID] = true
+			}
+			if err := renumberLessons(tx, course.ID, lesson.ModuleID, 1, nil); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		http.Error(w, "Failed to reorder lessons", http.StatusInternalServerError)
+		return
+	}
+
+	if err := loadCurriculum(&course, user.ID); err != nil {
+		http.Error(w, "Failed to load curriculum", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"modules": course.Modules,
+		"lessons": course.Lessons,
+	})
+}
+
+// LessonPrerequisitesHandler - замена списка уроков, которые нужно пройти до данного
+func LessonPrerequisitesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user, err := authentication.ValidateToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	lessonID, err := strconv.Atoi(r.URL.Query().Get("lesson_id"))
+	if err != nil || lessonID <= 0 {
+		http.Error(w, "Invalid lesson ID", http.StatusBadRequest)
+		return
+	}
+	var lesson courses.Lesson
+	if err := config.DB.First(&lesson, lessonID).Error; err != nil {
+		http.Error(w, "Lesson not found", http.StatusNotFound)
+		return
+	}
+	if _, ok := editableCourse(w, user, strconv.Itoa(int(lesson.CourseID))); !ok {
+		return
+	}
+
+	var input struct {
+		PrerequisiteIDs []uint `json:"prerequisite_ids"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+
+	if len(input.PrerequisiteIDs) > 0 {
+		var count int64
+		config.DB.Model(&courses.Lesson{}).Where("course_id = ? AND id IN ?", lesson.CourseID, input.PrerequisiteIDs).Count(&count)
+		if int(count) != len(input.PrerequisiteIDs) || !uniqueIDs(input.PrerequisiteIDs) {
+			http.Error(w, "Prerequisites must be unique lessons of the same course", http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Пререквизиты не должны образовывать цикл
+	var links []courses.LessonPrerequisite
+	config.DB.Where("lesson_id IN (?) AND lesson_id <> ?",
+		config.DB.Model(&courses.Lesson{}).Select("id").Where("course_id = ?", lesson.CourseID), lesson.ID).Find(&links)
+	graph := map[uint][]uint{lesson.ID: input.PrerequisiteIDs}
+	for _, link := range links {
+		graph[link.LessonID] = append(graph[link.LessonID], link.PrerequisiteID)
+	}
+	if dependsOn(graph, input.PrerequisiteIDs, lesson.ID) {
+		http.Error(w, "Prerequisites would create a cycle", http.StatusBadRequest)
+		return
+	}
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("lesson_id = ?", lesson.ID).Delete(&courses.LessonPrerequisite{}).Error; err != nil {
+			return err
+		}
+		for _, id := range input.PrerequisiteIDs {
+			if err := tx.Create(&courses.LessonPrerequisite{LessonID: lesson.ID, PrerequisiteID: id}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		http.Error(w, "Failed to update prerequisites", http.StatusInternalServerError)
+		return
+	}
+
+	lesson.PrerequisiteIDs = input.PrerequisiteIDs
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(lesson)
+}
+
+// dependsOn - достижим ли target из start по графу пререквизитов
+func dependsOn(graph map[uint][]uint, start []uint, target uint) bool {
+	visited := map[uint]bool{}
+	stack := append([]uint{}, start...)
+	for len(stack) > 0 {
+		id := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if id == target {
+			return true
+		}
+		if visited[id] {
+			continue
+		}
+		visited[id] = true
+		stack = append(stack, graph[id]...)
+	}
+	return false
+}
+
+// sameIDs - одинаковые наборы идентификаторов без повторов
+func sameIDs(expected, actual []uint) bool {
+	if len(expected) != len(actual) {
+		return false
+	}
+	seen := make(map[uint]bool, len(expected))
+	for _, id := range expected {
+		seen[id] = true
+	}
+	used := make(map[uint]bool, len(actual))
+	for _, id := range actual {
+		if !seen[id] || used[id] {
+			return false
+		}
+		used[id] = true
+	}
+	return true
+}
+
+// uniqueIDs - в списке нет повторов
+func uniqueIDs(ids []uint) bool {
+	return sameIDs(ids, ids)
+}
+
+// orderedLessons - уроки курса в порядке программы
+func orderedLessons(courseID uint) ([]courses.Lesson, error) {
+	var lessons []courses.Lesson
+	err := config.DB.Scopes(services.CurriculumOrder).Where("lessons.course_id = ?", courseID).Find(&lessons).Error
+	return lessons, err
+}
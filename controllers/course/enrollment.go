@@ -0,0 +1,297 @@
+package course
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"hired-valley-backend/config"
+	"hired-valley-backend/controllers/authentication"
+	"hired-valley-backend/models/billing"
+	"hired-valley-backend/models/courses"
+	"hired-valley-backend/models/users"
+	"hired-valley-backend/services"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+// lessonAccess - проверка доступа текущего пользователя к уроку с учетом расписания и пререквизитов;
+// ознакомительные уроки доступны и без авторизации. При отказе ответ уже отправлен
+func lessonAccess(w http.ResponseWriter, r *http.Request, lesson courses.Lesson) bool {
+	var userID uint
+	user, err := authentication.ValidateToken(r)
+	if err == nil {
+		userID = user.ID
+	} else if !lesson.IsPreview {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return false
+	}
+
+	var course courses.Course
+	if err := config.DB.First(&course, lesson.CourseID).Error; err != nil {
+		http.Error(w, "Course not found", http.StatusNotFound)
+		return false
+	}
+
+	gate, err := newLessonGate(userID, course)
+	if err != nil {
+		http.Error(w, "Failed to check lesson access", http.StatusInternalServerError)
+		return false
+	}
+	if reason := gate.check(&lesson); reason != "" {
+		http.Error(w, reason, http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// paymentError - ответ на ошибку проверки платежа; false, если ошибки нет
+func paymentError(w http.ResponseWriter, err error) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, services.ErrPaymentsUnavailable):
+		http.Error(w, "Paid purchases are unavailable: "+err.Error(), http.StatusServiceUnavailable)
+	case errors.Is(err, services.ErrPaymentNotVerified):
+		http.Error(w, "Payment is not completed or does not match this purchase", http.StatusPaymentRequired)
+	case errors.Is(err, services.ErrPaymentAlreadyUsed):
+		http.Error(w, "Payment has already been used", http.StatusConflict)
+	default:
+		log.Printf("Ошибка проверки платежа: %v", err)
+		http.Error(w, "Failed to verify payment", http.StatusBadGateway)
+	}
+	return true
+}
+
+// EnrollHandler - POST: запись на курс (бесплатный или платный; ?currency=&coupon= - валюта и купон,
+// цена рассчитывается как в /courses/quote), DELETE: отказ от бесплатного курса.
+// Платный курс оплачивается в два шага: запрос без ?payment_reference= создает платеж и возвращает 402
+// с его данными, повторный запрос с ID оплаченного платежа записывает на курс
+func EnrollHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := authentication.ValidateToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	courseID, err := strconv.Atoi(r.URL.Query().Get("course_id"))
+	if err != nil || courseID <= 0 {
+		http.Error(w, "Invalid course ID", http.StatusBadRequest)
+		return
+	}
+
+	var course courses.Course
+	if err := config.DB.First(&course, courseID).Error; err != nil {
+		http.Error(w, "Course not found", http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPost:
+		if course.InstructorID == user.ID {
+			http.Error(w, "You cannot enroll in your own course", http.StatusBadRequest)
+			return
+		}
+		if services.IsEnrolled(user.ID, &course) {
+			http.Error(w, "Already enrolled", http.StatusConflict)
+			return
+		}
+		if course.Status != courses.CourseStatusPublished {
+			http.Error(w, "Course is not open for enrollment", http.StatusConflict)
+			return
+		}
+
+		currency := ""
+		if value := r.URL.Query().Get("currency"); value != "" {
+			if currency = services.NormalizeCurrency(value); currency == "" {
+				http.Error(w, "Invalid currency", http.StatusBadRequest)
+				return
+			}
+		}
+		quote, err := services.QuoteCoursePrice(&course, user.ID, currency, 1, r.URL.Query().Get("coupon"))
+		if errors.Is(err, services.ErrCurrencyUnavailable) {
+			http.Error(w, "Course is not sold in this currency", http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Failed to calculate price", http.StatusInternalServerError)
+			return
+		}
+		if quote.CouponError != "" {
+			http.Error(w, "Coupon cannot be applied: "+quote.CouponError, http.StatusBadRequest)
+			return
+		}
+
+		// Платный курс: сначала платеж, запись - только после его проверки у платежной системы
+		order := services.PaymentOrder{
+			Purpose:     billing.PurposeCourseEnrollment,
+			ItemID:      services.CourseOriginID(&course),
+			UserID:      user.ID,
+			Amount:      quote.Total,
+			Currency:    quote.Currency,
+			Description: course.Title,
+		}
+		var provider services.PaymentProvider
+		var receipt *services.PaymentReceipt
+		if quote.Total > 0 {
+			provider = services.GetPaymentProvider()
+			if provider == nil {
+				paymentError(w, services.ErrPaymentsUnavailable)
+				return
+			}
+			reference := r.URL.Query().Get("payment_reference")
+			if reference == "" {
+				intent, err := provider.CreatePayment(r.Context(), order)
+				if err != nil {
+					log.Printf("Ошибка создания платежа за курс %d: %v", course.ID, err)
+					http.Error(w, "Failed to create payment", http.StatusBadGateway)
+					return
+				}
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusPaymentRequired)
+				json.NewEncoder(w).Encode(map[string]interface{}{
+					"payment": intent,
+					"quote":   quote,
+				})
+				return
+			}
+			receipt, err = services.VerifyPayment(r.Context(), provider, reference, order)
+			if paymentError(w, err) {
+				return
+			}
+		}
+
+		enrollment := courses.Enrollment{
+			UserID:   user.ID,
+			CourseID: course.ID,
+			Source:   courses.EnrollmentFree,
+			Currency: quote.Currency,
+			CouponID: quote.CouponID,
+		}
+		if quote.ListPrice > 0 {
+			enrollment.Source = courses.EnrollmentPaid
+			enrollment.AmountPaid = quote.Total
+			enrollment.PricePaid = services.FromMinorUnits(quote.Total, quote.Currency)
+		}
+		err = config.DB.Transaction(func(tx *gorm.DB) error {
+			if receipt != nil {
+				if _, err := services.RecordPayment(tx, provider, receipt, order); err != nil {
+					return err
+				}
+			}
+			if err := services.RedeemCoupon(tx, quote, user.ID); err != nil {
+				return err
+			}
+			return tx.Create(&enrollment).Error
+		})
+		if errors.Is(err, services.ErrCouponExhausted) {
+			http.Error(w, "Coupon cannot be applied: "+err.Error(), http.StatusConflict)
+			return
+		}
+		if errors.Is(err, services.ErrPaymentAlreadyUsed) {
+			paymentError(w, err)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Failed to enroll", http.StatusInternalServerError)
+			return
+		}
+		enrollment.Course = &course
+
+		config.DB.Create(&users.NotificationMentor{
+			UserID:  course.InstructorID,
+			Message: fmt.Sprintf("%s enrolled in your course \"%s\".", user.Name, course.Title),
+		})
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(enrollment)
+
+	case http.MethodDelete:
+		var enrollment courses.Enrollment
+		if err := config.DB.Where("user_id = ? AND course_id IN (?)", user.ID, services.CourseVersionIDs(config.DB, &course)).First(&enrollment).Error; err != nil {
+			http.Error(w, "Enrollment not found", http.StatusNotFound)
+			return
+		}
+		if enrollment.Source == courses.EnrollmentOrganization {
+			http.Error(w, "Seats provided by your organization can only be revoked by its admins", http.StatusConflict)
+			return
+		}
+		if enrollment.Source == courses.EnrollmentPaid {
+			http.Error(w, "Paid enrollments cannot be cancelled", http.StatusConflict)
+			return
+		}
+		if err := config.DB.Delete(&enrollment).Error; err != nil {
+			http.Error(w, "Failed to cancel enrollment", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// MyEnrollmentsHandler - курсы, на которые записан пользователь
+func MyEnrollmentsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user, err := authentication.ValidateToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	var enrollments []courses.Enrollment
+	if err := config.DB.Preload("Course").Where("user_id = ?", user.ID).
+		Order("created_at DESC").Find(&enrollments).Error; err != nil {
+		http.Error(w, "Failed to list enrollments", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(enrollments)
+}
+
+// CourseStudentsHandler - список записавшихся на курс (только для инструктора)
+func CourseStudentsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user, err := authentication.ValidateToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	courseID, err := strconv.Atoi(r.URL.Query().Get("course_id"))
+	if err != nil || courseID <= 0 {
+		http.Error(w, "Invalid course ID", http.StatusBadRequest)
+		return
+	}
+
+	var course courses.Course
+	if err := config.DB.First(&course, courseID).Error; err != nil {
+		http.Error(w, "Course not found", http.StatusNotFound)
+		return
+	}
+	if !services.HasCoursePermission(user, &course, courses.PermissionViewAnalytics) {
+		http.Error(w, "Permission denied", http.StatusForbidden)
+		return
+	}
+
+	var enrollments []courses.Enrollment
+	if err := config.DB.Where("course_id = ?", course.ID).Order("created_at DESC").Find(&enrollments).Error; err != nil {
+		http.Error(w, "Failed to list enrollments", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(enrollments)
+}
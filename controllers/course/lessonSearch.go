@@ -0,0 +1,245 @@
+package course
+
+import (
+	"encoding/json"
+	"hired-valley-backend/config"
+	"hired-valley-backend/controllers/authentication"
+	"hired-valley-backend/models/courses"
+	"hired-valley-backend/models/courses/videos"
+	"hired-valley-backend/services"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultLessonSearchLimit = 20
+	maxLessonSearchLimit     = 100
+	// maxLessonSearchHits - сколько совпадений каждого вида ранжируется перед группировкой по урокам
+	maxLessonSearchHits = 500
+	// maxMomentsPerLesson - сколько мест в видео показывается для одного урока
+	maxMomentsPerLesson = 5
+)
+
+// transcriptMoment - место в видео урока, где встречается запрос
+type transcriptMoment struct {
+	StartSeconds float64 `json:"start_seconds"`
+	Language     string  `json:"language"`
+	Snippet      string  `json:"snippet,omitempty"` // Фрагмент расшифровки, скрыт у недоступных уроков
+}
+
+// lessonSearchResult - урок, найденный по названию, тексту или расшифровке видео
+type lessonSearchResult struct {
+	LessonID    uint               `json:"lesson_id"`
+	LessonTitle string             `json:"lesson_title"`
+	CourseID    uint               `json:"course_id"`
+	CourseTitle string             `json:"course_title"`
+	TextMatch   bool               `json:"text_match"` // Запрос найден в названии или тексте урока
+	Locked      bool               `json:"locked"`
+	Moments     []transcriptMoment `json:"moments"`
+	Rank        float64            `json:"-"`
+}
+
+// lessonSearchHit - строка совпадения из базы данных
+type lessonSearchHit struct {
+	LessonID     uint
+	LessonTitle  string
+	CourseID     uint
+	CourseTitle  string
+	StartSeconds *float64
+	Language     string
+	Snippet      string
+	Rank         float64
+}
+
+// LessonSearchHandler - поиск по урокам опубликованных курсов с точностью до места в видео:
+// ?q=&course_id=&language=&limit=. Ищется в названии и тексте урока и в расшифровках видео
+func LessonSearchHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	params := r.URL.Query()
+	text := strings.TrimSpace(params.Get("q"))
+	if text == "" {
+		http.Error(w, "Search query q is required", http.StatusBadRequest)
+		return
+	}
+
+	var courseID int
+	if value := params.Get("course_id"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			http.Error(w, "Invalid course ID", http.StatusBadRequest)
+			return
+		}
+		courseID = parsed
+	}
+	var language string
+	if value := params.Get("language"); value != "" {
+		normalized, err := services.NormalizeLanguage(value)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		language = normalized
+	}
+	limit := defaultLessonSearchLimit
+	if value := params.Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		if parsed > maxLessonSearchLimit {
+			parsed = maxLessonSearchLimit
+		}
+		limit = parsed
+	}
+
+	// Совпадения в расшифровках: видео урока находится по ссылке, общей для всех версий курса.
+	// Выражения to_tsvector совпадают с GIN-индексами из services.EnsureSearchIndexes
+	transcriptVector := "to_tsvector('simple', transcript_segments.text)"
+	transcriptQuery := config.DB.Table("transcript_segments").
+		Select("lessons.id AS lesson_id, lessons.title AS lesson_title, courses.id AS course_id, courses.title AS course_title, "+
+			"transcript_segments.start_seconds, transcript_segments.language, "+
+			"ts_headline('simple', transcript_segments.text, plainto_tsquery('simple', ?), 'MaxWords=20, MinWords=8') AS snippet, "+
+			"ts_rank("+transcriptVector+", plainto_tsquery('simple', ?)) AS rank", text, text).
+		Joins("JOIN videos ON videos.id = transcript_segments.video_id AND videos.status = ?", videos.VideoReady).
+		Joins("JOIN lessons ON lessons.video_link = videos.video_link").
+		Joins("JOIN courses ON courses.id = lessons.course_id").
+		Where("courses.status = ?", courses.CourseStatusPublished).
+		Where(transcriptVector+" @@ plainto_tsquery('simple', ?)", text)
+	if courseID > 0 {
+		transcriptQuery = transcriptQuery.Where("courses.id = ?", courseID)
+	}
+	if language != "" {
+		transcriptQuery = transcriptQuery.Where("transcript_segments.language = ?", language)
+	}
+	var transcriptHits []lessonSearchHit
+	if err := transcriptQuery.Order("rank DESC, transcript_segments.start_seconds").Limit(maxLessonSearchHits).
+		Scan(&transcriptHits).Error; err != nil {
+		http.Error(w, "Failed to search lessons", http.StatusInternalServerError)
+		return
+	}
+
+	// Совпадения в названии и тексте уроков
+	lessonVector := "to_tsvector('simple', coalesce(lessons.title, '') || ' ' || coalesce(lessons.content, ''))"
+	lessonQuery := config.DB.Table("lessons").
+		Select("lessons.id AS lesson_id, lessons.title AS lesson_title, courses.id AS course_id, courses.title AS course_title, "+
+			"ts_rank("+lessonVector+", plainto_tsquery('simple', ?)) AS rank", text).
+		Joins("JOIN courses ON courses.id = lessons.course_id").
+		Where("courses.status = ?", courses.CourseStatusPublished).
+		Where(lessonVector+" @@ plainto_tsquery('simple', ?)", text)
+	if courseID > 0 {
+		lessonQuery = lessonQuery.Where("courses.id = ?", courseID)
+	}
+	var lessonHits []lessonSearchHit
+	if err := lessonQuery.Order("rank DESC").Limit(maxLessonSearchHits).Scan(&lessonHits).Error; err != nil {
+		http.Error(w, "Failed to search lessons", http.StatusInternalServerError)
+		return
+	}
+
+	// Группировка по урокам: совпадение в названии или тексте весит больше отдельной реплики
+	results := map[uint]*lessonSearchResult{}
+	result := func(hit lessonSearchHit) *lessonSearchResult {
+		item, ok := results[hit.LessonID]
+		if !ok {
+			item = &lessonSearchResult{LessonID: hit.LessonID, LessonTitle: hit.LessonTitle, CourseID: hit.CourseID,
+				CourseTitle: hit.CourseTitle, Moments: []transcriptMoment{}}
+			results[hit.LessonID] = item
+		}
+		return item
+	}
+	for _, hit := range lessonHits {
+		item := result(hit)
+		item.TextMatch = true
+		item.Rank += 2 * hit.Rank
+	}
+	for _, hit := range transcriptHits {
+		item := result(hit)
+		if len(item.Moments) >= maxMomentsPerLesson || hit.StartSeconds == nil {
+			continue
+		}
+		item.Moments = append(item.Moments, transcriptMoment{StartSeconds: *hit.StartSeconds, Language: hit.Language, Snippet: hit.Snippet})
+		item.Rank += hit.Rank
+	}
+
+	ranked := make([]*lessonSearchResult, 0, len(results))
+	for _, item := range results {
+		sort.Slice(item.Moments, func(i, j int) bool { return item.Moments[i].StartSeconds < item.Moments[j].StartSeconds })
+		ranked = append(ranked, item)
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].Rank != ranked[j].Rank {
+			return ranked[i].Rank > ranked[j].Rank
+		}
+		return ranked[i].LessonID < ranked[j].LessonID
+	})
+	if len(ranked) > limit {
+		ranked = ranked[:limit]
+	}
+
+	// Недоступные пользователю уроки остаются в выдаче, но без фрагментов расшифровки
+	if err := markLockedResults(r, ranked); err != nil {
+		http.Error(w, "Failed to search lessons", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"query":   text,
+		"results": ranked,
+	})
+}
+
+// markLockedResults - отметка уроков, закрытых для текущего пользователя, и скрытие их фрагментов
+func markLockedResults(r *http.Request, results []*lessonSearchResult) error {
+	if len(results) == 0 {
+		return nil
+	}
+	var viewerID uint
+	if viewer, err := authentication.ValidateToken(r); err == nil {
+		viewerID = viewer.ID
+	}
+
+	lessonIDs := make([]uint, 0, len(results))
+	for _, item := range results {
+		lessonIDs = append(lessonIDs, item.LessonID)
+	}
+	var lessons []courses.Lesson
+	if err := config.DB.Where("id IN ?", lessonIDs).Find(&lessons).Error; err != nil {
+		return err
+	}
+	lessonByID := map[uint]*courses.Lesson{}
+	for i := range lessons {
+		lessonByID[lessons[i].ID] = &lessons[i]
+	}
+
+	gates := map[uint]*lessonGate{}
+	for _, item := range results {
+		gate, ok := gates[item.CourseID]
+		if !ok {
+			var course courses.Course
+			if err := config.DB.First(&course, item.CourseID).Error; err != nil {
+				return err
+			}
+			created, err := newLessonGate(viewerID, course)
+			if err != nil {
+				return err
+			}
+			gate = created
+			gates[item.CourseID] = gate
+		}
+		lesson, ok := lessonByID[item.LessonID]
+		if !ok || gate.check(lesson) != "" {
+			item.Locked = true
+			for i := range item.Moments {
+				item.Moments[i].Snippet = ""
+			}
+		}
+	}
+	return nil
+}
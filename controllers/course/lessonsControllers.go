@@ -1,18 +1,13 @@
 package course
 
 import (
-	"context"
 	"encoding/json"
-	"fmt"
-	"golang.org/x/oauth2"
-	"google.golang.org/api/option"
-	"google.golang.org/api/youtube/v3"
+	"gorm.io/gorm"
 	"hired-valley-backend/config"
 	"hired-valley-backend/controllers/authentication"
 	"hired-valley-backend/models/courses"
 	"hired-valley-backend/models/courses/videos"
-	"hired-valley-backend/models/users"
-	"mime/multipart"
+	"hired-valley-backend/services"
 	"net/http"
 	"strconv"
 )
@@ -35,11 +30,29 @@ func ListLessons(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var lessons []courses.Lesson
-	if err := config.DB.Where("course_id = ?", uint(courseID)).Find(&lessons).Error; err != nil {
+	var course courses.Course
+	if err := config.DB.First(&course, courseID).Error; err != nil {
+		http.Error(w, "Course not found", http.StatusNotFound)
+		return
+	}
+
+	lessons, err := orderedLessons(course.ID)
+	if err != nil {
+		http.Error(w, "Failed to list lessons", http.StatusInternalServerError)
+		return
+	}
+
+	// Без записи на курс доступна только программа: содержимое и видео закрытых уроков скрываются
+	var viewerID uint
+	if viewer, err := authentication.ValidateToken(r); err == nil {
+		viewerID = viewer.ID
+	}
+	gate, err := newLessonGate(viewerID, course)
+	if err != nil {
 		http.Error(w, "Failed to list lessons", http.StatusInternalServerError)
 		return
 	}
+	gate.hideLocked(lessons)
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
@@ -71,6 +84,11 @@ func GetLessonByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !lessonAccess(w, r, lesson) {
+		return
+	}
+	lesson.VideoLink = services.SignVideoLink(lesson.VideoLink)
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(lesson)
@@ -79,7 +97,7 @@ func GetLessonByID(w http.ResponseWriter, r *http.Request) {
 // CreateLesson - создание нового урока
 func CreateLesson(w http.ResponseWriter, r *http.Request) {
 	claims, err := authentication.ValidateToken(r)
-	if err != nil || claims.Role != "mentor" {
+	if err != nil {
 		http.Error(w, "Unauthorized or forbidden", http.StatusUnauthorized)
 		return
 	}
@@ -89,6 +107,17 @@ func CreateLesson(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid input", http.StatusBadRequest)
 		return
 	}
+	if _, ok := editableCourse(w, claims, strconv.Itoa(int(lesson.CourseID))); !ok {
+		return
+	}
+
+	if err := validateLessonModule(lesson); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if lesson.Position == 0 {
+		lesson.Position = nextLessonPosition(lesson.CourseID, lesson.ModuleID)
+	}
 
 	lesson.InstructorID = claims.ID
 	if err := config.DB.Create(&lesson).Error; err != nil {
@@ -104,7 +133,7 @@ func CreateLesson(w http.ResponseWriter, r *http.Request) {
 // UpdateLesson - обновление урока
 func UpdateLesson(w http.ResponseWriter, r *http.Request) {
 	claims, err := authentication.ValidateToken(r)
-	if err != nil || claims.Role != "mentor" {
+	if err != nil {
 		http.Error(w, "Unauthorized or forbidden", http.StatusUnauthorized)
 		return
 	}
@@ -127,15 +156,25 @@ func UpdateLesson(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if lesson.InstructorID != claims.ID {
-		http.Error(w, "Permission denied", http.StatusForbidden)
+	if _, ok := editableCourse(w, claims, strconv.Itoa(int(lesson.CourseID))); !ok {
 		return
 	}
 
+	courseID := lesson.CourseID
 	if err := json.NewDecoder(r.Body).Decode(&lesson); err != nil {
 		http.Error(w, "Invalid input", http.StatusBadRequest)
 		return
 	}
+	// Урок нельзя перенести в опубликованный курс в обход черновика
+	if lesson.CourseID != courseID {
+		if _, ok := editableCourse(w, claims, strconv.Itoa(int(lesson.CourseID))); !ok {
+			return
+		}
+	}
+	if err := validateLessonModule(lesson); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	if err := config.DB.Save(&lesson).Error; err != nil {
 		http.Error(w, "Failed to update lesson", http.StatusInternalServerError)
@@ -149,7 +188,7 @@ func UpdateLesson(w http.ResponseWriter, r *http.Request) {
 // DeleteLesson - удаление урока
 func DeleteLesson(w http.ResponseWriter, r *http.Request) {
 	claims, err := authentication.ValidateToken(r)
-	if err != nil || claims.Role != "mentor" {
+	if err != nil {
 		http.Error(w, "Unauthorized or forbidden", http.StatusUnauthorized)
 		return
 	}
@@ -172,8 +211,7 @@ func DeleteLesson(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if lesson.InstructorID != claims.ID {
-		http.Error(w, "Permission denied", http.StatusForbidden)
+	if _, ok := editableCourse(w, claims, strconv.Itoa(int(lesson.CourseID))); !ok {
 		return
 	}
 
@@ -185,10 +223,10 @@ func DeleteLesson(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
-// UploadVideoToLesson - загрузка видео на YouTube и сохранение ссылки в Lesson
+// UploadVideoToLesson - загрузка видео урока одним файлом (поле host: youtube или self);
+// публикация на площадку идет в фоне
 func UploadVideoToLesson(w http.ResponseWriter, r *http.Request) {
-	// Проверяем Google OAuth токен
-	token, err := authentication.ValidateGoogleToken(r)
+	uploader, err := authenticateUploader(r)
 	if err != nil {
 		http.Error(w, "Unauthorized or forbidden: "+err.Error(), http.StatusUnauthorized)
 		return
@@ -207,17 +245,8 @@ func UploadVideoToLesson(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Проверяем существование урока
-	var lesson courses.Lesson
-	if err := config.DB.First(&lesson, uint(lessonID)).Error; err != nil {
-		http.Error(w, "Lesson not found", http.StatusNotFound)
-		return
-	}
-
-	// Проверяем, является ли текущий пользователь владельцем урока
-	var googleUser users.GoogleUser
-	if err := config.DB.Where("user_id = ?", lesson.InstructorID).First(&googleUser).Error; err != nil {
-		http.Error(w, "Instructor not found or unauthorized", http.StatusForbidden)
+	lesson, ok := lessonUploadTarget(w, uploader.UserID, uint(lessonID))
+	if !ok {
 		return
 	}
 
@@ -229,86 +258,39 @@ func UploadVideoToLesson(w http.ResponseWriter, r *http.Request) {
 	}
 	defer file.Close()
 
-	// Загрузка видео на YouTube
-	videoID, err := uploadVideoToYouTube(file, header.Filename, token.AccessToken)
-	if err != nil {
-		http.Error(w, "Failed to upload video to YouTube: "+err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	// Создаем запись о видео в таблице videos
+	// Файл проверяется и публикуется фоновым обработчиком, ссылка в уроке появится после публикации
 	video := videos.Video{
-		Title:       header.Filename,
 		Description: "Uploaded via Hired Valley platform",
-		VideoLink:   fmt.Sprintf("https://www.youtube.com/watch?v=%s", videoID),
-		YouTubeID:   videoID,
-		UploadedBy:  lesson.InstructorID,
-		LessonID:    uint(lessonID),
+		UploadedBy:  uploader.UserID,
+		LessonID:    lesson.ID,
+		TargetType:  videos.TargetLesson,
+		Host:        r.FormValue("host"),
+		FileName:    header.Filename,
+		SizeBytes:   header.Size,
+		GoogleEmail: uploader.GoogleEmail,
+		GoogleToken: uploader.GoogleToken,
 	}
-
-	if err := config.DB.Create(&video).Error; err != nil {
-		http.Error(w, "Failed to save video data", http.StatusInternalServerError)
+	if video.Host == "" {
+		video.Host = services.DefaultVideoHost()
+	}
+	if !uploader.canPublish(video.Host) {
+		http.Error(w, "YouTube hosting requires a Google token or a connected YouTube channel", http.StatusBadRequest)
 		return
 	}
-
-	// Обновляем запись об уроке
-	lesson.VideoLink = video.VideoLink
-	if err := config.DB.Save(&lesson).Error; err != nil {
-		http.Error(w, "Failed to save lesson", http.StatusInternalServerError)
+	if err := services.StageVideoFile(&video, file); err != nil {
+		writeVideoUploadError(w, err)
 		return
 	}
 
-	// Успешный ответ
 	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"message":    "Video uploaded successfully",
-		"video_url":  video.VideoLink,
+		"message":    "Video accepted for processing, poll /videos/uploads?id= for its status",
 		"lesson_id":  lessonIDStr,
-		"video_id":   videoID,
-		"video_data": video, // Включаем информацию о видео в ответ
+		"video_data": video,
 	})
 }
 
-// uploadVideoToYouTube - загрузка видео на YouTube с использованием Google OAuth токена
-func uploadVideoToYouTube(file multipart.File, fileName string, accessToken string) (string, error) {
-	ctx := context.Background()
-
-	// Создаем токен OAuth вручную
-	token := &oauth2.Token{
-		AccessToken: accessToken,
-	}
-
-	// Создаем источник токена
-	tokenSource := authentication.GoogleOauthConfig.TokenSource(ctx, token)
-
-	// Создаем YouTube сервис
-	service, err := youtube.NewService(ctx, option.WithTokenSource(tokenSource))
-	if err != nil {
-		return "", fmt.Errorf("failed to create YouTube service: %v", err)
-	}
-
-	// Подготавливаем метаданные видео
-	video := &youtube.Video{
-		Snippet: &youtube.VideoSnippet{
-			Title:       fileName,
-			Description: "Uploaded via Hired Valley platform",
-		},
-		Status: &youtube.VideoStatus{
-			PrivacyStatus: "unlisted",
-		},
-	}
-
-	// Загружаем видео
-	call := service.Videos.Insert([]string{"snippet", "status"}, video)
-	response, err := call.Media(file).Do()
-	if err != nil {
-		return "", fmt.Errorf("failed to upload video: %v", err)
-	}
-
-	// Возвращаем ID видео
-	return response.Id, nil
-}
-
 func GetVideo(w http.ResponseWriter, r *http.Request) {
 	videoIDStr := r.URL.Query().Get("video_id")
 	if videoIDStr == "" {
@@ -328,80 +310,91 @@ func GetVideo(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	var lesson courses.Lesson
+	if err := config.DB.First(&lesson, video.LessonID).Error; err != nil {
+		http.Error(w, "Lesson not found", http.StatusNotFound)
+		return
+	}
+	if !lessonAccess(w, r, lesson) {
+		return
+	}
+	video.VideoLink = services.SignVideoLink(video.VideoLink)
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(video)
 }
 
-func UpdateVideo(w http.ResponseWriter, r *http.Request) {
-	// Получаем YouTube ID из запроса
-	youtubeID := r.URL.Query().Get("you_tube_id")
-	if youtubeID == "" {
-		http.Error(w, "YouTube ID is required", http.StatusBadRequest)
-		return
-	}
-
-	// Проверка Google OAuth токена
-	token, err := authentication.ValidateGoogleToken(r)
+// hostedVideo - видео по ?id= или ?you_tube_id=, загруженное текущим пользователем, и его площадка.
+// Google OAuth токен из запроса используется для YouTube вместо сохраненного. При ошибке ответ уже отправлен
+func hostedVideo(w http.ResponseWriter, r *http.Request) (videos.Video, services.VideoHost, bool) {
+	var video videos.Video
+	uploader, err := authenticateUploader(r)
 	if err != nil {
 		http.Error(w, "Unauthorized or forbidden: "+err.Error(), http.StatusUnauthorized)
-		return
+		return video, nil, false
 	}
 
-	// Чтение входных данных для обновления
-	var videoUpdate struct {
-		Title       string `json:"title"`
-		Description string `json:"description"`
-	}
-	if err := json.NewDecoder(r.Body).Decode(&videoUpdate); err != nil {
-		http.Error(w, "Invalid input", http.StatusBadRequest)
-		return
+	query := config.DB.Where("uploaded_by = ?", uploader.UserID)
+	if youtubeID := r.URL.Query().Get("you_tube_id"); youtubeID != "" {
+		query = query.Where("you_tube_id = ?", youtubeID)
+	} else if videoID, err := strconv.Atoi(r.URL.Query().Get("id")); err == nil && videoID > 0 {
+		query = query.Where("id = ?", videoID)
+	} else {
+		http.Error(w, "Video ID is required", http.StatusBadRequest)
+		return video, nil, false
 	}
-
-	// Получение информации о видео из базы данных по youtube_id
-	var videoRecord videos.Video
-	if err := config.DB.Where("you_tube_id = ?", youtubeID).First(&videoRecord).Error; err != nil {
+	if err := query.First(&video).Error; err != nil {
 		http.Error(w, "Video not found in database", http.StatusNotFound)
-		return
+		return video, nil, false
+	}
+	if video.Status != videos.VideoReady {
+		http.Error(w, "Video is not published yet, use /videos/uploads", http.StatusConflict)
+		return video, nil, false
 	}
 
-	// Настройка контекста и YouTube-сервиса
-	ctx := context.Background()
-	tokenSource := authentication.GoogleOauthConfig.TokenSource(ctx, &oauth2.Token{AccessToken: token.AccessToken})
-	service, err := youtube.NewService(ctx, option.WithTokenSource(tokenSource))
+	host, err := services.GetVideoHost(video.Host)
 	if err != nil {
-		http.Error(w, "Failed to create YouTube service", http.StatusInternalServerError)
-		return
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return video, nil, false
+	}
+	if uploader.GoogleToken != "" {
+		video.GoogleToken = uploader.GoogleToken
 	}
+	if uploader.GoogleEmail != "" {
+		video.GoogleEmail = uploader.GoogleEmail
+	}
+	return video, host, true
+}
 
-	// Получение текущих данных видео с YouTube
-	videoCall := service.Videos.List([]string{"snippet"}).Id(youtubeID)
-	response, err := videoCall.Do()
-	if err != nil {
-		http.Error(w, "Failed to fetch video from YouTube", http.StatusInternalServerError)
+// UpdateVideo - PUT: название и описание опубликованного видео (?id= или ?you_tube_id=)
+func UpdateVideo(w http.ResponseWriter, r *http.Request) {
+	videoRecord, host, ok := hostedVideo(w, r)
+	if !ok {
 		return
 	}
-	if len(response.Items) == 0 {
-		http.Error(w, "Video not found on YouTube", http.StatusNotFound)
+
+	// Чтение входных данных для обновления
+	var videoUpdate struct {
+		Title       string `json:"title"`
+		Description string `json:"description"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&videoUpdate); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
 		return
 	}
 
-	// Обновляем данные видео
-	video := response.Items[0]
-	video.Snippet.Title = videoUpdate.Title
-	video.Snippet.Description = videoUpdate.Description
-
-	// Выполняем обновление видео на YouTube
-	updateCall := service.Videos.Update([]string{"snippet"}, video)
-	updatedVideo, err := updateCall.Do()
-	if err != nil {
-		http.Error(w, "Failed to update video on YouTube", http.StatusInternalServerError)
+	// Обновляем видео на площадке
+	videoRecord.Title = videoUpdate.Title
+	videoRecord.Description = videoUpdate.Description
+	if err := host.Update(r.Context(), &videoRecord); err != nil {
+		http.Error(w, "Failed to update video on the video host: "+err.Error(), http.StatusBadGateway)
 		return
 	}
 
 	// Обновляем запись в базе данных
-	if err := config.DB.Model(&videoRecord).Updates(videos.Video{
-		Title:       videoUpdate.Title,
-		Description: videoUpdate.Description,
+	if err := config.DB.Model(&videoRecord).Updates(map[string]interface{}{
+		"title":       videoUpdate.Title,
+		"description": videoUpdate.Description,
 	}).Error; err != nil {
 		http.Error(w, "Failed to update video in database", http.StatusInternalServerError)
 		return
@@ -411,41 +404,32 @@ func UpdateVideo(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"message":     "Video updated successfully",
-		"updatedData": updatedVideo,
+		"updatedData": videoRecord,
 	})
 }
 
+// DeleteVideo - удаление опубликованного видео с площадки и из базы данных (?id= или ?you_tube_id=)
 func DeleteVideo(w http.ResponseWriter, r *http.Request) {
-	you_tube_id := r.URL.Query().Get("you_tube_id")
-	if you_tube_id == "" {
-		http.Error(w, "Video ID is required", http.StatusBadRequest)
+	videoRecord, host, ok := hostedVideo(w, r)
+	if !ok {
 		return
 	}
 
-	// Проверяем авторизацию Google
-	token, err := authentication.ValidateGoogleToken(r)
-	if err != nil {
-		http.Error(w, "Unauthorized or forbidden: "+err.Error(), http.StatusUnauthorized)
+	if err := host.Delete(r.Context(), &videoRecord); err != nil {
+		http.Error(w, "Failed to delete video from the video host: "+err.Error(), http.StatusBadGateway)
 		return
 	}
 
-	// Удаляем видео с YouTube
-	ctx := context.Background()
-	tokenSource := authentication.GoogleOauthConfig.TokenSource(ctx, &oauth2.Token{AccessToken: token.AccessToken})
-	service, err := youtube.NewService(ctx, option.WithTokenSource(tokenSource))
+	// Удаляем данные из базы данных вместе с субтитрами, расшифровками и главами
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		for _, model := range []interface{}{&videos.CaptionTrack{}, &videos.TranscriptSegment{}, &videos.VideoChapter{}} {
+			if err := tx.Where("video_id = ?", videoRecord.ID).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Delete(&videoRecord).Error
+	})
 	if err != nil {
-		http.Error(w, "Failed to create YouTube service", http.StatusInternalServerError)
-		return
-	}
-
-	call := service.Videos.Delete(you_tube_id)
-	if err := call.Do(); err != nil {
-		http.Error(w, "Failed to delete video from YouTube", http.StatusInternalServerError)
-		return
-	}
-
-	// Удаляем данные из базы данных
-	if err := config.DB.Where("you_tube_id = ?", you_tube_id).Delete(&videos.Video{}).Error; err != nil {
 		http.Error(w, "Failed to delete video from database", http.StatusInternalServerError)
 		return
 	}
@@ -0,0 +1,395 @@
+package course
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"hired-valley-backend/config"
+	"hired-valley-backend/controllers/authentication"
+	"hired-valley-backend/models/courses"
+	"hired-valley-backend/models/users"
+	"hired-valley-backend/services"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var couponCodePattern = regexp.MustCompile(`^[A-Z0-9_-]{3,32}$`)
+
+// pricingInput - цены курса и скидки за количество мест; заменяют текущие целиком
+type pricingInput struct {
+	Prices    []courses.CoursePrice   `json:"prices"`
+	SeatTiers []courses.SeatPriceTier `json:"seat_tiers"`
+}
+
+// validatePricing - проверка цен: валюты без повторов, суммы в минимальных единицах, распродажа дешевле цены
+func validatePricing(input *pricingInput) error {
+	if len(input.Prices) == 0 {
+		return errors.New("at least one price is required")
+	}
+	currencies := map[string]bool{}
+	for i := range input.Prices {
+		price := &input.Prices[i]
+		price.Currency = services.NormalizeCurrency(price.Currency)
+		if price.Currency == "" {
+			return fmt.Errorf("price %d: currency must be a 3-letter ISO 4217 code", i+1)
+		}
+		if currencies[price.Currency] {
+			return fmt.Errorf("price %d: duplicate currency %s", i+1, price.Currency)
+		}
+		currencies[price.Currency] = true
+		if price.Amount < 0 {
+			return fmt.Errorf("price %d: amount cannot be negative", i+1)
+		}
+		if price.SaleAmount != nil {
+			if *price.SaleAmount < 0 || *price.SaleAmount >= price.Amount {
+				return fmt.Errorf("price %d: sale_amount must be lower than amount", i+1)
+			}
+			if price.SaleStartsAt != nil && price.SaleEndsAt != nil && !price.SaleEndsAt.After(*price.SaleStartsAt) {
+				return fmt.Errorf("price %d: sale_ends_at must be after sale_starts_at", i+1)
+			}
+		}
+	}
+
+	seats := map[int]bool{}
+	for i, tier := range input.SeatTiers {
+		if tier.MinSeats < 2 {
+			return fmt.Errorf("seat tier %d: min_seats must be at least 2", i+1)
+		}
+		if seats[tier.MinSeats] {
+			return fmt.Errorf("seat tier %d: duplicate min_seats %d", i+1, tier.MinSeats)
+		}
+		seats[tier.MinSeats] = true
+		if tier.DiscountPercent < 1 || tier.DiscountPercent > 100 {
+			return fmt.Errorf("seat tier %d: discount_percent must be between 1 and 100", i+1)
+		}
+	}
+	return nil
+}
+
+// CoursePricingHandler - цены курса (?course_id=). GET: цены во всех валютах и скидки за места,
+// PUT: замена цен автором или администратором. Цены общие для всех версий курса и меняются без черновика
+func CoursePricingHandler(w http.ResponseWriter, r *http.Request) {
+	courseID, err := strconv.Atoi(r.URL.Query().Get("course_id"))
+	if err != nil || courseID <= 0 {
+		http.Error(w, "Invalid course ID", http.StatusBadRequest)
+		return
+	}
+	var viewer *users.User
+	if user, err := authentication.ValidateToken(r); err == nil {
+		viewer = user
+	}
+	course, err := courseService.Get(viewer, uint(courseID))
+	if err != nil {
+		http.Error(w, "Course not found", http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		// Цены видны всем, кому виден курс
+
+	case http.MethodPut:
+		if viewer == nil {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if !services.CanManageCourse(viewer, course) {
+			http.Error(w, "Permission denied", http.StatusForbidden)
+			return
+		}
+
+		var input pricingInput
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			http.Error(w, "Invalid input", http.StatusBadRequest)
+			return
+		}
+		if err := validatePricing(&input); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		originID := services.CourseOriginID(course)
+		err := config.DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Where("course_id = ?", originID).Delete(&courses.CoursePrice{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("course_id = ?", originID).Delete(&courses.SeatPriceTier{}).Error; err != nil {
+				return err
+			}
+			for _, price := range input.Prices {
+				price.ID = 0
+				price.CourseID = originID
+				if err := tx.Create(&price).Error; err != nil {
+					return err
+				}
+				// Course.Price остается ценой в валюте по умолчанию для каталога и старых клиентов
+				if price.Currency == services.DefaultCurrency() {
+					if err := tx.Model(&courses.Course{}).Where("id = ? OR origin_id = ?", originID, originID).
+						Update("price", services.FromMinorUnits(price.Amount, price.Currency)).Error; err != nil {
+						return err
+					}
+				}
+			}
+			for _, tier := range input.SeatTiers {
+				tier.ID = 0
+				tier.CourseID = originID
+				if err := tx.Create(&tier).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			http.Error(w, "Failed to save prices", http.StatusInternalServerError)
+			return
+		}
+		config.DB.First(course, course.ID)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	prices, err := services.CoursePrices(course)
+	if err != nil {
+		http.Error(w, "Failed to load prices", http.StatusInternalServerError)
+		return
+	}
+	tiers, err := services.SeatPriceTiers(course)
+	if err != nil {
+		http.Error(w, "Failed to load prices", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"course_id":        course.ID,
+		"default_currency": services.DefaultCurrency(),
+		"prices":           prices,
+		"seat_tiers":       tiers,
+	})
+}
+
+// CourseQuoteHandler - GET: расчет стоимости перед оплатой (?course_id=&currency=&seats=&coupon=).
+// Суммы в минимальных единицах валюты; неприменимый купон не ошибка, причина - в coupon_error
+func CourseQuoteHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	params := r.URL.Query()
+	courseID, err := strconv.Atoi(params.Get("course_id"))
+	if err != nil || courseID <= 0 {
+		http.Error(w, "Invalid course ID", http.StatusBadRequest)
+		return
+	}
+	var viewer *users.User
+	var viewerID uint
+	if user, err := authentication.ValidateToken(r); err == nil {
+		viewer, viewerID = user, user.ID
+	}
+	course, err := courseService.Get(viewer, uint(courseID))
+	if err != nil {
+		http.Error(w, "Course not found", http.StatusNotFound)
+		return
+	}
+
+	seats := 1
+	if value := params.Get("seats"); value != "" {
+		if seats, err = strconv.Atoi(value); err != nil || seats < 1 || seats > services.MaxSeatsPerPurchase {
+			http.Error(w, fmt.Sprintf("Seats must be between 1 and %d", services.MaxSeatsPerPurchase), http.StatusBadRequest)
+			return
+		}
+	}
+	currency := ""
+	if value := params.Get("currency"); value != "" {
+		if currency = services.NormalizeCurrency(value); currency == "" {
+			http.Error(w, "Invalid currency", http.StatusBadRequest)
+			return
+		}
+	}
+
+	quote, err := services.QuoteCoursePrice(course, viewerID, currency, seats, params.Get("coupon"))
+	if errors.Is(err, services.ErrCurrencyUnavailable) {
+		http.Error(w, "Course is not sold in this currency", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to calculate price", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(quote)
+}
+
+// couponInput - новый купон; для скидки суммой обязательна валюта
+type couponInput struct {
+	Code           string     `json:"code"`
+	CourseID       *uint      `json:"course_id"` // nil - купон на все курсы (только администратор)
+	PercentOff     int        `json:"percent_off"`
+	AmountOff      int64      `json:"amount_off"`
+	Currency       string     `json:"currency"`
+	MaxRedemptions int        `json:"max_redemptions"`
+	MaxPerUser     *int       `json:"max_per_user"` // По умолчанию 1
+	StartsAt       *time.Time `json:"starts_at"`
+	ExpiresAt      *time.Time `json:"expires_at"`
+}
+
+// CouponsHandler - купоны. GET ?course_id=: купоны курса (автору и администратору), без course_id -
+// общие купоны (администратору). POST: создание купона. DELETE ?id=: отключение купона
+func CouponsHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := authentication.ValidateToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	// couponCourse - курс купона и право автора на него; nil - общий купон администратора
+	couponCourse := func(courseID *uint) (*uint, bool) {
+		if courseID == nil {
+			if user.Role != "admin" {
+				http.Error(w, "Only admins can manage coupons for all courses", http.StatusForbidden)
+				return nil, false
+			}
+			return nil, true
+		}
+		var course courses.Course
+		if err := config.DB.First(&course, *courseID).Error; err != nil {
+			http.Error(w, "Course not found", http.StatusNotFound)
+			return nil, false
+		}
+		if !services.CanManageCourse(user, &course) {
+			http.Error(w, "Permission denied", http.StatusForbidden)
+			return nil, false
+		}
+		originID := services.CourseOriginID(&course)
+		return &originID, true
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		var courseID *uint
+		if value := r.URL.Query().Get("course_id"); value != "" {
+			id, err := strconv.Atoi(value)
+			if err != nil || id <= 0 {
+				http.Error(w, "Invalid course ID", http.StatusBadRequest)
+				return
+			}
+			parsed := uint(id)
+			courseID = &parsed
+		}
+		originID, ok := couponCourse(courseID)
+		if !ok {
+			return
+		}
+
+		query := config.DB.Order("created_at DESC")
+		if originID == nil {
+			query = query.Where("course_id IS NULL")
+		} else {
+			query = query.Where("course_id = ?", *originID)
+		}
+		coupons := []courses.Coupon{}
+		if err := query.Find(&coupons).Error; err != nil {
+			http.Error(w, "Failed to list coupons", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(coupons)
+
+	case http.MethodPost:
+		var input couponInput
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			http.Error(w, "Invalid input", http.StatusBadRequest)
+			return
+		}
+		originID, ok := couponCourse(input.CourseID)
+		if !ok {
+			return
+		}
+
+		coupon := courses.Coupon{
+			Code:           strings.ToUpper(strings.TrimSpace(input.Code)),
+			CourseID:       originID,
+			CreatedByID:    user.ID,
+			MaxRedemptions: input.MaxRedemptions,
+			MaxPerUser:     1,
+			StartsAt:       input.StartsAt,
+			ExpiresAt:      input.ExpiresAt,
+			IsActive:       true,
+		}
+		if input.MaxPerUser != nil {
+			coupon.MaxPerUser = *input.MaxPerUser
+		}
+		switch {
+		case !couponCodePattern.MatchString(coupon.Code):
+			http.Error(w, "Code must be 3-32 letters, digits, '-' or '_'", http.StatusBadRequest)
+			return
+		case (input.PercentOff > 0) == (input.AmountOff > 0):
+			http.Error(w, "Specify either percent_off or amount_off", http.StatusBadRequest)
+			return
+		case input.PercentOff > 100:
+			http.Error(w, "percent_off must be between 1 and 100", http.StatusBadRequest)
+			return
+		case coupon.MaxRedemptions < 0 || coupon.MaxPerUser < 0:
+			http.Error(w, "Usage limits must not be negative", http.StatusBadRequest)
+			return
+		case coupon.StartsAt != nil && coupon.ExpiresAt != nil && !coupon.ExpiresAt.After(*coupon.StartsAt):
+			http.Error(w, "expires_at must be after starts_at", http.StatusBadRequest)
+			return
+		}
+		if input.PercentOff > 0 {
+			coupon.DiscountType = courses.CouponPercent
+			coupon.PercentOff = input.PercentOff
+		} else {
+			coupon.DiscountType = courses.CouponAmount
+			coupon.AmountOff = input.AmountOff
+			if coupon.Currency = services.NormalizeCurrency(input.Currency); coupon.Currency == "" {
+				http.Error(w, "Currency is required for amount_off coupons", http.StatusBadRequest)
+				return
+			}
+		}
+
+		if _, err := services.FindCoupon(coupon.Code); err == nil {
+			http.Error(w, "Coupon code is already taken", http.StatusConflict)
+			return
+		}
+		if err := config.DB.Create(&coupon).Error; err != nil {
+			http.Error(w, "Failed to create coupon", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(coupon)
+
+	case http.MethodDelete:
+		couponID, err := strconv.Atoi(r.URL.Query().Get("id"))
+		if err != nil || couponID <= 0 {
+			http.Error(w, "Invalid coupon ID", http.StatusBadRequest)
+			return
+		}
+		var coupon courses.Coupon
+		if err := config.DB.First(&coupon, couponID).Error; err != nil {
+			http.Error(w, "Coupon not found", http.StatusNotFound)
+			return
+		}
+		if _, ok := couponCourse(coupon.CourseID); !ok {
+			return
+		}
+		// Использованные купоны не удаляются, чтобы сохранить историю покупок
+		if err := config.DB.Model(&coupon).Update("is_active", false).Error; err != nil {
+			http.Error(w, "Failed to deactivate coupon", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
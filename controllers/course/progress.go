@@ -0,0 +1,229 @@
+package course
+
+import (
+	"encoding/json"
+	"gorm.io/gorm"
+	"hired-valley-backend/config"
+	"hired-valley-backend/controllers/authentication"
+	"hired-valley-backend/models/courses"
+	"hired-valley-backend/models/users"
+	"hired-valley-backend/services"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Статусы урока, передаваемые клиентом
+const (
+	lessonStarted   = "started"
+	lessonCompleted = "completed"
+)
+
+// UpdateLessonProgressHandler - отметка начала/завершения урока и позиции просмотра видео
+func UpdateLessonProgressHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user, err := authentication.ValidateToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	lessonID, err := strconv.Atoi(r.URL.Query().Get("lesson_id"))
+	if err != nil || lessonID <= 0 {
+		http.Error(w, "Invalid lesson ID", http.StatusBadRequest)
+		return
+	}
+
+	var lesson courses.Lesson
+	if err := config.DB.First(&lesson, lessonID).Error; err != nil {
+		http.Error(w, "Lesson not found", http.StatusNotFound)
+		return
+	}
+	if !lessonAccess(w, r, lesson) {
+		return
+	}
+
+	var input struct {
+		Status          string `json:"status"`           // started или completed
+		PositionSeconds *int   `json:"position_seconds"` // Текущая позиция видео
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	if input.Status != "" && input.Status != lessonStarted && input.Status != lessonCompleted {
+		http.Error(w, "Status must be started or completed", http.StatusBadRequest)
+		return
+	}
+	if input.PositionSeconds != nil && *input.PositionSeconds < 0 {
+		http.Error(w, "Position must not be negative", http.StatusBadRequest)
+		return
+	}
+
+	// Урок с тестом засчитывается только после успешной сдачи теста
+	if input.Status == lessonCompleted {
+		var quiz courses.Quiz
+		if err := config.DB.Where("lesson_id = ?", lesson.ID).First(&quiz).Error; err == nil && !hasPassedQuiz(user.ID, quiz.ID) {
+			http.Error(w, "Pass the lesson quiz to complete this lesson", http.StatusConflict)
+			return
+		}
+	}
+
+	var progress courses.Progress
+	if err := config.DB.Where(courses.Progress{UserID: user.ID, LessonID: lesson.ID}).
+		FirstOrInit(&progress).Error; err != nil {
+		http.Error(w, "Failed to load progress", http.StatusInternalServerError)
+		return
+	}
+
+	now := time.Now()
+	progress.CourseID = lesson.CourseID
+	if progress.StartedAt == nil {
+		progress.StartedAt = &now
+	}
+	if input.PositionSeconds != nil {
+		progress.PositionSeconds = *input.PositionSeconds
+	}
+	if input.Status == lessonCompleted && !progress.Completed {
+		progress.Completed = true
+		progress.CompletedAt = &now
+	}
+
+	if err := config.DB.Save(&progress).Error; err != nil {
+		http.Error(w, "Failed to save progress", http.StatusInternalServerError)
+		return
+	}
+
+	var course courses.Course
+	config.DB.First(&course, lesson.CourseID)
+	courseProgress, err := services.GetCourseProgress(user.ID, course)
+	if err != nil {
+		http.Error(w, "Failed to calculate course progress", http.StatusInternalServerError)
+		return
+	}
+
+	var certificate *users.Certificate
+	if progress.Completed {
+		certificate = issueCertificate(user.ID, course.ID)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"progress":        progress,
+		"course_progress": courseProgress,
+		"certificate":     certificate,
+	})
+}
+
+// markLessonCompleted - отметка урока пройденным (например, после сдачи теста)
+func markLessonCompleted(tx *gorm.DB, userID uint, lesson courses.Lesson) error {
+	var progress courses.Progress
+	if err := tx.Where(courses.Progress{UserID: userID, LessonID: lesson.ID}).FirstOrInit(&progress).Error; err != nil {
+		return err
+	}
+	if progress.Completed {
+		return nil
+	}
+
+	now := time.Now()
+	progress.CourseID = lesson.CourseID
+	if progress.StartedAt == nil {
+		progress.StartedAt = &now
+	}
+	progress.Completed = true
+	progress.CompletedAt = &now
+	return tx.Save(&progress).Error
+}
+
+// CourseProgressHandler - процент прохождения курса, прогресс по урокам и точка продолжения
+func CourseProgressHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user, err := authentication.ValidateToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	courseID, err := strconv.Atoi(r.URL.Query().Get("course_id"))
+	if err != nil || courseID <= 0 {
+		http.Error(w, "Invalid course ID", http.StatusBadRequest)
+		return
+	}
+
+	var course courses.Course
+	if err := config.DB.First(&course, courseID).Error; err != nil {
+		http.Error(w, "Course not found", http.StatusNotFound)
+		return
+	}
+	if !services.IsEnrolled(user.ID, &course) {
+		http.Error(w, "You are not enrolled in this course", http.StatusForbidden)
+		return
+	}
+
+	courseProgress, err := services.GetCourseProgress(user.ID, course)
+	if err != nil {
+		http.Error(w, "Failed to calculate course progress", http.StatusInternalServerError)
+		return
+	}
+
+	var lessons []courses.Progress
+	if err := config.DB.Where("user_id = ? AND course_id = ?", user.ID, course.ID).Find(&lessons).Error; err != nil {
+		http.Error(w, "Failed to load progress", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"course_progress": courseProgress,
+		"lessons":         lessons,
+	})
+}
+
+// LearnerDashboardHandler - курсы пользователя с прогрессом и "продолжить с того места"
+func LearnerDashboardHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user, err := authentication.ValidateToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	progress, err := services.ContinueLearning(user.ID)
+	if err != nil {
+		http.Error(w, "Failed to load learning progress", http.StatusInternalServerError)
+		return
+	}
+
+	// Первым идет последний активный курс, который еще не пройден
+	var continueLearning *services.CourseProgress
+	completed := 0
+	for i := range progress {
+		if progress[i].TotalLessons > 0 && progress[i].CompletedLessons >= progress[i].TotalLessons {
+			completed++
+			continue
+		}
+		if continueLearning == nil && progress[i].Resume != nil {
+			continueLearning = &progress[i]
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"continue":          continueLearning,
+		"courses":           progress,
+		"enrolled_courses":  len(progress),
+		"completed_courses": completed,
+	})
+}
@@ -0,0 +1,352 @@
+package course
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"hired-valley-backend/config"
+	"hired-valley-backend/controllers/authentication"
+	"hired-valley-backend/models/courses"
+	"hired-valley-backend/models/users"
+	"hired-valley-backend/services"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// discussionAccess - участвовать в обсуждении могут команда курса и записавшиеся на курс;
+// при отказе ответ уже отправлен
+func discussionAccess(w http.ResponseWriter, userID, courseID uint) (courses.Course, bool) {
+	var course courses.Course
+	if err := config.DB.First(&course, courseID).Error; err != nil {
+		http.Error(w, "Course not found", http.StatusNotFound)
+		return course, false
+	}
+	if !services.IsCourseTeamMember(userID, &course) && !services.IsEnrolled(userID, &course) {
+		http.Error(w, "Enroll in the course to join the discussion", http.StatusForbidden)
+		return course, false
+	}
+	return course, true
+}
+
+// findQuestion - вопрос из параметра запроса; при ошибке ответ уже отправлен
+func findQuestion(w http.ResponseWriter, r *http.Request, param string) (courses.LessonQuestion, bool) {
+	var question courses.LessonQuestion
+	questionID, err := strconv.Atoi(r.URL.Query().Get(param))
+	if err != nil || questionID <= 0 {
+		http.Error(w, "Invalid question ID", http.StatusBadRequest)
+		return question, false
+	}
+	if err := config.DB.First(&question, questionID).Error; err != nil {
+		http.Error(w, "Question not found", http.StatusNotFound)
+		return question, false
+	}
+	return question, true
+}
+
+// LessonQuestionsHandler - GET: вопросы по уроку, POST: новый вопрос (инструктор получает уведомление)
+func LessonQuestionsHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := authentication.ValidateToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	lesson, ok := quizLesson(w, r)
+	if !ok {
+		return
+	}
+	course, ok := discussionAccess(w, user.ID, lesson.CourseID)
+	if !ok {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		query := config.DB.Where("lesson_id = ?", lesson.ID)
+		if r.URL.Query().Get("unanswered") == "true" {
+			query = query.Where("answers_count = 0")
+		}
+		var questions []courses.LessonQuestion
+		if err := query.Order("created_at DESC").Find(&questions).Error; err != nil {
+			http.Error(w, "Failed to list questions", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(questions)
+
+	case http.MethodPost:
+		var input struct {
+			Title string `json:"title"`
+			Body  string `json:"body"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			http.Error(w, "Invalid input", http.StatusBadRequest)
+			return
+		}
+		input.Title = strings.TrimSpace(input.Title)
+		if input.Title == "" {
+			http.Error(w, "Title is required", http.StatusBadRequest)
+			return
+		}
+
+		question := courses.LessonQuestion{
+			LessonID: lesson.ID,
+			CourseID: course.ID,
+			UserID:   user.ID,
+			Title:    input.Title,
+			Body:     strings.TrimSpace(input.Body),
+		}
+		if err := config.DB.Create(&question).Error; err != nil {
+			http.Error(w, "Failed to create question", http.StatusInternalServerError)
+			return
+		}
+
+		notifyCourseTeam(course, courses.PermissionAnswerQuestions, user.ID,
+			fmt.Sprintf("%s asked a question in \"%s\": %s", user.Name, lesson.Title, question.Title))
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(question)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// LessonQuestionHandler - вопрос с ответами: принятый ответ первым, затем по голосам
+func LessonQuestionHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user, err := authentication.ValidateToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	question, ok := findQuestion(w, r, "id")
+	if !ok {
+		return
+	}
+	if _, ok := discussionAccess(w, user.ID, question.CourseID); !ok {
+		return
+	}
+
+	order := "upvotes DESC, created_at"
+	if question.AcceptedAnswerID != nil {
+		order = fmt.Sprintf("id = %d DESC, %s", *question.AcceptedAnswerID, order)
+	}
+	if err := config.DB.Where("question_id = ?", question.ID).Order(order).Find(&question.Answers).Error; err != nil {
+		http.Error(w, "Failed to load answers", http.StatusInternalServerError)
+		return
+	}
+
+	// Ответы, за которые проголосовал текущий пользователь
+	var voted []uint
+	config.DB.Model(&courses.LessonAnswerVote{}).
+		Where("user_id = ? AND answer_id IN (?)", user.ID, config.DB.Model(&courses.LessonAnswer{}).Select("id").Where("question_id = ?", question.ID)).
+		Pluck("answer_id", &voted)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"question":   question,
+		"my_upvotes": voted,
+	})
+}
+
+// AnswerQuestionHandler - ответ на вопрос; автор вопроса получает уведомление
+func AnswerQuestionHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user, err := authentication.ValidateToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	question, ok := findQuestion(w, r, "question_id")
+	if !ok {
+		return
+	}
+	course, ok := discussionAccess(w, user.ID, question.CourseID)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Body string `json:"body"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil || strings.TrimSpace(input.Body) == "" {
+		http.Error(w, "Body is required", http.StatusBadRequest)
+		return
+	}
+
+	answer := courses.LessonAnswer{
+		QuestionID:   question.ID,
+		UserID:       user.ID,
+		Body:         strings.TrimSpace(input.Body),
+		IsInstructor: services.HasCoursePermission(user, &course, courses.PermissionAnswerQuestions),
+	}
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&answer).Error; err != nil {
+			return err
+		}
+		return tx.Model(&courses.LessonQuestion{}).Where("id = ?", question.ID).
+			Update("answers_count", gorm.Expr("answers_count + 1")).Error
+	})
+	if err != nil {
+		http.Error(w, "Failed to create answer", http.StatusInternalServerError)
+		return
+	}
+
+	if question.UserID != user.ID {
+		config.DB.Create(&users.NotificationMentor{
+			UserID:  question.UserID,
+			Message: fmt.Sprintf("%s answered your question \"%s\".", user.Name, question.Title),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(answer)
+}
+
+// UpvoteAnswerHandler - POST: голос за ответ, DELETE: отмена голоса
+func UpvoteAnswerHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost && r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user, err := authentication.ValidateToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	answerID, err := strconv.Atoi(r.URL.Query().Get("answer_id"))
+	if err != nil || answerID <= 0 {
+		http.Error(w, "Invalid answer ID", http.StatusBadRequest)
+		return
+	}
+	var answer courses.LessonAnswer
+	if err := config.DB.First(&answer, answerID).Error; err != nil {
+		http.Error(w, "Answer not found", http.StatusNotFound)
+		return
+	}
+	var question courses.LessonQuestion
+	if err := config.DB.First(&question, answer.QuestionID).Error; err != nil {
+		http.Error(w, "Question not found", http.StatusNotFound)
+		return
+	}
+	if _, ok := discussionAccess(w, user.ID, question.CourseID); !ok {
+		return
+	}
+	if answer.UserID == user.ID {
+		http.Error(w, "You cannot vote for your own answer", http.StatusBadRequest)
+		return
+	}
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		vote := courses.LessonAnswerVote{AnswerID: answer.ID, UserID: user.ID}
+		var existing courses.LessonAnswerVote
+		findErr := tx.Where(&vote).First(&existing).Error
+		if findErr != nil && !errors.Is(findErr, gorm.ErrRecordNotFound) {
+			return findErr
+		}
+
+		delta := 0
+		if r.Method == http.MethodPost && findErr != nil {
+			if err := tx.Create(&vote).Error; err != nil {
+				return err
+			}
+			delta = 1
+		}
+		if r.Method == http.MethodDelete && findErr == nil {
+			if err := tx.Where(&vote).Delete(&courses.LessonAnswerVote{}).Error; err != nil {
+				return err
+			}
+			delta = -1
+		}
+		if delta == 0 {
+			return nil
+		}
+		if err := tx.Model(&answer).Update("upvotes", gorm.Expr("upvotes + ?", delta)).Error; err != nil {
+			return err
+		}
+		return tx.First(&answer, answer.ID).Error
+	})
+	if err != nil {
+		http.Error(w, "Failed to update vote", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(answer)
+}
+
+// AcceptAnswerHandler - автор вопроса или инструктор отмечает принятый ответ (null - снять отметку)
+func AcceptAnswerHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user, err := authentication.ValidateToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	question, ok := findQuestion(w, r, "question_id")
+	if !ok {
+		return
+	}
+	course, ok := discussionAccess(w, user.ID, question.CourseID)
+	if !ok {
+		return
+	}
+	if question.UserID != user.ID && !services.HasCoursePermission(user, &course, courses.PermissionAnswerQuestions) {
+		http.Error(w, "Permission denied", http.StatusForbidden)
+		return
+	}
+
+	var input struct {
+		AnswerID *uint `json:"answer_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+
+	var answer courses.LessonAnswer
+	if input.AnswerID != nil {
+		if err := config.DB.First(&answer, *input.AnswerID).Error; err != nil || answer.QuestionID != question.ID {
+			http.Error(w, "Answer not found", http.StatusNotFound)
+			return
+		}
+	}
+
+	question.AcceptedAnswerID = input.AnswerID
+	if err := config.DB.Model(&question).Update("accepted_answer_id", input.AnswerID).Error; err != nil {
+		http.Error(w, "Failed to accept answer", http.StatusInternalServerError)
+		return
+	}
+
+	if input.AnswerID != nil && answer.UserID != user.ID {
+		config.DB.Create(&users.NotificationMentor{
+			UserID:  answer.UserID,
+			Message: fmt.Sprintf("Your answer to \"%s\" was accepted.", question.Title),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(question)
+}
@@ -0,0 +1,635 @@
+package course
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"hired-valley-backend/config"
+	"hired-valley-backend/controllers/authentication"
+	"hired-valley-backend/models/courses"
+	"hired-valley-backend/services"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// quizQuestionInput - вопрос в редакторе теста вместе с правильными ответами
+type quizQuestionInput struct {
+	ID              uint     `json:"id,omitempty"`
+	Type            string   `json:"type"`
+	Text            string   `json:"text"`
+	Options         []string `json:"options"`
+	CorrectOptions  []int64  `json:"correct_options"`
+	AcceptedAnswers []string `json:"accepted_answers"`
+	Points          int      `json:"points"`
+	Explanation     string   `json:"explanation"`
+}
+
+// quizInput - тест в редакторе инструктора
+type quizInput struct {
+	ID                  uint                `json:"id,omitempty"`
+	LessonID            uint                `json:"lesson_id"`
+	Title               string              `json:"title"`
+	PassingScore        int                 `json:"passing_score"`
+	MaxAttempts         int                 `json:"max_attempts"`
+	QuestionsPerAttempt int                 `json:"questions_per_attempt"`
+	Questions           []quizQuestionInput `json:"questions"`
+}
+
+// attemptReview - разбор вопроса после завершения попытки
+type attemptReview struct {
+	Question        courses.QuizQuestion `json:"question"`
+	Answer          *courses.QuizAnswer  `json:"answer"`
+	CorrectOptions  []int64              `json:"correct_options,omitempty"`
+	AcceptedAnswers []string             `json:"accepted_answers,omitempty"`
+	Explanation     string               `json:"explanation,omitempty"`
+}
+
+// toQuizInput - представление теста для инструктора
+func toQuizInput(quiz courses.Quiz) quizInput {
+	result := quizInput{
+		ID:                  quiz.ID,
+		LessonID:            quiz.LessonID,
+		Title:               quiz.Title,
+		PassingScore:        quiz.PassingScore,
+		MaxAttempts:         quiz.MaxAttempts,
+		QuestionsPerAttempt: quiz.QuestionsPerAttempt,
+		Questions:           make([]quizQuestionInput, 0, len(quiz.Questions)),
+	}
+	for _, question := range quiz.Questions {
+		result.Questions = append(result.Questions, quizQuestionInput{
+			ID:              question.ID,
+			Type:            question.Type,
+			Text:            question.Text,
+			Options:         question.Options,
+			CorrectOptions:  question.CorrectOptions,
+			AcceptedAnswers: question.AcceptedAnswers,
+			Points:          question.Points,
+			Explanation:     question.Explanation,
+		})
+	}
+	return result
+}
+
+// validateQuiz - проверка настроек теста и вопросов
+func validateQuiz(input *quizInput) error {
+	input.Title = strings.TrimSpace(input.Title)
+	if input.PassingScore == 0 {
+		input.PassingScore = 70
+	}
+	if input.PassingScore < 1 || input.PassingScore > 100 {
+		return errors.New("passing_score must be between 1 and 100")
+	}
+	if input.MaxAttempts < 0 || input.QuestionsPerAttempt < 0 {
+		return errors.New("max_attempts and questions_per_attempt must not be negative")
+	}
+	if len(input.Questions) == 0 {
+		return errors.New("quiz must have at least one question")
+	}
+	if input.QuestionsPerAttempt > len(input.Questions) {
+		return errors.New("questions_per_attempt exceeds the number of questions")
+	}
+
+	for i := range input.Questions {
+		question := &input.Questions[i]
+		question.Text = strings.TrimSpace(question.Text)
+		if question.Text == "" {
+			return fmt.Errorf("question %d: text is required", i+1)
+		}
+		if question.Points == 0 {
+			question.Points = 1
+		}
+		if question.Points < 0 {
+			return fmt.Errorf("question %d: points must be positive", i+1)
+		}
+
+		switch question.Type {
+		case courses.QuestionSingleChoice, courses.QuestionMultiSelect:
+			if len(question.Options) < 2 {
+				return fmt.Errorf("question %d: at least two options are required", i+1)
+			}
+			if len(question.CorrectOptions) == 0 {
+				return fmt.Errorf("question %d: correct_options are required", i+1)
+			}
+			if question.Type == courses.QuestionSingleChoice && len(question.CorrectOptions) != 1 {
+				return fmt.Errorf("question %d: single_choice must have exactly one correct option", i+1)
+			}
+			for _, option := range question.CorrectOptions {
+				if option < 0 || int(option) >= len(question.Options) {
+					return fmt.Errorf("question %d: correct option %d is out of range", i+1, option)
+				}
+			}
+		case courses.QuestionShortAnswer, courses.QuestionCodeSnippet:
+			if len(question.AcceptedAnswers) == 0 {
+				return fmt.Errorf("question %d: accepted_answers are required", i+1)
+			}
+		default:
+			return fmt.Errorf("question %d: unknown type %q", i+1, question.Type)
+		}
+	}
+	return nil
+}
+
+// lessonQuiz - тест урока с вопросами
+func lessonQuiz(lessonID uint) (courses.Quiz, error) {
+	var quiz courses.Quiz
+	err := config.DB.Preload("Questions", func(db *gorm.DB) *gorm.DB { return db.Order("position, id") }).
+		Where("lesson_id = ?", lessonID).First(&quiz).Error
+	return quiz, err
+}
+
+// hasPassedQuiz - сдан ли тест урока пользователем
+func hasPassedQuiz(userID, quizID uint) bool {
+	var count int64
+	config.DB.Model(&courses.QuizAttempt{}).Where("quiz_id = ? AND user_id = ? AND passed = ?", quizID, userID, true).Count(&count)
+	return count > 0
+}
+
+// answersRevealed - можно ли показывать ученику правильные ответы: после сдачи теста или когда попыток
+// больше не осталось
+func answersRevealed(userID uint, quiz courses.Quiz) bool {
+	if hasPassedQuiz(userID, quiz.ID) {
+		return true
+	}
+	if quiz.MaxAttempts == 0 {
+		return false
+	}
+	var used int64
+	config.DB.Model(&courses.QuizAttempt{}).Where("quiz_id = ? AND user_id = ?", quiz.ID, userID).Count(&used)
+	return int(used) >= quiz.MaxAttempts
+}
+
+// quizLesson - урок из параметра lesson_id; при ошибке ответ уже отправлен
+func quizLesson(w http.ResponseWriter, r *http.Request) (courses.Lesson, bool) {
+	var lesson courses.Lesson
+	lessonID, err := strconv.Atoi(r.URL.Query().Get("lesson_id"))
+	if err != nil || lessonID <= 0 {
+		http.Error(w, "Invalid lesson ID", http.StatusBadRequest)
+		return lesson, false
+	}
+	if err := config.DB.First(&lesson, lessonID).Error; err != nil {
+		http.Error(w, "Lesson not found", http.StatusNotFound)
+		return lesson, false
+	}
+	return lesson, true
+}
+
+// LessonQuizHandler - GET: тест урока, PUT: создание/замена теста, DELETE: удаление теста
+func LessonQuizHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := authentication.ValidateToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	lesson, ok := quizLesson(w, r)
+	if !ok {
+		return
+	}
+
+	var course courses.Course
+	if err := config.DB.First(&course, lesson.CourseID).Error; err != nil {
+		http.Error(w, "Course not found", http.StatusNotFound)
+		return
+	}
+	isInstructor := services.HasCoursePermission(user, &course, courses.PermissionEditLessons)
+
+	switch r.Method {
+	case http.MethodGet:
+		quiz, err := lessonQuiz(lesson.ID)
+		if err != nil {
+			http.Error(w, "Quiz not found", http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if isInstructor {
+			json.NewEncoder(w).Encode(toQuizInput(quiz))
+			return
+		}
+		if !lessonAccess(w, r, lesson) {
+			return
+		}
+
+		// Ученику вопросы выдаются только в рамках попытки
+		var attempts int64
+		config.DB.Model(&courses.QuizAttempt{}).Where("quiz_id = ? AND user_id = ?", quiz.ID, user.ID).Count(&attempts)
+		questionsCount := len(quiz.Questions)
+		if quiz.QuestionsPerAttempt > 0 {
+			questionsCount = quiz.QuestionsPerAttempt
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"id":              quiz.ID,
+			"lesson_id":       quiz.LessonID,
+			"title":           quiz.Title,
+			"passing_score":   quiz.PassingScore,
+			"max_attempts":    quiz.MaxAttempts,
+			"questions_count": questionsCount,
+			"attempts_used":   attempts,
+			"passed":          hasPassedQuiz(user.ID, quiz.ID),
+		})
+
+	case http.MethodPut:
+		if !isInstructor {
+			http.Error(w, "Permission denied", http.StatusForbidden)
+			return
+		}
+		if course.Status != courses.CourseStatusDraft {
+			http.Error(w, "Only drafts can be edited, create a draft version of the course", http.StatusConflict)
+			return
+		}
+
+		var input quizInput
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			http.Error(w, "Invalid input", http.StatusBadRequest)
+			return
+		}
+		if err := validateQuiz(&input); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var quiz courses.Quiz
+		err := config.DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Where(courses.Quiz{LessonID: lesson.ID}).FirstOrInit(&quiz).Error; err != nil {
+				return err
+			}
+			quiz.Title = input.Title
+			quiz.PassingScore = input.PassingScore
+			quiz.MaxAttempts = input.MaxAttempts
+			quiz.QuestionsPerAttempt = input.QuestionsPerAttempt
+			if err := tx.Omit("Questions").Save(&quiz).Error; err != nil {
+				return err
+			}
+
+			// Старые вопросы удаляются мягко, чтобы разбор прошлых попыток оставался доступен
+			if err := tx.Where("quiz_id = ?", quiz.ID).Delete(&courses.QuizQuestion{}).Error; err != nil {
+				return err
+			}
+			quiz.Questions = nil
+			for i, item := range input.Questions {
+				question := courses.QuizQuestion{
+					QuizID:          quiz.ID,
+					Type:            item.Type,
+					Text:            item.Text,
+					Options:         item.Options,
+					CorrectOptions:  item.CorrectOptions,
+					AcceptedAnswers: item.AcceptedAnswers,
+					Points:          item.Points,
+					Explanation:     item.Explanation,
+					Position:        i + 1,
+				}
+				if err := tx.Create(&question).Error; err != nil {
+					return err
+				}
+				quiz.Questions = append(quiz.Questions, question)
+			}
+			return nil
+		})
+		if err != nil {
+			http.Error(w, "Failed to save quiz", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(toQuizInput(quiz))
+
+	case http.MethodDelete:
+		if !isInstructor {
+			http.Error(w, "Permission denied", http.StatusForbidden)
+			return
+		}
+		if course.Status != courses.CourseStatusDraft {
+			http.Error(w, "Only drafts can be edited, create a draft version of the course", http.StatusConflict)
+			return
+		}
+		// Вопросы удаляются мягко вместе с тестом, чтобы разбор прошлых попыток оставался доступен
+		err := config.DB.Transaction(func(tx *gorm.DB) error {
+			var quiz courses.Quiz
+			if err := tx.Where("lesson_id = ?", lesson.ID).First(&quiz).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("quiz_id = ?", quiz.ID).Delete(&courses.QuizQuestion{}).Error; err != nil {
+				return err
+			}
+			return tx.Delete(&quiz).Error
+		})
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, "Quiz not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Failed to delete quiz", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// StartQuizAttemptHandler - начало попытки: случайная выборка вопросов из пула
+func StartQuizAttemptHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user, err := authentication.ValidateToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	lesson, ok := quizLesson(w, r)
+	if !ok {
+		return
+	}
+	if !lessonAccess(w, r, lesson) {
+		return
+	}
+
+	quiz, err := lessonQuiz(lesson.ID)
+	if err != nil {
+		http.Error(w, "Quiz not found", http.StatusNotFound)
+		return
+	}
+
+	// Незавершенная попытка продолжается, новая не создается
+	var attempt courses.QuizAttempt
+	err = config.DB.Where("quiz_id = ? AND user_id = ? AND submitted_at IS NULL", quiz.ID, user.ID).First(&attempt).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		var used int64
+		config.DB.Model(&courses.QuizAttempt{}).Where("quiz_id = ? AND user_id = ?", quiz.ID, user.ID).Count(&used)
+		if quiz.MaxAttempts > 0 && int(used) >= quiz.MaxAttempts {
+			http.Error(w, "No attempts left", http.StatusConflict)
+			return
+		}
+
+		pool := rand.Perm(len(quiz.Questions))
+		if quiz.QuestionsPerAttempt > 0 {
+			pool = pool[:quiz.QuestionsPerAttempt]
+		}
+		attempt = courses.QuizAttempt{QuizID: quiz.ID, UserID: user.ID, StartedAt: time.Now()}
+		for _, index := range pool {
+			attempt.QuestionIDs = append(attempt.QuestionIDs, int64(quiz.Questions[index].ID))
+			attempt.MaxScore += quiz.Questions[index].Points
+		}
+		if err := config.DB.Create(&attempt).Error; err != nil {
+			http.Error(w, "Failed to start attempt", http.StatusInternalServerError)
+			return
+		}
+	} else if err != nil {
+		http.Error(w, "Failed to start attempt", http.StatusInternalServerError)
+		return
+	}
+
+	questions, err := attemptQuestions(attempt)
+	if err != nil {
+		http.Error(w, "Failed to load questions", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"attempt":   attempt,
+		"questions": questions,
+	})
+}
+
+// attemptQuestions - вопросы попытки в порядке выдачи (включая удаленные позже)
+func attemptQuestions(attempt courses.QuizAttempt) ([]courses.QuizQuestion, error) {
+	var questions []courses.QuizQuestion
+	if err := config.DB.Unscoped().Where("id IN ?", []int64(attempt.QuestionIDs)).Find(&questions).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]courses.QuizQuestion, len(questions))
+	for _, question := range questions {
+		byID[question.ID] = question
+	}
+	ordered := make([]courses.QuizQuestion, 0, len(attempt.QuestionIDs))
+	for _, id := range attempt.QuestionIDs {
+		if question, ok := byID[uint(id)]; ok {
+			ordered = append(ordered, question)
+		}
+	}
+	return ordered, nil
+}
+
+// SubmitQuizAttemptHandler - сдача попытки, подсчет баллов; успешная сдача засчитывает урок
+func SubmitQuizAttemptHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user, err := authentication.ValidateToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	attemptID, err := strconv.Atoi(r.URL.Query().Get("attempt_id"))
+	if err != nil || attemptID <= 0 {
+		http.Error(w, "Invalid attempt ID", http.StatusBadRequest)
+		return
+	}
+
+	var attempt courses.QuizAttempt
+	if err := config.DB.First(&attempt, attemptID).Error; err != nil || attempt.UserID != user.ID {
+		http.Error(w, "Attempt not found", http.StatusNotFound)
+		return
+	}
+	if attempt.SubmittedAt != nil {
+		http.Error(w, "Attempt has already been submitted", http.StatusConflict)
+		return
+	}
+
+	var input struct {
+		Answers []struct {
+			QuestionID      uint    `json:"question_id"`
+			SelectedOptions []int64 `json:"selected_options"`
+			Text            string  `json:"text"`
+		} `json:"answers"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+
+	var quiz courses.Quiz
+	if err := config.DB.First(&quiz, attempt.QuizID).Error; err != nil {
+		http.Error(w, "Quiz not found", http.StatusNotFound)
+		return
+	}
+	// Доступ к уроку мог пропасть после начала попытки (отзыв места, отказ от курса)
+	var lesson courses.Lesson
+	if err := config.DB.First(&lesson, quiz.LessonID).Error; err != nil {
+		http.Error(w, "Lesson not found", http.StatusNotFound)
+		return
+	}
+	if !lessonAccess(w, r, lesson) {
+		return
+	}
+	questions, err := attemptQuestions(attempt)
+	if err != nil {
+		http.Error(w, "Failed to load questions", http.StatusInternalServerError)
+		return
+	}
+
+	given := make(map[uint]courses.QuizAnswer, len(input.Answers))
+	for _, answer := range input.Answers {
+		given[answer.QuestionID] = courses.QuizAnswer{
+			QuestionID:      answer.QuestionID,
+			SelectedOptions: answer.SelectedOptions,
+			TextAnswer:      answer.Text,
+		}
+	}
+
+	now := time.Now()
+	services.GradeQuizAttempt(&attempt, questions, given, quiz.PassingScore)
+	attempt.SubmittedAt = &now
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&attempt).Error; err != nil {
+			return err
+		}
+		if attempt.Passed {
+			return markLessonCompleted(tx, user.ID, lesson)
+		}
+		return nil
+	})
+	if err != nil {
+		http.Error(w, "Failed to submit attempt", http.StatusInternalServerError)
+		return
+	}
+
+	if attempt.Passed {
+		issueCertificate(user.ID, lesson.CourseID)
+	}
+
+	writeAttemptReview(w, attempt, questions, answersRevealed(user.ID, quiz))
+}
+
+// writeAttemptReview - попытка с разбором каждого вопроса; правильные ответы и пояснения - только при reveal
+func writeAttemptReview(w http.ResponseWriter, attempt courses.QuizAttempt, questions []courses.QuizQuestion, reveal bool) {
+	answers := make(map[uint]courses.QuizAnswer, len(attempt.Answers))
+	for _, answer := range attempt.Answers {
+		answers[answer.QuestionID] = answer
+	}
+
+	review := make([]attemptReview, 0, len(questions))
+	for _, question := range questions {
+		item := attemptReview{Question: question}
+		if reveal {
+			item.CorrectOptions = question.CorrectOptions
+			item.AcceptedAnswers = question.AcceptedAnswers
+			item.Explanation = question.Explanation
+		}
+		if answer, ok := answers[question.ID]; ok {
+			item.Answer = &answer
+		}
+		review = append(review, item)
+	}
+
+	attempt.Answers = nil
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"attempt":          attempt,
+		"review":           review,
+		"answers_revealed": reveal,
+	})
+}
+
+// QuizAttemptsHandler - попытки пользователя по тесту урока
+func QuizAttemptsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user, err := authentication.ValidateToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	lesson, ok := quizLesson(w, r)
+	if !ok {
+		return
+	}
+	var quiz courses.Quiz
+	if err := config.DB.Where("lesson_id = ?", lesson.ID).First(&quiz).Error; err != nil {
+		http.Error(w, "Quiz not found", http.StatusNotFound)
+		return
+	}
+
+	var attempts []courses.QuizAttempt
+	if err := config.DB.Where("quiz_id = ? AND user_id = ?", quiz.ID, user.ID).Order("started_at DESC").Find(&attempts).Error; err != nil {
+		http.Error(w, "Failed to list attempts", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(attempts)
+}
+
+// QuizAttemptReviewHandler - разбор завершенной попытки (ученик или инструктор курса)
+func QuizAttemptReviewHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user, err := authentication.ValidateToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	attemptID, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil || attemptID <= 0 {
+		http.Error(w, "Invalid attempt ID", http.StatusBadRequest)
+		return
+	}
+
+	var attempt courses.QuizAttempt
+	if err := config.DB.Preload("Answers").First(&attempt, attemptID).Error; err != nil {
+		http.Error(w, "Attempt not found", http.StatusNotFound)
+		return
+	}
+
+	// Инструктор видит разбор с ответами; ученик - только после сдачи или когда попыток не осталось
+	reveal := true
+	if attempt.UserID != user.ID {
+		var instructorID uint
+		config.DB.Model(&courses.Course{}).Select("courses.instructor_id").
+			Joins("JOIN lessons ON lessons.course_id = courses.id").
+			Joins("JOIN quizzes ON quizzes.lesson_id = lessons.id").
+			Where("quizzes.id = ?", attempt.QuizID).Scan(&instructorID)
+		if instructorID != user.ID {
+			http.Error(w, "Attempt not found", http.StatusNotFound)
+			return
+		}
+	} else {
+		var quiz courses.Quiz
+		if config.DB.First(&quiz, attempt.QuizID).Error == nil {
+			reveal = answersRevealed(user.ID, quiz)
+		}
+	}
+	if attempt.SubmittedAt == nil {
+		http.Error(w, "Attempt has not been submitted yet", http.StatusConflict)
+		return
+	}
+
+	questions, err := attemptQuestions(attempt)
+	if err != nil {
+		http.Error(w, "Failed to load questions", http.StatusInternalServerError)
+		return
+	}
+	writeAttemptReview(w, attempt, questions, reveal)
+}
@@ -0,0 +1,185 @@
+package course
+
+import (
+	"encoding/json"
+	"fmt"
+	"gorm.io/gorm"
+	"hired-valley-backend/config"
+	"hired-valley-backend/controllers/authentication"
+	"hired-valley-backend/models/courses"
+	"hired-valley-backend/models/users"
+	"hired-valley-backend/services"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// recalculateCourseRating - пересчет средней оценки и количества отзывов по всем версиям курса
+func recalculateCourseRating(tx *gorm.DB, course *courses.Course) error {
+	var stats struct {
+		Average float64
+		Count   int
+	}
+	if err := tx.Model(&courses.CourseReview{}).
+		Select("COALESCE(AVG(rating), 0) AS average, COUNT(*) AS count").
+		Where("course_id IN (?)", services.CourseVersionIDs(tx, course)).
+		Scan(&stats).Error; err != nil {
+		return err
+	}
+	originID := services.CourseOriginID(course)
+	return tx.Model(&courses.Course{}).Where("id = ? OR origin_id = ?", originID, originID).Updates(map[string]interface{}{
+		"average_rating": stats.Average,
+		"reviews_count":  stats.Count,
+	}).Error
+}
+
+// CourseReviewsHandler - GET: отзывы о курсе с распределением оценок, POST: отзыв записавшегося ученика
+func CourseReviewsHandler(w http.ResponseWriter, r *http.Request) {
+	courseID, err := strconv.Atoi(r.URL.Query().Get("course_id"))
+	if err != nil || courseID <= 0 {
+		http.Error(w, "Invalid course ID", http.StatusBadRequest)
+		return
+	}
+
+	var course courses.Course
+	if err := config.DB.First(&course, courseID).Error; err != nil {
+		http.Error(w, "Course not found", http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		var reviews []courses.CourseReview
+		if err := config.DB.Where("course_id IN (?)", services.CourseVersionIDs(config.DB, &course)).Order("created_at DESC").Find(&reviews).Error; err != nil {
+			http.Error(w, "Failed to list reviews", http.StatusInternalServerError)
+			return
+		}
+
+		distribution := map[int]int{1: 0, 2: 0, 3: 0, 4: 0, 5: 0}
+		for _, review := range reviews {
+			distribution[review.Rating]++
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"average_rating": course.AverageRating,
+			"reviews_count":  course.ReviewsCount,
+			"distribution":   distribution,
+			"reviews":        reviews,
+		})
+
+	case http.MethodPost:
+		user, err := authentication.ValidateToken(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		if !services.IsEnrolled(user.ID, &course) {
+			http.Error(w, "Only enrolled learners can review this course", http.StatusForbidden)
+			return
+		}
+
+		var input struct {
+			Rating  int    `json:"rating"`
+			Comment string `json:"comment"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			http.Error(w, "Invalid input", http.StatusBadRequest)
+			return
+		}
+		if input.Rating < 1 || input.Rating > 5 {
+			http.Error(w, "Rating must be between 1 and 5", http.StatusBadRequest)
+			return
+		}
+
+		// Повторный отзыв обновляет предыдущий
+		var review courses.CourseReview
+		created := false
+		err = config.DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Where(courses.CourseReview{CourseID: course.ID, UserID: user.ID}).FirstOrInit(&review).Error; err != nil {
+				return err
+			}
+			created = review.ID == 0
+			review.Rating = input.Rating
+			review.Comment = strings.TrimSpace(input.Comment)
+			if err := tx.Save(&review).Error; err != nil {
+				return err
+			}
+			return recalculateCourseRating(tx, &course)
+		})
+		if err != nil {
+			http.Error(w, "Failed to save review", http.StatusInternalServerError)
+			return
+		}
+
+		if created {
+			config.DB.Create(&users.NotificationMentor{
+				UserID:  course.InstructorID,
+				Message: fmt.Sprintf("%s left a %d-star review for \"%s\".", user.Name, review.Rating, course.Title),
+			})
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if created {
+			w.WriteHeader(http.StatusCreated)
+		}
+		json.NewEncoder(w).Encode(review)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// ReplyCourseReviewHandler - ответ инструктора на отзыв о курсе
+func ReplyCourseReviewHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user, err := authentication.ValidateToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	reviewID, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil || reviewID <= 0 {
+		http.Error(w, "Invalid review ID", http.StatusBadRequest)
+		return
+	}
+
+	var review courses.CourseReview
+	if err := config.DB.First(&review, reviewID).Error; err != nil {
+		http.Error(w, "Review not found", http.StatusNotFound)
+		return
+	}
+	if _, ok := teamCourse(w, user, strconv.Itoa(int(review.CourseID)), courses.PermissionAnswerQuestions); !ok {
+		return
+	}
+
+	var input struct {
+		Reply string `json:"reply"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil || strings.TrimSpace(input.Reply) == "" {
+		http.Error(w, "Reply is required", http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now()
+	review.Reply = strings.TrimSpace(input.Reply)
+	review.RepliedAt = &now
+	if err := config.DB.Save(&review).Error; err != nil {
+		http.Error(w, "Failed to save reply", http.StatusInternalServerError)
+		return
+	}
+
+	config.DB.Create(&users.NotificationMentor{
+		UserID:  review.UserID,
+		Message: fmt.Sprintf("%s replied to your course review.", user.Name),
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(review)
+}
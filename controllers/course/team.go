@@ -0,0 +1,595 @@
+package course
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"hired-valley-backend/config"
+	"hired-valley-backend/controllers/authentication"
+	"hired-valley-backend/models/courses"
+	"hired-valley-backend/models/users"
+	"hired-valley-backend/services"
+	"net/http"
+	"net/mail"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// teamMember - участник команды курса в ответе API
+type teamMember struct {
+	UserID      uint      `json:"user_id"`
+	Name        string    `json:"name"`
+	Email       string    `json:"email"`
+	Role        string    `json:"role"`
+	Permissions []string  `json:"permissions"`
+	JoinedAt    time.Time `json:"joined_at"`
+}
+
+// teamInput - роль и права участника; permissions = nil - права роли по умолчанию
+type teamInput struct {
+	Email       string    `json:"email"`
+	Role        string    `json:"role"`
+	Permissions *[]string `json:"permissions"`
+}
+
+// findTeamCourse - курс из параметра course_id без проверки прав; при ошибке ответ уже отправлен
+func findTeamCourse(w http.ResponseWriter, r *http.Request) (courses.Course, bool) {
+	var course courses.Course
+	courseID, err := strconv.Atoi(r.URL.Query().Get("course_id"))
+	if err != nil || courseID <= 0 {
+		http.Error(w, "Invalid course ID", http.StatusBadRequest)
+		return course, false
+	}
+	if err := config.DB.First(&course, courseID).Error; err != nil {
+		http.Error(w, "Course not found", http.StatusNotFound)
+		return course, false
+	}
+	return course, true
+}
+
+// notifyCourseTeam - уведомление владельцу и участникам команды с правом permission, кроме автора события
+func notifyCourseTeam(course courses.Course, permission string, exceptUserID uint, message string) {
+	recipients := []uint{course.InstructorID}
+	var members []uint
+	config.DB.Model(&courses.CourseCollaborator{}).
+		Where("course_id = ? AND ? = ANY(permissions)", services.CourseOriginID(&course), permission).
+		Pluck("user_id", &members)
+	recipients = append(recipients, members...)
+
+	for _, userID := range recipients {
+		if userID != exceptUserID {
+			config.DB.Create(&users.NotificationMentor{UserID: userID, Message: message})
+		}
+	}
+}
+
+// CourseTeamHandler - GET: команда курса (владелец и участники); владельцу и администратору -
+// еще и ожидающие приглашения
+func CourseTeamHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user, err := authentication.ValidateToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	course, ok := findTeamCourse(w, r)
+	if !ok {
+		return
+	}
+	manager := services.CanManageCourse(user, &course)
+	if !manager && !services.IsCourseTeamMember(user.ID, &course) {
+		http.Error(w, "Permission denied", http.StatusForbidden)
+		return
+	}
+	originID := services.CourseOriginID(&course)
+
+	var owner users.User
+	if err := config.DB.First(&owner, course.InstructorID).Error; err != nil {
+		http.Error(w, "Failed to load course team", http.StatusInternalServerError)
+		return
+	}
+	var origin courses.Course
+	config.DB.Unscoped().First(&origin, originID)
+	members := []teamMember{{
+		UserID:      owner.ID,
+		Name:        owner.Name,
+		Email:       owner.Email,
+		Role:        courses.CourseRoleOwner,
+		Permissions: courses.AllCoursePermissions,
+		JoinedAt:    origin.CreatedAt,
+	}}
+
+	var collaborators []courses.CourseCollaborator
+	if err := config.DB.Where("course_id = ?", originID).Order("created_at").Find(&collaborators).Error; err != nil {
+		http.Error(w, "Failed to load course team", http.StatusInternalServerError)
+		return
+	}
+	for _, collaborator := range collaborators {
+		var member users.User
+		config.DB.First(&member, collaborator.UserID)
+		members = append(members, teamMember{
+			UserID:      collaborator.UserID,
+			Name:        member.Name,
+			Email:       member.Email,
+			Role:        collaborator.Role,
+			Permissions: collaborator.Permissions,
+			JoinedAt:    collaborator.CreatedAt,
+		})
+	}
+
+	response := map[string]interface{}{
+		"course_id":      originID,
+		"members":        members,
+		"my_permissions": services.CoursePermissions(user.ID, &course),
+	}
+	if manager {
+		invitations := []courses.CourseInvitation{}
+		config.DB.Where("course_id = ? AND status = ? AND expires_at > ?", originID, courses.InvitationPending, time.Now()).
+			Order("created_at DESC").Find(&invitations)
+		response["invitations"] = invitations
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// CourseInvitationsHandler - POST: приглашение в команду курса по email (?course_id=),
+// DELETE: отзыв приглашения (?id=). Доступно владельцу курса и администратору
+func CourseInvitationsHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := authentication.ValidateToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPost:
+		course, ok := findTeamCourse(w, r)
+		if !ok {
+			return
+		}
+		if !services.CanManageCourse(user, &course) {
+			http.Error(w, "Only the course owner can invite collaborators", http.StatusForbidden)
+			return
+		}
+
+		var input teamInput
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			http.Error(w, "Invalid input", http.StatusBadRequest)
+			return
+		}
+		address, err := mail.ParseAddress(strings.TrimSpace(input.Email))
+		if err != nil {
+			http.Error(w, "Invalid email", http.StatusBadRequest)
+			return
+		}
+		email := strings.ToLower(address.Address)
+		var requested []string
+		if input.Permissions != nil {
+			requested = *input.Permissions
+		}
+		permissions, err := services.NormalizeCoursePermissions(input.Role, requested)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		originID := services.CourseOriginID(&course)
+
+		// Пользователь с этим email уже может быть в команде
+		var invitee users.User
+		inviteeFound := config.DB.Where("LOWER(email) = ?", email).First(&invitee).Error == nil
+		if inviteeFound && services.IsCourseTeamMember(invitee.ID, &course) {
+			http.Error(w, "User is already in the course team", http.StatusConflict)
+			return
+		}
+
+		token, err := services.NewInvitationToken()
+		if err != nil {
+			http.Error(w, "Failed to create invitation", http.StatusInternalServerError)
+			return
+		}
+
+		// Повторное приглашение на тот же email обновляет ожидающее и отправляет новую ссылку
+		var invitation courses.CourseInvitation
+		err = config.DB.Where("course_id = ? AND email = ? AND status = ?", originID, email, courses.InvitationPending).
+			First(&invitation).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, "Failed to create invitation", http.StatusInternalServerError)
+			return
+		}
+		invitation.CourseID = originID
+		invitation.Email = email
+		invitation.Role = input.Role
+		invitation.Permissions = permissions
+		invitation.Token = token
+		invitation.InvitedByID = user.ID
+		invitation.Status = courses.InvitationPending
+		invitation.ExpiresAt = time.Now().Add(services.CourseInvitationTTL)
+		if err := config.DB.Save(&invitation).Error; err != nil {
+			http.Error(w, "Failed to create invitation", http.StatusInternalServerError)
+			return
+		}
+
+		emailSent := services.SendCourseInvitation(invitation, course, *user) == nil
+		if inviteeFound {
+			config.DB.Create(&users.NotificationMentor{
+				UserID:  invitee.ID,
+				Message: fmt.Sprintf("%s invited you to join the team of \"%s\"", user.Name, course.Title),
+			})
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"invitation": invitation,
+			"email_sent": emailSent,
+		})
+
+	case http.MethodDelete:
+		invitationID, err := strconv.Atoi(r.URL.Query().Get("id"))
+		if err != nil || invitationID <= 0 {
+			http.Error(w, "Invalid invitation ID", http.StatusBadRequest)
+			return
+		}
+		var invitation courses.CourseInvitation
+		if err := config.DB.First(&invitation, invitationID).Error; err != nil {
+			http.Error(w, "Invitation not found", http.StatusNotFound)
+			return
+		}
+		var course courses.Course
+		if err := config.DB.First(&course, invitation.CourseID).Error; err != nil || !services.CanManageCourse(user, &course) {
+			http.Error(w, "Permission denied", http.StatusForbidden)
+			return
+		}
+		if invitation.Status != courses.InvitationPending {
+			http.Error(w, "Invitation is no longer pending", http.StatusConflict)
+			return
+		}
+
+		now := time.Now()
+		if err := config.DB.Model(&invitation).Updates(map[string]interface{}{
+			"status":       courses.InvitationRevoked,
+			"responded_at": now,
+		}).Error; err != nil {
+			http.Error(w, "Failed to revoke invitation", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// MyInvitationsHandler - GET: ожидающие приглашения на email текущего пользователя
+func MyInvitationsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user, err := authentication.ValidateToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	var invitations []courses.CourseInvitation
+	if err := config.DB.Where("email = ? AND status = ? AND expires_at > ?", strings.ToLower(user.Email), courses.InvitationPending, time.Now()).
+		Order("created_at DESC").Find(&invitations).Error; err != nil {
+		http.Error(w, "Failed to list invitations", http.StatusInternalServerError)
+		return
+	}
+
+	type invitationView struct {
+		courses.CourseInvitation
+		CourseTitle string `json:"course_title"`
+		InvitedBy   string `json:"invited_by"`
+	}
+	result := []invitationView{}
+	for _, invitation := range invitations {
+		var course courses.Course
+		var inviter users.User
+		config.DB.First(&course, invitation.CourseID)
+		config.DB.First(&inviter, invitation.InvitedByID)
+		result = append(result, invitationView{invitation, course.Title, inviter.Name})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// pendingInvitation - ожидающее приглашение по token из письма или id из списка приглашений.
+// Принять его может только владелец email, на который оно отправлено. При ошибке ответ уже отправлен
+func pendingInvitation(w http.ResponseWriter, r *http.Request, user *users.User) (courses.CourseInvitation, bool) {
+	var invitation courses.CourseInvitation
+	var input struct {
+		Token string `json:"token"`
+		ID    uint   `json:"id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil && r.URL.Query().Get("token") == "" {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return invitation, false
+	}
+	if input.Token == "" {
+		input.Token = r.URL.Query().Get("token")
+	}
+
+	query := config.DB.Model(&courses.CourseInvitation{})
+	switch {
+	case input.Token != "":
+		query = query.Where("token = ?", input.Token)
+	case input.ID != 0:
+		query = query.Where("id = ?", input.ID)
+	default:
+		http.Error(w, "Invitation token is required", http.StatusBadRequest)
+		return invitation, false
+	}
+	if err := query.First(&invitation).Error; err != nil {
+		http.Error(w, "Invitation not found", http.StatusNotFound)
+		return invitation, false
+	}
+
+	if !strings.EqualFold(invitation.Email, user.Email) {
+		http.Error(w, "This invitation was sent to a different email", http.StatusForbidden)
+		return invitation, false
+	}
+	if invitation.Status != courses.InvitationPending {
+		http.Error(w, "Invitation is no longer pending", http.StatusConflict)
+		return invitation, false
+	}
+	if time.Now().After(invitation.ExpiresAt) {
+		http.Error(w, "Invitation has expired", http.StatusGone)
+		return invitation, false
+	}
+	return invitation, true
+}
+
+// AcceptInvitationHandler - POST: принятие приглашения, пользователь становится участником команды
+func AcceptInvitationHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user, err := authentication.ValidateToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	invitation, ok := pendingInvitation(w, r, user)
+	if !ok {
+		return
+	}
+	var course courses.Course
+	if err := config.DB.First(&course, invitation.CourseID).Error; err != nil {
+		http.Error(w, "Course not found", http.StatusNotFound)
+		return
+	}
+	if course.InstructorID == user.ID {
+		http.Error(w, "You already own this course", http.StatusConflict)
+		return
+	}
+
+	var collaborator courses.CourseCollaborator
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("course_id = ? AND user_id = ?", invitation.CourseID, user.ID).First(&collaborator).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		collaborator.CourseID = invitation.CourseID
+		collaborator.UserID = user.ID
+		collaborator.Role = invitation.Role
+		collaborator.Permissions = invitation.Permissions
+		collaborator.InvitedByID = invitation.InvitedByID
+		if err := tx.Save(&collaborator).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		return tx.Model(&invitation).Updates(map[string]interface{}{
+			"status":       courses.InvitationAccepted,
+			"responded_at": now,
+		}).Error
+	})
+	if err != nil {
+		http.Error(w, "Failed to accept invitation", http.StatusInternalServerError)
+		return
+	}
+
+	config.DB.Create(&users.NotificationMentor{
+		UserID:  invitation.InvitedByID,
+		Message: fmt.Sprintf("%s joined the team of \"%s\"", user.Name, course.Title),
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(collaborator)
+}
+
+// DeclineInvitationHandler - POST: отказ от приглашения в команду курса
+func DeclineInvitationHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user, err := authentication.ValidateToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	invitation, ok := pendingInvitation(w, r, user)
+	if !ok {
+		return
+	}
+	now := time.Now()
+	if err := config.DB.Model(&invitation).Updates(map[string]interface{}{
+		"status":       courses.InvitationDeclined,
+		"responded_at": now,
+	}).Error; err != nil {
+		http.Error(w, "Failed to decline invitation", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// CourseTeamMemberHandler - участник команды (?course_id=&user_id=).
+// PUT: смена роли и прав владельцем; роль owner передает курс участнику, прежний владелец
+// становится соавтором. DELETE: исключение владельцем или выход участника из команды
+func CourseTeamMemberHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := authentication.ValidateToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	course, ok := findTeamCourse(w, r)
+	if !ok {
+		return
+	}
+	memberID, err := strconv.Atoi(r.URL.Query().Get("user_id"))
+	if err != nil || memberID <= 0 {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+	originID := services.CourseOriginID(&course)
+
+	var collaborator courses.CourseCollaborator
+	if err := config.DB.Where("course_id = ? AND user_id = ?", originID, memberID).First(&collaborator).Error; err != nil {
+		http.Error(w, "Team member not found", http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		if !services.CanManageCourse(user, &course) {
+			http.Error(w, "Only the course owner can change the team", http.StatusForbidden)
+			return
+		}
+		var input teamInput
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			http.Error(w, "Invalid input", http.StatusBadRequest)
+			return
+		}
+		if input.Role == "" {
+			input.Role = collaborator.Role
+		}
+
+		if input.Role == courses.CourseRoleOwner {
+			previousOwner := course.InstructorID
+			err := config.DB.Transaction(func(tx *gorm.DB) error {
+				if err := tx.Model(&courses.Course{}).Where("id = ? OR origin_id = ?", originID, originID).
+					Update("instructor_id", collaborator.UserID).Error; err != nil {
+					return err
+				}
+				collaborator.UserID = previousOwner
+				collaborator.Role = courses.CourseRoleCoInstructor
+				collaborator.Permissions = courses.AllCoursePermissions
+				return tx.Save(&collaborator).Error
+			})
+			if err != nil {
+				http.Error(w, "Failed to transfer ownership", http.StatusInternalServerError)
+				return
+			}
+			config.DB.Create(&users.NotificationMentor{
+				UserID:  uint(memberID),
+				Message: fmt.Sprintf("You are now the owner of \"%s\"", course.Title),
+			})
+
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"course_id": originID,
+				"owner_id":  memberID,
+				"previous":  collaborator,
+			})
+			return
+		}
+
+		var requested []string
+		if input.Permissions != nil {
+			requested = *input.Permissions
+		}
+		permissions, err := services.NormalizeCoursePermissions(input.Role, requested)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		collaborator.Role = input.Role
+		collaborator.Permissions = permissions
+		if err := config.DB.Save(&collaborator).Error; err != nil {
+			http.Error(w, "Failed to update team member", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(collaborator)
+
+	case http.MethodDelete:
+		if collaborator.UserID != user.ID && !services.CanManageCourse(user, &course) {
+			http.Error(w, "Permission denied", http.StatusForbidden)
+			return
+		}
+		if err := config.DB.Delete(&collaborator).Error; err != nil {
+			http.Error(w, "Failed to remove team member", http.StatusInternalServerError)
+			return
+		}
+		if collaborator.UserID != user.ID {
+			config.DB.Create(&users.NotificationMentor{
+				UserID:  collaborator.UserID,
+				Message: fmt.Sprintf("You were removed from the team of \"%s\"", course.Title),
+			})
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// TeachingCoursesHandler - GET: курсы, в командах которых состоит пользователь, с его ролью и правами
+func TeachingCoursesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user, err := authentication.ValidateToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	var collaborations []courses.CourseCollaborator
+	if err := config.DB.Where("user_id = ?", user.ID).Order("created_at DESC").Find(&collaborations).Error; err != nil {
+		http.Error(w, "Failed to list courses", http.StatusInternalServerError)
+		return
+	}
+
+	type teachingCourse struct {
+		Role        string           `json:"role"`
+		Permissions []string         `json:"permissions"`
+		Versions    []courses.Course `json:"versions"` // Все версии курса, включая черновик
+	}
+	result := []teachingCourse{}
+	for _, collaboration := range collaborations {
+		var versions []courses.Course
+		if err := config.DB.Where("id = ? OR origin_id = ?", collaboration.CourseID, collaboration.CourseID).
+			Order("version").Find(&versions).Error; err != nil {
+			http.Error(w, "Failed to list courses", http.StatusInternalServerError)
+			return
+		}
+		result = append(result, teachingCourse{collaboration.Role, collaboration.Permissions, versions})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
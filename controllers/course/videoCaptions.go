@@ -0,0 +1,431 @@
+package course
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"hired-valley-backend/config"
+	"hired-valley-backend/controllers/authentication"
+	"hired-valley-backend/models/courses"
+	"hired-valley-backend/models/courses/videos"
+	"hired-valley-backend/services"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// captionTrackView - дорожка субтитров со ссылкой на файл
+type captionTrackView struct {
+	videos.CaptionTrack
+	URL string `json:"url"`
+}
+
+// requestVideo - видео из параметра video_id или видео урока lesson_id; при ошибке ответ уже отправлен
+func requestVideo(w http.ResponseWriter, r *http.Request) (videos.Video, bool) {
+	var video videos.Video
+	if r.URL.Query().Get("video_id") == "" && r.URL.Query().Get("lesson_id") != "" {
+		lessonID, err := strconv.Atoi(r.URL.Query().Get("lesson_id"))
+		if err != nil || lessonID <= 0 {
+			http.Error(w, "Invalid lesson ID", http.StatusBadRequest)
+			return video, false
+		}
+		var lesson courses.Lesson
+		if err := config.DB.First(&lesson, lessonID).Error; err != nil {
+			http.Error(w, "Lesson not found", http.StatusNotFound)
+			return video, false
+		}
+		if video, err = services.LessonVideo(lesson); err != nil {
+			http.Error(w, "Lesson has no published video", http.StatusNotFound)
+			return video, false
+		}
+		return video, true
+	}
+
+	videoID, err := strconv.Atoi(r.URL.Query().Get("video_id"))
+	if err != nil || videoID <= 0 {
+		http.Error(w, "Invalid video ID", http.StatusBadRequest)
+		return video, false
+	}
+	if err := config.DB.First(&video, videoID).Error; err != nil {
+		http.Error(w, "Video not found", http.StatusNotFound)
+		return video, false
+	}
+	return video, true
+}
+
+// videoLesson - урок версии курса, из которой открыто видео урока: ?lesson_id=, иначе урок загрузки.
+// При ошибке ответ уже отправлен
+func videoLesson(w http.ResponseWriter, r *http.Request, video videos.Video) (courses.Lesson, bool) {
+	var lesson courses.Lesson
+	lessonID := video.LessonID
+	if value := r.URL.Query().Get("lesson_id"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			http.Error(w, "Invalid lesson ID", http.StatusBadRequest)
+			return lesson, false
+		}
+		lessonID = uint(parsed)
+	}
+	if err := config.DB.First(&lesson, lessonID).Error; err != nil {
+		http.Error(w, "Lesson not found", http.StatusNotFound)
+		return lesson, false
+	}
+	if lesson.ID != video.LessonID && lesson.VideoLink != video.VideoLink {
+		http.Error(w, "Video does not belong to this lesson", http.StatusBadRequest)
+		return lesson, false
+	}
+	return lesson, true
+}
+
+// videoViewAccess - видео контента открыто всем, видео урока - тем, кому доступен урок
+func videoViewAccess(w http.ResponseWriter, r *http.Request, video videos.Video) bool {
+	if video.TargetType == videos.TargetContent {
+		return true
+	}
+	lesson, ok := videoLesson(w, r, video)
+	if !ok {
+		return false
+	}
+	return lessonAccess(w, r, lesson)
+}
+
+// videoEditAccess - субтитры и главы меняет загрузивший видео, администратор или участник команды курса
+// с правом upload_videos; у видео урока - только в черновике курса, как и остальное содержимое урока.
+// При ошибке ответ уже отправлен
+func videoEditAccess(w http.ResponseWriter, r *http.Request, video videos.Video) (uint, bool) {
+	user, err := authentication.ValidateToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return 0, false
+	}
+	owner := video.UploadedBy == user.ID || user.Role == "admin"
+	if video.TargetType != videos.TargetLesson {
+		if !owner {
+			http.Error(w, "Permission denied", http.StatusForbidden)
+			return 0, false
+		}
+		return user.ID, true
+	}
+
+	lesson, ok := videoLesson(w, r, video)
+	if !ok {
+		return 0, false
+	}
+	var course courses.Course
+	if err := config.DB.First(&course, lesson.CourseID).Error; err != nil {
+		http.Error(w, "Course not found", http.StatusNotFound)
+		return 0, false
+	}
+	if !owner && !services.HasCoursePermission(user, &course, courses.PermissionUploadVideos) {
+		http.Error(w, "Permission denied", http.StatusForbidden)
+		return 0, false
+	}
+	if course.Status != courses.CourseStatusDraft {
+		http.Error(w, "Only drafts can be edited, create a draft version of the course", http.StatusConflict)
+		return 0, false
+	}
+	return user.ID, true
+}
+
+// VideoCaptionsHandler - субтитры видео (?video_id= или ?lesson_id=).
+// GET: список дорожек со ссылками на файлы. POST (multipart/form-data: file, language, label, default):
+// загрузка WebVTT или SRT, дорожка языка заменяется, расшифровка строится из реплик. DELETE (?id=): удаление дорожки
+func VideoCaptionsHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		video, ok := requestVideo(w, r)
+		if !ok || !videoViewAccess(w, r, video) {
+			return
+		}
+		var tracks []videos.CaptionTrack
+		if err := config.DB.Where("video_id = ?", video.ID).Order("is_default DESC, language").Find(&tracks).Error; err != nil {
+			http.Error(w, "Failed to list captions", http.StatusInternalServerError)
+			return
+		}
+		views := make([]captionTrackView, 0, len(tracks))
+		for _, track := range tracks {
+			url := fmt.Sprintf("/videos/captions/file?id=%d", track.ID)
+			if lessonID := r.URL.Query().Get("lesson_id"); lessonID != "" && video.TargetType == videos.TargetLesson {
+				url += "&lesson_id=" + lessonID
+			}
+			views = append(views, captionTrackView{CaptionTrack: track, URL: url})
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(views)
+
+	case http.MethodPost:
+		video, ok := requestVideo(w, r)
+		if !ok {
+			return
+		}
+		userID, ok := videoEditAccess(w, r, video)
+		if !ok {
+			return
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, services.MaxCaptionFileBytes+1<<20)
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			http.Error(w, "Caption file is required", http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+		data, err := io.ReadAll(io.LimitReader(file, services.MaxCaptionFileBytes+1))
+		if err != nil {
+			http.Error(w, "Failed to read caption file", http.StatusBadRequest)
+			return
+		}
+		if len(data) > services.MaxCaptionFileBytes {
+			http.Error(w, fmt.Sprintf("caption file must not exceed %d bytes", services.MaxCaptionFileBytes), http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		language, err := services.NormalizeLanguage(r.FormValue("language"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		format := services.DetectCaptionFormat(header.Filename, data)
+		cues, err := services.ParseCaptions(data, format)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		label := strings.TrimSpace(r.FormValue("label"))
+		if label == "" {
+			label = language
+		}
+		track := videos.CaptionTrack{
+			VideoID:   video.ID,
+			Language:  language,
+			Label:     label,
+			Format:    format,
+			IsDefault: r.FormValue("default") == "true",
+			CreatedBy: userID,
+		}
+		if err := services.SaveCaptionTrack(&track, cues); err != nil {
+			http.Error(w, "Failed to save captions", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(captionTrackView{CaptionTrack: track, URL: fmt.Sprintf("/videos/captions/file?id=%d", track.ID)})
+
+	case http.MethodDelete:
+		trackID, err := strconv.Atoi(r.URL.Query().Get("id"))
+		if err != nil || trackID <= 0 {
+			http.Error(w, "Invalid caption track ID", http.StatusBadRequest)
+			return
+		}
+		var track videos.CaptionTrack
+		if err := config.DB.First(&track, trackID).Error; err != nil {
+			http.Error(w, "Caption track not found", http.StatusNotFound)
+			return
+		}
+		var video videos.Video
+		if err := config.DB.First(&video, track.VideoID).Error; err != nil {
+			http.Error(w, "Video not found", http.StatusNotFound)
+			return
+		}
+		if _, ok := videoEditAccess(w, r, video); !ok {
+			return
+		}
+		if err := services.DeleteCaptionTrack(&track); err != nil {
+			http.Error(w, "Failed to delete captions", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// CaptionFileHandler - GET: файл субтитров (?id=&format=vtt|srt, по умолчанию vtt)
+func CaptionFileHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	trackID, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil || trackID <= 0 {
+		http.Error(w, "Invalid caption track ID", http.StatusBadRequest)
+		return
+	}
+	var track videos.CaptionTrack
+	if err := config.DB.First(&track, trackID).Error; err != nil {
+		http.Error(w, "Caption track not found", http.StatusNotFound)
+		return
+	}
+	var video videos.Video
+	if err := config.DB.First(&video, track.VideoID).Error; err != nil {
+		http.Error(w, "Video not found", http.StatusNotFound)
+		return
+	}
+	if !videoViewAccess(w, r, video) {
+		return
+	}
+
+	switch r.URL.Query().Get("format") {
+	case "", services.CaptionFormatVTT:
+		w.Header().Set("Content-Type", "text/vtt; charset=utf-8")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`inline; filename="%d.%s.vtt"`, video.ID, track.Language))
+		io.WriteString(w, track.Content)
+	case services.CaptionFormatSRT:
+		cues, err := services.ParseCaptions([]byte(track.Content), services.CaptionFormatVTT)
+		if err != nil {
+			http.Error(w, "Failed to convert captions", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/x-subrip; charset=utf-8")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%d.%s.srt"`, video.ID, track.Language))
+		io.WriteString(w, services.RenderSRT(cues))
+	default:
+		http.Error(w, "Invalid format. Use vtt or srt", http.StatusBadRequest)
+	}
+}
+
+// VideoTranscriptHandler - расшифровка видео (?video_id= или ?lesson_id=, &language=).
+// GET: фрагменты с временем начала и полный текст. PUT: замена расшифровки языка списком
+// фрагментов [{start_seconds, end_seconds, text}], например из сервиса распознавания речи
+func VideoTranscriptHandler(w http.ResponseWriter, r *http.Request) {
+	video, ok := requestVideo(w, r)
+	if !ok {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		if !videoViewAccess(w, r, video) {
+			return
+		}
+		query := config.DB.Where("video_id = ?", video.ID)
+		if value := r.URL.Query().Get("language"); value != "" {
+			language, err := services.NormalizeLanguage(value)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			query = query.Where("language = ?", language)
+		}
+		var segments []videos.TranscriptSegment
+		if err := query.Order("language, position").Find(&segments).Error; err != nil {
+			http.Error(w, "Failed to load transcript", http.StatusInternalServerError)
+			return
+		}
+
+		// Полный текст по языкам
+		languages := []string{}
+		texts := map[string][]string{}
+		for _, segment := range segments {
+			if _, ok := texts[segment.Language]; !ok {
+				languages = append(languages, segment.Language)
+			}
+			texts[segment.Language] = append(texts[segment.Language], segment.Text)
+		}
+		fullText := map[string]string{}
+		for _, language := range languages {
+			fullText[language] = strings.Join(texts[language], " ")
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"video_id":  video.ID,
+			"languages": languages,
+			"segments":  segments,
+			"text":      fullText,
+		})
+
+	case http.MethodPut:
+		if _, ok := videoEditAccess(w, r, video); !ok {
+			return
+		}
+		language, err := services.NormalizeLanguage(r.URL.Query().Get("language"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		var segments []videos.TranscriptSegment
+		if err := json.NewDecoder(r.Body).Decode(&segments); err != nil {
+			http.Error(w, "Invalid input", http.StatusBadRequest)
+			return
+		}
+		if err := services.ReplaceTranscript(video.ID, language, segments); err != nil {
+			if errors.Is(err, services.ErrInvalidCaptions) {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			http.Error(w, "Failed to save transcript", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(segments)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// VideoChaptersHandler - главы видео (?video_id= или ?lesson_id=). GET: список по времени начала.
+// PUT: замена всех глав [{title, start_seconds}]
+func VideoChaptersHandler(w http.ResponseWriter, r *http.Request) {
+	video, ok := requestVideo(w, r)
+	if !ok {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		if !videoViewAccess(w, r, video) {
+			return
+		}
+		var chapters []videos.VideoChapter
+		if err := config.DB.Where("video_id = ?", video.ID).Order("start_seconds").Find(&chapters).Error; err != nil {
+			http.Error(w, "Failed to load chapters", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(chapters)
+
+	case http.MethodPut:
+		if _, ok := videoEditAccess(w, r, video); !ok {
+			return
+		}
+		var chapters []videos.VideoChapter
+		if err := json.NewDecoder(r.Body).Decode(&chapters); err != nil {
+			http.Error(w, "Invalid input", http.StatusBadRequest)
+			return
+		}
+		if err := services.ValidateChapters(chapters, video.DurationSeconds); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		for i := range chapters {
+			chapters[i].ID = 0
+			chapters[i].VideoID = video.ID
+		}
+
+		err := config.DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Where("video_id = ?", video.ID).Delete(&videos.VideoChapter{}).Error; err != nil {
+				return err
+			}
+			if len(chapters) == 0 {
+				return nil
+			}
+			return tx.Create(&chapters).Error
+		})
+		if err != nil {
+			http.Error(w, "Failed to save chapters", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(chapters)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
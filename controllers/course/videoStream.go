@@ -0,0 +1,59 @@
+package course
+
+import (
+	"errors"
+	"fmt"
+	"hired-valley-backend/config"
+	"hired-valley-backend/models/courses/videos"
+	"hired-valley-backend/services"
+	"net/http"
+	"path/filepath"
+	"time"
+)
+
+// videoContentTypes - MIME-тип файла по контейнеру
+var videoContentTypes = map[string]string{
+	"mp4":  "video/mp4",
+	"mov":  "video/quicktime",
+	"webm": "video/webm",
+	"mkv":  "video/x-matroska",
+}
+
+// VideoStreamHandler - GET/HEAD: просмотр видео из собственного хранилища по подписанной ссылке
+// (?id=&expires=&signature=). Ссылку выдают уроки и контент тем, у кого есть к ним доступ;
+// поддерживаются Range-запросы для перемотки
+func VideoStreamHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	videoID, expires, err := services.VerifyVideoLink(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	var video videos.Video
+	if err := config.DB.First(&video, videoID).Error; err != nil {
+		http.Error(w, "Video not found", http.StatusNotFound)
+		return
+	}
+	file, modified, err := services.OpenHostedVideo(&video)
+	if err != nil {
+		if errors.Is(err, services.ErrVideoFileUnavailable) {
+			http.Error(w, "Video not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to open video", http.StatusInternalServerError)
+		return
+	}
+	defer file.Close()
+
+	if contentType, ok := videoContentTypes[video.Container]; ok {
+		w.Header().Set("Content-Type", contentType)
+	}
+	// Браузер может кэшировать файл, пока действует ссылка, но не общие кэши
+	w.Header().Set("Cache-Control", fmt.Sprintf("private, max-age=%d", int(time.Until(expires).Seconds())))
+	http.ServeContent(w, r, filepath.Base(video.StorageKey), modified, file)
+}
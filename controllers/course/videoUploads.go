@@ -0,0 +1,398 @@
+package course
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"golang.org/x/oauth2"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
+	"google.golang.org/api/youtube/v3"
+	"hired-valley-backend/config"
+	"hired-valley-backend/controllers/authentication"
+	"hired-valley-backend/models/courses"
+	"hired-valley-backend/models/courses/videos"
+	"hired-valley-backend/models/users"
+	"hired-valley-backend/services"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// videoUploadInput - параметры новой загрузки видео
+type videoUploadInput struct {
+	Target      string   `json:"target"` // lesson или content
+	Host        string   `json:"host"`   // youtube или self, по умолчанию VIDEO_DEFAULT_HOST
+	LessonID    uint     `json:"lesson_id"`
+	FileName    string   `json:"file_name"`
+	Size        int64    `json:"size"`
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	Category    string   `json:"category"`
+	Tags        []string `json:"tags"`
+}
+
+// videoUploader - автор загрузки. Google-аккаунт нужен только для размещения на YouTube,
+// в собственное хранилище можно загружать с токеном платформы
+type videoUploader struct {
+	UserID      uint
+	GoogleEmail string
+	GoogleToken string
+}
+
+// canPublish - есть ли у автора доступ к площадке
+func (u videoUploader) canPublish(host string) bool {
+	return host != videos.HostYouTube || u.GoogleToken != "" || u.GoogleEmail != ""
+}
+
+// authenticateUploader - авторизация по токену платформы (с YouTube-каналом, подключенным через
+// /login/youtube) или по Google OAuth токену
+func authenticateUploader(r *http.Request) (videoUploader, error) {
+	if user, err := authentication.ValidateToken(r); err == nil {
+		uploader := videoUploader{UserID: user.ID}
+		var account users.YoutubeUser
+		if config.DB.Where("user_id = ?", user.ID).First(&account).Error == nil {
+			uploader.GoogleEmail = account.Email
+		}
+		return uploader, nil
+	}
+	token, err := authentication.ValidateGoogleToken(r)
+	if err != nil {
+		return videoUploader{}, err
+	}
+	return videoUploader{UserID: token.UserID, GoogleEmail: token.Email, GoogleToken: token.AccessToken}, nil
+}
+
+// lessonUploadTarget - урок черновика курса, в который пользователь может загружать
+// видео (право upload_videos). При ошибке ответ уже отправлен
+func lessonUploadTarget(w http.ResponseWriter, userID uint, lessonID uint) (courses.Lesson, bool) {
+	var lesson courses.Lesson
+	if err := config.DB.First(&lesson, lessonID).Error; err != nil {
+		http.Error(w, "Lesson not found", http.StatusNotFound)
+		return lesson, false
+	}
+
+	var uploader users.User
+	if err := config.DB.First(&uploader, userID).Error; err != nil {
+		http.Error(w, "User not found", http.StatusUnauthorized)
+		return lesson, false
+	}
+	course, ok := teamCourse(w, &uploader, strconv.Itoa(int(lesson.CourseID)), courses.PermissionUploadVideos)
+	if !ok {
+		return lesson, false
+	}
+	if course.Status != courses.CourseStatusDraft {
+		http.Error(w, "Only drafts can be edited, create a draft version of the course", http.StatusConflict)
+		return lesson, false
+	}
+	return lesson, true
+}
+
+// writeVideoUploadError - ответ на ошибку конвейера загрузки
+func writeVideoUploadError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrUnknownVideoHost):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, services.ErrUnsupportedFileType):
+		http.Error(w, err.Error(), http.StatusUnsupportedMediaType)
+	case errors.Is(err, services.ErrUploadTooLarge):
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+	case errors.Is(err, services.ErrUploadOffsetMismatch), errors.Is(err, services.ErrUploadNotActive):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, "Failed to process video upload", http.StatusInternalServerError)
+	}
+}
+
+// writeVideoStatus - состояние загрузки; Upload-Offset - смещение, с которого продолжать загрузку
+func writeVideoStatus(w http.ResponseWriter, video videos.Video, status int) {
+	w.Header().Set("Upload-Offset", strconv.FormatInt(video.ReceivedBytes, 10))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(video)
+}
+
+// uploaderVideo - видео из параметра id, загруженное текущим пользователем; при ошибке ответ уже отправлен
+func uploaderVideo(w http.ResponseWriter, r *http.Request, uploader videoUploader) (videos.Video, bool) {
+	var video videos.Video
+	videoID, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil || videoID <= 0 {
+		http.Error(w, "Invalid video ID", http.StatusBadRequest)
+		return video, false
+	}
+	if err := config.DB.Where("id = ? AND uploaded_by = ?", videoID, uploader.UserID).First(&video).Error; err != nil {
+		http.Error(w, "Video not found", http.StatusNotFound)
+		return video, false
+	}
+	return video, true
+}
+
+// VideoUploadsHandler - конвейер загрузки видео (токен платформы или Google OAuth токен).
+// POST: новая загрузка урока или контента {target, host, lesson_id, file_name, size, ...}; файл затем
+// передается частями в /videos/uploads/chunk. GET: состояние загрузки (?id=), клиент опрашивает его
+// до статуса ready или failed. DELETE: отмена загрузки (?id=)
+func VideoUploadsHandler(w http.ResponseWriter, r *http.Request) {
+	uploader, err := authenticateUploader(r)
+	if err != nil {
+		http.Error(w, "Unauthorized or forbidden: "+err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPost:
+		var input videoUploadInput
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			http.Error(w, "Invalid input", http.StatusBadRequest)
+			return
+		}
+
+		video := videos.Video{
+			Title:       strings.TrimSpace(input.Title),
+			Description: strings.TrimSpace(input.Description),
+			UploadedBy:  uploader.UserID,
+			TargetType:  input.Target,
+			Host:        input.Host,
+			FileName:    input.FileName,
+			SizeBytes:   input.Size,
+			GoogleEmail: uploader.GoogleEmail,
+			GoogleToken: uploader.GoogleToken,
+		}
+		if video.Host == "" {
+			video.Host = services.DefaultVideoHost()
+		}
+		if !uploader.canPublish(video.Host) {
+			http.Error(w, "YouTube hosting requires a Google token or a connected YouTube channel", http.StatusBadRequest)
+			return
+		}
+		switch input.Target {
+		case videos.TargetLesson:
+			lesson, ok := lessonUploadTarget(w, uploader.UserID, input.LessonID)
+			if !ok {
+				return
+			}
+			video.LessonID = lesson.ID
+			if video.Description == "" {
+				video.Description = "Uploaded via Hired Valley platform"
+			}
+		case videos.TargetContent:
+			if video.Title == "" || video.Description == "" || strings.TrimSpace(input.Category) == "" {
+				http.Error(w, "Missing required fields (title, description, category)", http.StatusBadRequest)
+				return
+			}
+			video.Category = strings.TrimSpace(input.Category)
+			for _, tag := range input.Tags {
+				if tag = strings.TrimSpace(tag); tag != "" {
+					video.Tags = append(video.Tags, tag)
+				}
+			}
+		default:
+			http.Error(w, fmt.Sprintf("target must be %q or %q", videos.TargetLesson, videos.TargetContent), http.StatusBadRequest)
+			return
+		}
+
+		if err := services.CreateVideoUpload(&video); err != nil {
+			writeVideoUploadError(w, err)
+			return
+		}
+		w.Header().Set("Location", fmt.Sprintf("/videos/uploads/chunk?id=%d", video.ID))
+		writeVideoStatus(w, video, http.StatusCreated)
+
+	case http.MethodGet:
+		video, ok := uploaderVideo(w, r, uploader)
+		if !ok {
+			return
+		}
+		writeVideoStatus(w, video, http.StatusOK)
+
+	case http.MethodDelete:
+		video, ok := uploaderVideo(w, r, uploader)
+		if !ok {
+			return
+		}
+		if err := services.CancelVideoUpload(&video); err != nil {
+			writeVideoUploadError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// VideoUploadChunkHandler - PUT: часть файла (?id=&offset=), тело запроса - байты файла.
+// offset должен совпадать с received_bytes; после обрыва связи клиент узнает его через GET /videos/uploads
+func VideoUploadChunkHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	uploader, err := authenticateUploader(r)
+	if err != nil {
+		http.Error(w, "Unauthorized or forbidden: "+err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	video, ok := uploaderVideo(w, r, uploader)
+	if !ok {
+		return
+	}
+	offset, err := strconv.ParseInt(r.URL.Query().Get("offset"), 10, 64)
+	if err != nil || offset < 0 {
+		http.Error(w, "Invalid offset", http.StatusBadRequest)
+		return
+	}
+	if r.ContentLength > services.MaxVideoChunkBytes {
+		http.Error(w, fmt.Sprintf("chunk must not exceed %d bytes", services.MaxVideoChunkBytes), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	body := http.MaxBytesReader(w, r.Body, services.MaxVideoChunkBytes)
+	if err := services.WriteVideoChunk(&video, offset, body); err != nil {
+		var maxBytes *http.MaxBytesError
+		if errors.As(err, &maxBytes) {
+			http.Error(w, fmt.Sprintf("chunk must not exceed %d bytes", services.MaxVideoChunkBytes), http.StatusRequestEntityTooLarge)
+			return
+		}
+		if errors.Is(err, services.ErrUploadOffsetMismatch) {
+			w.Header().Set("Upload-Offset", strconv.FormatInt(video.ReceivedBytes, 10))
+		}
+		writeVideoUploadError(w, err)
+		return
+	}
+
+	status := http.StatusOK
+	if video.Status != videos.VideoUploading {
+		status = http.StatusAccepted // Файл получен полностью и поставлен в очередь (или отклонен проверкой)
+	}
+	writeVideoStatus(w, video, status)
+}
+
+// RetryVideoUploadHandler - POST: повторная публикация видео со статусом failed (?id=);
+// токен из запроса заменяет сохраненный, если тот истек
+func RetryVideoUploadHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	uploader, err := authenticateUploader(r)
+	if err != nil {
+		http.Error(w, "Unauthorized or forbidden: "+err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	video, ok := uploaderVideo(w, r, uploader)
+	if !ok {
+		return
+	}
+	if err := services.RetryVideoPublish(&video, uploader.GoogleToken); err != nil {
+		if errors.Is(err, services.ErrUploadNotActive) {
+			http.Error(w, "Only failed uploads can be retried", http.StatusConflict)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusGone)
+		return
+	}
+	writeVideoStatus(w, video, http.StatusAccepted)
+}
+
+// YouTubeHost - размещение видео на канале Google-аккаунта, с которого оно загружено (доступ по ссылке).
+// Если аккаунт подключен через /login/youtube, токен обновляется по сохраненному refresh token
+type YouTubeHost struct{}
+
+// service - клиент YouTube API с токеном аккаунта видео
+func (YouTubeHost) service(ctx context.Context, video *videos.Video) (*youtube.Service, error) {
+	token := &oauth2.Token{AccessToken: video.GoogleToken}
+	var account users.YoutubeUser
+	if video.GoogleEmail != "" && config.DB.Where("email = ?", video.GoogleEmail).First(&account).Error == nil &&
+		account.RefreshToken != "" {
+		token = &oauth2.Token{AccessToken: account.AccessToken, RefreshToken: account.RefreshToken, Expiry: account.Expiry}
+	}
+	if token.AccessToken == "" && token.RefreshToken == "" {
+		return nil, fmt.Errorf("%w: YouTube hosting requires Google authorization", services.ErrVideoRejected)
+	}
+	tokenSource := authentication.GoogleOauthConfig.TokenSource(ctx, token)
+
+	service, err := youtube.NewService(ctx, option.WithTokenSource(tokenSource))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create YouTube service: %v", err)
+	}
+	return service, nil
+}
+
+// youtubeError - ошибки авторизации и отклоненные запросы не исправятся повтором
+func youtubeError(action string, err error) error {
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) && apiErr.Code >= 400 && apiErr.Code < 500 && apiErr.Code != http.StatusTooManyRequests {
+		return fmt.Errorf("%w: %v", services.ErrVideoRejected, apiErr)
+	}
+	var retrieveErr *oauth2.RetrieveError
+	if errors.As(err, &retrieveErr) {
+		return fmt.Errorf("%w: Google authorization expired, retry with a fresh token", services.ErrVideoRejected)
+	}
+	return fmt.Errorf("failed to %s video: %v", action, err)
+}
+
+func (h YouTubeHost) Publish(ctx context.Context, video *videos.Video, file io.Reader) error {
+	service, err := h.service(ctx, video)
+	if err != nil {
+		return err
+	}
+
+	call := service.Videos.Insert([]string{"snippet", "status"}, &youtube.Video{
+		Snippet: &youtube.VideoSnippet{
+			Title:       video.Title,
+			Description: video.Description,
+			Tags:        video.Tags,
+		},
+		Status: &youtube.VideoStatus{
+			PrivacyStatus: "unlisted",
+		},
+	})
+	response, err := call.Media(file).Context(ctx).Do()
+	if err != nil {
+		return youtubeError("upload", err)
+	}
+	video.YouTubeID = response.Id
+	video.VideoLink = fmt.Sprintf("https://www.youtube.com/watch?v=%s", response.Id)
+	return nil
+}
+
+func (h YouTubeHost) Update(ctx context.Context, video *videos.Video) error {
+	service, err := h.service(ctx, video)
+	if err != nil {
+		return err
+	}
+
+	// Получение текущих данных видео с YouTube
+	response, err := service.Videos.List([]string{"snippet"}).Id(video.YouTubeID).Context(ctx).Do()
+	if err != nil {
+		return youtubeError("fetch", err)
+	}
+	if len(response.Items) == 0 {
+		return fmt.Errorf("%w: video not found on YouTube", services.ErrVideoRejected)
+	}
+
+	item := response.Items[0]
+	item.Snippet.Title = video.Title
+	item.Snippet.Description = video.Description
+	if _, err := service.Videos.Update([]string{"snippet"}, item).Context(ctx).Do(); err != nil {
+		return youtubeError("update", err)
+	}
+	return nil
+}
+
+func (h YouTubeHost) Delete(ctx context.Context, video *videos.Video) error {
+	service, err := h.service(ctx, video)
+	if err != nil {
+		return err
+	}
+	if err := service.Videos.Delete(video.YouTubeID).Context(ctx).Do(); err != nil {
+		return youtubeError("delete", err)
+	}
+	return nil
+}
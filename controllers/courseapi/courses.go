@@ -0,0 +1,372 @@
+package courseapi
+
+import (
+	"encoding/json"
+	"errors"
+	"hired-valley-backend/models/courses"
+	"hired-valley-backend/models/users"
+	"hired-valley-backend/services"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Authenticator - определение пользователя запроса по одному из видов учетных данных
+type Authenticator func(r *http.Request) (*users.User, error)
+
+// AnyCredential - первый успешный из способов аутентификации (например, JWT, затем Google OAuth)
+func AnyCredential(authenticators ...Authenticator) Authenticator {
+	return func(r *http.Request) (*users.User, error) {
+		err := errors.New("missing authorization header")
+		for _, authenticate := range authenticators {
+			var user *users.User
+			if user, err = authenticate(r); err == nil {
+				return user, nil
+			}
+		}
+		return nil, err
+	}
+}
+
+// API - REST API курсов (/v1/courses) и устаревшие маршруты для совместимости
+type API struct {
+	Courses      *services.CourseService
+	Authenticate Authenticator
+	// Describe - дополнение карточки курса программой и учет просмотра (viewerID = 0 для гостя)
+	Describe func(course *courses.Course, viewerID uint) error
+}
+
+// Register - регистрация маршрутов API курсов
+func (api *API) Register(mux *http.ServeMux) {
+	mux.HandleFunc("GET /v1/courses", api.listCourses)
+	mux.HandleFunc("POST /v1/courses", api.createCourse)
+	mux.HandleFunc("GET /v1/courses/{id}", api.getCourse)
+	mux.HandleFunc("PUT /v1/courses/{id}", api.updateCourse)
+	mux.HandleFunc("PATCH /v1/courses/{id}", api.updateCourse)
+	mux.HandleFunc("DELETE /v1/courses/{id}", api.deleteCourse)
+	mux.HandleFunc("GET /v1/courses/{id}/versions", api.courseVersions)
+	mux.HandleFunc("POST /v1/courses/{id}/draft", api.createDraft)
+	mux.HandleFunc("POST /v1/courses/{id}/submit", api.transition(api.Courses.Submit))
+	mux.HandleFunc("POST /v1/courses/{id}/publish", api.transition(api.Courses.Publish))
+	mux.HandleFunc("POST /v1/courses/{id}/archive", api.transition(api.Courses.Archive))
+	mux.HandleFunc("POST /v1/courses/{id}/reject", api.rejectCourse)
+
+	// Устаревшие маршруты: отдельные наборы для JWT и Google OAuth больше не нужны
+	mux.HandleFunc("GET /list/courses", deprecated("/v1/courses", api.listCourses))
+	mux.HandleFunc("POST /create/courses", deprecated("/v1/courses", api.createCourse))
+	mux.HandleFunc("GET /get/courses", deprecated("/v1/courses/{id}", api.getCourse))
+	mux.HandleFunc("PUT /update/courses", deprecated("/v1/courses/{id}", api.updateCourse))
+	mux.HandleFunc("DELETE /delete/courses", deprecated("/v1/courses/{id}", api.deleteCourse))
+	mux.HandleFunc("GET /list/google/courses", deprecated("/v1/courses?mine=true", withMine(api.listCourses)))
+	mux.HandleFunc("POST /create/google/courses", deprecated("/v1/courses", api.createCourse))
+	mux.HandleFunc("GET /get/google/courses", deprecated("/v1/courses/{id}", api.getCourse))
+	mux.HandleFunc("PUT /update/google/courses", deprecated("/v1/courses/{id}", api.updateCourse))
+	mux.HandleFunc("DELETE /delete/google/courses", deprecated("/v1/courses/{id}", api.deleteCourse))
+}
+
+// deprecated - устаревший маршрут: ID курса из query-параметра и ссылка на новый маршрут
+func deprecated(successor string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Deprecation", "true")
+		w.Header().Set("Link", "<"+successor+">; rel=\"successor-version\"")
+		if id := r.URL.Query().Get("id"); id != "" {
+			r.SetPathValue("id", id)
+		}
+		next(w, r)
+	}
+}
+
+// withMine - список курсов текущего пользователя (поведение /list/google/courses)
+func withMine(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		query.Set("mine", "true")
+		r.URL.RawQuery = query.Encode()
+		next(w, r)
+	}
+}
+
+// courseID - ID курса из пути; при ошибке ответ уже отправлен
+func courseID(w http.ResponseWriter, r *http.Request) (uint, bool) {
+	idStr := r.PathValue("id")
+	if idStr == "" {
+		http.Error(w, "Course ID is required", http.StatusBadRequest)
+		return 0, false
+	}
+	id, err := strconv.Atoi(idStr)
+	if err != nil || id <= 0 {
+		http.Error(w, "Invalid course ID", http.StatusBadRequest)
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// writeCourseError - ответ по ошибке сервиса курсов
+func writeCourseError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, services.ErrCourseNotFound):
+		http.Error(w, "Course not found", http.StatusNotFound)
+	case errors.Is(err, services.ErrCourseForbidden):
+		http.Error(w, "Permission denied", http.StatusForbidden)
+	case errors.Is(err, services.ErrMentorOnly):
+		http.Error(w, "Only mentors can create courses", http.StatusForbidden)
+	case errors.Is(err, services.ErrInvalidCourse):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, services.ErrCourseLocked):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		log.Printf("%s: %v", fallback, err)
+		http.Error(w, fallback, http.StatusInternalServerError)
+	}
+}
+
+// optionalUser - пользователь запроса, если он передал учетные данные; nil для гостя
+func (api *API) optionalUser(r *http.Request) *users.User {
+	if strings.TrimSpace(r.Header.Get("Authorization")) == "" {
+		return nil
+	}
+	user, err := api.Authenticate(r)
+	if err != nil {
+		return nil
+	}
+	return user
+}
+
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(value)
+}
+
+// listCourses - курсы инструктора (instructor_id) или текущего пользователя (mine=true)
+func (api *API) listCourses(w http.ResponseWriter, r *http.Request) {
+	var instructorID uint
+	var user *users.User
+	if r.URL.Query().Get("mine") == "true" {
+		var err error
+		if user, err = api.Authenticate(r); err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		instructorID = user.ID
+	} else {
+		user = api.optionalUser(r)
+		instructorIDStr := r.URL.Query().Get("instructor_id")
+		if instructorIDStr == "" {
+			http.Error(w, "Instructor ID is required", http.StatusBadRequest)
+			return
+		}
+		id, err := strconv.Atoi(instructorIDStr)
+		if err != nil || id <= 0 {
+			http.Error(w, "Invalid Instructor ID", http.StatusBadRequest)
+			return
+		}
+		instructorID = uint(id)
+	}
+
+	list, err := api.Courses.List(user, instructorID)
+	if err != nil {
+		writeCourseError(w, err, "Failed to list courses")
+		return
+	}
+	if list == nil {
+		list = []courses.Course{}
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+// getCourse - карточка курса с программой; опубликованные курсы доступны и без авторизации
+func (api *API) getCourse(w http.ResponseWriter, r *http.Request) {
+	id, ok := courseID(w, r)
+	if !ok {
+		return
+	}
+
+	viewer := api.optionalUser(r)
+	course, err := api.Courses.Get(viewer, id)
+	if err != nil {
+		writeCourseError(w, err, "Failed to load course")
+		return
+	}
+
+	var viewerID uint
+	if viewer != nil {
+		viewerID = viewer.ID
+	}
+	if api.Describe != nil {
+		if err := api.Describe(course, viewerID); err != nil {
+			http.Error(w, "Failed to load curriculum", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	writeJSON(w, http.StatusOK, course)
+}
+
+// createCourse - создание курса ментором
+func (api *API) createCourse(w http.ResponseWriter, r *http.Request) {
+	user, err := api.Authenticate(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	var input services.CourseInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+
+	course, err := api.Courses.Create(user, input)
+	if err != nil {
+		writeCourseError(w, err, "Failed to create course")
+		return
+	}
+	writeJSON(w, http.StatusCreated, course)
+}
+
+// updateCourse - частичное обновление курса
+func (api *API) updateCourse(w http.ResponseWriter, r *http.Request) {
+	id, ok := courseID(w, r)
+	if !ok {
+		return
+	}
+
+	user, err := api.Authenticate(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	var input services.CourseInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+
+	course, err := api.Courses.Update(user, id, input)
+	if err != nil {
+		writeCourseError(w, err, "Failed to update course")
+		return
+	}
+	writeJSON(w, http.StatusOK, course)
+}
+
+// deleteCourse - удаление курса
+func (api *API) deleteCourse(w http.ResponseWriter, r *http.Request) {
+	id, ok := courseID(w, r)
+	if !ok {
+		return
+	}
+
+	user, err := api.Authenticate(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	if err := api.Courses.Delete(user, id); err != nil {
+		writeCourseError(w, err, "Failed to delete course")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// courseVersions - история версий курса для автора и администратора
+func (api *API) courseVersions(w http.ResponseWriter, r *http.Request) {
+	id, ok := courseID(w, r)
+	if !ok {
+		return
+	}
+
+	user, err := api.Authenticate(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	versions, err := api.Courses.Versions(user, id)
+	if err != nil {
+		writeCourseError(w, err, "Failed to list course versions")
+		return
+	}
+	writeJSON(w, http.StatusOK, versions)
+}
+
+// createDraft - черновик новой версии опубликованного курса (201 - создан, 200 - уже существует)
+func (api *API) createDraft(w http.ResponseWriter, r *http.Request) {
+	id, ok := courseID(w, r)
+	if !ok {
+		return
+	}
+
+	user, err := api.Authenticate(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	draft, created, err := api.Courses.CreateDraft(user, id)
+	if err != nil {
+		writeCourseError(w, err, "Failed to create draft version")
+		return
+	}
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	writeJSON(w, status, draft)
+}
+
+// transition - смена статуса курса без параметров (отправка на проверку, публикация, архивирование)
+func (api *API) transition(change func(user *users.User, id uint) (*courses.Course, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, ok := courseID(w, r)
+		if !ok {
+			return
+		}
+
+		user, err := api.Authenticate(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		course, err := change(user, id)
+		if err != nil {
+			writeCourseError(w, err, "Failed to change course status")
+			return
+		}
+		writeJSON(w, http.StatusOK, course)
+	}
+}
+
+// rejectCourse - возврат курса с проверки на доработку с комментарием
+func (api *API) rejectCourse(w http.ResponseWriter, r *http.Request) {
+	id, ok := courseID(w, r)
+	if !ok {
+		return
+	}
+
+	user, err := api.Authenticate(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	var input struct {
+		Note string `json:"note"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil || strings.TrimSpace(input.Note) == "" {
+		http.Error(w, "Note is required", http.StatusBadRequest)
+		return
+	}
+
+	course, err := api.Courses.Reject(user, id, input.Note)
+	if err != nil {
+		writeCourseError(w, err, "Failed to change course status")
+		return
+	}
+	writeJSON(w, http.StatusOK, course)
+}
@@ -0,0 +1,509 @@
+package courseapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/dgrijalva/jwt-go"
+	"hired-valley-backend/models/courses"
+	"hired-valley-backend/models/users"
+	"hired-valley-backend/services"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// memoryCourseStore - хранилище курсов в памяти для тестов
+type memoryCourseStore struct {
+	nextID      uint
+	courses     map[uint]courses.Course
+	enrollments map[[2]uint]bool     // [userID, courseID]
+	team        map[[2]uint][]string // [userID, originID] -> права участника команды
+}
+
+func newMemoryCourseStore() *memoryCourseStore {
+	return &memoryCourseStore{courses: map[uint]courses.Course{}, enrollments: map[[2]uint]bool{}, team: map[[2]uint][]string{}}
+}
+
+func (s *memoryCourseStore) FindCourse(id uint) (*courses.Course, error) {
+	course, ok := s.courses[id]
+	if !ok {
+		return nil, services.ErrCourseNotFound
+	}
+	return &course, nil
+}
+
+func (s *memoryCourseStore) InstructorCourses(instructorID uint) ([]courses.Course, error) {
+	var list []courses.Course
+	for id := uint(1); id <= s.nextID; id++ {
+		if course, ok := s.courses[id]; ok && course.InstructorID == instructorID {
+			list = append(list, course)
+		}
+	}
+	return list, nil
+}
+
+func (s *memoryCourseStore) CreateCourse(course *courses.Course) error {
+	s.nextID++
+	course.ID = s.nextID
+	s.courses[course.ID] = *course
+	return nil
+}
+
+func (s *memoryCourseStore) SaveCourse(course *courses.Course) error {
+	s.courses[course.ID] = *course
+	return nil
+}
+
+func (s *memoryCourseStore) DeleteCourse(course *courses.Course) error {
+	delete(s.courses, course.ID)
+	return nil
+}
+
+func (s *memoryCourseStore) IsEnrolled(userID uint, course *courses.Course) bool {
+	return s.enrollments[[2]uint{userID, course.ID}]
+}
+
+func (s *memoryCourseStore) IsTeamMember(userID uint, course *courses.Course) bool {
+	_, ok := s.team[[2]uint{userID, services.CourseOriginID(course)}]
+	return course.InstructorID == userID || ok
+}
+
+func (s *memoryCourseStore) HasPermission(user *users.User, course *courses.Course, permission string) bool {
+	if user == nil {
+		return false
+	}
+	if course.InstructorID == user.ID {
+		return true
+	}
+	for _, granted := range s.team[[2]uint{user.ID, services.CourseOriginID(course)}] {
+		if granted == permission {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *memoryCourseStore) CourseVersions(originID uint) ([]courses.Course, error) {
+	var list []courses.Course
+	for id := uint(1); id <= s.nextID; id++ {
+		if course, ok := s.courses[id]; ok && services.CourseOriginID(&course) == originID {
+			list = append(list, course)
+		}
+	}
+	return list, nil
+}
+
+func (s *memoryCourseStore) CreateDraftVersion(course *courses.Course) (*courses.Course, error) {
+	originID := services.CourseOriginID(course)
+	versions, _ := s.CourseVersions(originID)
+	draft := *course
+	draft.Status = courses.CourseStatusDraft
+	draft.Version = versions[len(versions)-1].Version + 1
+	draft.OriginID = &originID
+	draft.PublishedAt = nil
+	s.CreateCourse(&draft)
+	return &draft, nil
+}
+
+func (s *memoryCourseStore) PublishCourse(course *courses.Course, previous *courses.Course) error {
+	if previous != nil {
+		previous.Status = courses.CourseStatusArchived
+		previous.SupersededBy = &course.ID
+		s.courses[previous.ID] = *previous
+	}
+	s.courses[course.ID] = *course
+	return nil
+}
+
+var testJWTKey = []byte("test-secret")
+
+var testUsers = map[uint]*users.User{
+	1: {ID: 1, Role: "mentor"},
+	2: {ID: 2, Role: "mentor"},
+	3: {ID: 3, Role: "user"},
+	4: {ID: 4, Role: "admin"},
+}
+
+// jwtAuthenticator - проверка подписанного JWT, как в authentication.ValidateToken
+func jwtAuthenticator(r *http.Request) (*users.User, error) {
+	tokenString := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	claims := jwt.MapClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return testJWTKey, nil
+	})
+	if err != nil || !token.Valid {
+		return nil, errors.New("invalid token")
+	}
+	userID, _ := claims["user_id"].(float64)
+	user, ok := testUsers[uint(userID)]
+	if !ok {
+		return nil, errors.New("access token mismatch")
+	}
+	return user, nil
+}
+
+// googleAuthenticator - Google OAuth токены, выданные пользователям приложения
+func googleAuthenticator(r *http.Request) (*users.User, error) {
+	tokens := map[string]uint{"ya29.mentor-one": 1, "ya29.mentor-two": 2, "ya29.learner": 3, "ya29.admin": 4}
+	userID, ok := tokens[strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")]
+	if !ok {
+		return nil, errors.New("invalid token")
+	}
+	return testUsers[userID], nil
+}
+
+// credential - заголовок Authorization пользователя для выбранного вида учетных данных
+type credential func(t *testing.T, userID uint) string
+
+func jwtCredential(t *testing.T, userID uint) string {
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"user_id": userID}).SignedString(testJWTKey)
+	if err != nil {
+		t.Fatalf("sign token: %v", err)
+	}
+	return "Bearer " + token
+}
+
+func googleCredential(t *testing.T, userID uint) string {
+	names := map[uint]string{1: "mentor-one", 2: "mentor-two", 3: "learner", 4: "admin"}
+	return "Bearer ya29." + names[userID]
+}
+
+var credentials = map[string]credential{
+	"jwt":    jwtCredential,
+	"google": googleCredential,
+}
+
+type testServer struct {
+	mux       *http.ServeMux
+	store     *memoryCourseStore
+	described []uint
+}
+
+func newTestServer() *testServer {
+	server := &testServer{mux: http.NewServeMux(), store: newMemoryCourseStore()}
+	api := &API{
+		Courses:      services.NewCourseService(server.store),
+		Authenticate: AnyCredential(jwtAuthenticator, googleAuthenticator),
+		Describe: func(course *courses.Course, viewerID uint) error {
+			server.described = append(server.described, viewerID)
+			return nil
+		},
+	}
+	api.Register(server.mux)
+	return server
+}
+
+func (s *testServer) do(method, target, authorization, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	rec := httptest.NewRecorder()
+	s.mux.ServeHTTP(rec, req)
+	return rec
+}
+
+// seedCourse - курс инструктора с ID 1 в указанном статусе
+func (s *testServer) seedCourse(status string) uint {
+	course := courses.Course{Title: "Go basics", InstructorID: 1, Tags: []string{"go"}, Status: status, Version: 1}
+	s.store.CreateCourse(&course)
+	return course.ID
+}
+
+func decodeCourse(t *testing.T, rec *httptest.ResponseRecorder) courses.Course {
+	t.Helper()
+	var course courses.Course
+	if err := json.NewDecoder(rec.Body).Decode(&course); err != nil {
+		t.Fatalf("decode course: %v", err)
+	}
+	return course
+}
+
+func TestCreateCourse(t *testing.T) {
+	for name, auth := range credentials {
+		t.Run(name, func(t *testing.T) {
+			server := newTestServer()
+
+			rec := server.do(http.MethodPost, "/v1/courses", auth(t, 1), `{"title":"Go basics","price":10,"tags":["go"]}`)
+			if rec.Code != http.StatusCreated {
+				t.Fatalf("mentor create: status %d, body %q", rec.Code, rec.Body.String())
+			}
+			if course := decodeCourse(t, rec); course.InstructorID != 1 || course.Title != "Go basics" {
+				t.Fatalf("unexpected course %+v", course)
+			}
+
+			if rec := server.do(http.MethodPost, "/v1/courses", auth(t, 3), `{"title":"Go","tags":["go"]}`); rec.Code != http.StatusForbidden {
+				t.Fatalf("learner create: status %d", rec.Code)
+			}
+			if rec := server.do(http.MethodPost, "/v1/courses", auth(t, 1), `{"title":"Go"}`); rec.Code != http.StatusBadRequest {
+				t.Fatalf("create without tags: status %d", rec.Code)
+			}
+		})
+	}
+}
+
+func TestUpdateCourseAuthorization(t *testing.T) {
+	for name, auth := range credentials {
+		t.Run(name, func(t *testing.T) {
+			server := newTestServer()
+			id := server.seedCourse(courses.CourseStatusDraft)
+			target := fmt.Sprintf("/v1/courses/%d", id)
+
+			cases := []struct {
+				userID uint
+				status int
+			}{
+				{1, http.StatusOK},        // инструктор курса
+				{2, http.StatusForbidden}, // другой ментор
+				{3, http.StatusForbidden}, // ученик
+				{4, http.StatusOK},        // администратор
+			}
+			for _, c := range cases {
+				rec := server.do(http.MethodPut, target, auth(t, c.userID), `{"title":"Go in practice"}`)
+				if rec.Code != c.status {
+					t.Errorf("user %d: status %d, want %d", c.userID, rec.Code, c.status)
+				}
+			}
+
+			if course := server.store.courses[id]; course.Title != "Go in practice" || len(course.Tags) != 1 {
+				t.Fatalf("unexpected course after update %+v", course)
+			}
+		})
+	}
+}
+
+func TestDeleteCourse(t *testing.T) {
+	for name, auth := range credentials {
+		t.Run(name, func(t *testing.T) {
+			server := newTestServer()
+			id := server.seedCourse(courses.CourseStatusDraft)
+			target := fmt.Sprintf("/v1/courses/%d", id)
+
+			if rec := server.do(http.MethodDelete, target, auth(t, 2), ""); rec.Code != http.StatusForbidden {
+				t.Fatalf("other mentor delete: status %d", rec.Code)
+			}
+			if rec := server.do(http.MethodDelete, target, auth(t, 1), ""); rec.Code != http.StatusNoContent {
+				t.Fatalf("instructor delete: status %d", rec.Code)
+			}
+			if rec := server.do(http.MethodDelete, target, auth(t, 1), ""); rec.Code != http.StatusNotFound {
+				t.Fatalf("repeated delete: status %d", rec.Code)
+			}
+		})
+	}
+}
+
+func TestGetCourseRecordsViewer(t *testing.T) {
+	for name, auth := range credentials {
+		t.Run(name, func(t *testing.T) {
+			server := newTestServer()
+			id := server.seedCourse(courses.CourseStatusPublished)
+			target := fmt.Sprintf("/v1/courses/%d", id)
+
+			if rec := server.do(http.MethodGet, target, "", ""); rec.Code != http.StatusOK {
+				t.Fatalf("guest get: status %d", rec.Code)
+			}
+			if rec := server.do(http.MethodGet, target, auth(t, 3), ""); rec.Code != http.StatusOK {
+				t.Fatalf("learner get: status %d", rec.Code)
+			}
+			if len(server.described) != 2 || server.described[0] != 0 || server.described[1] != 3 {
+				t.Fatalf("unexpected viewers %v", server.described)
+			}
+		})
+	}
+}
+
+func TestUnauthenticated(t *testing.T) {
+	server := newTestServer()
+	id := server.seedCourse(courses.CourseStatusDraft)
+
+	for _, authorization := range []string{"", "Bearer not-a-token", "Bearer ya29.unknown"} {
+		if rec := server.do(http.MethodPost, "/v1/courses", authorization, `{"title":"Go","tags":["go"]}`); rec.Code != http.StatusUnauthorized {
+			t.Errorf("create with %q: status %d", authorization, rec.Code)
+		}
+		if rec := server.do(http.MethodDelete, fmt.Sprintf("/v1/courses/%d", id), authorization, ""); rec.Code != http.StatusUnauthorized {
+			t.Errorf("delete with %q: status %d", authorization, rec.Code)
+		}
+	}
+}
+
+func TestDeprecatedAliases(t *testing.T) {
+	for name, auth := range credentials {
+		t.Run(name, func(t *testing.T) {
+			server := newTestServer()
+
+			// Маршруты JWT и Google ведут себя одинаково для любого вида учетных данных
+			for _, prefix := range []string{"", "/google"} {
+				rec := server.do(http.MethodPost, "/create"+prefix+"/courses", auth(t, 1), `{"title":"Go","tags":["go"]}`)
+				if rec.Code != http.StatusCreated || rec.Header().Get("Deprecation") != "true" {
+					t.Fatalf("create%s: status %d, deprecation %q", prefix, rec.Code, rec.Header().Get("Deprecation"))
+				}
+				id := decodeCourse(t, rec).ID
+
+				if rec := server.do(http.MethodGet, fmt.Sprintf("/get%s/courses?id=%d", prefix, id), auth(t, 1), ""); rec.Code != http.StatusOK {
+					t.Fatalf("get%s: status %d", prefix, rec.Code)
+				}
+				if rec := server.do(http.MethodPut, fmt.Sprintf("/update%s/courses?id=%d", prefix, id), auth(t, 2), `{"title":"x"}`); rec.Code != http.StatusForbidden {
+					t.Fatalf("update%s by other mentor: status %d", prefix, rec.Code)
+				}
+				if rec := server.do(http.MethodDelete, fmt.Sprintf("/delete%s/courses?id=%d", prefix, id), auth(t, 1), ""); rec.Code != http.StatusNoContent {
+					t.Fatalf("delete%s: status %d", prefix, rec.Code)
+				}
+			}
+
+			server.seedCourse(courses.CourseStatusDraft)
+			server.seedCourse(courses.CourseStatusPublished)
+			var mine []courses.Course
+			rec := server.do(http.MethodGet, "/list/google/courses", auth(t, 1), "")
+			if err := json.NewDecoder(rec.Body).Decode(&mine); err != nil || len(mine) != 2 {
+				t.Fatalf("list mine: status %d, courses %v, err %v", rec.Code, mine, err)
+			}
+			var public []courses.Course
+			rec = server.do(http.MethodGet, "/list/courses?instructor_id=1", auth(t, 2), "")
+			if err := json.NewDecoder(rec.Body).Decode(&public); err != nil || len(public) != 1 || public[0].Status != courses.CourseStatusPublished {
+				t.Fatalf("list by instructor: status %d, courses %v, err %v", rec.Code, public, err)
+			}
+			if rec := server.do(http.MethodGet, "/list/courses?instructor_id=2", "", ""); rec.Code != http.StatusOK || strings.TrimSpace(rec.Body.String()) != "[]" {
+				t.Fatalf("list by other instructor: status %d, body %q", rec.Code, rec.Body.String())
+			}
+			if rec := server.do(http.MethodPost, "/delete/courses?id=1", auth(t, 1), ""); rec.Code != http.StatusMethodNotAllowed {
+				t.Fatalf("wrong method: status %d", rec.Code)
+			}
+		})
+	}
+}
+
+func TestPublishingWorkflow(t *testing.T) {
+	for name, auth := range credentials {
+		t.Run(name, func(t *testing.T) {
+			server := newTestServer()
+
+			rec := server.do(http.MethodPost, "/v1/courses", auth(t, 1), `{"title":"Go basics","tags":["go"]}`)
+			first := decodeCourse(t, rec)
+			if first.Status != courses.CourseStatusDraft {
+				t.Fatalf("new course status %q", first.Status)
+			}
+			target := fmt.Sprintf("/v1/courses/%d", first.ID)
+
+			// Черновик не виден другим пользователям
+			if rec := server.do(http.MethodGet, target, auth(t, 3), ""); rec.Code != http.StatusNotFound {
+				t.Fatalf("learner sees draft: status %d", rec.Code)
+			}
+			if rec := server.do(http.MethodPost, target+"/submit", auth(t, 2), ""); rec.Code != http.StatusForbidden {
+				t.Fatalf("other mentor submit: status %d", rec.Code)
+			}
+			if rec := server.do(http.MethodPost, target+"/submit", auth(t, 1), ""); rec.Code != http.StatusOK {
+				t.Fatalf("submit: status %d", rec.Code)
+			}
+			if rec := server.do(http.MethodPut, target, auth(t, 1), `{"title":"x"}`); rec.Code != http.StatusConflict {
+				t.Fatalf("edit under review: status %d", rec.Code)
+			}
+			if rec := server.do(http.MethodPost, target+"/publish", auth(t, 1), ""); rec.Code != http.StatusForbidden {
+				t.Fatalf("author publish: status %d", rec.Code)
+			}
+			if rec := server.do(http.MethodPost, target+"/publish", auth(t, 4), ""); rec.Code != http.StatusOK {
+				t.Fatalf("admin publish: status %d", rec.Code)
+			}
+			server.store.enrollments[[2]uint{3, first.ID}] = true
+
+			// Опубликованный курс меняется только через черновик новой версии
+			if rec := server.do(http.MethodPut, target, auth(t, 1), `{"title":"x"}`); rec.Code != http.StatusConflict {
+				t.Fatalf("edit published: status %d", rec.Code)
+			}
+			if rec := server.do(http.MethodDelete, target, auth(t, 1), ""); rec.Code != http.StatusConflict {
+				t.Fatalf("delete published: status %d", rec.Code)
+			}
+			rec = server.do(http.MethodPost, target+"/draft", auth(t, 1), "")
+			if rec.Code != http.StatusCreated {
+				t.Fatalf("create draft: status %d", rec.Code)
+			}
+			draft := decodeCourse(t, rec)
+			if draft.Version != 2 || draft.OriginID == nil || *draft.OriginID != first.ID {
+				t.Fatalf("unexpected draft %+v", draft)
+			}
+			if rec := server.do(http.MethodPost, target+"/draft", auth(t, 1), ""); rec.Code != http.StatusOK || decodeCourse(t, rec).ID != draft.ID {
+				t.Fatalf("repeated draft: status %d", rec.Code)
+			}
+
+			draftTarget := fmt.Sprintf("/v1/courses/%d", draft.ID)
+			if rec := server.do(http.MethodPut, draftTarget, auth(t, 1), `{"title":"Go in practice"}`); rec.Code != http.StatusOK {
+				t.Fatalf("edit draft: status %d", rec.Code)
+			}
+			if live := server.store.courses[first.ID]; live.Title != "Go basics" {
+				t.Fatalf("draft edit changed the live course: %q", live.Title)
+			}
+			server.do(http.MethodPost, draftTarget+"/submit", auth(t, 1), "")
+			if rec := server.do(http.MethodPost, draftTarget+"/reject", auth(t, 4), `{"note":"Add a summary"}`); rec.Code != http.StatusOK || decodeCourse(t, rec).ReviewNote != "Add a summary" {
+				t.Fatalf("reject: status %d", rec.Code)
+			}
+			server.do(http.MethodPost, draftTarget+"/submit", auth(t, 1), "")
+			if rec := server.do(http.MethodPost, draftTarget+"/publish", auth(t, 4), ""); rec.Code != http.StatusOK {
+				t.Fatalf("publish draft: status %d", rec.Code)
+			}
+
+			previous := server.store.courses[first.ID]
+			if previous.Status != courses.CourseStatusArchived || previous.SupersededBy == nil || *previous.SupersededBy != draft.ID {
+				t.Fatalf("previous version not superseded: %+v", previous)
+			}
+			// Записанный ученик сохраняет доступ к своей версии, остальные видят только новую
+			if rec := server.do(http.MethodGet, target, auth(t, 3), ""); rec.Code != http.StatusOK {
+				t.Fatalf("enrolled learner on old version: status %d", rec.Code)
+			}
+			if rec := server.do(http.MethodGet, target, auth(t, 2), ""); rec.Code != http.StatusNotFound {
+				t.Fatalf("archived version visible: status %d", rec.Code)
+			}
+
+			var versions []courses.Course
+			rec = server.do(http.MethodGet, draftTarget+"/versions", auth(t, 1), "")
+			if err := json.NewDecoder(rec.Body).Decode(&versions); err != nil || len(versions) != 2 {
+				t.Fatalf("versions: status %d, %v, err %v", rec.Code, versions, err)
+			}
+		})
+	}
+}
+
+func TestCourseTeamAccess(t *testing.T) {
+	for name, auth := range credentials {
+		t.Run(name, func(t *testing.T) {
+			server := newTestServer()
+			published := server.seedCourse(courses.CourseStatusPublished)
+			target := fmt.Sprintf("/v1/courses/%d", published)
+
+			// Ассистент видит черновики, но не может их создавать и менять карточку курса
+			server.store.team[[2]uint{3, published}] = courses.DefaultRolePermissions[courses.CourseRoleAssistant]
+			if rec := server.do(http.MethodPost, target+"/draft", auth(t, 3), ""); rec.Code != http.StatusForbidden {
+				t.Fatalf("assistant create draft: status %d", rec.Code)
+			}
+
+			// Соавтор создает черновик новой версии, но публикацией и карточкой курса управляет владелец
+			server.store.team[[2]uint{2, published}] = courses.DefaultRolePermissions[courses.CourseRoleCoInstructor]
+			rec := server.do(http.MethodPost, target+"/draft", auth(t, 2), "")
+			if rec.Code != http.StatusCreated {
+				t.Fatalf("co-instructor create draft: status %d", rec.Code)
+			}
+			draft := decodeCourse(t, rec)
+			draftTarget := fmt.Sprintf("/v1/courses/%d", draft.ID)
+
+			for _, userID := range []uint{2, 3} {
+				if rec := server.do(http.MethodGet, draftTarget, auth(t, userID), ""); rec.Code != http.StatusOK {
+					t.Fatalf("team member %d get draft: status %d", userID, rec.Code)
+				}
+			}
+			if rec := server.do(http.MethodGet, draftTarget+"/versions", auth(t, 2), ""); rec.Code != http.StatusOK {
+				t.Fatalf("co-instructor versions: status %d", rec.Code)
+			}
+			if rec := server.do(http.MethodPut, draftTarget, auth(t, 2), `{"title":"x"}`); rec.Code != http.StatusForbidden {
+				t.Fatalf("co-instructor edit course card: status %d", rec.Code)
+			}
+			if rec := server.do(http.MethodPost, draftTarget+"/submit", auth(t, 2), ""); rec.Code != http.StatusForbidden {
+				t.Fatalf("co-instructor submit: status %d", rec.Code)
+			}
+
+			delete(server.store.team, [2]uint{3, published})
+			if rec := server.do(http.MethodGet, draftTarget, auth(t, 3), ""); rec.Code != http.StatusNotFound {
+				t.Fatalf("removed member get draft: status %d", rec.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,289 @@
+package mentors
+
+import (
+	"encoding/json"
+	"fmt"
+	"gorm.io/gorm"
+	"hired-valley-backend/config"
+	"hired-valley-backend/controllers/authentication"
+	"hired-valley-backend/models/analytics"
+	"hired-valley-backend/models/content"
+	"hired-valley-backend/models/courses"
+	"hired-valley-backend/models/users"
+	"hired-valley-backend/services"
+	"net/http"
+	"time"
+)
+
+type periodCount struct {
+	Period time.Time `json:"period"`
+	Count  int64     `json:"count"`
+}
+
+type periodValue struct {
+	Period time.Time `json:"period"`
+	Value  float64   `json:"value"`
+	Count  int64     `json:"count"`
+}
+
+// periodAmount - выручка за период в минимальных единицах валюты
+type periodAmount struct {
+	Period   time.Time `json:"period"`
+	Currency string    `json:"currency"`
+	Amount   int64     `json:"amount"`
+	Count    int64     `json:"count"`
+}
+
+type itemEngagement struct {
+	ItemID        uint   `json:"item_id"`
+	Title         string `json:"title"`
+	Views         int64  `json:"views"`
+	UniqueViewers int64  `json:"unique_viewers"`
+}
+
+// parseAnalyticsRange - период (from, to в формате 2006-01-02 или RFC3339) и шаг группировки
+func parseAnalyticsRange(r *http.Request) (time.Time, time.Time, string, bool) {
+	parse := func(value string) (time.Time, error) {
+		if t, err := time.Parse(time.RFC3339, value); err == nil {
+			return t, nil
+		}
+		return time.Parse("2006-01-02", value)
+	}
+
+	to := time.Now()
+	from := to.AddDate(0, 0, -30)
+	var err error
+	if value := r.URL.Query().Get("from"); value != "" {
+		if from, err = parse(value); err != nil {
+			return from, to, "", false
+		}
+	}
+	if value := r.URL.Query().Get("to"); value != "" {
+		if to, err = parse(value); err != nil {
+			return from, to, "", false
+		}
+	}
+	if !to.After(from) {
+		return from, to, "", false
+	}
+
+	granularity := r.URL.Query().Get("granularity")
+	if granularity == "" {
+		granularity = "day"
+	}
+	if granularity != "day" && granularity != "week" && granularity != "month" {
+		return from, to, "", false
+	}
+	return from, to, granularity, true
+}
+
+// MentorAnalyticsHandler - агрегированная статистика ментора за период
+func MentorAnalyticsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user, err := authentication.ValidateToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	profile, ok := currentMentorProfile(w, user.ID)
+	if !ok {
+		return
+	}
+
+	from, to, granularity, ok := parseAnalyticsRange(r)
+	if !ok {
+		http.Error(w, "Invalid range. Use from/to as YYYY-MM-DD or RFC3339 and granularity day, week or month", http.StatusBadRequest)
+		return
+	}
+
+	db := config.DB
+	bucket := "date_trunc('" + granularity + "', %s) AS period"
+	slotsInRange := func() *gorm.DB {
+		return db.Model(&users.Slot{}).Where("mentor_id = ? AND start_time >= ? AND start_time < ?", profile.ID, from, to)
+	}
+
+	// Бронирования по периодам
+	var bookings []periodCount
+	if err := slotsInRange().Where("is_booked = ?", true).
+		Select(fmt.Sprintf(bucket, "start_time") + ", COUNT(*) AS count").
+		Group("period").Order("period").Scan(&bookings).Error; err != nil {
+		http.Error(w, "Error calculating bookings", http.StatusInternalServerError)
+		return
+	}
+
+	// Загрузка предложенных слотов
+	var offered, booked int64
+	if err := slotsInRange().Count(&offered).Error; err != nil {
+		http.Error(w, "Error calculating utilization", http.StatusInternalServerError)
+		return
+	}
+	if err := slotsInRange().Where("is_booked = ?", true).Count(&booked).Error; err != nil {
+		http.Error(w, "Error calculating utilization", http.StatusInternalServerError)
+		return
+	}
+	utilization := 0.0
+	if offered > 0 {
+		utilization = float64(booked) / float64(offered)
+	}
+
+	// Отмены по периодам
+	var cancellations []periodCount
+	if err := db.Model(&users.BookingCancellation{}).
+		Where("mentor_id = ? AND created_at >= ? AND created_at < ?", profile.ID, from, to).
+		Select(fmt.Sprintf(bucket, "created_at") + ", COUNT(*) AS count").
+		Group("period").Order("period").Scan(&cancellations).Error; err != nil {
+		http.Error(w, "Error calculating cancellations", http.StatusInternalServerError)
+		return
+	}
+	var cancelledByMentor int64
+	db.Model(&users.BookingCancellation{}).
+		Where("mentor_id = ? AND cancelled_by_id = ? AND created_at >= ? AND created_at < ?", profile.ID, user.ID, from, to).
+		Count(&cancelledByMentor)
+
+	// Повторные менти
+	var mentees struct {
+		Unique int64
+		Repeat int64
+	}
+	if err := db.Table("(?) AS per_mentee", slotsInRange().Where("is_booked = ?", true).
+		Select("user_id, COUNT(*) AS sessions").Group("user_id")).
+		Select("COUNT(*) AS \"unique\", COUNT(*) FILTER (WHERE sessions > 1) AS repeat").
+		Scan(&mentees).Error; err != nil {
+		http.Error(w, "Error calculating mentees", http.StatusInternalServerError)
+		return
+	}
+
+	// Доход в валюте по умолчанию: почасовые сессии по цене, зафиксированной при бронировании
+	// (сессии за кредиты пакета не учитываются), и полученные платежи за пакеты за вычетом возвратов
+	currency := services.DefaultCurrency()
+	var hourlyEarnings []periodValue
+	if err := slotsInRange().Where("is_booked = ? AND price IS NOT NULL", true).
+		Select(fmt.Sprintf(bucket, "start_time") + ", COALESCE(SUM(price), 0) AS value, COUNT(*) AS count").
+		Group("period").Order("period").Scan(&hourlyEarnings).Error; err != nil {
+		http.Error(w, "Error calculating earnings", http.StatusInternalServerError)
+		return
+	}
+	var packageEarnings []periodValue
+	if err := db.Table("package_purchases").
+		Joins("JOIN payments ON payments.id = package_purchases.payment_id").
+		Where("package_purchases.mentor_id = ? AND payments.created_at >= ? AND payments.created_at < ?", profile.ID, from, to).
+		Select(fmt.Sprintf(bucket, "payments.created_at") + ", COALESCE(SUM(payments.amount - " +
+			"COALESCE((SELECT SUM(refunds.amount) FROM refunds WHERE refunds.payment_id = payments.id), 0)), 0) AS value, " +
+			"COUNT(*) AS count").
+		Group("period").Order("period").Scan(&packageEarnings).Error; err != nil {
+		http.Error(w, "Error calculating earnings", http.StatusInternalServerError)
+		return
+	}
+	for i := range packageEarnings {
+		packageEarnings[i].Value /= float64(services.MinorUnitsFactor(currency))
+	}
+
+	// Продажи курсов по фактически оплаченным суммам записей, по валютам оплаты
+	var courseEarnings []periodAmount
+	if err := db.Model(&courses.Enrollment{}).
+		Where("source = ? AND created_at >= ? AND created_at < ?", courses.EnrollmentPaid, from, to).
+		Where("course_id IN (?)", db.Model(&courses.Course{}).Select("id").Where("instructor_id = ?", user.ID)).
+		Select(fmt.Sprintf(bucket, "created_at") + ", currency, COALESCE(SUM(amount_paid), 0) AS amount, COUNT(*) AS count").
+		Group("period, currency").Order("period, currency").Scan(&courseEarnings).Error; err != nil {
+		http.Error(w, "Error calculating earnings", http.StatusInternalServerError)
+		return
+	}
+	if courseEarnings == nil {
+		courseEarnings = []periodAmount{}
+	}
+
+	totalEarnings := 0.0
+	for _, item := range hourlyEarnings {
+		totalEarnings += item.Value
+	}
+	for _, item := range packageEarnings {
+		totalEarnings += item.Value
+	}
+
+	// Динамика рейтинга
+	var ratings []periodValue
+	if err := db.Model(&users.MentorReview{}).
+		Where("mentor_id = ? AND is_hidden = ? AND created_at >= ? AND created_at < ?", profile.ID, false, from, to).
+		Select(fmt.Sprintf(bucket, "created_at") + ", AVG(rating) AS value, COUNT(*) AS count").
+		Group("period").Order("period").Scan(&ratings).Error; err != nil {
+		http.Error(w, "Error calculating ratings", http.StatusInternalServerError)
+		return
+	}
+
+	// Просмотры курсов и контента ментора; в курсы входят и те, где у него есть право на аналитику в команде
+	teamCourses := db.Model(&courses.CourseCollaborator{}).Select("course_id").
+		Where("user_id = ? AND ? = ANY(permissions)", user.ID, courses.PermissionViewAnalytics)
+	courseEngagement, err := engagementFor(analytics.ItemCourse, db.Model(&courses.Course{}).Select("id, title").
+		Where("instructor_id = ? OR COALESCE(origin_id, id) IN (?)", user.ID, teamCourses), from, to)
+	if err != nil {
+		http.Error(w, "Error calculating course engagement", http.StatusInternalServerError)
+		return
+	}
+	contentEngagement, err := engagementFor(analytics.ItemContent, db.Model(&content.Content{}).Select("id, title").Where("author_id = ?", user.ID), from, to)
+	if err != nil {
+		http.Error(w, "Error calculating content engagement", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"from":        from,
+		"to":          to,
+		"granularity": granularity,
+		"bookings": map[string]interface{}{
+			"total":     booked,
+			"over_time": bookings,
+		},
+		"utilization": map[string]interface{}{
+			"offered_slots": offered,
+			"booked_slots":  booked,
+			"rate":          utilization,
+		},
+		"cancellations": map[string]interface{}{
+			"over_time":           cancellations,
+			"cancelled_by_mentor": cancelledByMentor,
+		},
+		"mentees": map[string]interface{}{
+			"unique": mentees.Unique,
+			"repeat": mentees.Repeat,
+		},
+		"earnings": map[string]interface{}{
+			"currency": currency,
+			"total":    totalEarnings,
+			"sessions": hourlyEarnings,
+			"packages": packageEarnings,
+			"courses":  courseEarnings,
+		},
+		"ratings": map[string]interface{}{
+			"current_average": profile.AverageRating,
+			"reviews_count":   profile.ReviewsCount,
+			"over_time":       ratings,
+		},
+		"engagement": map[string]interface{}{
+			"courses": courseEngagement,
+			"content": contentEngagement,
+		},
+	})
+}
+
+// engagementFor - просмотры и уникальные зрители для объектов автора
+func engagementFor(itemType string, items *gorm.DB, from, to time.Time) ([]itemEngagement, error) {
+	var result []itemEngagement
+	err := config.DB.Table("(?) AS items", items).
+		Select(`items.id AS item_id, items.title,
+			COUNT(view_events.id) AS views,
+			COUNT(DISTINCT NULLIF(view_events.user_id, 0)) AS unique_viewers`).
+		Joins("LEFT JOIN view_events ON view_events.item_id = items.id AND view_events.item_type = ? AND view_events.created_at >= ? AND view_events.created_at < ?", itemType, from, to).
+		Group("items.id, items.title").
+		Order("views DESC").
+		Scan(&result).Error
+	if result == nil {
+		result = []itemEngagement{}
+	}
+	return result, err
+}
@@ -0,0 +1,318 @@
+package mentors
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"hired-valley-backend/config"
+	"hired-valley-backend/controllers/authentication"
+	"hired-valley-backend/models/users"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ApplyMentorHandler - подача заявки на роль ментора
+func ApplyMentorHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user, err := authentication.ValidateToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	var application users.MentorApplication
+	if err := json.NewDecoder(r.Body).Decode(&application); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := validateApplication(&application); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Нельзя подать новую заявку, пока предыдущая не рассмотрена
+	var pending users.MentorApplication
+	if err := config.DB.Where("user_id = ? AND status = ?", user.ID, users.ApplicationPending).First(&pending).Error; err == nil {
+		http.Error(w, "You already have a pending application", http.StatusConflict)
+		return
+	}
+
+	// Активному ментору заявка не нужна
+	var profile users.MentorProfile
+	if err := config.DB.Where("user_id = ? AND status <> ?", user.ID, users.MentorDeactivated).First(&profile).Error; err == nil {
+		http.Error(w, "You already have a mentor profile", http.StatusConflict)
+		return
+	}
+
+	application.ID = 0
+	application.UserID = user.ID
+	application.Status = users.ApplicationPending
+	application.ReviewerID = nil
+	application.ReviewNote = ""
+	application.ReviewedAt = nil
+	for i := range application.Documents {
+		application.Documents[i].ID = 0
+		application.Documents[i].ApplicationID = 0
+	}
+
+	if err := config.DB.Create(&application).Error; err != nil {
+		http.Error(w, "Error creating application", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(application)
+}
+
+// MyApplicationsHandler - заявки текущего пользователя
+func MyApplicationsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user, err := authentication.ValidateToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	var applications []users.MentorApplication
+	if err := config.DB.Preload("Documents").Where("user_id = ?", user.ID).Order("created_at DESC").Find(&applications).Error; err != nil {
+		http.Error(w, "Error fetching applications", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(applications)
+}
+
+// ListApplicationsHandler - список заявок для администратора (фильтр по status)
+func ListApplicationsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user, err := authentication.ValidateToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	if user.Role != "admin" {
+		http.Error(w, "Only admins can review applications", http.StatusForbidden)
+		return
+	}
+
+	query := config.DB.Preload("Documents")
+	if status := r.URL.Query().Get("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var applications []users.MentorApplication
+	if err := query.Order("created_at ASC").Find(&applications).Error; err != nil {
+		http.Error(w, "Error fetching applications", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(applications)
+}
+
+// ReviewApplicationHandler - одобрение или отклонение заявки администратором, а также отзыв одобренной
+func ReviewApplicationHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	admin, err := authentication.ValidateToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	if admin.Role != "admin" {
+		http.Error(w, "Only admins can review applications", http.StatusForbidden)
+		return
+	}
+
+	applicationID, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil || applicationID <= 0 {
+		http.Error(w, "Invalid application ID", http.StatusBadRequest)
+		return
+	}
+
+	var input struct {
+		Decision string `json:"decision"` // approve, reject или revoke
+		Note     string `json:"note"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if input.Decision != "approve" && input.Decision != "reject" && input.Decision != "revoke" {
+		http.Error(w, "Decision must be 'approve', 'reject' or 'revoke'", http.StatusBadRequest)
+		return
+	}
+
+	var application users.MentorApplication
+	if err := config.DB.First(&application, applicationID).Error; err != nil {
+		http.Error(w, "Application not found", http.StatusNotFound)
+		return
+	}
+	if input.Decision == "revoke" && application.Status != users.ApplicationApproved {
+		http.Error(w, "Only approved applications can be revoked", http.StatusConflict)
+		return
+	}
+	if input.Decision == "revoke" {
+		// Роль выдана последней одобренной заявкой; более ранние отзывать нельзя
+		var newer int64
+		config.DB.Model(&users.MentorApplication{}).
+			Where("user_id = ? AND status = ? AND id > ?", application.UserID, users.ApplicationApproved, application.ID).
+			Count(&newer)
+		if newer > 0 {
+			http.Error(w, "Only the latest approved application can be revoked", http.StatusConflict)
+			return
+		}
+	}
+	if input.Decision != "revoke" && application.Status != users.ApplicationPending {
+		http.Error(w, "Application has already been reviewed", http.StatusConflict)
+		return
+	}
+
+	now := time.Now()
+	application.ReviewerID = &admin.ID
+	application.ReviewNote = strings.TrimSpace(input.Note)
+	application.ReviewedAt = &now
+
+	var profile users.MentorProfile
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if input.Decision != "approve" {
+			application.Status = users.ApplicationRejected
+			if input.Decision == "revoke" {
+				application.Status = users.ApplicationRevoked
+			}
+			if err := tx.Save(&application).Error; err != nil {
+				return err
+			}
+			// Отклоненная заявка роль не выдавала: действующий ментор остается ментором
+			if input.Decision == "reject" {
+				return nil
+			}
+			return revokeMentorRole(tx, application.UserID)
+		}
+
+		application.Status = users.ApplicationApproved
+		if err := tx.Save(&application).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&users.User{}).Where("id = ?", application.UserID).Update("role", "mentor").Error; err != nil {
+			return err
+		}
+
+		// Профиль создается при первом одобрении и активируется заново при повторной заявке
+		err := tx.Where("user_id = ?", application.UserID).First(&profile).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		profile.ID = application.UserID
+		profile.UserID = application.UserID
+		profile.Status = users.MentorActive
+		profile.Headline = application.Headline
+		profile.Bio = application.Bio
+		profile.Skills = application.Skills
+		profile.Languages = application.Languages
+		profile.YearsOfExperience = application.YearsOfExperience
+		profile.LinkedInURL = application.LinkedInURL
+		profile.PricePerHour = application.PricePerHour
+		profile.VerifiedAt = &now
+		if err := tx.Omit("SkillTags", "User").Save(&profile).Error; err != nil {
+			return err
+		}
+		return syncMentorSkills(tx, &profile)
+	})
+	if err != nil {
+		http.Error(w, "Error reviewing application", http.StatusInternalServerError)
+		return
+	}
+
+	// Уведомление для заявителя
+	message := "Your mentor application has been approved."
+	if application.Status != users.ApplicationApproved {
+		message = "Your mentor application has been rejected."
+		if application.Status == users.ApplicationRevoked {
+			message = "Your mentor status has been revoked."
+		}
+		if application.ReviewNote != "" {
+			message = fmt.Sprintf("%s Note: %s", message, application.ReviewNote)
+		}
+	}
+	config.DB.Create(&users.NotificationMentor{UserID: application.UserID, Message: message})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(application)
+}
+
+// revokeMentorRole - снятие роли ментора и деактивация профиля; роль администратора не меняется
+func revokeMentorRole(tx *gorm.DB, userID uint) error {
+	if err := tx.Model(&users.User{}).Where("id = ? AND role = ?", userID, "mentor").Update("role", "user").Error; err != nil {
+		return err
+	}
+	return tx.Model(&users.MentorProfile{}).Where("user_id = ?", userID).Update("status", users.MentorDeactivated).Error
+}
+
+// validateApplication - проверка обязательных полей заявки
+func validateApplication(application *users.MentorApplication) error {
+	application.Headline = strings.TrimSpace(application.Headline)
+	application.Bio = strings.TrimSpace(application.Bio)
+	application.LinkedInURL = strings.TrimSpace(application.LinkedInURL)
+
+	if application.Headline == "" || application.Bio == "" {
+		return errors.New("headline and bio are required")
+	}
+	if len(splitSkills(application.Skills)) == 0 {
+		return errors.New("at least one skill is required")
+	}
+	if application.YearsOfExperience < 0 {
+		return errors.New("years_of_experience must not be negative")
+	}
+	if application.PricePerHour < 0 {
+		return errors.New("price_per_hour must not be negative")
+	}
+	if application.LinkedInURL != "" && !isLinkedInURL(application.LinkedInURL) {
+		return errors.New("invalid linkedin_url")
+	}
+	if len(application.Documents) == 0 {
+		return errors.New("at least one verification document is required")
+	}
+	for _, document := range application.Documents {
+		if strings.TrimSpace(document.Type) == "" {
+			return errors.New("document type is required")
+		}
+		if parsed, err := url.ParseRequestURI(document.URL); err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+			return errors.New("invalid document url")
+		}
+	}
+	return nil
+}
+
+func isLinkedInURL(raw string) bool {
+	parsed, err := url.ParseRequestURI(raw)
+	if err != nil || parsed.Scheme != "https" {
+		return false
+	}
+	host := strings.ToLower(parsed.Host)
+	return host == "linkedin.com" || strings.HasSuffix(host, ".linkedin.com")
+}
@@ -0,0 +1,115 @@
+package mentors
+
+import (
+	"errors"
+	"gorm.io/gorm"
+	"hired-valley-backend/models/users"
+	"strings"
+)
+
+// MentorDTO - публичное представление ментора без токенов и служебных полей пользователя
+type MentorDTO struct {
+	ID                uint     `json:"id"`
+	UserID            uint     `json:"user_id"`
+	Name              string   `json:"name"`
+	Position          string   `json:"position"`
+	Company           string   `json:"company"`
+	Industry          string   `json:"industry"`
+	City              string   `json:"city"`
+	Headline          string   `json:"headline"`
+	Bio               string   `json:"bio"`
+	Skills            []string `json:"skills"`
+	Languages         []string `json:"languages"`
+	PricePerHour      float64  `json:"price_per_hour"`
+	YearsOfExperience int      `json:"years_of_experience"`
+	LinkedInURL       string   `json:"linkedin_url"`
+	IsVerified        bool     `json:"is_verified"`
+	Status            string   `json:"status"`
+	AverageRating     float64  `json:"average_rating"`
+	ReviewsCount      int      `json:"reviews_count"`
+}
+
+// toMentorDTO - преобразование профиля ментора (с загруженными User и SkillTags) в DTO
+func toMentorDTO(profile users.MentorProfile) MentorDTO {
+	skills := make([]string, 0, len(profile.SkillTags))
+	for _, skill := range profile.SkillTags {
+		skills = append(skills, skill.Name)
+	}
+	// Для старых профилей без канонических навыков используем строку Skills
+	if len(skills) == 0 {
+		skills = splitSkills(profile.Skills)
+	}
+
+	languages := []string(profile.Languages)
+	if languages == nil {
+		languages = []string{}
+	}
+
+	return MentorDTO{
+		ID:                profile.ID,
+		UserID:            profile.UserID,
+		Name:              profile.User.Name,
+		Position:          profile.User.Position,
+		Company:           profile.User.Company,
+		Industry:          profile.User.Industry,
+		City:              profile.User.City,
+		Headline:          profile.Headline,
+		Bio:               profile.Bio,
+		Skills:            skills,
+		Languages:         languages,
+		PricePerHour:      profile.PricePerHour,
+		YearsOfExperience: profile.YearsOfExperience,
+		LinkedInURL:       profile.LinkedInURL,
+		IsVerified:        profile.VerifiedAt != nil,
+		Status:            profile.Status,
+		AverageRating:     profile.AverageRating,
+		ReviewsCount:      profile.ReviewsCount,
+	}
+}
+
+func toMentorDTOs(profiles []users.MentorProfile) []MentorDTO {
+	result := make([]MentorDTO, 0, len(profiles))
+	for _, profile := range profiles {
+		result = append(result, toMentorDTO(profile))
+	}
+	return result
+}
+
+// canonicalSkill - приведение названия навыка к каноническому виду
+func canonicalSkill(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
+
+// splitSkills - разбор строки навыков через запятую в список канонических названий
+func splitSkills(raw string) []string {
+	var result []string
+	seen := map[string]bool{}
+	for _, part := range strings.Split(raw, ",") {
+		name := canonicalSkill(part)
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		result = append(result, name)
+	}
+	return result
+}
+
+// syncMentorSkills - привязка канонических навыков из строки Skills к профилю ментора
+func syncMentorSkills(tx *gorm.DB, profile *users.MentorProfile) error {
+	var skills []users.Skill
+	for _, name := range splitSkills(profile.Skills) {
+		// Навык, заведенный раньше в другом регистре ("Go"), используется повторно
+		var skill users.Skill
+		err := tx.Where("LOWER(name) = ?", name).Order("id").First(&skill).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			skill = users.Skill{Name: name}
+			err = tx.Create(&skill).Error
+		}
+		if err != nil {
+			return err
+		}
+		skills = append(skills, skill)
+	}
+	return tx.Model(profile).Association("SkillTags").Replace(skills)
+}
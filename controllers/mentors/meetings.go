@@ -0,0 +1,106 @@
+package mentors
+
+import (
+	"encoding/json"
+	"hired-valley-backend/config"
+	"hired-valley-backend/controllers/authentication"
+	"hired-valley-backend/models/users"
+	"hired-valley-backend/services"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// attachMeetingRoom - создание комнаты видеовстречи для подтвержденного бронирования
+func attachMeetingRoom(slot *users.Slot) error {
+	if slot.MeetingURL != "" {
+		return nil
+	}
+
+	room, err := services.GetMeetingProvider().CreateRoom(slot.ID, slot.StartTime, slot.EndTime)
+	if err != nil {
+		return err
+	}
+
+	slot.MeetingProvider = room.Provider
+	slot.MeetingRoomID = room.RoomID
+	slot.MeetingURL = room.JoinURL
+	return config.DB.Model(slot).Updates(map[string]interface{}{
+		"meeting_provider": room.Provider,
+		"meeting_room_id":  room.RoomID,
+		"meeting_url":      room.JoinURL,
+	}).Error
+}
+
+// MeetingHandler - ссылка на видеовстречу для участников бронирования
+func MeetingHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user, err := authentication.ValidateToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	slotID, err := strconv.Atoi(r.URL.Query().Get("slot_id"))
+	if err != nil || slotID <= 0 {
+		http.Error(w, "Invalid slot ID", http.StatusBadRequest)
+		return
+	}
+
+	var slot users.Slot
+	if err := config.DB.First(&slot, slotID).Error; err != nil {
+		http.Error(w, "Slot not found", http.StatusNotFound)
+		return
+	}
+	if !slot.IsBooked || slot.UserID == nil {
+		http.Error(w, "Slot is not booked", http.StatusConflict)
+		return
+	}
+
+	// Ссылка доступна только менти и ментору
+	var mentor users.MentorProfile
+	if err := config.DB.First(&mentor, slot.MentorID).Error; err != nil {
+		http.Error(w, "Mentor not found", http.StatusNotFound)
+		return
+	}
+	if *slot.UserID != user.ID && mentor.UserID != user.ID {
+		http.Error(w, "Only session participants can join the meeting", http.StatusForbidden)
+		return
+	}
+
+	now := time.Now()
+	opensAt := slot.StartTime.Add(-services.MeetingJoinWindow())
+	if now.After(slot.EndTime) {
+		http.Error(w, "Session has already ended", http.StatusGone)
+		return
+	}
+	if now.Before(opensAt) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusTooEarly)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error":    "Meeting link is not available yet",
+			"opens_at": opensAt,
+		})
+		return
+	}
+
+	// Бронирования, созданные до появления видеовстреч, получают комнату при первом запросе
+	if err := attachMeetingRoom(&slot); err != nil {
+		http.Error(w, "Error creating meeting room", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"slot_id":    slot.ID,
+		"provider":   slot.MeetingProvider,
+		"join_url":   slot.MeetingURL,
+		"opens_at":   opensAt,
+		"start_time": slot.StartTime,
+		"end_time":   slot.EndTime,
+	})
+}
@@ -6,82 +6,76 @@ import (
 	"hired-valley-backend/config"
 	"hired-valley-backend/controllers/authentication"
 	"hired-valley-backend/models/users"
+	"log"
+	"math"
 	"net/http"
 	"strconv"
 	"time"
 )
 
+// MentorsHandler - GET: список активных менторов. Профиль создается при одобрении заявки (/mentors/applications/review)
 func MentorsHandler(w http.ResponseWriter, r *http.Request) {
-	user, err := authentication.ValidateToken(r)
-	if err != nil {
+	if _, err := authentication.ValidateToken(r); err != nil {
 		http.Error(w, err.Error(), http.StatusUnauthorized)
 		return
 	}
 
-	if r.Method == http.MethodPost {
-		// Создаем новый профиль ментора
-		var mentorProfile users.MentorProfile
-		if err := json.NewDecoder(r.Body).Decode(&mentorProfile); err != nil {
-			http.Error(w, "Invalid request body", http.StatusBadRequest)
-			return
-		}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
 
-		// Проверяем роль пользователя
-		if user.Role != "mentor" {
-			http.Error(w, "User is not authorized to create a mentor profile", http.StatusForbidden)
-			return
-		}
+	var mentors []users.MentorProfile
+	query := config.DB.Preload("User").Preload("SkillTags").Where("status = ?", users.MentorActive)
 
-		// Присваиваем user.ID в поле id и user_id
-		mentorProfile.ID = user.ID // Записываем user.ID в поле id
-		mentorProfile.UserID = user.ID
+	// Фильтрация по навыкам
+	skills := r.URL.Query().Get("skills")
+	if skills != "" {
+		query = query.Where("skills LIKE ?", fmt.Sprintf("%%%s%%", skills))
+	}
 
-		// Создаем профиль ментора в базе данных
-		if err := config.DB.Create(&mentorProfile).Error; err != nil {
-			http.Error(w, "Error creating mentor profile", http.StatusInternalServerError)
+	// Фильтрация по цене
+	priceRange := r.URL.Query().Get("price_range")
+	if priceRange != "" {
+		price, err := strconv.ParseFloat(priceRange, 64)
+		if err != nil || price < 0 {
+			http.Error(w, "Invalid price_range", http.StatusBadRequest)
 			return
 		}
+		query = query.Where("price_per_hour <= ?", price)
+	}
 
-		// Прелоадим данные пользователя для ответа
-		if err := config.DB.Preload("User").First(&mentorProfile, mentorProfile.ID).Error; err != nil {
-			http.Error(w, "Error fetching created mentor profile", http.StatusInternalServerError)
+	// Фильтрация по минимальному рейтингу
+	minRating := r.URL.Query().Get("min_rating")
+	if minRating != "" {
+		rating, err := strconv.ParseFloat(minRating, 64)
+		if err != nil {
+			http.Error(w, "Invalid min_rating", http.StatusBadRequest)
 			return
 		}
+		query = query.Where("average_rating >= ?", rating)
+	}
 
-		// Возвращаем успешный ответ
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(mentorProfile)
-
-	} else if r.Method == http.MethodGet {
-		// Обработчик GET-запроса для получения списка менторов
-		var mentors []users.MentorProfile
-		query := config.DB.Preload("User")
-
-		// Фильтрация по навыкам
-		skills := r.URL.Query().Get("skills")
-		if skills != "" {
-			query = query.Where("skills LIKE ?", fmt.Sprintf("%%%s%%", skills))
-		}
+	// Сортировка по рейтингу
+	if r.URL.Query().Get("sort") == "rating" {
+		query = query.Order("average_rating DESC").Order("reviews_count DESC")
+	}
 
-		// Фильтрация по цене
-		priceRange := r.URL.Query().Get("price_range")
-		if priceRange != "" {
-			price, _ := strconv.ParseFloat(priceRange, 64)
-			query = query.Where("price_per_hour <= ?", price)
-		}
+	// Получаем список менторов
+	if err := query.Find(&mentors).Error; err != nil {
+		http.Error(w, "Error fetching mentors", http.StatusInternalServerError)
+		return
+	}
 
-		// Получаем список менторов
-		if err := query.Find(&mentors).Error; err != nil {
-			http.Error(w, "Error fetching mentors", http.StatusInternalServerError)
-			return
-		}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(toMentorDTOs(mentors))
 
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(mentors)
+}
 
-	} else {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-	}
+// sessionPrice - стоимость сессии по текущей почасовой цене ментора, фиксируется при бронировании
+func sessionPrice(slot users.Slot, mentor users.MentorProfile) *float64 {
+	price := math.Round(slot.EndTime.Sub(slot.StartTime).Hours()*mentor.PricePerHour*100) / 100
+	return &price
 }
 
 func CreateSlotHandler(w http.ResponseWriter, r *http.Request) {
@@ -110,6 +104,10 @@ func CreateSlotHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Mentor profile not found. Create a mentor profile first.", http.StatusBadRequest)
 		return
 	}
+	if mentorProfile.Status != users.MentorActive {
+		http.Error(w, "Mentor profile is not active", http.StatusForbidden)
+		return
+	}
 
 	// Декодирование данных слота
 	var input struct {
@@ -155,6 +153,11 @@ func CreateSlotHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Новый слот предлагается первому подходящему пользователю из листа ожидания
+	if err := offerSlotToWaitlist(config.DB, &slot); err != nil {
+		log.Printf("Error offering slot to waitlist: %v", err)
+	}
+
 	// Возвращаем успешный ответ
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(slot)
@@ -183,22 +186,48 @@ func BookSlotHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Mentor not found", http.StatusBadRequest)
 		return
 	}
+	if mentor.Status != users.MentorActive {
+		http.Error(w, "Mentor is not accepting bookings", http.StatusConflict)
+		return
+	}
+
+	// Удержание слота задается только листом ожидания
+	slot.HeldForUserID = nil
+	slot.HeldUntil = nil
 
 	var existingSlot users.Slot
-	if err := config.DB.First(&existingSlot, "mentor_id = ? AND start_time = ? AND end_time = ?", slot.MentorID, slot.StartTime, slot.EndTime).Error; err == nil && existingSlot.IsBooked {
-		http.Error(w, "Slot is already booked", http.StatusConflict)
-		return
+	if err := config.DB.First(&existingSlot, "mentor_id = ? AND start_time = ? AND end_time = ?", slot.MentorID, slot.StartTime, slot.EndTime).Error; err == nil {
+		if existingSlot.IsBooked {
+			http.Error(w, "Slot is already booked", http.StatusConflict)
+			return
+		}
+		if isHeldForOther(existingSlot, user.ID) {
+			http.Error(w, "Slot is reserved for a waitlisted user", http.StatusConflict)
+			return
+		}
+		// Бронируем существующий свободный слот вместо создания дубликата
+		slot = existingSlot
+		slot.HeldForUserID = nil
+		slot.HeldUntil = nil
+	} else {
+		slot.ID = 0
 	}
 
 	// Заполнение данных о слоте
 	slot.IsBooked = true
 	slot.UserID = &user.ID // Сохраняем ID пользователя, который забронировал слот
-	if err := config.DB.Create(&slot).Error; err != nil {
+	slot.Price = sessionPrice(slot, mentor)
+	if err := config.DB.Save(&slot).Error; err != nil {
 		fmt.Printf("Error creating slot: %v\n", err)
 		http.Error(w, "Error booking slot", http.StatusInternalServerError)
 		return
 	}
 
+	// Ссылка на видеовстречу для подтвержденного бронирования
+	if err := attachMeetingRoom(&slot); err != nil {
+		log.Printf("Error creating meeting room: %v", err)
+	}
+
 	// Уведомление для ментора
 	notification := users.NotificationMentor{
 		UserID:  mentor.UserID,
@@ -249,9 +278,16 @@ func MentorBookedSlotsHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Слоты хранят ID профиля ментора, а не ID пользователя
+	var mentorProfile users.MentorProfile
+	if err := config.DB.First(&mentorProfile, "user_id = ?", user.ID).Error; err != nil {
+		http.Error(w, "Mentor profile not found", http.StatusNotFound)
+		return
+	}
+
 	// Получаем слоты для ментора с забронированными пользователями
 	var slots []users.Slot
-	if err := config.DB.Preload("User").Where("mentor_id = ? AND is_booked = ?", user.ID, true).Find(&slots).Error; err != nil {
+	if err := config.DB.Preload("User").Where("mentor_id = ? AND is_booked = ?", mentorProfile.ID, true).Find(&slots).Error; err != nil {
 		http.Error(w, "Error fetching booked slots", http.StatusInternalServerError)
 		return
 	}
@@ -260,6 +296,7 @@ func MentorBookedSlotsHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(slots)
 }
 
+// NotificationsHandler - GET: уведомления пользователя; создаются только обработчиками сервера
 func NotificationsHandler(w http.ResponseWriter, r *http.Request) {
 	user, err := authentication.ValidateToken(r)
 	if err != nil {
@@ -277,22 +314,6 @@ func NotificationsHandler(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(notifications)
 
-	case http.MethodPost:
-		var notification users.NotificationMentor
-		if err := json.NewDecoder(r.Body).Decode(&notification); err != nil {
-			http.Error(w, "Invalid request body", http.StatusBadRequest)
-			return
-		}
-
-		// Assign user ID to the notification
-		notification.UserID = user.ID
-		if err := config.DB.Create(&notification).Error; err != nil {
-			http.Error(w, "Error creating notification", http.StatusInternalServerError)
-			return
-		}
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(notification)
-
 	default:
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 	}
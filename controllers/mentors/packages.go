@@ -0,0 +1,529 @@
+package mentors
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"hired-valley-backend/config"
+	"hired-valley-backend/controllers/authentication"
+	"hired-valley-backend/models/billing"
+	"hired-valley-backend/models/users"
+	"hired-valley-backend/services"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PackagesHandler - GET: активные пакеты ментора (mentor_id), POST: новый пакет текущего ментора
+func PackagesHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := authentication.ValidateToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		mentorID, err := strconv.Atoi(r.URL.Query().Get("mentor_id"))
+		if err != nil || mentorID <= 0 {
+			http.Error(w, "Invalid mentor ID", http.StatusBadRequest)
+			return
+		}
+		var packages []users.MentorPackage
+		if err := config.DB.Where("mentor_id = ? AND is_active = ?", mentorID, true).Order("price ASC").Find(&packages).Error; err != nil {
+			http.Error(w, "Error fetching packages", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(packages)
+
+	case http.MethodPost:
+		profile, ok := currentMentorProfile(w, user.ID)
+		if !ok {
+			return
+		}
+		if profile.Status != users.MentorActive {
+			http.Error(w, "Mentor profile is not active", http.StatusForbidden)
+			return
+		}
+
+		var pkg users.MentorPackage
+		if err := json.NewDecoder(r.Body).Decode(&pkg); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		pkg.ID = 0
+		pkg.MentorID = profile.ID
+		pkg.IsActive = true
+		if err := validatePackage(&pkg); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err := config.DB.Create(&pkg).Error; err != nil {
+			http.Error(w, "Error creating package", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(pkg)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// UpdatePackageHandler - изменение или снятие пакета с продажи; уже купленные пакеты не меняются
+func UpdatePackageHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user, err := authentication.ValidateToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	profile, ok := currentMentorProfile(w, user.ID)
+	if !ok {
+		return
+	}
+
+	packageID, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil || packageID <= 0 {
+		http.Error(w, "Invalid package ID", http.StatusBadRequest)
+		return
+	}
+
+	var pkg users.MentorPackage
+	if err := config.DB.Where("id = ? AND mentor_id = ?", packageID, profile.ID).First(&pkg).Error; err != nil {
+		http.Error(w, "Package not found", http.StatusNotFound)
+		return
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&pkg); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	pkg.ID = uint(packageID)
+	pkg.MentorID = profile.ID
+	if err := validatePackage(&pkg); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := config.DB.Save(&pkg).Error; err != nil {
+		http.Error(w, "Error updating package", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(pkg)
+}
+
+// paymentError - ответ на ошибку проверки или возврата платежа; false, если ошибки нет
+func paymentError(w http.ResponseWriter, err error) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, services.ErrPaymentsUnavailable):
+		http.Error(w, "Paid purchases are unavailable: "+err.Error(), http.StatusServiceUnavailable)
+	case errors.Is(err, services.ErrPaymentNotVerified):
+		http.Error(w, "Payment is not completed or does not match this purchase", http.StatusPaymentRequired)
+	case errors.Is(err, services.ErrPaymentAlreadyUsed):
+		http.Error(w, "Payment has already been used", http.StatusConflict)
+	case errors.Is(err, services.ErrRefundNotAllowed):
+		http.Error(w, "Payment cannot be refunded for this amount", http.StatusConflict)
+	default:
+		log.Printf("Ошибка платежа: %v", err)
+		http.Error(w, "Payment provider error", http.StatusBadGateway)
+	}
+	return true
+}
+
+// BuyPackageHandler - покупка пакета; кредиты действуют ValidityDays дней.
+// Платный пакет оплачивается в два шага: запрос без ?payment_reference= создает платеж и возвращает 402
+// с его данными, повторный запрос с ID оплаченного платежа выдает кредиты
+func BuyPackageHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user, err := authentication.ValidateToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	packageID, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil || packageID <= 0 {
+		http.Error(w, "Invalid package ID", http.StatusBadRequest)
+		return
+	}
+
+	var pkg users.MentorPackage
+	if err := config.DB.Where("id = ? AND is_active = ?", packageID, true).First(&pkg).Error; err != nil {
+		http.Error(w, "Package not found", http.StatusNotFound)
+		return
+	}
+
+	var mentor users.MentorProfile
+	if err := config.DB.First(&mentor, pkg.MentorID).Error; err != nil || mentor.Status != users.MentorActive {
+		http.Error(w, "Mentor is not accepting bookings", http.StatusConflict)
+		return
+	}
+	if mentor.UserID == user.ID {
+		http.Error(w, "You cannot buy your own package", http.StatusBadRequest)
+		return
+	}
+
+	// Цена пакета задана в валюте по умолчанию
+	currency := services.DefaultCurrency()
+	order := services.PaymentOrder{
+		Purpose:     billing.PurposeMentorPackage,
+		ItemID:      pkg.ID,
+		UserID:      user.ID,
+		Amount:      services.ToMinorUnits(pkg.Price, currency),
+		Currency:    currency,
+		Description: pkg.Title,
+	}
+	var provider services.PaymentProvider
+	var receipt *services.PaymentReceipt
+	if order.Amount > 0 {
+		provider = services.GetPaymentProvider()
+		if provider == nil {
+			paymentError(w, services.ErrPaymentsUnavailable)
+			return
+		}
+		reference := r.URL.Query().Get("payment_reference")
+		if reference == "" {
+			intent, err := provider.CreatePayment(r.Context(), order)
+			if err != nil {
+				log.Printf("Ошибка создания платежа за пакет %d: %v", pkg.ID, err)
+				http.Error(w, "Failed to create payment", http.StatusBadGateway)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusPaymentRequired)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"payment": intent,
+				"package": pkg,
+			})
+			return
+		}
+		receipt, err = services.VerifyPayment(r.Context(), provider, reference, order)
+		if paymentError(w, err) {
+			return
+		}
+	}
+
+	purchase := users.PackagePurchase{
+		PackageID:        pkg.ID,
+		MentorID:         pkg.MentorID,
+		UserID:           user.ID,
+		CreditsTotal:     pkg.SessionsCount,
+		CreditsRemaining: pkg.SessionsCount,
+		PricePaid:        pkg.Price,
+		Status:           users.PurchaseActive,
+		ExpiresAt:        time.Now().AddDate(0, 0, pkg.ValidityDays),
+	}
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if receipt != nil {
+			payment, err := services.RecordPayment(tx, provider, receipt, order)
+			if err != nil {
+				return err
+			}
+			purchase.PaymentID = &payment.ID
+		}
+		return tx.Create(&purchase).Error
+	})
+	if errors.Is(err, services.ErrPaymentAlreadyUsed) {
+		paymentError(w, err)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error buying package", http.StatusInternalServerError)
+		return
+	}
+	purchase.Package = pkg
+
+	config.DB.Create(&users.NotificationMentor{
+		UserID:  mentor.UserID,
+		Message: fmt.Sprintf("%s bought your package \"%s\".", user.Name, pkg.Title),
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(purchase)
+}
+
+// MyPurchasesHandler - купленные пакеты пользователя с остатком кредитов
+func MyPurchasesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user, err := authentication.ValidateToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	if err := expirePurchases(config.DB); err != nil {
+		log.Printf("Ошибка обработки истекших пакетов: %v", err)
+	}
+
+	var purchases []users.PackagePurchase
+	if err := config.DB.Preload("Package").Where("user_id = ?", user.ID).Order("created_at DESC").Find(&purchases).Error; err != nil {
+		http.Error(w, "Error fetching purchases", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(purchases)
+}
+
+// RedeemCreditHandler - бронирование слота за кредит пакета
+func RedeemCreditHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user, err := authentication.ValidateToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	var input struct {
+		PurchaseID uint `json:"purchase_id"`
+		SlotID     uint `json:"slot_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	var slot users.Slot
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		// Пакет и слот блокируются до конца транзакции, чтобы параллельные бронирования не потратили
+		// один кредит дважды и не заняли один слот
+		var purchase users.PackagePurchase
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Package").
+			Where("id = ? AND user_id = ?", input.PurchaseID, user.ID).First(&purchase).Error; err != nil {
+			return errors.New("purchase not found")
+		}
+		if purchase.Status != users.PurchaseActive || purchase.ExpiresAt.Before(time.Now()) {
+			return errors.New("package is no longer active")
+		}
+		if purchase.CreditsRemaining <= 0 {
+			return errors.New("no credits remaining")
+		}
+
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&slot, input.SlotID).Error; err != nil {
+			return errors.New("slot not found")
+		}
+		if slot.MentorID != purchase.MentorID {
+			return errors.New("slot belongs to another mentor")
+		}
+		if slot.IsBooked || isHeldForOther(slot, user.ID) {
+			return errors.New("slot is not available")
+		}
+		if !slot.StartTime.After(time.Now()) || slot.StartTime.After(purchase.ExpiresAt) {
+			return errors.New("slot must start in the future and before the package expires")
+		}
+		if slot.EndTime.Sub(slot.StartTime) > time.Duration(purchase.Package.SessionMinutes)*time.Minute {
+			return fmt.Errorf("slot is longer than %d minutes", purchase.Package.SessionMinutes)
+		}
+
+		slot.IsBooked = true
+		slot.UserID = &user.ID
+		slot.HeldForUserID = nil
+		slot.HeldUntil = nil
+		slot.Price = nil
+		if err := tx.Model(&slot).Updates(map[string]interface{}{
+			"is_booked":        true,
+			"user_id":          user.ID,
+			"held_for_user_id": nil,
+			"held_until":       nil,
+			"price":            nil,
+		}).Error; err != nil {
+			return err
+		}
+		spent := tx.Model(&users.PackagePurchase{}).Where("id = ? AND credits_remaining > 0", purchase.ID).
+			UpdateColumn("credits_remaining", gorm.Expr("credits_remaining - 1"))
+		if spent.Error != nil {
+			return spent.Error
+		}
+		if spent.RowsAffected == 0 {
+			return errors.New("no credits remaining")
+		}
+		return tx.Create(&users.CreditRedemption{PurchaseID: purchase.ID, SlotID: slot.ID}).Error
+	})
+	if err != nil {
+		http.Error(w, "Error redeeming credit: "+err.Error(), http.StatusConflict)
+		return
+	}
+
+	if err := attachMeetingRoom(&slot); err != nil {
+		log.Printf("Error creating meeting room: %v", err)
+	}
+
+	var mentor users.MentorProfile
+	if err := config.DB.First(&mentor, slot.MentorID).Error; err == nil {
+		config.DB.Create(&users.NotificationMentor{
+			UserID:  mentor.UserID,
+			Message: fmt.Sprintf("Your slot from %s to %s has been booked by %s with a package credit.", slot.StartTime, slot.EndTime, user.Name),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(slot)
+}
+
+// RefundPurchaseHandler - возврат неиспользованных кредитов до истечения пакета через платежную систему
+func RefundPurchaseHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user, err := authentication.ValidateToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	purchaseID, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil || purchaseID <= 0 {
+		http.Error(w, "Invalid purchase ID", http.StatusBadRequest)
+		return
+	}
+
+	var purchase users.PackagePurchase
+	if err := config.DB.Preload("Package").Where("id = ? AND user_id = ?", purchaseID, user.ID).First(&purchase).Error; err != nil {
+		http.Error(w, "Purchase not found", http.StatusNotFound)
+		return
+	}
+	if purchase.Status != users.PurchaseActive || purchase.ExpiresAt.Before(time.Now()) {
+		http.Error(w, "Only active packages can be refunded", http.StatusConflict)
+		return
+	}
+	if purchase.CreditPoolID != nil {
+		http.Error(w, "Sessions provided by your organization cannot be refunded", http.StatusConflict)
+		return
+	}
+	if purchase.CreditsRemaining <= 0 {
+		http.Error(w, "No unused credits to refund", http.StatusConflict)
+		return
+	}
+
+	// Пакет блокируется на время возврата, чтобы параллельные запросы не вернули деньги дважды.
+	// Деньги возвращаются платежной системой по записанному платежу пакета
+	errAlreadyRefunded := errors.New("package has already been refunded or used")
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Package").First(&purchase, purchase.ID).Error; err != nil {
+			return err
+		}
+		if purchase.Status != users.PurchaseActive || purchase.CreditsRemaining <= 0 {
+			return errAlreadyRefunded
+		}
+
+		now := time.Now()
+		purchase.RefundedAmount = refundAmount(purchase)
+		purchase.RefundedAt = &now
+		purchase.Status = users.PurchaseRefunded
+		purchase.CreditsRemaining = 0
+		if purchase.RefundedAmount > 0 {
+			if purchase.PaymentID == nil {
+				return services.ErrRefundNotAllowed
+			}
+			var payment billing.Payment
+			if err := tx.First(&payment, *purchase.PaymentID).Error; err != nil {
+				return err
+			}
+			amount := services.ToMinorUnits(purchase.RefundedAmount, payment.Currency)
+			if _, err := services.RefundPayment(r.Context(), tx, services.GetPaymentProvider(), &payment, amount,
+				fmt.Sprintf("package-purchase-%d-refund", purchase.ID)); err != nil {
+				return err
+			}
+		}
+		return tx.Omit("Package").Save(&purchase).Error
+	})
+	if errors.Is(err, errAlreadyRefunded) {
+		http.Error(w, "Only active packages with unused credits can be refunded", http.StatusConflict)
+		return
+	}
+	if errors.Is(err, services.ErrPaymentsUnavailable) || errors.Is(err, services.ErrRefundNotAllowed) {
+		paymentError(w, err)
+		return
+	}
+	if err != nil {
+		log.Printf("Ошибка возврата пакета %d: %v", purchase.ID, err)
+		http.Error(w, "Error refunding purchase", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(purchase)
+}
+
+// refundAmount - стоимость неиспользованных сессий с учетом процента возврата пакета
+func refundAmount(purchase users.PackagePurchase) float64 {
+	if purchase.CreditsTotal == 0 {
+		return 0
+	}
+	unused := purchase.PricePaid * float64(purchase.CreditsRemaining) / float64(purchase.CreditsTotal)
+	return math.Round(unused*float64(purchase.Package.RefundPercent)) / 100
+}
+
+// returnPackageCredit - возврат кредита при отмене бронирования, оплаченного пакетом
+func returnPackageCredit(tx *gorm.DB, slotID uint) error {
+	var redemption users.CreditRedemption
+	if err := tx.Where("slot_id = ? AND returned_at IS NULL", slotID).Order("id DESC").First(&redemption).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	if err := tx.Model(&redemption).Update("returned_at", time.Now()).Error; err != nil {
+		return err
+	}
+	return tx.Model(&users.PackagePurchase{}).
+		Where("id = ? AND status = ?", redemption.PurchaseID, users.PurchaseActive).
+		UpdateColumn("credits_remaining", gorm.Expr("credits_remaining + 1")).Error
+}
+
+// expirePurchases - пакеты с истекшим сроком действия
+func expirePurchases(db *gorm.DB) error {
+	return db.Model(&users.PackagePurchase{}).
+		Where("status = ? AND expires_at < ?", users.PurchaseActive, time.Now()).
+		Update("status", users.PurchaseExpired).Error
+}
+
+func validatePackage(pkg *users.MentorPackage) error {
+	pkg.Title = strings.TrimSpace(pkg.Title)
+	if pkg.Title == "" {
+		return errors.New("title is required")
+	}
+	if pkg.SessionsCount <= 0 || pkg.SessionMinutes <= 0 || pkg.ValidityDays <= 0 {
+		return errors.New("sessions_count, session_minutes and validity_days must be positive")
+	}
+	if pkg.Price < 0 {
+		return errors.New("price must not be negative")
+	}
+	if pkg.RefundPercent < 0 || pkg.RefundPercent > 100 {
+		return errors.New("refund_percent must be between 0 and 100")
+	}
+	return nil
+}
@@ -0,0 +1,173 @@
+package mentors
+
+import (
+	"encoding/json"
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+	"hired-valley-backend/config"
+	"hired-valley-backend/controllers/authentication"
+	"hired-valley-backend/models/users"
+	"net/http"
+	"strings"
+)
+
+// UpdateMentorProfileHandler - обновление публичного профиля ментора
+func UpdateMentorProfileHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user, err := authentication.ValidateToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	profile, ok := currentMentorProfile(w, user.ID)
+	if !ok {
+		return
+	}
+	if profile.Status == users.MentorDeactivated {
+		http.Error(w, "Mentor profile is deactivated", http.StatusForbidden)
+		return
+	}
+
+	// Обновляем только те поля, которые были переданы
+	var input struct {
+		Headline          *string  `json:"headline"`
+		Bio               *string  `json:"bio"`
+		Skills            *string  `json:"skills"`
+		Languages         []string `json:"languages"`
+		PricePerHour      *float64 `json:"price_per_hour"`
+		YearsOfExperience *int     `json:"years_of_experience"`
+		LinkedInURL       *string  `json:"linkedin_url"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if input.Headline != nil {
+		profile.Headline = strings.TrimSpace(*input.Headline)
+	}
+	if input.Bio != nil {
+		profile.Bio = strings.TrimSpace(*input.Bio)
+	}
+	if input.Skills != nil {
+		if len(splitSkills(*input.Skills)) == 0 {
+			http.Error(w, "At least one skill is required", http.StatusBadRequest)
+			return
+		}
+		profile.Skills = *input.Skills
+	}
+	if input.Languages != nil {
+		profile.Languages = pq.StringArray(input.Languages)
+	}
+	if input.PricePerHour != nil {
+		if *input.PricePerHour < 0 {
+			http.Error(w, "price_per_hour must not be negative", http.StatusBadRequest)
+			return
+		}
+		profile.PricePerHour = *input.PricePerHour
+	}
+	if input.YearsOfExperience != nil {
+		if *input.YearsOfExperience < 0 {
+			http.Error(w, "years_of_experience must not be negative", http.StatusBadRequest)
+			return
+		}
+		profile.YearsOfExperience = *input.YearsOfExperience
+	}
+	if input.LinkedInURL != nil {
+		linkedIn := strings.TrimSpace(*input.LinkedInURL)
+		if linkedIn != "" && !isLinkedInURL(linkedIn) {
+			http.Error(w, "Invalid linkedin_url", http.StatusBadRequest)
+			return
+		}
+		profile.LinkedInURL = linkedIn
+	}
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("SkillTags", "User").Save(&profile).Error; err != nil {
+			return err
+		}
+		if input.Skills != nil {
+			return syncMentorSkills(tx, &profile)
+		}
+		return nil
+	})
+	if err != nil {
+		http.Error(w, "Error updating mentor profile", http.StatusInternalServerError)
+		return
+	}
+
+	writeMentorProfile(w, profile.ID)
+}
+
+// PauseMentorHandler - временно скрыть профиль из поиска и запретить новые бронирования
+func PauseMentorHandler(w http.ResponseWriter, r *http.Request) {
+	changeMentorStatus(w, r, users.MentorActive, users.MentorPaused)
+}
+
+// ResumeMentorHandler - вернуть приостановленный профиль в поиск
+func ResumeMentorHandler(w http.ResponseWriter, r *http.Request) {
+	changeMentorStatus(w, r, users.MentorPaused, users.MentorActive)
+}
+
+// DeactivateMentorHandler - деактивация профиля; для возврата нужна новая заявка
+func DeactivateMentorHandler(w http.ResponseWriter, r *http.Request) {
+	changeMentorStatus(w, r, "", users.MentorDeactivated)
+}
+
+// changeMentorStatus - переход статуса профиля; пустой from означает любой недеактивированный статус
+func changeMentorStatus(w http.ResponseWriter, r *http.Request, from, to string) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user, err := authentication.ValidateToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	profile, ok := currentMentorProfile(w, user.ID)
+	if !ok {
+		return
+	}
+
+	if (from != "" && profile.Status != from) || profile.Status == users.MentorDeactivated {
+		http.Error(w, "Mentor profile cannot be moved from "+profile.Status+" to "+to, http.StatusConflict)
+		return
+	}
+
+	if err := config.DB.Model(&profile).Update("status", to).Error; err != nil {
+		http.Error(w, "Error updating mentor status", http.StatusInternalServerError)
+		return
+	}
+
+	writeMentorProfile(w, profile.ID)
+}
+
+// currentMentorProfile - профиль ментора текущего пользователя
+func currentMentorProfile(w http.ResponseWriter, userID uint) (users.MentorProfile, bool) {
+	var profile users.MentorProfile
+	if err := config.DB.Where("user_id = ?", userID).First(&profile).Error; err != nil {
+		http.Error(w, "Mentor profile not found", http.StatusNotFound)
+		return profile, false
+	}
+	return profile, true
+}
+
+// writeMentorProfile - ответ с DTO профиля ментора
+func writeMentorProfile(w http.ResponseWriter, profileID uint) {
+	var profile users.MentorProfile
+	if err := config.DB.Preload("User").Preload("SkillTags").First(&profile, profileID).Error; err != nil {
+		http.Error(w, "Error fetching mentor profile", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(toMentorDTO(profile))
+}
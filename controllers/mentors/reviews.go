@@ -0,0 +1,344 @@
+package mentors
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"hired-valley-backend/config"
+	"hired-valley-backend/controllers/authentication"
+	"hired-valley-backend/models/users"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CreateReviewHandler - отзыв менти о завершенной сессии
+func CreateReviewHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user, err := authentication.ValidateToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	var input struct {
+		SlotID  uint   `json:"slot_id"`
+		Rating  int    `json:"rating"`
+		Comment string `json:"comment"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if input.Rating < 1 || input.Rating > 5 {
+		http.Error(w, "Rating must be between 1 and 5", http.StatusBadRequest)
+		return
+	}
+
+	// Отзыв можно оставить только по своему завершенному бронированию
+	var slot users.Slot
+	if err := config.DB.First(&slot, input.SlotID).Error; err != nil {
+		http.Error(w, "Booking not found", http.StatusNotFound)
+		return
+	}
+	if !slot.IsBooked || slot.UserID == nil || *slot.UserID != user.ID {
+		http.Error(w, "You can only review your own bookings", http.StatusForbidden)
+		return
+	}
+	if slot.EndTime.After(time.Now()) {
+		http.Error(w, "Session is not completed yet", http.StatusBadRequest)
+		return
+	}
+
+	var existing users.MentorReview
+	if err := config.DB.Where("slot_id = ?", slot.ID).First(&existing).Error; err == nil {
+		http.Error(w, "Review for this booking already exists", http.StatusConflict)
+		return
+	}
+
+	review := users.MentorReview{
+		MentorID: slot.MentorID,
+		SlotID:   slot.ID,
+		UserID:   user.ID,
+		Rating:   input.Rating,
+		Comment:  strings.TrimSpace(input.Comment),
+	}
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&review).Error; err != nil {
+			return err
+		}
+		return recalculateMentorRating(tx, review.MentorID)
+	})
+	if err != nil {
+		http.Error(w, "Error creating review", http.StatusInternalServerError)
+		return
+	}
+
+	// Уведомление для ментора
+	var mentor users.MentorProfile
+	if err := config.DB.First(&mentor, review.MentorID).Error; err == nil {
+		config.DB.Create(&users.NotificationMentor{
+			UserID:  mentor.UserID,
+			Message: fmt.Sprintf("%s left a %d-star review for your session.", user.Name, review.Rating),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(review)
+}
+
+// ListReviewsHandler - получение видимых отзывов о менторе
+func ListReviewsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user, err := authentication.ValidateToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	mentorID, err := strconv.Atoi(r.URL.Query().Get("mentor_id"))
+	if err != nil || mentorID <= 0 {
+		http.Error(w, "Invalid mentor ID", http.StatusBadRequest)
+		return
+	}
+
+	query := config.DB.Where("mentor_id = ?", mentorID)
+	// Администратор видит в том числе скрытые отзывы
+	if user.Role != "admin" {
+		query = query.Where("is_hidden = ?", false)
+	}
+
+	var reviews []users.MentorReview
+	if err := query.Order("created_at DESC").Find(&reviews).Error; err != nil {
+		http.Error(w, "Error fetching reviews", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(reviews)
+}
+
+// ReplyReviewHandler - ответ ментора на отзыв
+func ReplyReviewHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user, err := authentication.ValidateToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	review, ok := findReview(w, r)
+	if !ok {
+		return
+	}
+
+	var mentor users.MentorProfile
+	if err := config.DB.First(&mentor, review.MentorID).Error; err != nil || mentor.UserID != user.ID {
+		http.Error(w, "Only the reviewed mentor can reply", http.StatusForbidden)
+		return
+	}
+
+	var input struct {
+		Reply string `json:"reply"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil || strings.TrimSpace(input.Reply) == "" {
+		http.Error(w, "Reply is required", http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now()
+	review.Reply = strings.TrimSpace(input.Reply)
+	review.RepliedAt = &now
+	if err := config.DB.Save(&review).Error; err != nil {
+		http.Error(w, "Error saving reply", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(review)
+}
+
+// FlagReviewHandler - жалоба пользователя на отзыв
+func FlagReviewHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user, err := authentication.ValidateToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	review, ok := findReview(w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Reason string `json:"reason"`
+	}
+	json.NewDecoder(r.Body).Decode(&input)
+
+	flag := users.MentorReviewFlag{
+		ReviewID: review.ID,
+		UserID:   user.ID,
+		Reason:   strings.TrimSpace(input.Reason),
+	}
+
+	var existing users.MentorReviewFlag
+	if err := config.DB.Where("review_id = ? AND user_id = ?", review.ID, user.ID).First(&existing).Error; err == nil {
+		http.Error(w, "You have already flagged this review", http.StatusConflict)
+		return
+	}
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&flag).Error; err != nil {
+			return err
+		}
+		return tx.Model(&users.MentorReview{}).Where("id = ?", review.ID).
+			UpdateColumn("flags_count", gorm.Expr("flags_count + ?", 1)).Error
+	})
+	if err != nil {
+		http.Error(w, "Error flagging review", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(flag)
+}
+
+// ModerateReviewHandler - скрытие или восстановление отзыва администратором
+func ModerateReviewHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user, err := authentication.ValidateToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	if user.Role != "admin" {
+		http.Error(w, "Only admins can moderate reviews", http.StatusForbidden)
+		return
+	}
+
+	review, ok := findReview(w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Hidden bool `json:"hidden"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	review.IsHidden = input.Hidden
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&review).Error; err != nil {
+			return err
+		}
+		return recalculateMentorRating(tx, review.MentorID)
+	})
+	if err != nil {
+		http.Error(w, "Error moderating review", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(review)
+}
+
+// FlaggedReviewsHandler - очередь отзывов с жалобами для администратора
+func FlaggedReviewsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user, err := authentication.ValidateToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	if user.Role != "admin" {
+		http.Error(w, "Only admins can view flagged reviews", http.StatusForbidden)
+		return
+	}
+
+	var reviews []users.MentorReview
+	if err := config.DB.Where("flags_count > 0").Order("flags_count DESC").Find(&reviews).Error; err != nil {
+		http.Error(w, "Error fetching reviews", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(reviews)
+}
+
+// findReview - загрузка отзыва по параметру id
+func findReview(w http.ResponseWriter, r *http.Request) (users.MentorReview, bool) {
+	var review users.MentorReview
+
+	reviewID, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil || reviewID <= 0 {
+		http.Error(w, "Invalid review ID", http.StatusBadRequest)
+		return review, false
+	}
+
+	if err := config.DB.First(&review, reviewID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, "Review not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Error fetching review", http.StatusInternalServerError)
+		}
+		return review, false
+	}
+
+	return review, true
+}
+
+// recalculateMentorRating - пересчет агрегированного рейтинга ментора
+func recalculateMentorRating(tx *gorm.DB, mentorID uint) error {
+	var stats struct {
+		Average float64
+		Count   int
+	}
+	if err := tx.Model(&users.MentorReview{}).
+		Select("COALESCE(AVG(rating), 0) AS average, COUNT(*) AS count").
+		Where("mentor_id = ? AND is_hidden = ?", mentorID, false).
+		Scan(&stats).Error; err != nil {
+		return err
+	}
+
+	return tx.Model(&users.MentorProfile{}).Where("id = ?", mentorID).Updates(map[string]interface{}{
+		"average_rating": stats.Average,
+		"reviews_count":  stats.Count,
+	}).Error
+}
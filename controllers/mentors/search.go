@@ -0,0 +1,213 @@
+package mentors
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"hired-valley-backend/config"
+	"hired-valley-backend/controllers/authentication"
+	"hired-valley-backend/models/users"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+)
+
+// searchCursor - позиция последнего элемента страницы в порядке сортировки
+type searchCursor struct {
+	Relevance int     `json:"r"`
+	Rating    float64 `json:"a"`
+	ID        uint    `json:"id"`
+}
+
+type mentorRank struct {
+	ID            uint
+	AverageRating float64
+	Relevance     int
+}
+
+// SearchMentorsHandler - поиск менторов с фильтрами, ранжированием и курсорной пагинацией
+func SearchMentorsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if _, err := authentication.ValidateToken(r); err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	params := r.URL.Query()
+
+	// Навыки передаются через запятую: skills=go,postgresql
+	skills := splitSkills(params.Get("skills"))
+
+	// Релевантность: количество совпавших канонических навыков плюс совпадение текста
+	relevanceSQL := "0"
+	var relevanceArgs []interface{}
+	if len(skills) > 0 {
+		relevanceSQL = `(SELECT COUNT(*) FROM mentor_skills ms JOIN skills s ON s.id = ms.skill_id
+			WHERE ms.mentor_profile_id = mentor_profiles.id AND LOWER(s.name) IN ?)`
+		relevanceArgs = append(relevanceArgs, skills)
+	}
+	text := strings.TrimSpace(params.Get("q"))
+	if text != "" {
+		relevanceSQL += " + (CASE WHEN mentor_profiles.bio ILIKE ? OR users.name ILIKE ? OR users.position ILIKE ? THEN 1 ELSE 0 END)"
+		pattern := "%" + text + "%"
+		relevanceArgs = append(relevanceArgs, pattern, pattern, pattern)
+	}
+
+	ranked := config.DB.Model(&users.MentorProfile{}).
+		Select("mentor_profiles.id, mentor_profiles.average_rating, ("+relevanceSQL+") AS relevance", relevanceArgs...).
+		Joins("JOIN users ON users.id = mentor_profiles.user_id AND users.deleted_at IS NULL").
+		Where("mentor_profiles.status = ?", users.MentorActive)
+
+	// Фильтрация по цене
+	if value := params.Get("min_price"); value != "" {
+		price, err := strconv.ParseFloat(value, 64)
+		if err != nil || price < 0 {
+			http.Error(w, "Invalid min_price", http.StatusBadRequest)
+			return
+		}
+		ranked = ranked.Where("mentor_profiles.price_per_hour >= ?", price)
+	}
+	if value := params.Get("max_price"); value != "" {
+		price, err := strconv.ParseFloat(value, 64)
+		if err != nil || price < 0 {
+			http.Error(w, "Invalid max_price", http.StatusBadRequest)
+			return
+		}
+		ranked = ranked.Where("mentor_profiles.price_per_hour <= ?", price)
+	}
+
+	// Фильтрация по рейтингу
+	if value := params.Get("min_rating"); value != "" {
+		rating, err := strconv.ParseFloat(value, 64)
+		if err != nil || rating < 0 || rating > 5 {
+			http.Error(w, "Invalid min_rating", http.StatusBadRequest)
+			return
+		}
+		ranked = ranked.Where("mentor_profiles.average_rating >= ?", rating)
+	}
+
+	// Фильтрация по языку и индустрии
+	if language := strings.TrimSpace(params.Get("language")); language != "" {
+		ranked = ranked.Where("? ILIKE ANY(mentor_profiles.languages)", language)
+	}
+	if industry := strings.TrimSpace(params.Get("industry")); industry != "" {
+		ranked = ranked.Where("users.industry ILIKE ?", industry)
+	}
+
+	// Фильтрация по наличию свободных слотов в заданном окне
+	availableFrom, availableTo := params.Get("available_from"), params.Get("available_to")
+	if availableFrom != "" || availableTo != "" {
+		from, err := time.Parse(time.RFC3339, availableFrom)
+		if err != nil {
+			http.Error(w, "Invalid available_from format. Use RFC3339 format.", http.StatusBadRequest)
+			return
+		}
+		to, err := time.Parse(time.RFC3339, availableTo)
+		if err != nil || !to.After(from) {
+			http.Error(w, "Invalid available_to format. Use RFC3339 format after available_from.", http.StatusBadRequest)
+			return
+		}
+		ranked = ranked.Where(`EXISTS (SELECT 1 FROM slots WHERE slots.mentor_id = mentor_profiles.id
+			AND slots.is_booked = false AND slots.start_time >= ? AND slots.end_time <= ?)`, from, to)
+	}
+
+	limit := defaultSearchLimit
+	if value := params.Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		if parsed < maxSearchLimit {
+			limit = parsed
+		} else {
+			limit = maxSearchLimit
+		}
+	}
+
+	query := config.DB.Table("(?) AS ranked", ranked)
+	if len(skills) > 0 {
+		query = query.Where("relevance > 0")
+	}
+
+	if value := params.Get("cursor"); value != "" {
+		cursor, err := decodeSearchCursor(value)
+		if err != nil {
+			http.Error(w, "Invalid cursor", http.StatusBadRequest)
+			return
+		}
+		query = query.Where("(relevance, average_rating, id) < (?, ?, ?)", cursor.Relevance, cursor.Rating, cursor.ID)
+	}
+
+	var ranks []mentorRank
+	if err := query.Order("relevance DESC, average_rating DESC, id DESC").Limit(limit + 1).Find(&ranks).Error; err != nil {
+		http.Error(w, "Error searching mentors", http.StatusInternalServerError)
+		return
+	}
+
+	nextCursor := ""
+	if len(ranks) > limit {
+		ranks = ranks[:limit]
+		last := ranks[len(ranks)-1]
+		nextCursor = encodeSearchCursor(searchCursor{Relevance: last.Relevance, Rating: last.AverageRating, ID: last.ID})
+	}
+
+	ids := make([]uint, 0, len(ranks))
+	for _, rank := range ranks {
+		ids = append(ids, rank.ID)
+	}
+
+	var profiles []users.MentorProfile
+	if len(ids) > 0 {
+		if err := config.DB.Preload("User").Preload("SkillTags").Where("id IN ?", ids).Find(&profiles).Error; err != nil {
+			http.Error(w, "Error fetching mentors", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	// Восстанавливаем порядок ранжирования
+	byID := make(map[uint]users.MentorProfile, len(profiles))
+	for _, profile := range profiles {
+		byID[profile.ID] = profile
+	}
+	type rankedMentor struct {
+		MentorDTO
+		Relevance int `json:"relevance"`
+	}
+	result := make([]rankedMentor, 0, len(ranks))
+	for _, rank := range ranks {
+		if profile, ok := byID[rank.ID]; ok {
+			result = append(result, rankedMentor{MentorDTO: toMentorDTO(profile), Relevance: rank.Relevance})
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"mentors":     result,
+		"next_cursor": nextCursor,
+	})
+}
+
+func encodeSearchCursor(cursor searchCursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeSearchCursor(value string) (searchCursor, error) {
+	var cursor searchCursor
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return cursor, err
+	}
+	err = json.Unmarshal(data, &cursor)
+	return cursor, err
+}
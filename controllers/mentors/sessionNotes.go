@@ -0,0 +1,488 @@
+package mentors
+
+import (
+	"encoding/json"
+	"gorm.io/gorm"
+	"hired-valley-backend/config"
+	"hired-valley-backend/controllers/authentication"
+	"hired-valley-backend/models/career"
+	"hired-valley-backend/models/users"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sessionParticipants - участники бронирования слота. Заметки и задачи относятся к бронированию
+// (слот + менти): после отмены слот может занять другой менти
+type sessionParticipants struct {
+	Slot         users.Slot
+	MentorUserID uint
+	MenteeUserID uint
+	Active       bool // Бронирование действует: слот занят этим менти
+}
+
+func (p sessionParticipants) isMentor(userID uint) bool {
+	return p.MentorUserID == userID
+}
+
+func (p sessionParticipants) isParticipant(userID uint) bool {
+	return p.MentorUserID == userID || p.MenteeUserID == userID
+}
+
+// loadBooking - текущее или отмененное бронирование слота менти menteeID и проверка, что пользователь
+// его участник. menteeID 0 - сам пользователь, а для ментора - текущий менти слота
+func loadBooking(w http.ResponseWriter, slotID, menteeID, userID uint) (sessionParticipants, bool) {
+	var participants sessionParticipants
+
+	if err := config.DB.First(&participants.Slot, slotID).Error; err != nil {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return participants, false
+	}
+	var mentor users.MentorProfile
+	if err := config.DB.First(&mentor, participants.Slot.MentorID).Error; err != nil {
+		http.Error(w, "Mentor not found", http.StatusNotFound)
+		return participants, false
+	}
+	participants.MentorUserID = mentor.UserID
+
+	booked := participants.Slot.IsBooked && participants.Slot.UserID != nil
+	if menteeID == 0 {
+		if userID != mentor.UserID {
+			menteeID = userID
+		} else if booked {
+			menteeID = *participants.Slot.UserID
+		} else {
+			http.Error(w, "Slot is not booked", http.StatusConflict)
+			return participants, false
+		}
+	}
+	participants.MenteeUserID = menteeID
+	participants.Active = booked && *participants.Slot.UserID == menteeID
+
+	if !participants.isParticipant(userID) {
+		http.Error(w, "Only session participants can access this session", http.StatusForbidden)
+		return participants, false
+	}
+	if !participants.Active {
+		var cancelled int64
+		config.DB.Model(&users.BookingCancellation{}).Where("slot_id = ? AND user_id = ?", slotID, menteeID).Count(&cancelled)
+		if cancelled == 0 {
+			http.Error(w, "Only session participants can access this session", http.StatusForbidden)
+			return participants, false
+		}
+	}
+	return participants, true
+}
+
+// loadSession - действующее бронирование слота и проверка, что пользователь его участник
+func loadSession(w http.ResponseWriter, slotID uint, userID uint) (sessionParticipants, bool) {
+	participants, ok := loadBooking(w, slotID, 0, userID)
+	if ok && !participants.Active {
+		http.Error(w, "Booking has been cancelled", http.StatusConflict)
+		return participants, false
+	}
+	return participants, ok
+}
+
+// parseMenteeID - необязательный mentee_id: ментор открывает заметки и задачи прошлого бронирования слота
+func parseMenteeID(w http.ResponseWriter, r *http.Request) (uint, bool) {
+	value := r.URL.Query().Get("mentee_id")
+	if value == "" {
+		return 0, true
+	}
+	menteeID, err := strconv.Atoi(value)
+	if err != nil || menteeID <= 0 {
+		http.Error(w, "Invalid mentee ID", http.StatusBadRequest)
+		return 0, false
+	}
+	return uint(menteeID), true
+}
+
+func parseSlotID(w http.ResponseWriter, r *http.Request) (uint, bool) {
+	slotID, err := strconv.Atoi(r.URL.Query().Get("slot_id"))
+	if err != nil || slotID <= 0 {
+		http.Error(w, "Invalid slot ID", http.StatusBadRequest)
+		return 0, false
+	}
+	return uint(slotID), true
+}
+
+// SessionNotesHandler - GET: заметки по бронированию (в том числе отмененному, ?mentee_id= для ментора),
+// POST: новая заметка к действующему бронированию
+func SessionNotesHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := authentication.ValidateToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	slotID, ok := parseSlotID(w, r)
+	if !ok {
+		return
+	}
+	menteeID, ok := parseMenteeID(w, r)
+	if !ok {
+		return
+	}
+	session, ok := loadBooking(w, slotID, menteeID, user.ID)
+	if !ok {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		// Личные заметки ментора видит только их автор
+		var notes []users.SessionNote
+		if err := config.DB.Where("slot_id = ? AND mentee_id = ? AND (visibility = ? OR author_id = ?)",
+			slotID, session.MenteeUserID, users.NoteShared, user.ID).
+			Order("created_at ASC").Find(&notes).Error; err != nil {
+			http.Error(w, "Error fetching notes", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(notes)
+
+	case http.MethodPost:
+		if !session.Active {
+			http.Error(w, "Booking has been cancelled", http.StatusConflict)
+			return
+		}
+		var input struct {
+			Content    string `json:"content"`
+			Visibility string `json:"visibility"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil || strings.TrimSpace(input.Content) == "" {
+			http.Error(w, "Content is required", http.StatusBadRequest)
+			return
+		}
+		if input.Visibility == "" {
+			input.Visibility = users.NoteShared
+		}
+		if input.Visibility != users.NoteShared && input.Visibility != users.NotePrivate {
+			http.Error(w, "Visibility must be 'private' or 'shared'", http.StatusBadRequest)
+			return
+		}
+		if input.Visibility == users.NotePrivate && !session.isMentor(user.ID) {
+			http.Error(w, "Only the mentor can create private notes", http.StatusForbidden)
+			return
+		}
+
+		note := users.SessionNote{
+			SlotID:     slotID,
+			MenteeID:   session.MenteeUserID,
+			AuthorID:   user.ID,
+			Visibility: input.Visibility,
+			Content:    strings.TrimSpace(input.Content),
+		}
+		if err := config.DB.Create(&note).Error; err != nil {
+			http.Error(w, "Error creating note", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(note)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// SessionNoteHandler - PUT: изменение, DELETE: удаление заметки автором
+func SessionNoteHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := authentication.ValidateToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	noteID, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil || noteID <= 0 {
+		http.Error(w, "Invalid note ID", http.StatusBadRequest)
+		return
+	}
+
+	var note users.SessionNote
+	if err := config.DB.First(&note, noteID).Error; err != nil {
+		http.Error(w, "Note not found", http.StatusNotFound)
+		return
+	}
+	if note.AuthorID != user.ID {
+		http.Error(w, "Permission denied", http.StatusForbidden)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		var input struct {
+			Content string `json:"content"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil || strings.TrimSpace(input.Content) == "" {
+			http.Error(w, "Content is required", http.StatusBadRequest)
+			return
+		}
+		note.Content = strings.TrimSpace(input.Content)
+		if err := config.DB.Save(&note).Error; err != nil {
+			http.Error(w, "Error updating note", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(note)
+
+	case http.MethodDelete:
+		if err := config.DB.Delete(&note).Error; err != nil {
+			http.Error(w, "Error deleting note", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// ActionItemsHandler - GET: задачи по бронированию (или все задачи менти без slot_id), POST: новая задача
+// к действующему бронированию
+func ActionItemsHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := authentication.ValidateToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	if r.Method == http.MethodGet && r.URL.Query().Get("slot_id") == "" {
+		var items []users.ActionItem
+		if err := config.DB.Where("mentee_id = ?", user.ID).Order("completed ASC, due_date ASC NULLS LAST").Find(&items).Error; err != nil {
+			http.Error(w, "Error fetching action items", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(items)
+		return
+	}
+
+	slotID, ok := parseSlotID(w, r)
+	if !ok {
+		return
+	}
+	menteeID, ok := parseMenteeID(w, r)
+	if !ok {
+		return
+	}
+	session, ok := loadBooking(w, slotID, menteeID, user.ID)
+	if !ok {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		var items []users.ActionItem
+		if err := config.DB.Where("slot_id = ? AND mentee_id = ?", slotID, session.MenteeUserID).
+			Order("created_at ASC").Find(&items).Error; err != nil {
+			http.Error(w, "Error fetching action items", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(items)
+
+	case http.MethodPost:
+		if !session.Active {
+			http.Error(w, "Booking has been cancelled", http.StatusConflict)
+			return
+		}
+		var input struct {
+			Title       string     `json:"title"`
+			Description string     `json:"description"`
+			DueDate     *time.Time `json:"due_date"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil || strings.TrimSpace(input.Title) == "" {
+			http.Error(w, "Title is required", http.StatusBadRequest)
+			return
+		}
+
+		item := users.ActionItem{
+			SlotID:      slotID,
+			MenteeID:    session.MenteeUserID,
+			CreatedByID: user.ID,
+			Title:       strings.TrimSpace(input.Title),
+			Description: strings.TrimSpace(input.Description),
+			DueDate:     input.DueDate,
+		}
+		if err := config.DB.Create(&item).Error; err != nil {
+			http.Error(w, "Error creating action item", http.StatusInternalServerError)
+			return
+		}
+
+		if user.ID != session.MenteeUserID {
+			config.DB.Create(&users.NotificationMentor{
+				UserID:  session.MenteeUserID,
+				Message: "New action item from your mentoring session: " + item.Title,
+			})
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(item)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// UpdateActionItemHandler - изменение задачи и отметка о выполнении
+func UpdateActionItemHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user, err := authentication.ValidateToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	item, ok := findActionItem(w, r, user.ID)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Title       *string    `json:"title"`
+		Description *string    `json:"description"`
+		DueDate     *time.Time `json:"due_date"`
+		Completed   *bool      `json:"completed"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if input.Title != nil && strings.TrimSpace(*input.Title) != "" {
+		item.Title = strings.TrimSpace(*input.Title)
+	}
+	if input.Description != nil {
+		item.Description = strings.TrimSpace(*input.Description)
+	}
+	if input.DueDate != nil {
+		item.DueDate = input.DueDate
+	}
+	if input.Completed != nil && *input.Completed != item.Completed {
+		item.Completed = *input.Completed
+		item.CompletedAt = nil
+		if item.Completed {
+			now := time.Now()
+			item.CompletedAt = &now
+		}
+	}
+
+	// Выполнение задачи отражается в связанном шаге карьерного плана
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&item).Error; err != nil {
+			return err
+		}
+		if item.PlanStepID == nil {
+			return nil
+		}
+		return tx.Model(&career.PlanStep{}).Where("id = ?", *item.PlanStepID).Updates(map[string]interface{}{
+			"title":        item.Title,
+			"description":  item.Description,
+			"due_date":     item.DueDate,
+			"completed":    item.Completed,
+			"completed_at": item.CompletedAt,
+		}).Error
+	})
+	if err != nil {
+		http.Error(w, "Error updating action item", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(item)
+}
+
+// ActionItemToPlanHandler - перенос задачи в шаги карьерного плана менти
+func ActionItemToPlanHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user, err := authentication.ValidateToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	item, ok := findActionItem(w, r, user.ID)
+	if !ok {
+		return
+	}
+	if item.MenteeID != user.ID {
+		http.Error(w, "Only the mentee can add action items to a career plan", http.StatusForbidden)
+		return
+	}
+	if item.PlanStepID != nil {
+		http.Error(w, "Action item is already in a career plan", http.StatusConflict)
+		return
+	}
+
+	var input struct {
+		PlanID uint `json:"plan_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	var plan career.PlanCareer
+	if err := config.DB.Where("id = ? AND user_id = ?", input.PlanID, user.ID).First(&plan).Error; err != nil {
+		http.Error(w, "Career plan not found", http.StatusNotFound)
+		return
+	}
+
+	step := career.PlanStep{
+		PlanID:       plan.ID,
+		Title:        item.Title,
+		Description:  item.Description,
+		Source:       career.StepSourceMentor,
+		ActionItemID: &item.ID,
+		DueDate:      item.DueDate,
+		Completed:    item.Completed,
+		CompletedAt:  item.CompletedAt,
+	}
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&step).Error; err != nil {
+			return err
+		}
+		item.PlanStepID = &step.ID
+		return tx.Model(&item).Update("plan_step_id", step.ID).Error
+	})
+	if err != nil {
+		http.Error(w, "Error adding action item to career plan", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(step)
+}
+
+// findActionItem - загрузка задачи, доступной участнику ее бронирования
+func findActionItem(w http.ResponseWriter, r *http.Request, userID uint) (users.ActionItem, bool) {
+	var item users.ActionItem
+
+	itemID, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil || itemID <= 0 {
+		http.Error(w, "Invalid action item ID", http.StatusBadRequest)
+		return item, false
+	}
+	if err := config.DB.First(&item, itemID).Error; err != nil {
+		http.Error(w, "Action item not found", http.StatusNotFound)
+		return item, false
+	}
+	if _, ok := loadBooking(w, item.SlotID, item.MenteeID, userID); !ok {
+		return item, false
+	}
+	return item, true
+}
@@ -0,0 +1,472 @@
+package mentors
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"hired-valley-backend/config"
+	"hired-valley-backend/controllers/authentication"
+	"hired-valley-backend/models/users"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+// waitlistOfferTTL - время на подтверждение предложения (WAITLIST_OFFER_MINUTES)
+func waitlistOfferTTL() time.Duration {
+	minutes, err := strconv.Atoi(os.Getenv("WAITLIST_OFFER_MINUTES"))
+	if err != nil || minutes <= 0 {
+		minutes = 60
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// isHeldForOther - слот удерживается для другого пользователя из листа ожидания
+func isHeldForOther(slot users.Slot, userID uint) bool {
+	return slot.HeldForUserID != nil && *slot.HeldForUserID != userID &&
+		slot.HeldUntil != nil && slot.HeldUntil.After(time.Now())
+}
+
+// offerSlotToWaitlist - предложение свободного слота следующему подходящему пользователю из очереди
+func offerSlotToWaitlist(tx *gorm.DB, slot *users.Slot) error {
+	if slot.IsBooked || !slot.StartTime.After(time.Now()) {
+		return nil
+	}
+
+	var entries []users.WaitlistEntry
+	if err := tx.Preload("Windows").
+		Where("mentor_id = ? AND status = ?", slot.MentorID, users.WaitlistWaiting).
+		Order("created_at ASC").Find(&entries).Error; err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if !slotMatchesWindows(*slot, entry.Windows) {
+			continue
+		}
+
+		expiresAt := time.Now().Add(waitlistOfferTTL())
+		if expiresAt.After(slot.StartTime) {
+			expiresAt = slot.StartTime
+		}
+
+		if err := tx.Model(&entry).Updates(map[string]interface{}{
+			"status":           users.WaitlistOffered,
+			"offered_slot_id":  slot.ID,
+			"offer_expires_at": expiresAt,
+		}).Error; err != nil {
+			return err
+		}
+		slot.HeldForUserID = &entry.UserID
+		slot.HeldUntil = &expiresAt
+		if err := tx.Model(slot).Updates(map[string]interface{}{
+			"held_for_user_id": entry.UserID,
+			"held_until":       expiresAt,
+		}).Error; err != nil {
+			return err
+		}
+
+		return tx.Create(&users.NotificationMentor{
+			UserID: entry.UserID,
+			Message: fmt.Sprintf("A slot from %s to %s is available for you. Claim it before %s.",
+				slot.StartTime.Format(time.RFC3339), slot.EndTime.Format(time.RFC3339), expiresAt.Format(time.RFC3339)),
+		}).Error
+	}
+
+	return nil
+}
+
+func slotMatchesWindows(slot users.Slot, windows []users.WaitlistWindow) bool {
+	if len(windows) == 0 {
+		return true
+	}
+	for _, window := range windows {
+		if !slot.StartTime.Before(window.StartTime) && !slot.EndTime.After(window.EndTime) {
+			return true
+		}
+	}
+	return false
+}
+
+// releaseOffer - снятие предложения и передача слота следующему в очереди
+func releaseOffer(tx *gorm.DB, entry *users.WaitlistEntry, status string) error {
+	if err := tx.Model(entry).Updates(map[string]interface{}{
+		"status":           status,
+		"offer_expires_at": nil,
+	}).Error; err != nil {
+		return err
+	}
+	if entry.OfferedSlotID == nil {
+		return nil
+	}
+
+	var slot users.Slot
+	if err := tx.First(&slot, *entry.OfferedSlotID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	if slot.IsBooked {
+		return nil
+	}
+
+	slot.HeldForUserID = nil
+	slot.HeldUntil = nil
+	if err := tx.Model(&slot).Updates(map[string]interface{}{"held_for_user_id": nil, "held_until": nil}).Error; err != nil {
+		return err
+	}
+	return offerSlotToWaitlist(tx, &slot)
+}
+
+// expireWaitlistOffers - истекшие предложения переходят к следующему в очереди
+func expireWaitlistOffers() error {
+	var expired []users.WaitlistEntry
+	if err := config.DB.Where("status = ? AND offer_expires_at < ?", users.WaitlistOffered, time.Now()).
+		Find(&expired).Error; err != nil {
+		return err
+	}
+
+	for i := range expired {
+		entry := expired[i]
+		if err := config.DB.Transaction(func(tx *gorm.DB) error {
+			return releaseOffer(tx, &entry, users.WaitlistExpired)
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RunWaitlistWorker - периодическая обработка истекших предложений
+func RunWaitlistWorker(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if err := expireWaitlistOffers(); err != nil {
+			log.Printf("Ошибка обработки листа ожидания: %v", err)
+		}
+	}
+}
+
+// WaitlistHandler - GET: записи пользователя, POST: встать в очередь, DELETE: покинуть очередь
+func WaitlistHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := authentication.ValidateToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		if err := expireWaitlistOffers(); err != nil {
+			log.Printf("Ошибка обработки листа ожидания: %v", err)
+		}
+		var entries []users.WaitlistEntry
+		if err := config.DB.Preload("Windows").Where("user_id = ?", user.ID).Order("created_at DESC").Find(&entries).Error; err != nil {
+			http.Error(w, "Error fetching waitlist", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(entries)
+
+	case http.MethodPost:
+		var input struct {
+			MentorID uint `json:"mentor_id"`
+			Windows  []struct {
+				StartTime time.Time `json:"start_time"`
+				EndTime   time.Time `json:"end_time"`
+			} `json:"windows"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		var mentor users.MentorProfile
+		if err := config.DB.First(&mentor, input.MentorID).Error; err != nil {
+			http.Error(w, "Mentor not found", http.StatusNotFound)
+			return
+		}
+		if mentor.Status != users.MentorActive {
+			http.Error(w, "Mentor is not accepting bookings", http.StatusConflict)
+			return
+		}
+		if mentor.UserID == user.ID {
+			http.Error(w, "You cannot join your own waitlist", http.StatusBadRequest)
+			return
+		}
+
+		var existing users.WaitlistEntry
+		if err := config.DB.Where("mentor_id = ? AND user_id = ? AND status IN ?", mentor.ID, user.ID,
+			[]string{users.WaitlistWaiting, users.WaitlistOffered}).First(&existing).Error; err == nil {
+			http.Error(w, "You are already on this mentor's waitlist", http.StatusConflict)
+			return
+		}
+
+		entry := users.WaitlistEntry{
+			MentorID: mentor.ID,
+			UserID:   user.ID,
+			Status:   users.WaitlistWaiting,
+		}
+		for _, window := range input.Windows {
+			if !window.EndTime.After(window.StartTime) {
+				http.Error(w, "Each window must end after it starts", http.StatusBadRequest)
+				return
+			}
+			entry.Windows = append(entry.Windows, users.WaitlistWindow{StartTime: window.StartTime, EndTime: window.EndTime})
+		}
+
+		if err := config.DB.Create(&entry).Error; err != nil {
+			http.Error(w, "Error joining waitlist", http.StatusInternalServerError)
+			return
+		}
+
+		var position int64
+		config.DB.Model(&users.WaitlistEntry{}).
+			Where("mentor_id = ? AND status = ? AND created_at <= ?", mentor.ID, users.WaitlistWaiting, entry.CreatedAt).
+			Count(&position)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"entry":    entry,
+			"position": position,
+		})
+
+	case http.MethodDelete:
+		entry, ok := findWaitlistEntry(w, r, user.ID)
+		if !ok {
+			return
+		}
+		if entry.Status != users.WaitlistWaiting && entry.Status != users.WaitlistOffered {
+			http.Error(w, "Waitlist entry is no longer active", http.StatusConflict)
+			return
+		}
+		if err := config.DB.Transaction(func(tx *gorm.DB) error {
+			return releaseOffer(tx, &entry, users.WaitlistCancelled)
+		}); err != nil {
+			http.Error(w, "Error leaving waitlist", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// ClaimWaitlistOfferHandler - подтверждение предложенного слота
+func ClaimWaitlistOfferHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user, err := authentication.ValidateToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	entry, ok := findWaitlistEntry(w, r, user.ID)
+	if !ok {
+		return
+	}
+	if entry.Status != users.WaitlistOffered || entry.OfferedSlotID == nil {
+		http.Error(w, "There is no active offer for this entry", http.StatusConflict)
+		return
+	}
+	if entry.OfferExpiresAt == nil || entry.OfferExpiresAt.Before(time.Now()) {
+		config.DB.Transaction(func(tx *gorm.DB) error {
+			return releaseOffer(tx, &entry, users.WaitlistExpired)
+		})
+		http.Error(w, "Offer has expired", http.StatusGone)
+		return
+	}
+
+	var slot users.Slot
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&slot, *entry.OfferedSlotID).Error; err != nil {
+			return err
+		}
+		if slot.IsBooked {
+			return errors.New("slot is already booked")
+		}
+		var mentor users.MentorProfile
+		if err := tx.First(&mentor, slot.MentorID).Error; err != nil {
+			return err
+		}
+		slot.IsBooked = true
+		slot.UserID = &user.ID
+		slot.HeldForUserID = nil
+		slot.HeldUntil = nil
+		slot.Price = sessionPrice(slot, mentor)
+		if err := tx.Model(&slot).Updates(map[string]interface{}{
+			"is_booked":        true,
+			"user_id":          user.ID,
+			"held_for_user_id": nil,
+			"held_until":       nil,
+			"price":            slot.Price,
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&entry).Updates(map[string]interface{}{"status": users.WaitlistClaimed, "offer_expires_at": nil}).Error
+	})
+	if err != nil {
+		http.Error(w, "Error claiming slot: "+err.Error(), http.StatusConflict)
+		return
+	}
+
+	if err := attachMeetingRoom(&slot); err != nil {
+		log.Printf("Error creating meeting room: %v", err)
+	}
+
+	var mentor users.MentorProfile
+	if err := config.DB.First(&mentor, slot.MentorID).Error; err == nil {
+		config.DB.Create(&users.NotificationMentor{
+			UserID:  mentor.UserID,
+			Message: fmt.Sprintf("Your slot from %s to %s has been booked by %s from the waitlist.", slot.StartTime, slot.EndTime, user.Name),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(slot)
+}
+
+// DeclineWaitlistOfferHandler - отказ от предложения; слот переходит следующему, запись остается в очереди
+func DeclineWaitlistOfferHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user, err := authentication.ValidateToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	entry, ok := findWaitlistEntry(w, r, user.ID)
+	if !ok {
+		return
+	}
+	if entry.Status != users.WaitlistOffered {
+		http.Error(w, "There is no active offer for this entry", http.StatusConflict)
+		return
+	}
+
+	// Сначала передаем слот дальше, затем возвращаем запись в очередь
+	if err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := releaseOffer(tx, &entry, users.WaitlistCancelled); err != nil {
+			return err
+		}
+		return tx.Model(&entry).Updates(map[string]interface{}{"status": users.WaitlistWaiting, "offered_slot_id": nil}).Error
+	}); err != nil {
+		http.Error(w, "Error declining offer", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// CancelBookingHandler - отмена бронирования менти или ментором; слот предлагается листу ожидания
+func CancelBookingHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user, err := authentication.ValidateToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	slotID, ok := parseSlotID(w, r)
+	if !ok {
+		return
+	}
+	session, ok := loadSession(w, slotID, user.ID)
+	if !ok {
+		return
+	}
+	if !session.Slot.StartTime.After(time.Now()) {
+		http.Error(w, "Session has already started", http.StatusConflict)
+		return
+	}
+
+	var input struct {
+		Reason string `json:"reason"`
+	}
+	json.NewDecoder(r.Body).Decode(&input)
+
+	slot := session.Slot
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&users.BookingCancellation{
+			SlotID:        slot.ID,
+			MentorID:      slot.MentorID,
+			UserID:        session.MenteeUserID,
+			CancelledByID: user.ID,
+			Reason:        input.Reason,
+		}).Error; err != nil {
+			return err
+		}
+
+		// Заметки и задачи остаются у отмененного бронирования (слот + менти) и не видны следующему менти
+		slot.IsBooked = false
+		slot.UserID = nil
+		slot.Price = nil
+		slot.MeetingProvider, slot.MeetingRoomID, slot.MeetingURL = "", "", ""
+		if err := tx.Model(&slot).Updates(map[string]interface{}{
+			"is_booked":        false,
+			"user_id":          nil,
+			"price":            nil,
+			"meeting_provider": "",
+			"meeting_room_id":  "",
+			"meeting_url":      "",
+		}).Error; err != nil {
+			return err
+		}
+		if err := returnPackageCredit(tx, slot.ID); err != nil {
+			return err
+		}
+		return offerSlotToWaitlist(tx, &slot)
+	})
+	if err != nil {
+		http.Error(w, "Error cancelling booking", http.StatusInternalServerError)
+		return
+	}
+
+	// Уведомление второй стороны
+	notifyID := session.MentorUserID
+	if user.ID == session.MentorUserID {
+		notifyID = session.MenteeUserID
+	}
+	config.DB.Create(&users.NotificationMentor{
+		UserID:  notifyID,
+		Message: fmt.Sprintf("The session from %s to %s has been cancelled by %s.", slot.StartTime, slot.EndTime, user.Name),
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(slot)
+}
+
+// findWaitlistEntry - запись листа ожидания текущего пользователя по параметру id
+func findWaitlistEntry(w http.ResponseWriter, r *http.Request, userID uint) (users.WaitlistEntry, bool) {
+	var entry users.WaitlistEntry
+
+	entryID, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil || entryID <= 0 {
+		http.Error(w, "Invalid waitlist entry ID", http.StatusBadRequest)
+		return entry, false
+	}
+	if err := config.DB.Where("id = ? AND user_id = ?", entryID, userID).First(&entry).Error; err != nil {
+		http.Error(w, "Waitlist entry not found", http.StatusNotFound)
+		return entry, false
+	}
+	return entry, true
+}
@@ -0,0 +1,438 @@
+package messaging
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"hired-valley-backend/config"
+	"hired-valley-backend/controllers/authentication"
+	"hired-valley-backend/models/chat"
+	"hired-valley-backend/models/users"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultMessagesLimit = 50
+	maxMessagesLimit     = 200
+	maxAttachments       = 10
+)
+
+type conversationSummary struct {
+	chat.Conversation
+	LastMessage *chat.Message `json:"last_message"`
+	UnreadCount int64         `json:"unread_count"`
+}
+
+// ConversationsHandler - GET: список диалогов пользователя, POST: новый диалог
+func ConversationsHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := authentication.ValidateToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		listConversations(w, user)
+	case http.MethodPost:
+		createConversation(w, r, user)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func listConversations(w http.ResponseWriter, user *users.User) {
+	var conversations []chat.Conversation
+	if err := config.DB.Preload("Participants").
+		Joins("JOIN conversation_participants cp ON cp.conversation_id = conversations.id").
+		Where("cp.user_id = ?", user.ID).
+		Order("conversations.last_message_at DESC NULLS LAST, conversations.id DESC").
+		Find(&conversations).Error; err != nil {
+		http.Error(w, "Error fetching conversations", http.StatusInternalServerError)
+		return
+	}
+
+	result := make([]conversationSummary, 0, len(conversations))
+	for _, conversation := range conversations {
+		summary := conversationSummary{Conversation: conversation}
+
+		var last chat.Message
+		if err := config.DB.Preload("Attachments").Where("conversation_id = ?", conversation.ID).
+			Order("id DESC").First(&last).Error; err == nil {
+			summary.LastMessage = &last
+		}
+
+		unread, err := unreadCount(config.DB, conversation, user.ID)
+		if err != nil {
+			http.Error(w, "Error counting unread messages", http.StatusInternalServerError)
+			return
+		}
+		summary.UnreadCount = unread
+		result = append(result, summary)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+func createConversation(w http.ResponseWriter, r *http.Request, user *users.User) {
+	var input struct {
+		ParticipantIDs []uint `json:"participant_ids"`
+		Title          string `json:"title"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Уникальные участники без текущего пользователя
+	seen := map[uint]bool{user.ID: true}
+	var others []uint
+	for _, id := range input.ParticipantIDs {
+		if !seen[id] && id != 0 {
+			seen[id] = true
+			others = append(others, id)
+		}
+	}
+	if len(others) == 0 {
+		http.Error(w, "At least one other participant is required", http.StatusBadRequest)
+		return
+	}
+
+	var found int64
+	if err := config.DB.Model(&users.User{}).Where("id IN ?", others).Count(&found).Error; err != nil || int(found) != len(others) {
+		http.Error(w, "Participant not found", http.StatusBadRequest)
+		return
+	}
+
+	for _, id := range others {
+		if err := canMessage(config.DB, user.ID, id); err != nil {
+			if errors.Is(err, errMessagingNotAllowed) {
+				http.Error(w, err.Error(), http.StatusForbidden)
+			} else {
+				http.Error(w, "Error checking messaging permissions", http.StatusInternalServerError)
+			}
+			return
+		}
+	}
+
+	// Личный диалог между двумя пользователями не дублируется
+	if len(others) == 1 {
+		var existing chat.Conversation
+		err := config.DB.Preload("Participants").
+			Where("is_group = ?", false).
+			Where("id IN (?)", config.DB.Model(&chat.ConversationParticipant{}).Select("conversation_id").Where("user_id = ?", user.ID)).
+			Where("id IN (?)", config.DB.Model(&chat.ConversationParticipant{}).Select("conversation_id").Where("user_id = ?", others[0])).
+			First(&existing).Error
+		if err == nil {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(existing)
+			return
+		}
+	}
+
+	conversation := chat.Conversation{
+		Title:       strings.TrimSpace(input.Title),
+		IsGroup:     len(others) > 1,
+		CreatedByID: user.ID,
+	}
+	conversation.Participants = append(conversation.Participants, chat.ConversationParticipant{UserID: user.ID})
+	for _, id := range others {
+		conversation.Participants = append(conversation.Participants, chat.ConversationParticipant{UserID: id})
+	}
+
+	if err := config.DB.Create(&conversation).Error; err != nil {
+		http.Error(w, "Error creating conversation", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(conversation)
+}
+
+// MessagesHandler - GET: сообщения диалога с пагинацией (before_id, limit), POST: отправка сообщения
+func MessagesHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := authentication.ValidateToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	conversation, ok := loadConversation(w, r, user.ID)
+	if !ok {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		listMessages(w, r, conversation)
+	case http.MethodPost:
+		sendMessage(w, r, user, conversation)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func listMessages(w http.ResponseWriter, r *http.Request, conversation chat.Conversation) {
+	limit := defaultMessagesLimit
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+		if limit > maxMessagesLimit {
+			limit = maxMessagesLimit
+		}
+	}
+
+	query := config.DB.Preload("Attachments").Where("conversation_id = ?", conversation.ID)
+	if value := r.URL.Query().Get("before_id"); value != "" {
+		beforeID, err := strconv.Atoi(value)
+		if err != nil || beforeID <= 0 {
+			http.Error(w, "Invalid before_id", http.StatusBadRequest)
+			return
+		}
+		query = query.Where("id < ?", beforeID)
+	}
+
+	var messages []chat.Message
+	if err := query.Order("id DESC").Limit(limit + 1).Find(&messages).Error; err != nil {
+		http.Error(w, "Error fetching messages", http.StatusInternalServerError)
+		return
+	}
+
+	hasMore := len(messages) > limit
+	if hasMore {
+		messages = messages[:limit]
+	}
+
+	// Квитанции о прочтении: кто из участников дочитал до сообщения
+	type messageView struct {
+		chat.Message
+		ReadBy []uint `json:"read_by"`
+	}
+	result := make([]messageView, 0, len(messages))
+	for _, message := range messages {
+		view := messageView{Message: message, ReadBy: []uint{}}
+		for _, participant := range conversation.Participants {
+			if participant.UserID != message.SenderID && participant.LastReadMessageID >= message.ID {
+				view.ReadBy = append(view.ReadBy, participant.UserID)
+			}
+		}
+		result = append(result, view)
+	}
+
+	var nextBeforeID uint
+	if hasMore && len(messages) > 0 {
+		nextBeforeID = messages[len(messages)-1].ID
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"messages":       result,
+		"has_more":       hasMore,
+		"next_before_id": nextBeforeID,
+	})
+}
+
+func sendMessage(w http.ResponseWriter, r *http.Request, user *users.User, conversation chat.Conversation) {
+	var input struct {
+		Body        string                   `json:"body"`
+		Attachments []chat.MessageAttachment `json:"attachments"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	input.Body = strings.TrimSpace(input.Body)
+	if input.Body == "" && len(input.Attachments) == 0 {
+		http.Error(w, "Message body or attachment is required", http.StatusBadRequest)
+		return
+	}
+	if len(input.Attachments) > maxAttachments {
+		http.Error(w, fmt.Sprintf("No more than %d attachments are allowed", maxAttachments), http.StatusBadRequest)
+		return
+	}
+	for i := range input.Attachments {
+		attachment := &input.Attachments[i]
+		attachment.ID = 0
+		attachment.MessageID = 0
+		parsed, err := url.ParseRequestURI(attachment.URL)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || strings.TrimSpace(attachment.FileName) == "" {
+			http.Error(w, "Each attachment needs a file_name and a valid url", http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Разрешение проверяется при каждой отправке: бронирование могло быть отменено, а в группе
+	// могут оказаться менторы, которым отправитель не может писать напрямую
+	if err := canSend(config.DB, conversation, user.ID); err != nil {
+		if errors.Is(err, errMessagingNotAllowed) {
+			http.Error(w, err.Error(), http.StatusForbidden)
+		} else {
+			http.Error(w, "Error checking messaging permissions", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	message := chat.Message{
+		ConversationID: conversation.ID,
+		SenderID:       user.ID,
+		Body:           input.Body,
+		Attachments:    input.Attachments,
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&message).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&conversation).Update("last_message_at", message.CreatedAt).Error; err != nil {
+			return err
+		}
+		// Собственное сообщение считается прочитанным отправителем
+		return tx.Model(&chat.ConversationParticipant{}).
+			Where("conversation_id = ? AND user_id = ?", conversation.ID, user.ID).
+			Updates(map[string]interface{}{"last_read_message_id": message.ID, "last_read_at": message.CreatedAt}).Error
+	})
+	if err != nil {
+		http.Error(w, "Error sending message", http.StatusInternalServerError)
+		return
+	}
+
+	for _, participant := range conversation.Participants {
+		if participant.UserID != user.ID {
+			config.DB.Create(&users.NotificationMentor{
+				UserID:  participant.UserID,
+				Message: fmt.Sprintf("New message from %s", user.Name),
+			})
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(message)
+}
+
+// MarkReadHandler - отметка диалога прочитанным до message_id (по умолчанию до последнего)
+func MarkReadHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user, err := authentication.ValidateToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	conversation, ok := loadConversation(w, r, user.ID)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		MessageID uint `json:"message_id"`
+	}
+	json.NewDecoder(r.Body).Decode(&input)
+
+	var last chat.Message
+	query := config.DB.Where("conversation_id = ?", conversation.ID)
+	if input.MessageID != 0 {
+		query = query.Where("id <= ?", input.MessageID)
+	}
+	if err := query.Order("id DESC").First(&last).Error; err != nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	// Отметка о прочтении только продвигается вперед
+	now := time.Now()
+	if err := config.DB.Model(&chat.ConversationParticipant{}).
+		Where("conversation_id = ? AND user_id = ? AND last_read_message_id < ?", conversation.ID, user.ID, last.ID).
+		Updates(map[string]interface{}{"last_read_message_id": last.ID, "last_read_at": now}).Error; err != nil {
+		http.Error(w, "Error updating read receipt", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"conversation_id":      conversation.ID,
+		"last_read_message_id": last.ID,
+	})
+}
+
+// UnreadCountHandler - общее количество непрочитанных сообщений пользователя
+func UnreadCountHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user, err := authentication.ValidateToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	var total int64
+	if err := config.DB.Model(&chat.Message{}).
+		Joins("JOIN conversation_participants cp ON cp.conversation_id = messages.conversation_id AND cp.user_id = ?", user.ID).
+		Where("messages.id > cp.last_read_message_id AND messages.sender_id <> ?", user.ID).
+		Count(&total).Error; err != nil {
+		http.Error(w, "Error counting unread messages", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]int64{"unread_count": total})
+}
+
+// loadConversation - загрузка диалога по conversation_id с проверкой участия
+func loadConversation(w http.ResponseWriter, r *http.Request, userID uint) (chat.Conversation, bool) {
+	var conversation chat.Conversation
+
+	conversationID, err := strconv.Atoi(r.URL.Query().Get("conversation_id"))
+	if err != nil || conversationID <= 0 {
+		http.Error(w, "Invalid conversation ID", http.StatusBadRequest)
+		return conversation, false
+	}
+
+	if err := config.DB.Preload("Participants").First(&conversation, conversationID).Error; err != nil {
+		http.Error(w, "Conversation not found", http.StatusNotFound)
+		return conversation, false
+	}
+
+	for _, participant := range conversation.Participants {
+		if participant.UserID == userID {
+			return conversation, true
+		}
+	}
+
+	http.Error(w, "You are not a participant of this conversation", http.StatusForbidden)
+	return conversation, false
+}
+
+func unreadCount(db *gorm.DB, conversation chat.Conversation, userID uint) (int64, error) {
+	var lastRead uint
+	for _, participant := range conversation.Participants {
+		if participant.UserID == userID {
+			lastRead = participant.LastReadMessageID
+		}
+	}
+
+	var count int64
+	err := db.Model(&chat.Message{}).
+		Where("conversation_id = ? AND id > ? AND sender_id <> ?", conversation.ID, lastRead, userID).
+		Count(&count).Error
+	return count, err
+}
@@ -0,0 +1,67 @@
+package messaging
+
+import (
+	"errors"
+	"gorm.io/gorm"
+	"hired-valley-backend/models/chat"
+	"hired-valley-backend/models/users"
+)
+
+var errMessagingNotAllowed = errors.New("you can message this mentor only after booking a session or once they accept your request")
+
+// canMessage - менти может писать ментору только после бронирования или с согласия ментора
+func canMessage(db *gorm.DB, senderID, recipientID uint) error {
+	if senderID == recipientID {
+		return nil
+	}
+
+	var mentor users.MentorProfile
+	if err := db.Where("user_id = ?", recipientID).First(&mentor).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil // Получатель не ментор - ограничений нет
+		}
+		return err
+	}
+
+	// Действующий ментор, который сам пишет менти, или два ментора друг другу
+	var sender users.MentorProfile
+	if err := db.Where("user_id = ? AND status = ?", senderID, users.MentorActive).First(&sender).Error; err == nil {
+		return nil
+	}
+
+	var bookings int64
+	if err := db.Model(&users.Slot{}).
+		Where("mentor_id = ? AND user_id = ? AND is_booked = ?", mentor.ID, senderID, true).
+		Count(&bookings).Error; err != nil {
+		return err
+	}
+	if bookings > 0 {
+		return nil
+	}
+
+	var accepted int64
+	if err := db.Model(&chat.MessageRequest{}).
+		Where("mentee_id = ? AND mentor_user_id = ? AND status = ?", senderID, recipientID, chat.RequestAccepted).
+		Count(&accepted).Error; err != nil {
+		return err
+	}
+	if accepted > 0 {
+		return nil
+	}
+
+	return errMessagingNotAllowed
+}
+
+// canSend - проверка canMessage для каждого получателя сообщения в диалоге, в том числе групповом.
+// Создатель диалога сам начал переписку, поэтому ему могут отвечать без бронирования
+func canSend(db *gorm.DB, conversation chat.Conversation, senderID uint) error {
+	for _, participant := range conversation.Participants {
+		if participant.UserID == senderID || participant.UserID == conversation.CreatedByID {
+			continue
+		}
+		if err := canMessage(db, senderID, participant.UserID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,152 @@
+package messaging
+
+import (
+	"encoding/json"
+	"fmt"
+	"hired-valley-backend/config"
+	"hired-valley-backend/controllers/authentication"
+	"hired-valley-backend/models/chat"
+	"hired-valley-backend/models/users"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MessageRequestsHandler - GET: входящие и исходящие запросы, POST: запрос на переписку с ментором
+func MessageRequestsHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := authentication.ValidateToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		var incoming, outgoing []chat.MessageRequest
+		if err := config.DB.Where("mentor_user_id = ?", user.ID).Order("created_at DESC").Find(&incoming).Error; err != nil {
+			http.Error(w, "Error fetching requests", http.StatusInternalServerError)
+			return
+		}
+		if err := config.DB.Where("mentee_id = ?", user.ID).Order("created_at DESC").Find(&outgoing).Error; err != nil {
+			http.Error(w, "Error fetching requests", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"incoming": incoming,
+			"outgoing": outgoing,
+		})
+
+	case http.MethodPost:
+		var input struct {
+			MentorID uint   `json:"mentor_id"` // ID профиля ментора
+			Note     string `json:"note"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		var mentor users.MentorProfile
+		if err := config.DB.First(&mentor, input.MentorID).Error; err != nil {
+			http.Error(w, "Mentor not found", http.StatusNotFound)
+			return
+		}
+		if mentor.UserID == user.ID {
+			http.Error(w, "You cannot send a request to yourself", http.StatusBadRequest)
+			return
+		}
+
+		var existing chat.MessageRequest
+		if err := config.DB.Where("mentee_id = ? AND mentor_user_id = ? AND status IN ?", user.ID, mentor.UserID,
+			[]string{chat.RequestPending, chat.RequestAccepted}).First(&existing).Error; err == nil {
+			http.Error(w, "Request already exists", http.StatusConflict)
+			return
+		}
+
+		request := chat.MessageRequest{
+			MenteeID:     user.ID,
+			MentorUserID: mentor.UserID,
+			Status:       chat.RequestPending,
+			Note:         strings.TrimSpace(input.Note),
+		}
+		if err := config.DB.Create(&request).Error; err != nil {
+			http.Error(w, "Error creating request", http.StatusInternalServerError)
+			return
+		}
+
+		config.DB.Create(&users.NotificationMentor{
+			UserID:  mentor.UserID,
+			Message: fmt.Sprintf("%s would like to message you.", user.Name),
+		})
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(request)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// RespondMessageRequestHandler - ментор принимает или отклоняет запрос на переписку
+func RespondMessageRequestHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user, err := authentication.ValidateToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	requestID, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil || requestID <= 0 {
+		http.Error(w, "Invalid request ID", http.StatusBadRequest)
+		return
+	}
+
+	var request chat.MessageRequest
+	if err := config.DB.First(&request, requestID).Error; err != nil {
+		http.Error(w, "Request not found", http.StatusNotFound)
+		return
+	}
+	if request.MentorUserID != user.ID {
+		http.Error(w, "Permission denied", http.StatusForbidden)
+		return
+	}
+	if request.Status != chat.RequestPending {
+		http.Error(w, "Request has already been answered", http.StatusConflict)
+		return
+	}
+
+	var input struct {
+		Accept bool `json:"accept"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now()
+	request.Status = chat.RequestDeclined
+	if input.Accept {
+		request.Status = chat.RequestAccepted
+	}
+	request.RespondedAt = &now
+	if err := config.DB.Save(&request).Error; err != nil {
+		http.Error(w, "Error updating request", http.StatusInternalServerError)
+		return
+	}
+
+	config.DB.Create(&users.NotificationMentor{
+		UserID:  request.MenteeID,
+		Message: fmt.Sprintf("%s %s your message request.", user.Name, request.Status),
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(request)
+}
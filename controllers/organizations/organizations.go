@@ -0,0 +1,775 @@
+package organizations
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"hired-valley-backend/config"
+	"hired-valley-backend/controllers/authentication"
+	"hired-valley-backend/models/users"
+	"hired-valley-backend/services"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// organizationInput - данные организации; при обновлении nil-поля не меняются
+type organizationInput struct {
+	Name         *string   `json:"name"`
+	EmailDomains *[]string `json:"email_domains"`
+}
+
+// memberView - участник организации в ответе API
+type memberView struct {
+	UserID   uint      `json:"user_id"`
+	Name     string    `json:"name"`
+	Email    string    `json:"email"`
+	Role     string    `json:"role"`
+	JoinedAt time.Time `json:"joined_at"`
+}
+
+// findOrganization - организация из параметра organization_id; при ошибке ответ уже отправлен
+func findOrganization(w http.ResponseWriter, r *http.Request) (users.Organization, bool) {
+	var organization users.Organization
+	organizationID, err := strconv.Atoi(r.URL.Query().Get("organization_id"))
+	if err != nil || organizationID <= 0 {
+		http.Error(w, "Invalid organization ID", http.StatusBadRequest)
+		return organization, false
+	}
+	if err := config.DB.First(&organization, organizationID).Error; err != nil {
+		http.Error(w, "Organization not found", http.StatusNotFound)
+		return organization, false
+	}
+	return organization, true
+}
+
+// managedOrganization - организация, которой управляет пользователь; при ошибке ответ уже отправлен
+func managedOrganization(w http.ResponseWriter, r *http.Request, user *users.User) (users.Organization, bool) {
+	organization, ok := findOrganization(w, r)
+	if !ok {
+		return organization, false
+	}
+	if !services.CanManageOrganization(user, organization.ID) {
+		http.Error(w, "Only organization admins can do this", http.StatusForbidden)
+		return organization, false
+	}
+	return organization, true
+}
+
+// notifyOrganizationAdmins - уведомление администраторам организации, кроме автора события
+func notifyOrganizationAdmins(organizationID, exceptUserID uint, message string) {
+	var admins []uint
+	config.DB.Model(&users.OrganizationMember{}).
+		Where("organization_id = ? AND role = ?", organizationID, users.OrgRoleAdmin).Pluck("user_id", &admins)
+	for _, userID := range admins {
+		if userID != exceptUserID {
+			config.DB.Create(&users.NotificationMentor{UserID: userID, Message: message})
+		}
+	}
+}
+
+// OrganizationsHandler - GET: организации пользователя и организации, в которые он может вступить
+// по подтвержденному домену email; POST: создание организации, создатель становится ее администратором
+func OrganizationsHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := authentication.ValidateToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		type membership struct {
+			users.Organization
+			Role string `json:"role"`
+		}
+		var members []users.OrganizationMember
+		if err := config.DB.Where("user_id = ?", user.ID).Order("created_at").Find(&members).Error; err != nil {
+			http.Error(w, "Failed to list organizations", http.StatusInternalServerError)
+			return
+		}
+		mine := []membership{}
+		joined := []uint{}
+		for _, member := range members {
+			var organization users.Organization
+			if err := config.DB.First(&organization, member.OrganizationID).Error; err == nil {
+				mine = append(mine, membership{organization, member.Role})
+				joined = append(joined, organization.ID)
+			}
+		}
+
+		joinable := []users.Organization{}
+		if domain := services.JoinableDomain(user); domain != "" {
+			query := config.DB.Where("id IN (?)", config.DB.Model(&users.OrganizationDomain{}).Select("organization_id").
+				Where("domain = ? AND verified_at IS NOT NULL", domain))
+			if len(joined) > 0 {
+				query = query.Where("id NOT IN ?", joined)
+			}
+			query.Order("name").Find(&joinable)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"organizations": mine,
+			"joinable":      joinable,
+		})
+
+	case http.MethodPost:
+		var input organizationInput
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			http.Error(w, "Invalid input", http.StatusBadRequest)
+			return
+		}
+		if input.Name == nil || strings.TrimSpace(*input.Name) == "" {
+			http.Error(w, "Organization name is required", http.StatusBadRequest)
+			return
+		}
+		organization := users.Organization{Name: strings.TrimSpace(*input.Name), CreatedByID: user.ID}
+		if input.EmailDomains != nil {
+			domains, err := services.NormalizeEmailDomains(*input.EmailDomains)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			organization.EmailDomains = domains
+		}
+
+		err := config.DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Create(&organization).Error; err != nil {
+				return err
+			}
+			if err := services.SyncOrganizationDomains(tx, organization.ID, organization.EmailDomains); err != nil {
+				return err
+			}
+			return tx.Create(&users.OrganizationMember{
+				OrganizationID: organization.ID,
+				UserID:         user.ID,
+				Role:           users.OrgRoleAdmin,
+			}).Error
+		})
+		if err != nil {
+			http.Error(w, "Failed to create organization", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(organization)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// OrganizationHandler - организация (?organization_id=). GET: данные и участники (для участников),
+// PUT: название и домены email (для администраторов)
+func OrganizationHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := authentication.ValidateToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	organization, ok := findOrganization(w, r)
+	if !ok {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		role := services.OrganizationRole(user.ID, organization.ID)
+		if role == "" && user.Role != "admin" {
+			http.Error(w, "Permission denied", http.StatusForbidden)
+			return
+		}
+
+		var members []users.OrganizationMember
+		if err := config.DB.Where("organization_id = ?", organization.ID).Order("created_at").Find(&members).Error; err != nil {
+			http.Error(w, "Failed to load organization", http.StatusInternalServerError)
+			return
+		}
+		views := []memberView{}
+		for _, member := range members {
+			var account users.User
+			config.DB.First(&account, member.UserID)
+			views = append(views, memberView{member.UserID, account.Name, account.Email, member.Role, member.CreatedAt})
+		}
+
+		response := map[string]interface{}{
+			"organization": organization,
+			"members":      views,
+			"my_role":      role,
+		}
+		if services.CanManageOrganization(user, organization.ID) {
+			invitations := []users.OrganizationInvitation{}
+			config.DB.Where("organization_id = ? AND status = ? AND expires_at > ?", organization.ID, users.OrgInvitationPending, time.Now()).
+				Order("created_at DESC").Find(&invitations)
+			response["invitations"] = invitations
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+
+	case http.MethodPut:
+		if !services.CanManageOrganization(user, organization.ID) {
+			http.Error(w, "Only organization admins can do this", http.StatusForbidden)
+			return
+		}
+		var input organizationInput
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			http.Error(w, "Invalid input", http.StatusBadRequest)
+			return
+		}
+		if input.Name != nil {
+			if strings.TrimSpace(*input.Name) == "" {
+				http.Error(w, "Organization name is required", http.StatusBadRequest)
+				return
+			}
+			organization.Name = strings.TrimSpace(*input.Name)
+		}
+		if input.EmailDomains != nil {
+			domains, err := services.NormalizeEmailDomains(*input.EmailDomains)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			organization.EmailDomains = domains
+		}
+		err := config.DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Save(&organization).Error; err != nil {
+				return err
+			}
+			return services.SyncOrganizationDomains(tx, organization.ID, organization.EmailDomains)
+		})
+		if err != nil {
+			http.Error(w, "Failed to update organization", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(organization)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// JoinOrganizationHandler - POST: вступление в организацию по домену email (?organization_id=);
+// нужны подтвержденный email пользователя и подтвержденный организацией домен
+func JoinOrganizationHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user, err := authentication.ValidateToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	organization, ok := findOrganization(w, r)
+	if !ok {
+		return
+	}
+	if services.OrganizationRole(user.ID, organization.ID) != "" {
+		http.Error(w, "You are already a member of this organization", http.StatusConflict)
+		return
+	}
+	if user.EmailVerifiedAt == nil {
+		http.Error(w, "Verify your email address before joining an organization", http.StatusForbidden)
+		return
+	}
+	if !services.CanJoinByDomain(organization.ID, user) {
+		http.Error(w, "Your email domain is not a verified domain of this organization", http.StatusForbidden)
+		return
+	}
+
+	member := users.OrganizationMember{OrganizationID: organization.ID, UserID: user.ID, Role: users.OrgRoleMember}
+	if err := config.DB.Create(&member).Error; err != nil {
+		http.Error(w, "Failed to join organization", http.StatusInternalServerError)
+		return
+	}
+	notifyOrganizationAdmins(organization.ID, user.ID, fmt.Sprintf("%s joined %s", user.Name, organization.Name))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(member)
+}
+
+// OrganizationDomainsHandler - домены организации (?organization_id=, для администраторов).
+// GET: домены с TXT-записями для подтверждения, POST: проверка TXT-записи домена (?domain=)
+func OrganizationDomainsHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := authentication.ValidateToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	organization, ok := managedOrganization(w, r, user)
+	if !ok {
+		return
+	}
+
+	type domainView struct {
+		users.OrganizationDomain
+		TXTRecord string `json:"txt_record"`
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		// Домены, заявленные до появления подтверждения, получают токены при первом просмотре
+		if err := services.SyncOrganizationDomains(config.DB, organization.ID, organization.EmailDomains); err != nil {
+			http.Error(w, "Failed to list domains", http.StatusInternalServerError)
+			return
+		}
+		var domains []users.OrganizationDomain
+		if err := config.DB.Where("organization_id = ?", organization.ID).Order("domain").Find(&domains).Error; err != nil {
+			http.Error(w, "Failed to list domains", http.StatusInternalServerError)
+			return
+		}
+		views := []domainView{}
+		for i := range domains {
+			views = append(views, domainView{domains[i], services.DomainVerificationRecord(&domains[i])})
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(views)
+
+	case http.MethodPost:
+		name := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("domain")))
+		var domain users.OrganizationDomain
+		if err := config.DB.Where("organization_id = ? AND domain = ?", organization.ID, name).First(&domain).Error; err != nil {
+			http.Error(w, "Domain not found", http.StatusNotFound)
+			return
+		}
+		err := services.VerifyOrganizationDomain(&domain)
+		if errors.Is(err, services.ErrDomainClaimed) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		if errors.Is(err, services.ErrDomainNotVerified) {
+			http.Error(w, fmt.Sprintf("Add the TXT record %q to %s and try again", services.DomainVerificationRecord(&domain), domain.Domain),
+				http.StatusUnprocessableEntity)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Failed to verify domain", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(domainView{domain, services.DomainVerificationRecord(&domain)})
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// OrganizationMemberHandler - участник организации (?organization_id=&user_id=).
+// PUT: смена роли администратором. DELETE: исключение администратором или выход участника;
+// выданные места и неиспользованные кредиты возвращаются организации
+func OrganizationMemberHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := authentication.ValidateToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	organization, ok := findOrganization(w, r)
+	if !ok {
+		return
+	}
+	memberID, err := strconv.Atoi(r.URL.Query().Get("user_id"))
+	if err != nil || memberID <= 0 {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	var member users.OrganizationMember
+	if err := config.DB.Where("organization_id = ? AND user_id = ?", organization.ID, memberID).First(&member).Error; err != nil {
+		http.Error(w, "Member not found", http.StatusNotFound)
+		return
+	}
+	manager := services.CanManageOrganization(user, organization.ID)
+
+	// lastAdmin - организация не должна остаться без администратора
+	lastAdmin := func() bool {
+		if member.Role != users.OrgRoleAdmin {
+			return false
+		}
+		var admins int64
+		config.DB.Model(&users.OrganizationMember{}).
+			Where("organization_id = ? AND role = ?", organization.ID, users.OrgRoleAdmin).Count(&admins)
+		return admins <= 1
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		if !manager {
+			http.Error(w, "Only organization admins can do this", http.StatusForbidden)
+			return
+		}
+		var input struct {
+			Role string `json:"role"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			http.Error(w, "Invalid input", http.StatusBadRequest)
+			return
+		}
+		if input.Role != users.OrgRoleAdmin && input.Role != users.OrgRoleMember {
+			http.Error(w, fmt.Sprintf("role must be %q or %q", users.OrgRoleAdmin, users.OrgRoleMember), http.StatusBadRequest)
+			return
+		}
+		if input.Role == users.OrgRoleMember && lastAdmin() {
+			http.Error(w, "Organization must have at least one admin", http.StatusConflict)
+			return
+		}
+		if err := config.DB.Model(&member).Update("role", input.Role).Error; err != nil {
+			http.Error(w, "Failed to update member", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(member)
+
+	case http.MethodDelete:
+		if !manager && user.ID != member.UserID {
+			http.Error(w, "Permission denied", http.StatusForbidden)
+			return
+		}
+		if lastAdmin() {
+			http.Error(w, "Organization must have at least one admin", http.StatusConflict)
+			return
+		}
+
+		err := config.DB.Transaction(func(tx *gorm.DB) error {
+			var assignments []users.OrganizationSeatAssignment
+			if err := tx.Where("organization_id = ? AND user_id = ?", organization.ID, member.UserID).Find(&assignments).Error; err != nil {
+				return err
+			}
+			for i := range assignments {
+				if err := services.ReleaseCourseSeat(tx, &assignments[i]); err != nil {
+					return err
+				}
+			}
+
+			var purchases []users.PackagePurchase
+			if err := tx.Where("user_id = ? AND status = ? AND credit_pool_id IN (?)", member.UserID, users.PurchaseActive,
+				tx.Model(&users.OrganizationCreditPool{}).Select("id").Where("organization_id = ?", organization.ID)).
+				Find(&purchases).Error; err != nil {
+				return err
+			}
+			for i := range purchases {
+				if _, err := services.ReclaimOrganizationCredits(tx, &purchases[i]); err != nil {
+					return err
+				}
+			}
+			return tx.Delete(&member).Error
+		})
+		if err != nil {
+			http.Error(w, "Failed to remove member", http.StatusInternalServerError)
+			return
+		}
+
+		if user.ID != member.UserID {
+			config.DB.Create(&users.NotificationMentor{
+				UserID:  member.UserID,
+				Message: fmt.Sprintf("You were removed from %s", organization.Name),
+			})
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// OrganizationInvitationsHandler - POST: приглашение сотрудника по email (?organization_id=),
+// DELETE: отзыв приглашения (?id=). Доступно администраторам организации
+func OrganizationInvitationsHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := authentication.ValidateToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPost:
+		organization, ok := managedOrganization(w, r, user)
+		if !ok {
+			return
+		}
+		var input struct {
+			Email string `json:"email"`
+			Role  string `json:"role"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			http.Error(w, "Invalid input", http.StatusBadRequest)
+			return
+		}
+		email := strings.ToLower(strings.TrimSpace(input.Email))
+		if services.EmailDomain(email) == "" {
+			http.Error(w, "Invalid email", http.StatusBadRequest)
+			return
+		}
+		if input.Role == "" {
+			input.Role = users.OrgRoleMember
+		}
+		if input.Role != users.OrgRoleAdmin && input.Role != users.OrgRoleMember {
+			http.Error(w, fmt.Sprintf("role must be %q or %q", users.OrgRoleAdmin, users.OrgRoleMember), http.StatusBadRequest)
+			return
+		}
+
+		var invitee users.User
+		inviteeFound := config.DB.Where("LOWER(email) = ?", email).First(&invitee).Error == nil
+		if inviteeFound && services.OrganizationRole(invitee.ID, organization.ID) != "" {
+			http.Error(w, "User is already a member of this organization", http.StatusConflict)
+			return
+		}
+
+		token, err := services.NewInvitationToken()
+		if err != nil {
+			http.Error(w, "Failed to create invitation", http.StatusInternalServerError)
+			return
+		}
+
+		// Повторное приглашение на тот же email обновляет ожидающее и отправляет новую ссылку
+		var invitation users.OrganizationInvitation
+		err = config.DB.Where("organization_id = ? AND email = ? AND status = ?", organization.ID, email, users.OrgInvitationPending).
+			First(&invitation).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, "Failed to create invitation", http.StatusInternalServerError)
+			return
+		}
+		invitation.OrganizationID = organization.ID
+		invitation.Email = email
+		invitation.Role = input.Role
+		invitation.Token = token
+		invitation.InvitedByID = user.ID
+		invitation.Status = users.OrgInvitationPending
+		invitation.ExpiresAt = time.Now().Add(services.OrganizationInvitationTTL)
+		if err := config.DB.Save(&invitation).Error; err != nil {
+			http.Error(w, "Failed to create invitation", http.StatusInternalServerError)
+			return
+		}
+
+		emailSent := services.SendOrganizationInvitation(invitation, organization, *user) == nil
+		if inviteeFound {
+			config.DB.Create(&users.NotificationMentor{
+				UserID:  invitee.ID,
+				Message: fmt.Sprintf("%s invited you to join %s", user.Name, organization.Name),
+			})
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"invitation": invitation,
+			"email_sent": emailSent,
+		})
+
+	case http.MethodDelete:
+		invitationID, err := strconv.Atoi(r.URL.Query().Get("id"))
+		if err != nil || invitationID <= 0 {
+			http.Error(w, "Invalid invitation ID", http.StatusBadRequest)
+			return
+		}
+		var invitation users.OrganizationInvitation
+		if err := config.DB.First(&invitation, invitationID).Error; err != nil {
+			http.Error(w, "Invitation not found", http.StatusNotFound)
+			return
+		}
+		if !services.CanManageOrganization(user, invitation.OrganizationID) {
+			http.Error(w, "Permission denied", http.StatusForbidden)
+			return
+		}
+		if invitation.Status != users.OrgInvitationPending {
+			http.Error(w, "Invitation is no longer pending", http.StatusConflict)
+			return
+		}
+
+		now := time.Now()
+		if err := config.DB.Model(&invitation).Updates(map[string]interface{}{
+			"status":       users.OrgInvitationRevoked,
+			"responded_at": now,
+		}).Error; err != nil {
+			http.Error(w, "Failed to revoke invitation", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// MyOrganizationInvitationsHandler - GET: ожидающие приглашения на email текущего пользователя
+func MyOrganizationInvitationsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user, err := authentication.ValidateToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	var invitations []users.OrganizationInvitation
+	if err := config.DB.Where("email = ? AND status = ? AND expires_at > ?", strings.ToLower(user.Email), users.OrgInvitationPending, time.Now()).
+		Order("created_at DESC").Find(&invitations).Error; err != nil {
+		http.Error(w, "Failed to list invitations", http.StatusInternalServerError)
+		return
+	}
+
+	type invitationView struct {
+		users.OrganizationInvitation
+		OrganizationName string `json:"organization_name"`
+		InvitedBy        string `json:"invited_by"`
+	}
+	result := []invitationView{}
+	for _, invitation := range invitations {
+		var organization users.Organization
+		var inviter users.User
+		config.DB.First(&organization, invitation.OrganizationID)
+		config.DB.First(&inviter, invitation.InvitedByID)
+		result = append(result, invitationView{invitation, organization.Name, inviter.Name})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// pendingInvitation - ожидающее приглашение по token из письма или id из списка приглашений.
+// Принять его может только владелец email, на который оно отправлено. При ошибке ответ уже отправлен
+func pendingInvitation(w http.ResponseWriter, r *http.Request, user *users.User) (users.OrganizationInvitation, bool) {
+	var invitation users.OrganizationInvitation
+	var input struct {
+		Token string `json:"token"`
+		ID    uint   `json:"id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil && r.URL.Query().Get("token") == "" {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return invitation, false
+	}
+	if input.Token == "" {
+		input.Token = r.URL.Query().Get("token")
+	}
+
+	query := config.DB.Model(&users.OrganizationInvitation{})
+	switch {
+	case input.Token != "":
+		query = query.Where("token = ?", input.Token)
+	case input.ID != 0:
+		query = query.Where("id = ?", input.ID)
+	default:
+		http.Error(w, "Invitation token is required", http.StatusBadRequest)
+		return invitation, false
+	}
+	if err := query.First(&invitation).Error; err != nil {
+		http.Error(w, "Invitation not found", http.StatusNotFound)
+		return invitation, false
+	}
+
+	if !strings.EqualFold(invitation.Email, user.Email) {
+		http.Error(w, "This invitation was sent to a different email", http.StatusForbidden)
+		return invitation, false
+	}
+	if invitation.Status != users.OrgInvitationPending {
+		http.Error(w, "Invitation is no longer pending", http.StatusConflict)
+		return invitation, false
+	}
+	if time.Now().After(invitation.ExpiresAt) {
+		http.Error(w, "Invitation has expired", http.StatusGone)
+		return invitation, false
+	}
+	return invitation, true
+}
+
+// AcceptOrganizationInvitationHandler - POST: принятие приглашения, пользователь становится участником
+func AcceptOrganizationInvitationHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user, err := authentication.ValidateToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	invitation, ok := pendingInvitation(w, r, user)
+	if !ok {
+		return
+	}
+	var organization users.Organization
+	if err := config.DB.First(&organization, invitation.OrganizationID).Error; err != nil {
+		http.Error(w, "Organization not found", http.StatusNotFound)
+		return
+	}
+
+	var member users.OrganizationMember
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("organization_id = ? AND user_id = ?", organization.ID, user.ID).First(&member).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		member.OrganizationID = organization.ID
+		member.UserID = user.ID
+		member.Role = invitation.Role
+		if err := tx.Save(&member).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		return tx.Model(&invitation).Updates(map[string]interface{}{
+			"status":       users.OrgInvitationAccepted,
+			"responded_at": now,
+		}).Error
+	})
+	if err != nil {
+		http.Error(w, "Failed to accept invitation", http.StatusInternalServerError)
+		return
+	}
+
+	config.DB.Create(&users.NotificationMentor{
+		UserID:  invitation.InvitedByID,
+		Message: fmt.Sprintf("%s joined %s", user.Name, organization.Name),
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(member)
+}
+
+// DeclineOrganizationInvitationHandler - POST: отказ от приглашения в организацию
+func DeclineOrganizationInvitationHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user, err := authentication.ValidateToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	invitation, ok := pendingInvitation(w, r, user)
+	if !ok {
+		return
+	}
+	now := time.Now()
+	if err := config.DB.Model(&invitation).Updates(map[string]interface{}{
+		"status":       users.OrgInvitationDeclined,
+		"responded_at": now,
+	}).Error; err != nil {
+		http.Error(w, "Failed to decline invitation", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,639 @@
+package organizations
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"hired-valley-backend/config"
+	"hired-valley-backend/controllers/authentication"
+	"hired-valley-backend/models/billing"
+	"hired-valley-backend/models/courses"
+	"hired-valley-backend/models/users"
+	"hired-valley-backend/services"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// publishedCourse - опубликованная версия курса по любому ID его версии; при ошибке ответ уже отправлен
+func publishedCourse(w http.ResponseWriter, courseID uint) (*courses.Course, bool) {
+	var course courses.Course
+	if courseID == 0 || config.DB.First(&course, courseID).Error != nil {
+		http.Error(w, "Course not found", http.StatusNotFound)
+		return nil, false
+	}
+	published, err := services.PublishedCourseVersion(services.CourseOriginID(&course))
+	if errors.Is(err, services.ErrCourseNotFound) {
+		http.Error(w, "Course is not open for enrollment", http.StatusConflict)
+		return nil, false
+	}
+	if err != nil {
+		http.Error(w, "Failed to load course", http.StatusInternalServerError)
+		return nil, false
+	}
+	return published, true
+}
+
+// paymentError - ответ на ошибку проверки платежа; false, если ошибки нет
+func paymentError(w http.ResponseWriter, err error) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, services.ErrPaymentsUnavailable):
+		http.Error(w, "Paid purchases are unavailable: "+err.Error(), http.StatusServiceUnavailable)
+	case errors.Is(err, services.ErrPaymentNotVerified):
+		http.Error(w, "Payment is not completed or does not match this purchase", http.StatusPaymentRequired)
+	case errors.Is(err, services.ErrPaymentAlreadyUsed):
+		http.Error(w, "Payment has already been used", http.StatusConflict)
+	default:
+		log.Printf("Ошибка проверки платежа: %v", err)
+		http.Error(w, "Failed to verify payment", http.StatusBadGateway)
+	}
+	return true
+}
+
+// requirePayment - проверка оплаты покупки организации. Без paymentReference создается платеж и
+// возвращается 402 с его данными; при ошибке или созданном платеже ответ уже отправлен
+func requirePayment(w http.ResponseWriter, r *http.Request, order services.PaymentOrder, paymentReference string,
+	details map[string]interface{}) (services.PaymentProvider, *services.PaymentReceipt, bool) {
+	provider := services.GetPaymentProvider()
+	if provider == nil {
+		paymentError(w, services.ErrPaymentsUnavailable)
+		return nil, nil, false
+	}
+	if paymentReference == "" {
+		intent, err := provider.CreatePayment(r.Context(), order)
+		if err != nil {
+			log.Printf("Ошибка создания платежа организации %d: %v", order.ItemID, err)
+			http.Error(w, "Failed to create payment", http.StatusBadGateway)
+			return nil, nil, false
+		}
+		details["payment"] = intent
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusPaymentRequired)
+		json.NewEncoder(w).Encode(details)
+		return nil, nil, false
+	}
+	receipt, err := services.VerifyPayment(r.Context(), provider, paymentReference, order)
+	if paymentError(w, err) {
+		return nil, nil, false
+	}
+	return provider, receipt, true
+}
+
+// CourseSeatsHandler - места организации на курсах (?organization_id=). GET: места и история покупок,
+// POST: покупка мест {course_id, seats, currency, coupon, payment_reference}; цена рассчитывается как
+// в /courses/quote с учетом скидки за количество мест. Платные места выдаются только после оплаты:
+// запрос без payment_reference создает платеж и возвращает 402
+func CourseSeatsHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := authentication.ValidateToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	organization, ok := managedOrganization(w, r, user)
+	if !ok {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		type seatsView struct {
+			users.OrganizationCourseSeats
+			CourseTitle string `json:"course_title"`
+		}
+		var seats []users.OrganizationCourseSeats
+		if err := config.DB.Where("organization_id = ?", organization.ID).Order("created_at").Find(&seats).Error; err != nil {
+			http.Error(w, "Failed to list seats", http.StatusInternalServerError)
+			return
+		}
+		views := []seatsView{}
+		for _, item := range seats {
+			var course courses.Course
+			config.DB.Unscoped().First(&course, item.CourseID)
+			views = append(views, seatsView{item, course.Title})
+		}
+		purchases := []users.OrganizationSeatPurchase{}
+		config.DB.Where("organization_id = ?", organization.ID).Order("created_at DESC").Find(&purchases)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"seats":     views,
+			"purchases": purchases,
+		})
+
+	case http.MethodPost:
+		var input struct {
+			CourseID         uint   `json:"course_id"`
+			Seats            int    `json:"seats"`
+			Currency         string `json:"currency"`
+			Coupon           string `json:"coupon"`
+			PaymentReference string `json:"payment_reference"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			http.Error(w, "Invalid input", http.StatusBadRequest)
+			return
+		}
+		if input.Seats < 1 || input.Seats > services.MaxSeatsPerPurchase {
+			http.Error(w, fmt.Sprintf("seats must be between 1 and %d", services.MaxSeatsPerPurchase), http.StatusBadRequest)
+			return
+		}
+		currency := ""
+		if input.Currency != "" {
+			if currency = services.NormalizeCurrency(input.Currency); currency == "" {
+				http.Error(w, "Invalid currency", http.StatusBadRequest)
+				return
+			}
+		}
+		course, ok := publishedCourse(w, input.CourseID)
+		if !ok {
+			return
+		}
+
+		quote, err := services.QuoteCoursePrice(course, user.ID, currency, input.Seats, input.Coupon)
+		if errors.Is(err, services.ErrCurrencyUnavailable) {
+			http.Error(w, "Course is not sold in this currency", http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Failed to calculate price", http.StatusInternalServerError)
+			return
+		}
+		if quote.CouponError != "" {
+			http.Error(w, "Coupon cannot be applied: "+quote.CouponError, http.StatusBadRequest)
+			return
+		}
+
+		order := services.PaymentOrder{
+			Purpose:     billing.PurposeOrganizationSeats,
+			ItemID:      organization.ID,
+			UserID:      user.ID,
+			Amount:      quote.Total,
+			Currency:    quote.Currency,
+			Description: fmt.Sprintf("%d seats in \"%s\" for %s", input.Seats, course.Title, organization.Name),
+		}
+		var provider services.PaymentProvider
+		var receipt *services.PaymentReceipt
+		if quote.Total > 0 {
+			if provider, receipt, ok = requirePayment(w, r, order, input.PaymentReference,
+				map[string]interface{}{"quote": quote}); !ok {
+				return
+			}
+		}
+
+		originID := services.CourseOriginID(course)
+		purchase := users.OrganizationSeatPurchase{
+			OrganizationID: organization.ID,
+			CourseID:       originID,
+			PurchasedByID:  user.ID,
+			Seats:          input.Seats,
+			Currency:       quote.Currency,
+			AmountPaid:     quote.Total,
+			CouponID:       quote.CouponID,
+		}
+		var seats users.OrganizationCourseSeats
+		err = config.DB.Transaction(func(tx *gorm.DB) error {
+			if receipt != nil {
+				if _, err := services.RecordPayment(tx, provider, receipt, order); err != nil {
+					return err
+				}
+			}
+			if err := services.RedeemCoupon(tx, quote, user.ID); err != nil {
+				return err
+			}
+			if err := tx.Create(&purchase).Error; err != nil {
+				return err
+			}
+			err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+				Where("organization_id = ? AND course_id = ?", organization.ID, originID).First(&seats).Error
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+			seats.OrganizationID = organization.ID
+			seats.CourseID = originID
+			seats.SeatsTotal += input.Seats
+			return tx.Save(&seats).Error
+		})
+		if errors.Is(err, services.ErrCouponExhausted) {
+			http.Error(w, "Coupon cannot be applied: "+err.Error(), http.StatusConflict)
+			return
+		}
+		if errors.Is(err, services.ErrPaymentAlreadyUsed) {
+			paymentError(w, err)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Failed to buy seats", http.StatusInternalServerError)
+			return
+		}
+
+		config.DB.Create(&users.NotificationMentor{
+			UserID:  course.InstructorID,
+			Message: fmt.Sprintf("%s bought %d seats in your course \"%s\".", organization.Name, input.Seats, course.Title),
+		})
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"purchase": purchase,
+			"seats":    seats,
+			"quote":    quote,
+		})
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// SeatAssignmentsHandler - выданные места (?organization_id=). GET: список (?course_id= - по курсу),
+// POST: выдача мест участникам {course_id, user_ids}, DELETE: отзыв места (?id=)
+func SeatAssignmentsHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := authentication.ValidateToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	organization, ok := managedOrganization(w, r, user)
+	if !ok {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		query := config.DB.Where("organization_id = ?", organization.ID)
+		if value := r.URL.Query().Get("course_id"); value != "" {
+			courseID, err := strconv.Atoi(value)
+			if err != nil || courseID <= 0 {
+				http.Error(w, "Invalid course ID", http.StatusBadRequest)
+				return
+			}
+			var course courses.Course
+			if err := config.DB.Unscoped().First(&course, courseID).Error; err != nil {
+				http.Error(w, "Course not found", http.StatusNotFound)
+				return
+			}
+			query = query.Where("course_id = ?", services.CourseOriginID(&course))
+		}
+		assignments := []users.OrganizationSeatAssignment{}
+		if err := query.Order("created_at DESC").Find(&assignments).Error; err != nil {
+			http.Error(w, "Failed to list assignments", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(assignments)
+
+	case http.MethodPost:
+		var input struct {
+			CourseID uint   `json:"course_id"`
+			UserIDs  []uint `json:"user_ids"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			http.Error(w, "Invalid input", http.StatusBadRequest)
+			return
+		}
+		if len(input.UserIDs) == 0 {
+			http.Error(w, "user_ids is required", http.StatusBadRequest)
+			return
+		}
+		course, ok := publishedCourse(w, input.CourseID)
+		if !ok {
+			return
+		}
+
+		// Каждое место выдается в своей транзакции: ошибка по одному участнику не отменяет остальные
+		type assignResult struct {
+			UserID     uint                              `json:"user_id"`
+			Assignment *users.OrganizationSeatAssignment `json:"assignment,omitempty"`
+			Error      string                            `json:"error,omitempty"`
+		}
+		results := []assignResult{}
+		for _, memberID := range input.UserIDs {
+			var assignment *users.OrganizationSeatAssignment
+			err := config.DB.Transaction(func(tx *gorm.DB) error {
+				var err error
+				assignment, err = services.AssignCourseSeat(tx, organization.ID, course, memberID, user.ID)
+				return err
+			})
+			switch {
+			case err == nil:
+				results = append(results, assignResult{UserID: memberID, Assignment: assignment})
+				config.DB.Create(&users.NotificationMentor{
+					UserID:  memberID,
+					Message: fmt.Sprintf("%s gave you access to the course \"%s\"", organization.Name, course.Title),
+				})
+			case errors.Is(err, services.ErrNotOrgMember), errors.Is(err, services.ErrAlreadyEnrolled),
+				errors.Is(err, services.ErrNoSeatsAvailable):
+				results = append(results, assignResult{UserID: memberID, Error: err.Error()})
+			default:
+				http.Error(w, "Failed to assign seats", http.StatusInternalServerError)
+				return
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(results)
+
+	case http.MethodDelete:
+		assignmentID, err := strconv.Atoi(r.URL.Query().Get("id"))
+		if err != nil || assignmentID <= 0 {
+			http.Error(w, "Invalid assignment ID", http.StatusBadRequest)
+			return
+		}
+		var assignment users.OrganizationSeatAssignment
+		if err := config.DB.Where("id = ? AND organization_id = ?", assignmentID, organization.ID).First(&assignment).Error; err != nil {
+			http.Error(w, "Assignment not found", http.StatusNotFound)
+			return
+		}
+		if err := config.DB.Transaction(func(tx *gorm.DB) error {
+			return services.ReleaseCourseSeat(tx, &assignment)
+		}); err != nil {
+			http.Error(w, "Failed to revoke seat", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// CreditPoolsHandler - кредиты на сессии менторов (?organization_id=). GET: пулы кредитов,
+// POST: покупка пакета ментора для организации {package_id, quantity, payment_reference};
+// кредиты начисляются только после оплаты, запрос без payment_reference создает платеж и возвращает 402
+func CreditPoolsHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := authentication.ValidateToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	organization, ok := managedOrganization(w, r, user)
+	if !ok {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		pools := []users.OrganizationCreditPool{}
+		if err := config.DB.Preload("Package").Where("organization_id = ?", organization.ID).
+			Order("created_at DESC").Find(&pools).Error; err != nil {
+			http.Error(w, "Failed to list credits", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(pools)
+
+	case http.MethodPost:
+		var input struct {
+			PackageID        uint   `json:"package_id"`
+			Quantity         int    `json:"quantity"`
+			PaymentReference string `json:"payment_reference"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			http.Error(w, "Invalid input", http.StatusBadRequest)
+			return
+		}
+		if input.Quantity == 0 {
+			input.Quantity = 1
+		}
+		if input.Quantity < 1 || input.Quantity > services.MaxSeatsPerPurchase {
+			http.Error(w, fmt.Sprintf("quantity must be between 1 and %d", services.MaxSeatsPerPurchase), http.StatusBadRequest)
+			return
+		}
+
+		var pkg users.MentorPackage
+		if err := config.DB.Where("id = ? AND is_active = ?", input.PackageID, true).First(&pkg).Error; err != nil {
+			http.Error(w, "Package not found", http.StatusNotFound)
+			return
+		}
+		var mentor users.MentorProfile
+		if err := config.DB.First(&mentor, pkg.MentorID).Error; err != nil || mentor.Status != users.MentorActive {
+			http.Error(w, "Mentor is not accepting bookings", http.StatusConflict)
+			return
+		}
+
+		currency := services.DefaultCurrency()
+		order := services.PaymentOrder{
+			Purpose:     billing.PurposeOrganizationCredit,
+			ItemID:      organization.ID,
+			UserID:      user.ID,
+			Amount:      services.ToMinorUnits(pkg.Price*float64(input.Quantity), currency),
+			Currency:    currency,
+			Description: fmt.Sprintf("\"%s\" × %d for %s", pkg.Title, input.Quantity, organization.Name),
+		}
+		var provider services.PaymentProvider
+		var receipt *services.PaymentReceipt
+		if order.Amount > 0 {
+			if provider, receipt, ok = requirePayment(w, r, order, input.PaymentReference,
+				map[string]interface{}{"amount": order.Amount, "currency": currency}); !ok {
+				return
+			}
+		}
+
+		pool := users.OrganizationCreditPool{
+			OrganizationID: organization.ID,
+			PackageID:      pkg.ID,
+			MentorID:       pkg.MentorID,
+			PurchasedByID:  user.ID,
+			CreditsTotal:   pkg.SessionsCount * input.Quantity,
+			PricePaid:      pkg.Price * float64(input.Quantity),
+			ExpiresAt:      time.Now().AddDate(0, 0, pkg.ValidityDays),
+		}
+		err := config.DB.Transaction(func(tx *gorm.DB) error {
+			if receipt != nil {
+				if _, err := services.RecordPayment(tx, provider, receipt, order); err != nil {
+					return err
+				}
+			}
+			return tx.Create(&pool).Error
+		})
+		if errors.Is(err, services.ErrPaymentAlreadyUsed) {
+			paymentError(w, err)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Error buying package", http.StatusInternalServerError)
+			return
+		}
+		pool.Package = pkg
+
+		config.DB.Create(&users.NotificationMentor{
+			UserID:  mentor.UserID,
+			Message: fmt.Sprintf("%s bought your package \"%s\" × %d for its team.", organization.Name, pkg.Title, input.Quantity),
+		})
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(pool)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// CreditAllocationsHandler - кредиты участников (?organization_id=). GET: выданные кредиты,
+// POST: выдача кредитов из пула {pool_id, user_id, credits}, DELETE: возврат неиспользованных
+// кредитов участника в пул (?purchase_id=)
+func CreditAllocationsHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := authentication.ValidateToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	organization, ok := managedOrganization(w, r, user)
+	if !ok {
+		return
+	}
+	poolIDs := config.DB.Model(&users.OrganizationCreditPool{}).Select("id").Where("organization_id = ?", organization.ID)
+
+	switch r.Method {
+	case http.MethodGet:
+		purchases := []users.PackagePurchase{}
+		if err := config.DB.Preload("Package").Where("credit_pool_id IN (?)", poolIDs).
+			Order("created_at DESC").Find(&purchases).Error; err != nil {
+			http.Error(w, "Failed to list credits", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(purchases)
+
+	case http.MethodPost:
+		var input struct {
+			PoolID  uint `json:"pool_id"`
+			UserID  uint `json:"user_id"`
+			Credits int  `json:"credits"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			http.Error(w, "Invalid input", http.StatusBadRequest)
+			return
+		}
+		if input.Credits < 1 {
+			http.Error(w, "credits must be positive", http.StatusBadRequest)
+			return
+		}
+		var pool users.OrganizationCreditPool
+		if err := config.DB.Preload("Package").Where("id = ? AND organization_id = ?", input.PoolID, organization.ID).
+			First(&pool).Error; err != nil {
+			http.Error(w, "Credit pool not found", http.StatusNotFound)
+			return
+		}
+		if pool.ExpiresAt.Before(time.Now()) {
+			http.Error(w, "Credit pool has expired", http.StatusConflict)
+			return
+		}
+
+		var purchase *users.PackagePurchase
+		err := config.DB.Transaction(func(tx *gorm.DB) error {
+			var err error
+			purchase, err = services.AllocateOrganizationCredits(tx, &pool, input.UserID, input.Credits)
+			return err
+		})
+		if errors.Is(err, services.ErrNotOrgMember) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, services.ErrNoCreditsLeft) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Failed to allocate credits", http.StatusInternalServerError)
+			return
+		}
+		purchase.Package = pool.Package
+
+		config.DB.Create(&users.NotificationMentor{
+			UserID:  input.UserID,
+			Message: fmt.Sprintf("%s gave you %d mentoring sessions (\"%s\")", organization.Name, input.Credits, pool.Package.Title),
+		})
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(purchase)
+
+	case http.MethodDelete:
+		purchaseID, err := strconv.Atoi(r.URL.Query().Get("purchase_id"))
+		if err != nil || purchaseID <= 0 {
+			http.Error(w, "Invalid purchase ID", http.StatusBadRequest)
+			return
+		}
+		var purchase users.PackagePurchase
+		if err := config.DB.Where("id = ? AND credit_pool_id IN (?)", purchaseID, poolIDs).First(&purchase).Error; err != nil {
+			http.Error(w, "Purchase not found", http.StatusNotFound)
+			return
+		}
+		var reclaimed int
+		if err := config.DB.Transaction(func(tx *gorm.DB) error {
+			var err error
+			reclaimed, err = services.ReclaimOrganizationCredits(tx, &purchase)
+			return err
+		}); err != nil {
+			http.Error(w, "Failed to reclaim credits", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"purchase_id": purchase.ID,
+			"reclaimed":   reclaimed,
+		})
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// ProgressReportHandler - GET: прогресс участников по выданным курсам (?organization_id=&course_id=)
+func ProgressReportHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user, err := authentication.ValidateToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	organization, ok := managedOrganization(w, r, user)
+	if !ok {
+		return
+	}
+	var originID uint
+	if value := r.URL.Query().Get("course_id"); value != "" {
+		courseID, err := strconv.Atoi(value)
+		if err != nil || courseID <= 0 {
+			http.Error(w, "Invalid course ID", http.StatusBadRequest)
+			return
+		}
+		var course courses.Course
+		if err := config.DB.Unscoped().First(&course, courseID).Error; err != nil {
+			http.Error(w, "Course not found", http.StatusNotFound)
+			return
+		}
+		originID = services.CourseOriginID(&course)
+	}
+
+	members, summary, err := services.OrganizationProgressReport(organization.ID, originID)
+	if err != nil {
+		http.Error(w, "Failed to build progress report", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"organization_id": organization.ID,
+		"courses":         summary,
+		"members":         members,
+	})
+}
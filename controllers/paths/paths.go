@@ -0,0 +1,619 @@
+package paths
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+	"hired-valley-backend/config"
+	"hired-valley-backend/controllers/authentication"
+	"hired-valley-backend/models/career"
+	"hired-valley-backend/models/learning"
+	"hired-valley-backend/models/users"
+	"hired-valley-backend/services"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// pathInput - данные пути от автора; при обновлении nil-поля не меняются
+type pathInput struct {
+	Title       *string   `json:"title"`
+	Description *string   `json:"description"`
+	Tags        *[]string `json:"tags"`
+	Status      *string   `json:"status"`
+}
+
+// findPath - путь из параметра запроса param с шагами по порядку; при ошибке ответ уже отправлен
+func findPath(w http.ResponseWriter, r *http.Request, param string) (learning.Path, bool) {
+	var path learning.Path
+	pathID, err := strconv.Atoi(r.URL.Query().Get(param))
+	if err != nil || pathID <= 0 {
+		http.Error(w, "Invalid path ID", http.StatusBadRequest)
+		return path, false
+	}
+	if err := config.DB.Preload("Steps", func(db *gorm.DB) *gorm.DB {
+		return db.Order("position, id")
+	}).First(&path, pathID).Error; err != nil {
+		http.Error(w, "Learning path not found", http.StatusNotFound)
+		return path, false
+	}
+	return path, true
+}
+
+// editablePath - путь, который пользователь может редактировать; при ошибке ответ уже отправлен
+func editablePath(w http.ResponseWriter, r *http.Request, user *users.User, param string) (learning.Path, bool) {
+	path, ok := findPath(w, r, param)
+	if !ok {
+		return path, false
+	}
+	if !services.CanEditPath(user, &path) {
+		http.Error(w, "Permission denied", http.StatusForbidden)
+		return path, false
+	}
+	return path, true
+}
+
+// applyPathInput - перенос данных пути из запроса
+func applyPathInput(path *learning.Path, input pathInput) error {
+	if input.Title != nil {
+		if strings.TrimSpace(*input.Title) == "" {
+			return errors.New("title is required")
+		}
+		path.Title = strings.TrimSpace(*input.Title)
+	}
+	if input.Description != nil {
+		path.Description = *input.Description
+	}
+	if input.Tags != nil {
+		path.Tags = pq.StringArray(*input.Tags)
+	}
+	if input.Status != nil {
+		switch *input.Status {
+		case learning.PathStatusDraft, learning.PathStatusPublished, learning.PathStatusArchived:
+			path.Status = *input.Status
+		default:
+			return errors.New("status must be draft, published or archived")
+		}
+	}
+	return nil
+}
+
+// PathsHandler - GET: каталог опубликованных путей (?tag=, ?q=), POST: создание пути (менторы и администраторы)
+func PathsHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		query := config.DB.Where("status = ? AND owner_id IS NULL", learning.PathStatusPublished)
+		if tag := strings.TrimSpace(r.URL.Query().Get("tag")); tag != "" {
+			query = query.Where("? = ANY(tags)", tag)
+		}
+		if q := strings.TrimSpace(r.URL.Query().Get("q")); q != "" {
+			query = query.Where("title ILIKE ? OR description ILIKE ?", "%"+q+"%", "%"+q+"%")
+		}
+		paths := []learning.Path{}
+		if err := query.Order("created_at DESC").Find(&paths).Error; err != nil {
+			http.Error(w, "Failed to list learning paths", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(paths)
+
+	case http.MethodPost:
+		user, err := authentication.ValidateToken(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		if user.Role != "mentor" && user.Role != "admin" {
+			http.Error(w, "Only mentors can create learning paths", http.StatusForbidden)
+			return
+		}
+
+		var input pathInput
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			http.Error(w, "Invalid input", http.StatusBadRequest)
+			return
+		}
+		if input.Title == nil {
+			http.Error(w, "title is required", http.StatusBadRequest)
+			return
+		}
+		path := learning.Path{AuthorID: user.ID, Status: learning.PathStatusDraft}
+		if err := applyPathInput(&path, input); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := config.DB.Create(&path).Error; err != nil {
+			http.Error(w, "Failed to create learning path", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(path)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// PathHandler - учебный путь (?id=). GET: путь с шагами и количеством записавшихся,
+// PUT: изменение (автор и администратор), DELETE: удаление (автор и администратор)
+func PathHandler(w http.ResponseWriter, r *http.Request) {
+	var viewer *users.User
+	if user, err := authentication.ValidateToken(r); err == nil {
+		viewer = user
+	} else if r.Method != http.MethodGet {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	path, ok := findPath(w, r, "id")
+	if !ok {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		if !services.CanViewPath(viewer, &path) {
+			http.Error(w, "Learning path not found", http.StatusNotFound)
+			return
+		}
+		var enrolled int64
+		config.DB.Model(&learning.PathEnrollment{}).Where("path_id = ?", path.ID).Count(&enrolled)
+		response := map[string]interface{}{
+			"path":           path,
+			"enrolled_count": enrolled,
+			"is_enrolled":    false,
+		}
+		if viewer != nil {
+			var count int64
+			config.DB.Model(&learning.PathEnrollment{}).Where("path_id = ? AND user_id = ?", path.ID, viewer.ID).Count(&count)
+			response["is_enrolled"] = count > 0
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+
+	case http.MethodPut:
+		if !services.CanEditPath(viewer, &path) {
+			http.Error(w, "Permission denied", http.StatusForbidden)
+			return
+		}
+		var input pathInput
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			http.Error(w, "Invalid input", http.StatusBadRequest)
+			return
+		}
+		if err := applyPathInput(&path, input); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if path.Status == learning.PathStatusPublished && len(path.Steps) == 0 {
+			http.Error(w, "Add at least one step before publishing", http.StatusConflict)
+			return
+		}
+		if err := config.DB.Omit("Steps").Save(&path).Error; err != nil {
+			http.Error(w, "Failed to update learning path", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(path)
+
+	case http.MethodDelete:
+		if !services.CanEditPath(viewer, &path) {
+			http.Error(w, "Permission denied", http.StatusForbidden)
+			return
+		}
+		if err := config.DB.Delete(&path).Error; err != nil {
+			http.Error(w, "Failed to delete learning path", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// PathStepsHandler - шаги пути. POST: новый шаг в конце пути (?path_id=),
+// PUT: изменение шага (?id=), DELETE: удаление шага (?id=)
+func PathStepsHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := authentication.ValidateToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	if r.Method == http.MethodPost {
+		path, ok := editablePath(w, r, user, "path_id")
+		if !ok {
+			return
+		}
+		var step learning.PathStep
+		if err := json.NewDecoder(r.Body).Decode(&step); err != nil {
+			http.Error(w, "Invalid input", http.StatusBadRequest)
+			return
+		}
+		if err := services.ValidatePathStep(&step); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		step.ID = 0
+		step.PathID = path.ID
+		step.Position = len(path.Steps) + 1
+		if err := config.DB.Create(&step).Error; err != nil {
+			http.Error(w, "Failed to add step", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(step)
+		return
+	}
+
+	stepID, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil || stepID <= 0 {
+		http.Error(w, "Invalid step ID", http.StatusBadRequest)
+		return
+	}
+	var step learning.PathStep
+	if err := config.DB.First(&step, stepID).Error; err != nil {
+		http.Error(w, "Step not found", http.StatusNotFound)
+		return
+	}
+	var path learning.Path
+	if err := config.DB.First(&path, step.PathID).Error; err != nil || !services.CanEditPath(user, &path) {
+		http.Error(w, "Permission denied", http.StatusForbidden)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		var input learning.PathStep
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			http.Error(w, "Invalid input", http.StatusBadRequest)
+			return
+		}
+		if err := services.ValidatePathStep(&input); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		input.ID = step.ID
+		input.PathID = step.PathID
+		input.Position = step.Position
+		input.CreatedAt = step.CreatedAt
+		if err := config.DB.Save(&input).Error; err != nil {
+			http.Error(w, "Failed to update step", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(input)
+
+	case http.MethodDelete:
+		err := config.DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Where("step_id = ?", step.ID).Delete(&learning.StepCompletion{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Delete(&step).Error; err != nil {
+				return err
+			}
+			return tx.Model(&learning.PathStep{}).Where("path_id = ? AND position > ?", step.PathID, step.Position).
+				UpdateColumn("position", gorm.Expr("position - 1")).Error
+		})
+		if err != nil {
+			http.Error(w, "Failed to delete step", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// ReorderPathStepsHandler - новый порядок шагов пути (?path_id=)
+func ReorderPathStepsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user, err := authentication.ValidateToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	path, ok := editablePath(w, r, user, "path_id")
+	if !ok {
+		return
+	}
+
+	var input struct {
+		StepIDs []uint `json:"step_ids"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	existing := map[uint]bool{}
+	for _, step := range path.Steps {
+		existing[step.ID] = true
+	}
+	seen := map[uint]bool{}
+	for _, id := range input.StepIDs {
+		if !existing[id] || seen[id] {
+			break
+		}
+		seen[id] = true
+	}
+	if len(seen) != len(existing) || len(input.StepIDs) != len(existing) {
+		http.Error(w, "step_ids must list every step of the path exactly once", http.StatusBadRequest)
+		return
+	}
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		for i, id := range input.StepIDs {
+			if err := tx.Model(&learning.PathStep{}).Where("id = ?", id).Update("position", i+1).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		http.Error(w, "Failed to reorder steps", http.StatusInternalServerError)
+		return
+	}
+
+	path, _ = findPath(w, r, "path_id")
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(path.Steps)
+}
+
+// EnrollPathHandler - POST: запись на учебный путь (?path_id=), DELETE: выход из пути
+func EnrollPathHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := authentication.ValidateToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	path, ok := findPath(w, r, "path_id")
+	if !ok {
+		return
+	}
+	if !services.CanViewPath(user, &path) {
+		http.Error(w, "Learning path not found", http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPost:
+		if path.Status != learning.PathStatusPublished {
+			http.Error(w, "Learning path is not open for enrollment", http.StatusConflict)
+			return
+		}
+		var count int64
+		config.DB.Model(&learning.PathEnrollment{}).Where("path_id = ? AND user_id = ?", path.ID, user.ID).Count(&count)
+		if count > 0 {
+			http.Error(w, "Already enrolled", http.StatusConflict)
+			return
+		}
+		enrollment := learning.PathEnrollment{PathID: path.ID, UserID: user.ID}
+		if err := config.DB.Create(&enrollment).Error; err != nil {
+			http.Error(w, "Failed to enroll", http.StatusInternalServerError)
+			return
+		}
+		progress, err := services.SyncPathProgress(&enrollment, path.Steps)
+		if err != nil {
+			http.Error(w, "Failed to calculate progress", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(progress)
+
+	case http.MethodDelete:
+		var enrollment learning.PathEnrollment
+		if err := config.DB.Where("path_id = ? AND user_id = ?", path.ID, user.ID).First(&enrollment).Error; err != nil {
+			http.Error(w, "Enrollment not found", http.StatusNotFound)
+			return
+		}
+		err := config.DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Where("enrollment_id = ?", enrollment.ID).Delete(&learning.StepCompletion{}).Error; err != nil {
+				return err
+			}
+			return tx.Delete(&enrollment).Error
+		})
+		if err != nil {
+			http.Error(w, "Failed to leave learning path", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// PathProgressHandler - GET: прогресс текущего пользователя по пути (?path_id=)
+func PathProgressHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user, err := authentication.ValidateToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	path, ok := findPath(w, r, "path_id")
+	if !ok {
+		return
+	}
+	var enrollment learning.PathEnrollment
+	if err := config.DB.Where("path_id = ? AND user_id = ?", path.ID, user.ID).First(&enrollment).Error; err != nil {
+		http.Error(w, "You are not enrolled in this learning path", http.StatusNotFound)
+		return
+	}
+
+	progress, err := services.SyncPathProgress(&enrollment, path.Steps)
+	if err != nil {
+		http.Error(w, "Failed to calculate progress", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(progress)
+}
+
+// MyPathsHandler - GET: пути, на которые записан пользователь, с процентом прохождения
+func MyPathsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user, err := authentication.ValidateToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	var enrollments []learning.PathEnrollment
+	if err := config.DB.Where("user_id = ?", user.ID).Order("created_at DESC").Find(&enrollments).Error; err != nil {
+		http.Error(w, "Failed to list learning paths", http.StatusInternalServerError)
+		return
+	}
+
+	type myPath struct {
+		Path           learning.Path `json:"path"`
+		TotalSteps     int           `json:"total_steps"`
+		CompletedSteps int           `json:"completed_steps"`
+		Percent        float64       `json:"percent"`
+		NextStepID     *uint         `json:"next_step_id"`
+	}
+	result := []myPath{}
+	for i := range enrollments {
+		var path learning.Path
+		if err := config.DB.Preload("Steps", func(db *gorm.DB) *gorm.DB {
+			return db.Order("position, id")
+		}).First(&path, enrollments[i].PathID).Error; err != nil {
+			continue
+		}
+		progress, err := services.SyncPathProgress(&enrollments[i], path.Steps)
+		if err != nil {
+			http.Error(w, "Failed to calculate progress", http.StatusInternalServerError)
+			return
+		}
+		path.Steps = nil
+		result = append(result, myPath{path, progress.TotalSteps, progress.CompletedSteps, progress.Percent, progress.NextStepID})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// CompletePathStepHandler - отметка шага с контентом просмотренным вручную (?id=):
+// POST - отметить, DELETE - снять отметку. Остальные шаги засчитываются автоматически
+func CompletePathStepHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := authentication.ValidateToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	stepID, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil || stepID <= 0 {
+		http.Error(w, "Invalid step ID", http.StatusBadRequest)
+		return
+	}
+	var step learning.PathStep
+	if err := config.DB.First(&step, stepID).Error; err != nil {
+		http.Error(w, "Step not found", http.StatusNotFound)
+		return
+	}
+	var enrollment learning.PathEnrollment
+	if err := config.DB.Where("path_id = ? AND user_id = ?", step.PathID, user.ID).First(&enrollment).Error; err != nil {
+		http.Error(w, "You are not enrolled in this learning path", http.StatusNotFound)
+		return
+	}
+	if step.Type != learning.StepContent {
+		http.Error(w, "Only content steps can be marked manually", http.StatusConflict)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPost:
+		completion := learning.StepCompletion{EnrollmentID: enrollment.ID, StepID: step.ID}
+		err := config.DB.Where("enrollment_id = ? AND step_id = ?", enrollment.ID, step.ID).
+			Attrs(learning.StepCompletion{Manual: true, CompletedAt: time.Now()}).
+			FirstOrCreate(&completion).Error
+		if err != nil {
+			http.Error(w, "Failed to complete step", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(completion)
+
+	case http.MethodDelete:
+		if err := config.DB.Where("enrollment_id = ? AND step_id = ? AND manual = ?", enrollment.ID, step.ID, true).
+			Delete(&learning.StepCompletion{}).Error; err != nil {
+			http.Error(w, "Failed to update step", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// GeneratePathHandler - POST: личный учебный путь по карьерному плану (?plan_id=);
+// пользователь сразу записывается на него
+func GeneratePathHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user, err := authentication.ValidateToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	planID, err := strconv.Atoi(r.URL.Query().Get("plan_id"))
+	if err != nil || planID <= 0 {
+		http.Error(w, "Invalid plan ID", http.StatusBadRequest)
+		return
+	}
+	var plan career.PlanCareer
+	if err := config.DB.Preload("PlanSteps").Where("id = ? AND user_id = ?", planID, user.ID).First(&plan).Error; err != nil {
+		http.Error(w, "Career plan not found", http.StatusNotFound)
+		return
+	}
+
+	path, err := services.GeneratePathFromPlan(plan)
+	if errors.Is(err, services.ErrNothingToLearn) {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to generate learning path", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(path)
+}
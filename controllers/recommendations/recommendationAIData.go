@@ -8,6 +8,7 @@ import (
 	"hired-valley-backend/models/content"
 	"hired-valley-backend/models/courses"
 	"hired-valley-backend/models/users"
+	"hired-valley-backend/services"
 	"net/http"
 	"os"
 	"strings"
@@ -40,8 +41,22 @@ func PersonalizedRecommendationsHandler(w http.ResponseWriter, r *http.Request)
 	skills := extractSkillNames(user.Skills)
 	interests := extractInterestNames(user.Interests)
 
+	// Теги курсов, которые пользователь уже проходит, дополняют интересы
+	learningTags, err := services.LearningTags(user.ID)
+	if err != nil {
+		http.Error(w, "Failed to fetch learning progress: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	interests = mergeNames(interests, learningTags)
+
+	continueLearning, err := services.ContinueLearning(user.ID)
+	if err != nil {
+		http.Error(w, "Failed to fetch learning progress: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
 	// Выборка данных из базы
-	matchedCourses, matchedContent, matchedMentors, err := fetchDataFromDatabase(interests, skills)
+	matchedCourses, matchedContent, matchedMentors, err := fetchDataFromDatabase(user.ID, interests, skills)
 	if err != nil {
 		http.Error(w, "Failed to fetch data: "+err.Error(), http.StatusInternalServerError)
 		return
@@ -71,6 +86,7 @@ func PersonalizedRecommendationsHandler(w http.ResponseWriter, r *http.Request)
 		"personalized_courses": matchedCourses,
 		"personalized_content": matchedContent,
 		"personalized_mentors": matchedMentors,
+		"continue_learning":    continueLearning,
 		"ai_suggestions":       aiResponse,
 		"motivational_message": "Your potential is limitless. With the right knowledge and guidance, you can achieve your dreams. Keep going—you’re closer than you think!",
 	}
@@ -80,9 +96,12 @@ func PersonalizedRecommendationsHandler(w http.ResponseWriter, r *http.Request)
 }
 
 // fetchDataFromDatabase - выборка данных из базы
-func fetchDataFromDatabase(interests, skills []string) ([]courses.Course, []content.Content, []users.User, error) {
+func fetchDataFromDatabase(userID uint, interests, skills []string) ([]courses.Course, []content.Content, []users.User, error) {
+	// Рекомендуются только опубликованные курсы, на которые пользователь еще не записан
 	var matchedCourses []courses.Course
-	if err := config.DB.Where("tags && ?", pq.Array(interests)).Find(&matchedCourses).Error; err != nil {
+	if err := config.DB.Where("status = ? AND tags && ?", courses.CourseStatusPublished, pq.Array(interests)).
+		Where("id NOT IN (?)", config.DB.Model(&courses.Enrollment{}).Select("course_id").Where("user_id = ?", userID)).
+		Find(&matchedCourses).Error; err != nil {
 		return nil, nil, nil, fmt.Errorf("failed to fetch courses: %v", err)
 	}
 
@@ -238,3 +257,18 @@ func extractInterestNames(interests []users.Interest) []string {
 	}
 	return interestStrings
 }
+
+// mergeNames - объединение списков без повторов
+func mergeNames(base, extra []string) []string {
+	seen := make(map[string]bool, len(base))
+	for _, name := range base {
+		seen[name] = true
+	}
+	for _, name := range extra {
+		if !seen[name] {
+			seen[name] = true
+			base = append(base, name)
+		}
+	}
+	return base
+}
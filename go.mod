@@ -7,6 +7,7 @@ require (
 	github.com/gorilla/sessions v1.4.0
 	github.com/lib/pq v1.10.9
 	golang.org/x/crypto v0.29.0
+	golang.org/x/image v0.18.0
 	golang.org/x/oauth2 v0.24.0
 	google.golang.org/api v0.209.0
 	gorm.io/driver/postgres v1.5.9
@@ -7,19 +7,29 @@ import (
 	"hired-valley-backend/controllers/careers"
 	"hired-valley-backend/controllers/contentsControl"
 	"hired-valley-backend/controllers/course"
+	"hired-valley-backend/controllers/courseapi"
 	"hired-valley-backend/controllers/mentors"
+	"hired-valley-backend/controllers/messaging"
+	"hired-valley-backend/controllers/organizations"
+	"hired-valley-backend/controllers/paths"
 	"hired-valley-backend/controllers/recommendations"
 	"hired-valley-backend/controllers/stories"
+	"hired-valley-backend/models/analytics"
+	"hired-valley-backend/models/billing"
 	"hired-valley-backend/models/career"
+	"hired-valley-backend/models/chat"
 	"hired-valley-backend/models/content"
 	"hired-valley-backend/models/courses"
 	"hired-valley-backend/models/courses/videos"
+	"hired-valley-backend/models/learning"
 	"hired-valley-backend/models/recommend"
 	"hired-valley-backend/models/story"
 	"hired-valley-backend/models/users"
+	"hired-valley-backend/services"
 	"log"
 	"net/http"
 	"os"
+	"time"
 )
 
 func main() {
@@ -40,9 +50,38 @@ func main() {
 		&users.GoogleUser{},
 		&users.YoutubeUser{},
 		&users.LinkedInUser{},
+		&users.EmailVerification{},
 		&courses.Course{},
 		&courses.Lesson{},
+		&courses.CourseModule{},
+		&courses.LessonPrerequisite{},
+		&courses.Quiz{},
+		&courses.QuizQuestion{},
+		&courses.QuizAttempt{},
+		&courses.QuizAnswer{},
+		&courses.CourseReview{},
+		&courses.LessonQuestion{},
+		&courses.LessonAnswer{},
+		&courses.LessonAnswerVote{},
+		&courses.CourseCollaborator{},
+		&courses.CourseInvitation{},
+		&courses.CoursePrice{},
+		&courses.SeatPriceTier{},
+		&courses.Coupon{},
+		&courses.CouponRedemption{},
+		&users.Certificate{},
+		&courses.Enrollment{},
+		&billing.Payment{},
+		&billing.Refund{},
+		&courses.Progress{},
+		&learning.Path{},
+		&learning.PathStep{},
+		&learning.PathEnrollment{},
+		&learning.StepCompletion{},
 		&videos.Video{},
+		&videos.CaptionTrack{},
+		&videos.TranscriptSegment{},
+		&videos.VideoChapter{},
 		&story.Story{},
 		&story.Reaction{},
 		&story.ViewStory{},
@@ -54,10 +93,40 @@ func main() {
 		&users.MentorProfile{},
 		&users.Slot{},
 		&users.NotificationMentor{},
+		&users.MentorReview{},
+		&users.MentorReviewFlag{},
+		&users.MentorApplication{},
+		&users.MentorVerificationDocument{},
+		&users.SessionNote{},
+		&users.ActionItem{},
+		&career.PlanStep{},
+		&users.WaitlistEntry{},
+		&users.WaitlistWindow{},
+		&users.BookingCancellation{},
+		&users.MentorPackage{},
+		&users.PackagePurchase{},
+		&users.CreditRedemption{},
+		&users.Organization{},
+		&users.OrganizationDomain{},
+		&users.OrganizationMember{},
+		&users.OrganizationInvitation{},
+		&users.OrganizationCourseSeats{},
+		&users.OrganizationSeatPurchase{},
+		&users.OrganizationSeatAssignment{},
+		&users.OrganizationCreditPool{},
+		&chat.Conversation{},
+		&chat.ConversationParticipant{},
+		&chat.Message{},
+		&chat.MessageAttachment{},
+		&chat.MessageRequest{},
+		&analytics.ViewEvent{},
 	)
 	if err != nil {
 		log.Fatalf("Ошибка миграции базы данных: %v", err)
 	}
+	if err := services.EnsureSearchIndexes(); err != nil {
+		log.Fatalf("Ошибка создания индексов поиска: %v", err)
+	}
 
 	// Проверка подключения к базе данных
 	sqlDB, err := config.DB.DB()
@@ -72,6 +141,19 @@ func main() {
 		log.Println("Подключение к базе данных успешно")
 	}
 
+	// Фоновая обработка истекших предложений листа ожидания
+	go mentors.RunWaitlistWorker(time.Minute)
+
+	// Ключ подписи ссылок на видео из собственного хранилища
+	if err := services.SetVideoSigningKey([]byte(os.Getenv("VIDEO_SIGNING_KEY"))); err != nil {
+		log.Fatalf("Некорректный VIDEO_SIGNING_KEY: %v", err)
+	}
+
+	// Площадки размещения видео и фоновая публикация загруженных файлов
+	services.RegisterVideoHost(videos.HostYouTube, course.YouTubeHost{})
+	services.RegisterVideoHost(videos.HostSelf, services.SelfVideoHost{Storage: services.GetVideoStorage()})
+	go services.RunVideoWorker(30 * time.Second)
+
 	// authorization endpoints
 	http.HandleFunc("/", handleHome)
 	http.HandleFunc("/login/google", authentication.HandleGoogleLogin)
@@ -86,36 +168,152 @@ func main() {
 	http.HandleFunc("/login", authentication.Login)
 	http.HandleFunc("/profile", authentication.GetProfile)
 	http.HandleFunc("/logout", authentication.Logout)
+	http.HandleFunc("/email/verify", authentication.SendEmailVerificationHandler)
+	http.HandleFunc("/email/verify/confirm", authentication.ConfirmEmailVerificationHandler)
 
 	//users profile endpoints
 	http.HandleFunc("/profile/update", authentication.UpdateProfile)
 	http.HandleFunc("/users/search", authentication.SearchUsers)
 
 	http.HandleFunc("/mentors", mentors.MentorsHandler)
+	http.HandleFunc("/mentors/search", mentors.SearchMentorsHandler)
+	http.HandleFunc("/mentors/profile/update", mentors.UpdateMentorProfileHandler)
+	http.HandleFunc("/mentors/profile/pause", mentors.PauseMentorHandler)
+	http.HandleFunc("/mentors/profile/resume", mentors.ResumeMentorHandler)
+	http.HandleFunc("/mentors/profile/deactivate", mentors.DeactivateMentorHandler)
+
+	//mentor applications endpoints
+	http.HandleFunc("/mentors/apply", mentors.ApplyMentorHandler)
+	http.HandleFunc("/mentors/applications/my", mentors.MyApplicationsHandler)
+	http.HandleFunc("/mentors/applications", mentors.ListApplicationsHandler)
+	http.HandleFunc("/mentors/applications/review", mentors.ReviewApplicationHandler)
 	http.HandleFunc("/mentors/slots/create", mentors.CreateSlotHandler)
 	http.HandleFunc("/mentors/book", mentors.BookSlotHandler)
 	http.HandleFunc("/mentors/slots", mentors.SlotsHandler)
 	http.HandleFunc("/mentors/booked-slots", mentors.MentorBookedSlotsHandler)
+	http.HandleFunc("/mentors/meeting", mentors.MeetingHandler)
+	http.HandleFunc("/mentors/book/cancel", mentors.CancelBookingHandler)
+	http.HandleFunc("/mentors/analytics", mentors.MentorAnalyticsHandler)
+
+	//mentor packages endpoints
+	http.HandleFunc("/mentors/packages", mentors.PackagesHandler)
+	http.HandleFunc("/mentors/packages/update", mentors.UpdatePackageHandler)
+	http.HandleFunc("/mentors/packages/buy", mentors.BuyPackageHandler)
+	http.HandleFunc("/mentors/packages/purchases", mentors.MyPurchasesHandler)
+	http.HandleFunc("/mentors/packages/redeem", mentors.RedeemCreditHandler)
+	http.HandleFunc("/mentors/packages/refund", mentors.RefundPurchaseHandler)
+
+	//organization endpoints
+	http.HandleFunc("/organizations", organizations.OrganizationsHandler)
+	http.HandleFunc("/organizations/item", organizations.OrganizationHandler)
+	http.HandleFunc("/organizations/join", organizations.JoinOrganizationHandler)
+	http.HandleFunc("/organizations/domains", organizations.OrganizationDomainsHandler)
+	http.HandleFunc("/organizations/members", organizations.OrganizationMemberHandler)
+	http.HandleFunc("/organizations/invitations", organizations.OrganizationInvitationsHandler)
+	http.HandleFunc("/organizations/invitations/mine", organizations.MyOrganizationInvitationsHandler)
+	http.HandleFunc("/organizations/invitations/accept", organizations.AcceptOrganizationInvitationHandler)
+	http.HandleFunc("/organizations/invitations/decline", organizations.DeclineOrganizationInvitationHandler)
+	http.HandleFunc("/organizations/seats", organizations.CourseSeatsHandler)
+	http.HandleFunc("/organizations/seats/assignments", organizations.SeatAssignmentsHandler)
+	http.HandleFunc("/organizations/credits", organizations.CreditPoolsHandler)
+	http.HandleFunc("/organizations/credits/allocations", organizations.CreditAllocationsHandler)
+	http.HandleFunc("/organizations/progress", organizations.ProgressReportHandler)
+
+	//mentor waitlist endpoints
+	http.HandleFunc("/mentors/waitlist", mentors.WaitlistHandler)
+	http.HandleFunc("/mentors/waitlist/claim", mentors.ClaimWaitlistOfferHandler)
+	http.HandleFunc("/mentors/waitlist/decline", mentors.DeclineWaitlistOfferHandler)
+
+	//mentoring session notes endpoints
+	http.HandleFunc("/sessions/notes", mentors.SessionNotesHandler)
+	http.HandleFunc("/sessions/notes/item", mentors.SessionNoteHandler)
+	http.HandleFunc("/sessions/action-items", mentors.ActionItemsHandler)
+	http.HandleFunc("/sessions/action-items/update", mentors.UpdateActionItemHandler)
+	http.HandleFunc("/sessions/action-items/to-plan", mentors.ActionItemToPlanHandler)
 	http.HandleFunc("/notifications", mentors.NotificationsHandler)
 
+	//mentor reviews endpoints
+	http.HandleFunc("/mentors/reviews", mentors.ListReviewsHandler)
+	http.HandleFunc("/mentors/reviews/create", mentors.CreateReviewHandler)
+	http.HandleFunc("/mentors/reviews/reply", mentors.ReplyReviewHandler)
+	http.HandleFunc("/mentors/reviews/flag", mentors.FlagReviewHandler)
+	http.HandleFunc("/mentors/reviews/flagged", mentors.FlaggedReviewsHandler)
+	http.HandleFunc("/mentors/reviews/moderate", mentors.ModerateReviewHandler)
+
+	//direct messaging endpoints
+	http.HandleFunc("/conversations", messaging.ConversationsHandler)
+	http.HandleFunc("/conversations/messages", messaging.MessagesHandler)
+	http.HandleFunc("/conversations/read", messaging.MarkReadHandler)
+	http.HandleFunc("/messages/unread", messaging.UnreadCountHandler)
+	http.HandleFunc("/messages/requests", messaging.MessageRequestsHandler)
+	http.HandleFunc("/messages/requests/respond", messaging.RespondMessageRequestHandler)
+
 	http.HandleFunc("/upload/content", contentsControl.UploadContent)
 	http.HandleFunc("/list/content", contentsControl.ListContent)
 	http.HandleFunc("/get/content", contentsControl.GetContentByID)
 	http.HandleFunc("/delete/content", contentsControl.DeleteContent)
 
 	//courses endpoints
-	http.HandleFunc("/list/courses", course.ListCourses)
-	http.HandleFunc("/create/courses", course.CreateCourse)
-	http.HandleFunc("/get/courses", course.GetCourseByID)
-	http.HandleFunc("/update/courses", course.UpdateCourse)
-	http.HandleFunc("/delete/courses", course.DeleteCourse)
-
-	//coursesGoogle endpoints
-	http.HandleFunc("/list/google/courses", course.ListCoursesGoogle)
-	http.HandleFunc("/create/google/courses", course.CreateCourseGoogle)
-	http.HandleFunc("/get/google/courses", course.GetCourseByIDGoogle)
-	http.HandleFunc("/update/google/courses", course.UpdateCourseGoogle)
-	http.HandleFunc("/delete/google/courses", course.DeleteCourseGoogle)
+	http.HandleFunc("/courses/catalog", course.CourseCatalogHandler)
+	http.HandleFunc("/courses/enroll", course.EnrollHandler)
+	http.HandleFunc("/courses/enrollments", course.MyEnrollmentsHandler)
+	http.HandleFunc("/courses/students", course.CourseStudentsHandler)
+	http.HandleFunc("/courses/progress", course.CourseProgressHandler)
+	http.HandleFunc("/courses/modules", course.CourseModulesHandler)
+	http.HandleFunc("/courses/modules/item", course.CourseModuleHandler)
+	http.HandleFunc("/courses/modules/reorder", course.ReorderModulesHandler)
+	http.HandleFunc("/lessons/reorder", course.ReorderLessonsHandler)
+	http.HandleFunc("/lessons/prerequisites", course.LessonPrerequisitesHandler)
+	http.HandleFunc("/lessons/quiz", course.LessonQuizHandler)
+	http.HandleFunc("/lessons/quiz/start", course.StartQuizAttemptHandler)
+	http.HandleFunc("/lessons/quiz/submit", course.SubmitQuizAttemptHandler)
+	http.HandleFunc("/lessons/quiz/attempts", course.QuizAttemptsHandler)
+	http.HandleFunc("/lessons/quiz/attempt", course.QuizAttemptReviewHandler)
+	http.HandleFunc("/courses/prices", course.CoursePricingHandler)
+	http.HandleFunc("/courses/quote", course.CourseQuoteHandler)
+	http.HandleFunc("/courses/coupons", course.CouponsHandler)
+	http.HandleFunc("/courses/team", course.CourseTeamHandler)
+	http.HandleFunc("/courses/team/members", course.CourseTeamMemberHandler)
+	http.HandleFunc("/courses/team/invitations", course.CourseInvitationsHandler)
+	http.HandleFunc("/courses/team/invitations/mine", course.MyInvitationsHandler)
+	http.HandleFunc("/courses/team/invitations/accept", course.AcceptInvitationHandler)
+	http.HandleFunc("/courses/team/invitations/decline", course.DeclineInvitationHandler)
+	http.HandleFunc("/courses/teaching", course.TeachingCoursesHandler)
+	http.HandleFunc("/courses/export", course.ExportCourseHandler)
+	http.HandleFunc("/courses/import", course.ImportCourseHandler)
+	http.HandleFunc("/courses/reviews", course.CourseReviewsHandler)
+	http.HandleFunc("/courses/reviews/reply", course.ReplyCourseReviewHandler)
+	http.HandleFunc("/lessons/questions", course.LessonQuestionsHandler)
+	http.HandleFunc("/lessons/questions/item", course.LessonQuestionHandler)
+	http.HandleFunc("/lessons/questions/answers", course.AnswerQuestionHandler)
+	http.HandleFunc("/lessons/questions/upvote", course.UpvoteAnswerHandler)
+	http.HandleFunc("/lessons/questions/accept", course.AcceptAnswerHandler)
+	http.HandleFunc("/certificates", course.UserCertificatesHandler)
+	http.HandleFunc("/certificates/my", course.MyCertificatesHandler)
+	http.HandleFunc("/certificates/verify", course.VerifyCertificateHandler)
+	http.HandleFunc("/certificates/pdf", course.CertificatePDFHandler)
+	http.HandleFunc("/certificates/revoke", course.RevokeCertificateHandler)
+	http.HandleFunc("/lessons/progress", course.UpdateLessonProgressHandler)
+	http.HandleFunc("/learning/dashboard", course.LearnerDashboardHandler)
+
+	//learning paths endpoints
+	http.HandleFunc("/paths", paths.PathsHandler)
+	http.HandleFunc("/paths/item", paths.PathHandler)
+	http.HandleFunc("/paths/steps", paths.PathStepsHandler)
+	http.HandleFunc("/paths/steps/reorder", paths.ReorderPathStepsHandler)
+	http.HandleFunc("/paths/steps/complete", paths.CompletePathStepHandler)
+	http.HandleFunc("/paths/enroll", paths.EnrollPathHandler)
+	http.HandleFunc("/paths/progress", paths.PathProgressHandler)
+	http.HandleFunc("/paths/mine", paths.MyPathsHandler)
+	http.HandleFunc("/paths/generate", paths.GeneratePathHandler)
+
+	// courses API v1 (JWT или Google OAuth токен); /list|create|get|update|delete/[google/]courses - устаревшие алиасы
+	courseAPI := &courseapi.API{
+		Courses:      services.NewCourseService(nil),
+		Authenticate: courseapi.AnyCredential(authentication.ValidateToken, authentication.ValidateGoogleUser),
+		Describe:     course.DescribeCourse,
+	}
+	courseAPI.Register(http.DefaultServeMux)
 
 	//lessons endpoints
 	http.HandleFunc("/list/lessons", course.ListLessons)
@@ -127,6 +325,15 @@ func main() {
 	http.HandleFunc("/video/get", course.GetVideo)
 	http.HandleFunc("/video/update", course.UpdateVideo)
 	http.HandleFunc("/video/delete", course.DeleteVideo)
+	http.HandleFunc("/videos/uploads", course.VideoUploadsHandler)
+	http.HandleFunc("/videos/uploads/chunk", course.VideoUploadChunkHandler)
+	http.HandleFunc("/videos/uploads/retry", course.RetryVideoUploadHandler)
+	http.HandleFunc("/videos/stream", course.VideoStreamHandler)
+	http.HandleFunc("/videos/captions", course.VideoCaptionsHandler)
+	http.HandleFunc("/videos/captions/file", course.CaptionFileHandler)
+	http.HandleFunc("/videos/transcript", course.VideoTranscriptHandler)
+	http.HandleFunc("/videos/chapters", course.VideoChaptersHandler)
+	http.HandleFunc("/lessons/search", course.LessonSearchHandler)
 
 	//stories endpoints
 	http.HandleFunc("/create/stories", stories.CreateStory)
@@ -178,6 +385,8 @@ func main() {
 	// AI  endpoints
 	http.HandleFunc("/generate-recommendations", recommendations.PersonalizedRecommendationsHandler)
 	http.HandleFunc("/careersPlan", careers.GenerateCareerPlanHandler)
+	http.HandleFunc("/careersPlan/progress", careers.CareerPlanProgressHandler)
+	http.HandleFunc("/careersPlan/steps/update", careers.UpdatePlanStepHandler)
 
 	// Запускаем сервер
 	log.Printf("Сервер запущен на порту %s", port)
@@ -0,0 +1,18 @@
+package analytics
+
+import "time"
+
+// Типы просматриваемых объектов
+const (
+	ItemCourse  = "course"
+	ItemContent = "content"
+)
+
+// ViewEvent - просмотр курса или контента (UserID = 0 для анонимных просмотров)
+type ViewEvent struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	ItemType  string    `gorm:"index:idx_view_item;not null" json:"item_type"`
+	ItemID    uint      `gorm:"index:idx_view_item;not null" json:"item_id"`
+	UserID    uint      `gorm:"index" json:"user_id"`
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+}
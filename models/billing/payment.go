@@ -0,0 +1,33 @@
+package billing
+
+import "time"
+
+// Назначения платежа
+const (
+	PurposeCourseEnrollment   = "course_enrollment"
+	PurposeOrganizationSeats  = "organization_seats"
+	PurposeOrganizationCredit = "organization_credits"
+	PurposeMentorPackage      = "mentor_package"
+)
+
+// Payment - подтвержденный платежной системой платеж; каждый платеж засчитывается за одну покупку
+type Payment struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Provider  string    `gorm:"not null" json:"provider"`
+	Reference string    `gorm:"uniqueIndex;not null" json:"reference"` // ID платежа у платежной системы
+	Purpose   string    `gorm:"index;not null" json:"purpose"`
+	ItemID    uint      `gorm:"not null" json:"item_id"` // Курс, организация и т.п. в зависимости от Purpose
+	UserID    uint      `gorm:"index;not null" json:"user_id"`
+	Amount    int64     `gorm:"not null" json:"amount"` // В минимальных единицах Currency
+	Currency  string    `gorm:"size:3;not null" json:"currency"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Refund - возврат части или всей суммы платежа, проведенный платежной системой
+type Refund struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	PaymentID uint      `gorm:"index;not null" json:"payment_id"`
+	Reference string    `gorm:"uniqueIndex;not null" json:"reference"` // ID возврата у платежной системы
+	Amount    int64     `gorm:"not null" json:"amount"`                // В минимальных единицах валюты платежа
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -3,11 +3,12 @@ package career
 import "time"
 
 type PlanCareer struct {
-	ID             uint   `gorm:"primaryKey"`
-	UserID         uint   `json:"user_id"`
-	ShortTermGoals string `json:"short_term_goals"`
-	LongTermGoals  string `json:"long_term_goals"`
-	Steps          string `json:"steps" gorm:"type:text"` // JSON-строка с шагами
+	ID             uint       `gorm:"primaryKey"`
+	UserID         uint       `json:"user_id"`
+	ShortTermGoals string     `json:"short_term_goals"`
+	LongTermGoals  string     `json:"long_term_goals"`
+	Steps          string     `json:"steps" gorm:"type:text"`              // JSON-строка с шагами
+	PlanSteps      []PlanStep `json:"plan_steps" gorm:"foreignKey:PlanID"` // Отслеживаемые шаги плана
 	CreatedAt      time.Time
 	UpdatedAt      time.Time
 }
@@ -0,0 +1,23 @@
+package career
+
+import "time"
+
+// Источник шага карьерного плана
+const (
+	StepSourceManual = "manual"
+	StepSourceMentor = "mentor_session"
+)
+
+type PlanStep struct {
+	ID           uint       `gorm:"primaryKey" json:"id"`
+	PlanID       uint       `gorm:"index;not null" json:"plan_id"`
+	Title        string     `gorm:"not null" json:"title"`
+	Description  string     `gorm:"type:text" json:"description"`
+	Source       string     `gorm:"not null;default:manual" json:"source"`
+	ActionItemID *uint      `gorm:"index" json:"action_item_id"`
+	DueDate      *time.Time `json:"due_date"`
+	Completed    bool       `gorm:"default:false" json:"completed"`
+	CompletedAt  *time.Time `json:"completed_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
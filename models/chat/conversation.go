@@ -0,0 +1,64 @@
+package chat
+
+import (
+	"gorm.io/gorm"
+	"time"
+)
+
+type Conversation struct {
+	ID            uint                      `gorm:"primaryKey" json:"id"`
+	Title         string                    `json:"title"`
+	IsGroup       bool                      `gorm:"default:false" json:"is_group"`
+	CreatedByID   uint                      `gorm:"not null" json:"created_by_id"`
+	Participants  []ConversationParticipant `gorm:"foreignKey:ConversationID" json:"participants"`
+	LastMessageAt *time.Time                `gorm:"index" json:"last_message_at"`
+	CreatedAt     time.Time                 `json:"created_at"`
+	UpdatedAt     time.Time                 `json:"updated_at"`
+}
+
+type ConversationParticipant struct {
+	ID                uint       `gorm:"primaryKey" json:"id"`
+	ConversationID    uint       `gorm:"uniqueIndex:idx_conversation_user;not null" json:"conversation_id"`
+	UserID            uint       `gorm:"uniqueIndex:idx_conversation_user;index;not null" json:"user_id"`
+	LastReadMessageID uint       `gorm:"default:0" json:"last_read_message_id"` // Квитанция о прочтении
+	LastReadAt        *time.Time `json:"last_read_at"`
+	CreatedAt         time.Time  `json:"joined_at"`
+}
+
+type Message struct {
+	ID             uint                `gorm:"primaryKey" json:"id"`
+	ConversationID uint                `gorm:"index;not null" json:"conversation_id"`
+	SenderID       uint                `gorm:"index;not null" json:"sender_id"`
+	Body           string              `gorm:"type:text" json:"body"`
+	Attachments    []MessageAttachment `gorm:"foreignKey:MessageID" json:"attachments"`
+	CreatedAt      time.Time           `json:"created_at"`
+	UpdatedAt      time.Time           `json:"updated_at"`
+	DeletedAt      gorm.DeletedAt      `gorm:"index" json:"-"`
+}
+
+type MessageAttachment struct {
+	ID          uint   `gorm:"primaryKey" json:"id"`
+	MessageID   uint   `gorm:"index;not null" json:"message_id"`
+	FileName    string `gorm:"not null" json:"file_name"`
+	URL         string `gorm:"type:text;not null" json:"url"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+}
+
+// Статусы запроса на переписку с ментором
+const (
+	RequestPending  = "pending"
+	RequestAccepted = "accepted"
+	RequestDeclined = "declined"
+)
+
+// MessageRequest - запрос менти на переписку с ментором без бронирования
+type MessageRequest struct {
+	ID           uint       `gorm:"primaryKey" json:"id"`
+	MenteeID     uint       `gorm:"index;not null" json:"mentee_id"`
+	MentorUserID uint       `gorm:"index;not null" json:"mentor_user_id"`
+	Status       string     `gorm:"not null;default:pending" json:"status"`
+	Note         string     `gorm:"type:text" json:"note"`
+	RespondedAt  *time.Time `json:"responded_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
@@ -7,16 +7,33 @@ import (
 	"time"
 )
 
+// Статусы курса
+const (
+	CourseStatusDraft     = "draft"     // Черновик, доступен только автору
+	CourseStatusReview    = "review"    // Отправлен на проверку администратору
+	CourseStatusPublished = "published" // Опубликован в каталоге
+	CourseStatusArchived  = "archived"  // Снят с публикации или заменен новой версией; записанные ученики сохраняют доступ
+)
+
 type Course struct {
-	ID           uint           `gorm:"primaryKey"`
-	Title        string         `json:"title" gorm:"not null"`
-	Description  string         `json:"description" gorm:"type:text"`
-	Price        float64        `json:"price"`
-	InstructorID uint           `json:"instructor_id" gorm:"not null"`    // Внешний ключ на инструктора
-	Instructor   users.User     `json:"-" gorm:"foreignKey:InstructorID"` // Связь с таблицей users
-	Tags         pq.StringArray `gorm:"type:text[]" json:"tags"`          // Используем pq.StringArray для массивов
-	CreatedAt    time.Time      `gorm:"default:current_timestamp"`
-	UpdatedAt    time.Time
-	DeletedAt    gorm.DeletedAt `gorm:"index"`
-	Lessons      []Lesson       `gorm:"foreignKey:CourseID"` // Связь с уроками
+	ID            uint           `gorm:"primaryKey"`
+	Title         string         `json:"title" gorm:"not null"`
+	Description   string         `json:"description" gorm:"type:text"`
+	Price         float64        `json:"price"`
+	InstructorID  uint           `json:"instructor_id" gorm:"not null"`    // Внешний ключ на инструктора
+	Instructor    users.User     `json:"-" gorm:"foreignKey:InstructorID"` // Связь с таблицей users
+	Tags          pq.StringArray `gorm:"type:text[]" json:"tags"`          // Используем pq.StringArray для массивов
+	AverageRating float64        `gorm:"default:0" json:"average_rating"`  // Средняя оценка по отзывам
+	ReviewsCount  int            `gorm:"default:0" json:"reviews_count"`
+	Status        string         `gorm:"default:published;index" json:"status"` // Курсы, созданные до появления черновиков, остаются опубликованными
+	Version       int            `gorm:"default:1" json:"version"`
+	OriginID      *uint          `gorm:"index" json:"origin_id"` // Первая версия курса, nil - это и есть первая версия
+	ReviewNote    string         `json:"review_note"`            // Комментарий администратора при возврате на доработку
+	PublishedAt   *time.Time     `json:"published_at"`
+	SupersededBy  *uint          `json:"superseded_by"` // Версия, заменившая эту при публикации
+	CreatedAt     time.Time      `gorm:"default:current_timestamp"`
+	UpdatedAt     time.Time
+	DeletedAt     gorm.DeletedAt `gorm:"index"`
+	Lessons       []Lesson       `gorm:"foreignKey:CourseID"`                // Связь с уроками (в программе курса - уроки вне разделов)
+	Modules       []CourseModule `gorm:"foreignKey:CourseID" json:"modules"` // Разделы курса по порядку
 }
@@ -0,0 +1,50 @@
+package courses
+
+import "time"
+
+// CourseReview - оценка и отзыв ученика о курсе (один отзыв на курс)
+type CourseReview struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	CourseID  uint       `gorm:"uniqueIndex:idx_course_review_user;not null" json:"course_id"`
+	UserID    uint       `gorm:"uniqueIndex:idx_course_review_user;not null" json:"user_id"`
+	Rating    int        `gorm:"not null" json:"rating"` // Оценка от 1 до 5
+	Comment   string     `gorm:"type:text" json:"comment"`
+	Reply     string     `gorm:"type:text" json:"reply"` // Ответ инструктора
+	RepliedAt *time.Time `json:"replied_at"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// LessonQuestion - вопрос ученика в обсуждении урока
+type LessonQuestion struct {
+	ID               uint           `gorm:"primaryKey" json:"id"`
+	LessonID         uint           `gorm:"index;not null" json:"lesson_id"`
+	CourseID         uint           `gorm:"index;not null" json:"course_id"`
+	UserID           uint           `gorm:"index;not null" json:"user_id"`
+	Title            string         `gorm:"not null" json:"title"`
+	Body             string         `gorm:"type:text" json:"body"`
+	AcceptedAnswerID *uint          `json:"accepted_answer_id"`
+	AnswersCount     int            `gorm:"default:0" json:"answers_count"`
+	Answers          []LessonAnswer `gorm:"foreignKey:QuestionID" json:"answers,omitempty"`
+	CreatedAt        time.Time      `json:"created_at"`
+	UpdatedAt        time.Time      `json:"updated_at"`
+}
+
+// LessonAnswer - ответ в обсуждении урока
+type LessonAnswer struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	QuestionID   uint      `gorm:"index;not null" json:"question_id"`
+	UserID       uint      `gorm:"index;not null" json:"user_id"`
+	Body         string    `gorm:"type:text;not null" json:"body"`
+	IsInstructor bool      `gorm:"default:false" json:"is_instructor"` // Ответ инструктора курса
+	Upvotes      int       `gorm:"default:0" json:"upvotes"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// LessonAnswerVote - голос пользователя за ответ
+type LessonAnswerVote struct {
+	AnswerID  uint      `gorm:"primaryKey" json:"answer_id"`
+	UserID    uint      `gorm:"primaryKey" json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
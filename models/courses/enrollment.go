@@ -0,0 +1,24 @@
+package courses
+
+import "time"
+
+// Способ зачисления на курс
+const (
+	EnrollmentFree         = "free"
+	EnrollmentPaid         = "paid"
+	EnrollmentOrganization = "organization" // Место выдано организацией
+)
+
+// Enrollment - запись пользователя на курс, открывает доступ к урокам
+type Enrollment struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	UserID     uint      `gorm:"uniqueIndex:idx_enrollment_user_course;not null" json:"user_id"`
+	CourseID   uint      `gorm:"uniqueIndex:idx_enrollment_user_course;index;not null" json:"course_id"`
+	Course     *Course   `gorm:"foreignKey:CourseID" json:"course,omitempty"`
+	Source     string    `gorm:"not null" json:"source"`           // free, paid или organization
+	PricePaid  float64   `gorm:"default:0" json:"price_paid"`      // Сумма в основных единицах валюты, оставлена для совместимости
+	Currency   string    `gorm:"size:3" json:"currency,omitempty"` // Валюта оплаты
+	AmountPaid int64     `gorm:"default:0" json:"amount_paid"`     // Сумма в минимальных единицах Currency
+	CouponID   *uint     `json:"coupon_id,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
@@ -5,12 +5,36 @@ import (
 )
 
 type Lesson struct {
-	ID           uint      `gorm:"primaryKey" json:"id"`
-	Title        string    `json:"title"`         // Название урока
-	Content      string    `json:"content"`       // Описание или текст урока
-	VideoLink    string    `json:"video_url"`     // Ссылка на видео
-	CourseID     uint      `json:"course_id"`     // ID курса, к которому принадлежит урок
-	InstructorID uint      `json:"instructor_id"` // ID инструктора, создавшего урок
-	CreatedAt    time.Time `json:"created_at"`    // Время создания
-	UpdatedAt    time.Time `json:"updated_at"`    // Время обновления
+	ID              uint       `gorm:"primaryKey" json:"id"`
+	Title           string     `json:"title"`                               // Название урока
+	Content         string     `json:"content"`                             // Описание или текст урока
+	VideoLink       string     `gorm:"index" json:"video_url"`              // Ссылка на видео; по ней находится видео урока
+	CourseID        uint       `json:"course_id"`                           // ID курса, к которому принадлежит урок
+	ModuleID        *uint      `gorm:"index" json:"module_id"`              // Раздел курса, nil - урок вне разделов
+	Position        int        `gorm:"default:0" json:"position"`           // Порядок урока внутри раздела
+	DripDays        int        `gorm:"default:0" json:"drip_days"`          // Урок открывается через N дней после записи
+	IsPreview       bool       `gorm:"default:false" json:"is_preview"`     // Бесплатный ознакомительный урок, доступен без записи
+	InstructorID    uint       `json:"instructor_id"`                       // ID инструктора, создавшего урок
+	CreatedAt       time.Time  `json:"created_at"`                          // Время создания
+	UpdatedAt       time.Time  `json:"updated_at"`                          // Время обновления
+	PrerequisiteIDs []uint     `gorm:"-" json:"prerequisite_ids,omitempty"` // Уроки, которые нужно пройти заранее
+	Locked          bool       `gorm:"-" json:"locked"`                     // Урок еще недоступен текущему пользователю
+	UnlockAt        *time.Time `gorm:"-" json:"unlock_at,omitempty"`        // Когда урок откроется по расписанию
+}
+
+// LessonPrerequisite - урок PrerequisiteID должен быть пройден до урока LessonID
+type LessonPrerequisite struct {
+	LessonID       uint `gorm:"primaryKey" json:"lesson_id"`
+	PrerequisiteID uint `gorm:"primaryKey" json:"prerequisite_id"`
+}
+
+// CourseModule - раздел курса, объединяющий уроки
+type CourseModule struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CourseID  uint      `gorm:"index;not null" json:"course_id"`
+	Title     string    `gorm:"not null" json:"title"`
+	Position  int       `gorm:"default:0" json:"position"`
+	Lessons   []Lesson  `gorm:"foreignKey:ModuleID" json:"lessons"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
 }
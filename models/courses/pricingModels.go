@@ -0,0 +1,63 @@
+package courses
+
+import "time"
+
+// Виды скидки по купону
+const (
+	CouponPercent = "percent" // Процент от цены
+	CouponAmount  = "amount"  // Фиксированная сумма в валюте купона
+)
+
+// CoursePrice - цена курса в валюте. Суммы хранятся в минимальных единицах (центы, копейки).
+// Цены общие для всех версий курса: CourseID - ID первой версии
+type CoursePrice struct {
+	ID           uint       `gorm:"primaryKey" json:"id"`
+	CourseID     uint       `gorm:"uniqueIndex:idx_course_price_currency;not null" json:"course_id"`
+	Currency     string     `gorm:"uniqueIndex:idx_course_price_currency;size:3;not null" json:"currency"` // ISO 4217, например USD
+	Amount       int64      `gorm:"not null" json:"amount"`
+	SaleAmount   *int64     `json:"sale_amount"`    // Цена распродажи, nil - распродажи нет
+	SaleStartsAt *time.Time `json:"sale_starts_at"` // nil - распродажа уже действует
+	SaleEndsAt   *time.Time `json:"sale_ends_at"`   // nil - без даты окончания
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// SeatPriceTier - скидка при покупке нескольких мест (для команд), например от 10 мест - 15%
+type SeatPriceTier struct {
+	ID              uint      `gorm:"primaryKey" json:"id"`
+	CourseID        uint      `gorm:"uniqueIndex:idx_seat_tier_min;not null" json:"course_id"` // ID первой версии курса
+	MinSeats        int       `gorm:"uniqueIndex:idx_seat_tier_min;not null" json:"min_seats"`
+	DiscountPercent int       `gorm:"not null" json:"discount_percent"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// Coupon - промокод на курс инструктора или, если CourseID = nil, на любой курс (создает администратор)
+type Coupon struct {
+	ID               uint       `gorm:"primaryKey" json:"id"`
+	Code             string     `gorm:"uniqueIndex;not null" json:"code"` // Хранится в верхнем регистре
+	CourseID         *uint      `gorm:"index" json:"course_id"`           // ID первой версии курса
+	CreatedByID      uint       `gorm:"not null" json:"created_by_id"`
+	DiscountType     string     `gorm:"not null" json:"discount_type"`
+	PercentOff       int        `gorm:"default:0" json:"percent_off"`
+	AmountOff        int64      `gorm:"default:0" json:"amount_off"` // В минимальных единицах Currency
+	Currency         string     `gorm:"size:3" json:"currency,omitempty"`
+	MaxRedemptions   int        `gorm:"default:0" json:"max_redemptions"` // 0 - без ограничения
+	MaxPerUser       int        `gorm:"default:1" json:"max_per_user"`    // 0 - без ограничения
+	RedemptionsCount int        `gorm:"default:0" json:"redemptions_count"`
+	StartsAt         *time.Time `json:"starts_at"`
+	ExpiresAt        *time.Time `json:"expires_at"`
+	IsActive         bool       `gorm:"default:true" json:"is_active"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+}
+
+// CouponRedemption - использование купона при записи на курс
+type CouponRedemption struct {
+	ID             uint      `gorm:"primaryKey" json:"id"`
+	CouponID       uint      `gorm:"index;not null" json:"coupon_id"`
+	UserID         uint      `gorm:"index;not null" json:"user_id"`
+	CourseID       uint      `gorm:"index;not null" json:"course_id"`
+	Currency       string    `gorm:"size:3" json:"currency"`
+	DiscountAmount int64     `json:"discount_amount"`
+	CreatedAt      time.Time `json:"created_at"`
+}
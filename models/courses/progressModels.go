@@ -6,11 +6,14 @@ import (
 )
 
 type Progress struct {
-	ID          uint       `gorm:"primaryKey"`
-	UserID      uint       `gorm:"not null"`
-	LessonID    uint       `gorm:"not null"`
-	Completed   bool       `gorm:"default:false"`
-	CompletedAt *time.Time // This field can be null
-	UpdatedAt   time.Time
-	DeletedAt   gorm.DeletedAt `gorm:"index"`
+	ID              uint           `gorm:"primaryKey" json:"id"`
+	UserID          uint           `gorm:"uniqueIndex:idx_progress_user_lesson;not null" json:"user_id"`
+	LessonID        uint           `gorm:"uniqueIndex:idx_progress_user_lesson;not null" json:"lesson_id"`
+	CourseID        uint           `gorm:"index" json:"course_id"` // Курс урока, для подсчета процента прохождения
+	Completed       bool           `gorm:"default:false" json:"completed"`
+	StartedAt       *time.Time     `json:"started_at"`
+	CompletedAt     *time.Time     `json:"completed_at"`                      // This field can be null
+	PositionSeconds int            `gorm:"default:0" json:"position_seconds"` // Позиция просмотра видео
+	UpdatedAt       time.Time      `json:"updated_at"`
+	DeletedAt       gorm.DeletedAt `gorm:"index" json:"-"`
 }
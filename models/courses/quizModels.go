@@ -0,0 +1,69 @@
+package courses
+
+import (
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+	"time"
+)
+
+// Типы вопросов
+const (
+	QuestionSingleChoice = "single_choice"
+	QuestionMultiSelect  = "multi_select"
+	QuestionShortAnswer  = "short_answer"
+	QuestionCodeSnippet  = "code_snippet"
+)
+
+// Quiz - тест к уроку; прохождение теста засчитывает урок
+type Quiz struct {
+	ID                  uint           `gorm:"primaryKey" json:"id"`
+	LessonID            uint           `gorm:"uniqueIndex;not null" json:"lesson_id"`
+	Title               string         `json:"title"`
+	PassingScore        int            `gorm:"default:70" json:"passing_score"`        // Проходной балл в процентах
+	MaxAttempts         int            `gorm:"default:0" json:"max_attempts"`          // 0 - без ограничений
+	QuestionsPerAttempt int            `gorm:"default:0" json:"questions_per_attempt"` // Случайная выборка из пула, 0 - все вопросы
+	Questions           []QuizQuestion `gorm:"foreignKey:QuizID" json:"questions,omitempty"`
+	CreatedAt           time.Time      `json:"created_at"`
+	UpdatedAt           time.Time      `json:"updated_at"`
+}
+
+// QuizQuestion - вопрос теста; правильные ответы не отдаются ученикам
+type QuizQuestion struct {
+	ID              uint           `gorm:"primaryKey" json:"id"`
+	QuizID          uint           `gorm:"index;not null" json:"quiz_id"`
+	Type            string         `gorm:"not null" json:"type"`
+	Text            string         `gorm:"type:text;not null" json:"text"`
+	Options         pq.StringArray `gorm:"type:text[]" json:"options,omitempty"` // Варианты для single_choice и multi_select
+	CorrectOptions  pq.Int64Array  `gorm:"type:integer[]" json:"-"`              // Индексы правильных вариантов
+	AcceptedAnswers pq.StringArray `gorm:"type:text[]" json:"-"`                 // Допустимые ответы для short_answer и code_snippet
+	Points          int            `gorm:"default:1" json:"points"`
+	Explanation     string         `gorm:"type:text" json:"-"` // Показывается при разборе попытки
+	Position        int            `gorm:"default:0" json:"position"`
+	DeletedAt       gorm.DeletedAt `gorm:"index" json:"-"` // Удаленные вопросы нужны для разбора старых попыток
+}
+
+// QuizAttempt - попытка прохождения теста
+type QuizAttempt struct {
+	ID          uint          `gorm:"primaryKey" json:"id"`
+	QuizID      uint          `gorm:"index;not null" json:"quiz_id"`
+	UserID      uint          `gorm:"index;not null" json:"user_id"`
+	QuestionIDs pq.Int64Array `gorm:"type:integer[]" json:"question_ids"` // Вопросы, выпавшие в этой попытке
+	Score       int           `gorm:"default:0" json:"score"`
+	MaxScore    int           `gorm:"default:0" json:"max_score"`
+	Percent     float64       `gorm:"default:0" json:"percent"`
+	Passed      bool          `gorm:"default:false" json:"passed"`
+	Answers     []QuizAnswer  `gorm:"foreignKey:AttemptID" json:"answers,omitempty"`
+	StartedAt   time.Time     `json:"started_at"`
+	SubmittedAt *time.Time    `json:"submitted_at"` // nil - попытка еще не завершена
+}
+
+// QuizAnswer - ответ на вопрос в рамках попытки
+type QuizAnswer struct {
+	ID              uint          `gorm:"primaryKey" json:"id"`
+	AttemptID       uint          `gorm:"index;not null" json:"attempt_id"`
+	QuestionID      uint          `gorm:"not null" json:"question_id"`
+	SelectedOptions pq.Int64Array `gorm:"type:integer[]" json:"selected_options"`
+	TextAnswer      string        `gorm:"type:text" json:"text_answer"`
+	IsCorrect       bool          `json:"is_correct"`
+	PointsAwarded   int           `json:"points_awarded"`
+}
@@ -0,0 +1,68 @@
+package courses
+
+import (
+	"github.com/lib/pq"
+	"time"
+)
+
+// Роли в команде курса
+const (
+	CourseRoleOwner        = "owner"              // Автор курса (Course.InstructorID)
+	CourseRoleCoInstructor = "co_instructor"      // Соавтор: программа, видео, обсуждения, аналитика
+	CourseRoleAssistant    = "teaching_assistant" // Ассистент: ответы на вопросы учеников
+)
+
+// Права участников команды курса
+const (
+	PermissionEditLessons     = "edit_lessons"     // Программа курса, уроки и тесты
+	PermissionUploadVideos    = "upload_videos"    // Загрузка видео к урокам
+	PermissionAnswerQuestions = "answer_questions" // Ответы на вопросы и отзывы от имени курса
+	PermissionViewAnalytics   = "view_analytics"   // Ученики, прогресс и статистика курса
+)
+
+// AllCoursePermissions - полный набор прав, он же права владельца
+var AllCoursePermissions = []string{PermissionEditLessons, PermissionUploadVideos, PermissionAnswerQuestions, PermissionViewAnalytics}
+
+// DefaultRolePermissions - права роли по умолчанию; владелец курса может назначить участнику свой набор
+var DefaultRolePermissions = map[string][]string{
+	CourseRoleOwner:        AllCoursePermissions,
+	CourseRoleCoInstructor: AllCoursePermissions,
+	CourseRoleAssistant:    {PermissionAnswerQuestions},
+}
+
+// Статусы приглашения в команду курса
+const (
+	InvitationPending  = "pending"
+	InvitationAccepted = "accepted"
+	InvitationDeclined = "declined"
+	InvitationRevoked  = "revoked"
+)
+
+// CourseCollaborator - участник команды курса, кроме владельца.
+// Команда общая для всех версий курса: CourseID - ID первой версии
+type CourseCollaborator struct {
+	ID          uint           `gorm:"primaryKey" json:"id"`
+	CourseID    uint           `gorm:"uniqueIndex:idx_course_collaborator;not null" json:"course_id"`
+	UserID      uint           `gorm:"uniqueIndex:idx_course_collaborator;index;not null" json:"user_id"`
+	Role        string         `gorm:"not null" json:"role"`
+	Permissions pq.StringArray `gorm:"type:text[]" json:"permissions"`
+	InvitedByID uint           `json:"invited_by_id"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+}
+
+// CourseInvitation - приглашение в команду курса по email
+type CourseInvitation struct {
+	ID          uint           `gorm:"primaryKey" json:"id"`
+	CourseID    uint           `gorm:"index;not null" json:"course_id"` // ID первой версии курса
+	Email       string         `gorm:"index;not null" json:"email"`
+	Role        string         `gorm:"not null" json:"role"`
+	Permissions pq.StringArray `gorm:"type:text[]" json:"permissions"`
+	Token       string         `gorm:"uniqueIndex;not null" json:"-"` // Секрет ссылки из письма
+	InvitedByID uint           `gorm:"not null" json:"invited_by_id"`
+	Status      string         `gorm:"default:pending;index" json:"status"`
+	ExpiresAt   time.Time      `json:"expires_at"`
+	RespondedAt *time.Time     `json:"responded_at"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+}
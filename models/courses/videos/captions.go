@@ -0,0 +1,38 @@
+package videos
+
+import "time"
+
+// CaptionTrack - субтитры видео на одном языке; хранятся в WebVTT независимо от формата загрузки
+type CaptionTrack struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	VideoID   uint      `gorm:"uniqueIndex:idx_caption_video_language;not null" json:"video_id"`
+	Language  string    `gorm:"uniqueIndex:idx_caption_video_language;not null" json:"language"` // Код языка: en, ru, pt-BR
+	Label     string    `json:"label"`                                                           // Название дорожки в плеере
+	Format    string    `json:"format"`                                                          // Формат исходного файла: vtt или srt
+	Content   string    `gorm:"type:text;not null" json:"-"`
+	CuesCount int       `gorm:"default:0" json:"cues_count"`
+	IsDefault bool      `gorm:"default:false" json:"is_default"` // Дорожка, включаемая плеером по умолчанию
+	CreatedBy uint      `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TranscriptSegment - фрагмент расшифровки видео с временем начала; по ним ищется место в уроке
+type TranscriptSegment struct {
+	ID           uint    `gorm:"primaryKey" json:"id"`
+	VideoID      uint    `gorm:"index:idx_transcript_video_language;not null" json:"video_id"`
+	Language     string  `gorm:"index:idx_transcript_video_language;not null" json:"language"`
+	Position     int     `gorm:"default:0" json:"position"`
+	StartSeconds float64 `gorm:"not null" json:"start_seconds"`
+	EndSeconds   float64 `json:"end_seconds"`
+	Text         string  `gorm:"type:text;not null" json:"text"`
+}
+
+// VideoChapter - глава видео с временем начала
+type VideoChapter struct {
+	ID           uint    `gorm:"primaryKey" json:"id"`
+	VideoID      uint    `gorm:"index;not null" json:"video_id"`
+	Title        string  `gorm:"not null" json:"title"`
+	StartSeconds float64 `gorm:"not null" json:"start_seconds"`
+	Position     int     `gorm:"default:0" json:"position"`
+}
@@ -1,15 +1,63 @@
 package videos
 
-import "time"
+import (
+	"github.com/lib/pq"
+	"time"
+)
+
+// Статусы обработки видео
+const (
+	VideoUploading  = "uploading"  // Файл загружается частями во временное хранилище
+	VideoQueued     = "queued"     // Файл проверен и ждет публикации
+	VideoPublishing = "publishing" // Фоновый обработчик загружает файл на YouTube
+	VideoReady      = "ready"      // Видео опубликовано
+	VideoFailed     = "failed"     // Файл не прошел проверку или публикация не удалась
+	VideoCancelled  = "cancelled"  // Загрузка отменена пользователем
+)
+
+// Где размещается видео
+const (
+	HostYouTube = "youtube" // Канал Google-аккаунта загрузившего, доступ по ссылке (unlisted)
+	HostSelf    = "self"    // Собственное хранилище платформы, просмотр по подписанным ссылкам
+)
+
+// Куда публикуется видео
+const (
+	TargetLesson  = "lesson"  // Видео урока
+	TargetContent = "content" // Новая запись в разделе контента
+)
 
 type Video struct {
 	ID          uint      `gorm:"primaryKey" json:"id"`
 	Title       string    `json:"title"`
 	Description string    `json:"description"`
-	VideoLink   string    `json:"video_link"`
+	VideoLink   string    `gorm:"index" json:"video_link"`
 	YouTubeID   string    `json:"youtube_id"`
 	UploadedBy  uint      `json:"uploaded_by"` // ID пользователя, загрузившего видео
 	LessonID    uint      `json:"lesson_id"`   // Связь с уроком
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
+
+	// Конвейер загрузки; видео, загруженные до его появления, считаются опубликованными
+	Status          string         `gorm:"index;not null;default:ready" json:"status"`
+	TargetType      string         `gorm:"not null;default:lesson" json:"target_type"`
+	Host            string         `gorm:"not null;default:youtube" json:"host"`
+	StorageKey      string         `json:"-"`                       // Ключ файла в собственном хранилище (HostSelf)
+	ContentID       *uint          `json:"content_id,omitempty"`    // Запись контента, созданная при публикации
+	Category        string         `json:"category,omitempty"`      // Категория для TargetContent
+	Tags            pq.StringArray `gorm:"type:text[]" json:"tags"` // Теги для TargetContent
+	FileName        string         `json:"file_name"`               // Имя исходного файла
+	SizeBytes       int64          `gorm:"default:0" json:"size_bytes"`
+	ReceivedBytes   int64          `gorm:"default:0" json:"received_bytes"` // Смещение для продолжения загрузки
+	StagingPath     string         `json:"-"`
+	Container       string         `json:"container,omitempty"` // mp4, mov, webm, mkv
+	VideoCodec      string         `json:"video_codec,omitempty"`
+	AudioCodec      string         `json:"audio_codec,omitempty"`
+	DurationSeconds float64        `gorm:"default:0" json:"duration_seconds"`
+	Attempts        int            `gorm:"default:0" json:"attempts"` // Попытки публикации
+	NextAttemptAt   *time.Time     `gorm:"index" json:"next_attempt_at"`
+	LastError       string         `gorm:"type:text" json:"last_error,omitempty"`
+	PublishedAt     *time.Time     `json:"published_at"`
+	GoogleEmail     string         `json:"-"`                  // Google-аккаунт, на канал которого публикуется видео
+	GoogleToken     string         `gorm:"type:text" json:"-"` // Токен доступа, переданный при загрузке
 }
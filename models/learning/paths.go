@@ -0,0 +1,73 @@
+package learning
+
+import (
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+	"time"
+)
+
+// Статусы учебного пути
+const (
+	PathStatusDraft     = "draft"     // Виден только автору
+	PathStatusPublished = "published" // Доступен для записи
+	PathStatusArchived  = "archived"  // Снят с публикации; записанные пользователи продолжают обучение
+)
+
+// Типы шагов учебного пути
+const (
+	StepCourse        = "course"         // Пройти курс (любую его версию)
+	StepContent       = "content"        // Посмотреть видео из раздела контента
+	StepQuiz          = "quiz"           // Сдать тест урока
+	StepMentorSession = "mentor_session" // Провести сессию с ментором, владеющим навыком Skill
+)
+
+// Path - учебный путь: упорядоченная последовательность курсов, контента, тестов и сессий с менторами.
+// Личный путь (OwnerID != nil) создается из карьерного плана и виден только владельцу
+type Path struct {
+	ID           uint           `gorm:"primaryKey" json:"id"`
+	Title        string         `gorm:"not null" json:"title"`
+	Description  string         `gorm:"type:text" json:"description"`
+	Tags         pq.StringArray `gorm:"type:text[]" json:"tags"`
+	AuthorID     uint           `gorm:"index;not null" json:"author_id"`
+	Status       string         `gorm:"index;not null;default:draft" json:"status"`
+	OwnerID      *uint          `gorm:"index" json:"owner_id,omitempty"`
+	CareerPlanID *uint          `gorm:"index" json:"career_plan_id,omitempty"`
+	Steps        []PathStep     `gorm:"foreignKey:PathID" json:"steps,omitempty"`
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+	DeletedAt    gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// PathStep - шаг учебного пути; заполняется ссылка, соответствующая Type
+type PathStep struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	PathID      uint      `gorm:"index;not null" json:"path_id"`
+	Position    int       `gorm:"default:0" json:"position"`
+	Type        string    `gorm:"not null" json:"type"`
+	Title       string    `json:"title"`
+	Description string    `gorm:"type:text" json:"description"`
+	CourseID    *uint     `json:"course_id,omitempty"` // ID первой версии курса
+	ContentID   *uint     `json:"content_id,omitempty"`
+	QuizID      *uint     `json:"quiz_id,omitempty"`
+	Skill       string    `json:"skill,omitempty"` // Навык ментора для mentor_session
+	Optional    bool      `gorm:"default:false" json:"optional"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// PathEnrollment - запись пользователя на учебный путь
+type PathEnrollment struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	PathID      uint       `gorm:"uniqueIndex:idx_path_enrollment;not null" json:"path_id"`
+	UserID      uint       `gorm:"uniqueIndex:idx_path_enrollment;index;not null" json:"user_id"`
+	CompletedAt *time.Time `json:"completed_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// StepCompletion - выполненный шаг пути; Manual - отмечен пользователем, а не засчитан автоматически
+type StepCompletion struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	EnrollmentID uint      `gorm:"uniqueIndex:idx_step_completion;not null" json:"enrollment_id"`
+	StepID       uint      `gorm:"uniqueIndex:idx_step_completion;not null" json:"step_id"`
+	Manual       bool      `gorm:"default:false" json:"manual"`
+	CompletedAt  time.Time `json:"completed_at"`
+}
@@ -1,17 +1,36 @@
 package users
 
-import "time"
+import (
+	"github.com/lib/pq"
+	"time"
+)
+
+// Статусы профиля ментора
+const (
+	MentorActive      = "active"
+	MentorPaused      = "paused"
+	MentorDeactivated = "deactivated"
+)
 
 type MentorProfile struct {
-	ID             uint `gorm:"primaryKey;autoIncrement:false"` // Отключаем автоинкремент
-	UserID         uint `gorm:"index;unique"`
-	User           User `gorm:"constraint:OnDelete:CASCADE;"`
-	Bio            string
-	Skills         string
-	PricePerHour   float64
-	AvailableSlots []Slot `gorm:"foreignKey:MentorID"`
-	CreatedAt      time.Time
-	UpdatedAt      time.Time
+	ID                uint   `gorm:"primaryKey;autoIncrement:false"` // Отключаем автоинкремент
+	UserID            uint   `gorm:"index;unique"`
+	User              User   `gorm:"constraint:OnDelete:CASCADE;"`
+	Status            string `gorm:"index;not null;default:active"`
+	Headline          string
+	Bio               string
+	Skills            string
+	SkillTags         []Skill        `gorm:"many2many:mentor_skills"` // Канонические навыки из таблицы skills
+	Languages         pq.StringArray `gorm:"type:text[]"`             // Языки, на которых проводятся сессии
+	PricePerHour      float64
+	YearsOfExperience int
+	LinkedInURL       string
+	VerifiedAt        *time.Time // Время одобрения заявки администратором
+	AvailableSlots    []Slot     `gorm:"foreignKey:MentorID"`
+	AverageRating     float64    `gorm:"default:0"` // Средняя оценка по видимым отзывам
+	ReviewsCount      int        `gorm:"default:0"` // Количество видимых отзывов
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
 }
 
 type Slot struct {
@@ -21,8 +40,17 @@ type Slot struct {
 	StartTime time.Time `gorm:"not null" json:"start_time"`
 	EndTime   time.Time `gorm:"not null" json:"end_time"`
 	IsBooked  bool      `gorm:"default:false" json:"is_booked"`
-	CreatedAt time.Time `json:"created_at"`
-	User      User      `gorm:"foreignKey:UserID" json:"user"` // Связь с пользователем
+	// Стоимость сессии по цене ментора на момент бронирования; nil - слот свободен или оплачен кредитом пакета
+	Price *float64 `json:"price,omitempty"`
+	// Слот удерживается для пользователя из листа ожидания до HeldUntil
+	HeldForUserID *uint      `json:"held_for_user_id"`
+	HeldUntil     *time.Time `json:"held_until"`
+	// Данные видеовстречи видны только участникам через /mentors/meeting
+	MeetingProvider string    `json:"-"`
+	MeetingRoomID   string    `json:"-"`
+	MeetingURL      string    `gorm:"type:text" json:"-"`
+	CreatedAt       time.Time `json:"created_at"`
+	User            User      `gorm:"foreignKey:UserID" json:"user"` // Связь с пользователем
 }
 
 type NotificationMentor struct {
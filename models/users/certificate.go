@@ -0,0 +1,18 @@
+package users
+
+import "time"
+
+// Certificate - сертификат о прохождении курса с публичной проверкой по VerificationID
+type Certificate struct {
+	ID               uint       `gorm:"primaryKey" json:"id"`
+	VerificationID   string     `gorm:"uniqueIndex;not null" json:"verification_id"`
+	UserID           uint       `gorm:"uniqueIndex:idx_certificate_user_course;not null" json:"user_id"`
+	CourseID         uint       `gorm:"uniqueIndex:idx_certificate_user_course;index;not null" json:"course_id"`
+	RecipientName    string     `json:"recipient_name"` // Данные на момент выдачи
+	CourseTitle      string     `json:"course_title"`
+	InstructorName   string     `json:"instructor_name"`
+	IssuedAt         time.Time  `json:"issued_at"`
+	RevokedAt        *time.Time `json:"revoked_at"`
+	RevokedByID      *uint      `json:"-"`
+	RevocationReason string     `json:"revocation_reason,omitempty"`
+}
@@ -0,0 +1,13 @@
+package users
+
+import "time"
+
+// EmailVerification - ссылка подтверждения адреса email; действует только для адреса, на который отправлена
+type EmailVerification struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	UserID    uint      `gorm:"index;not null" json:"user_id"`
+	Email     string    `gorm:"not null" json:"email"`
+	Token     string    `gorm:"uniqueIndex;not null" json:"-"`
+	ExpiresAt time.Time `gorm:"not null" json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
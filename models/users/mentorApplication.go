@@ -0,0 +1,41 @@
+package users
+
+import (
+	"github.com/lib/pq"
+	"time"
+)
+
+// Статусы заявки на роль ментора
+const (
+	ApplicationPending  = "pending"
+	ApplicationApproved = "approved"
+	ApplicationRejected = "rejected"
+	ApplicationRevoked  = "revoked" // Одобрение отозвано администратором
+)
+
+type MentorApplication struct {
+	ID                uint                         `gorm:"primaryKey" json:"id"`
+	UserID            uint                         `gorm:"index;not null" json:"user_id"`
+	Status            string                       `gorm:"index;not null;default:pending" json:"status"`
+	Headline          string                       `json:"headline"`
+	Bio               string                       `gorm:"type:text" json:"bio"`
+	Skills            string                       `json:"skills"` // Навыки через запятую
+	Languages         pq.StringArray               `gorm:"type:text[]" json:"languages"`
+	YearsOfExperience int                          `json:"years_of_experience"`
+	LinkedInURL       string                       `json:"linkedin_url"`
+	PricePerHour      float64                      `json:"price_per_hour"`
+	Documents         []MentorVerificationDocument `gorm:"foreignKey:ApplicationID" json:"documents"`
+	ReviewerID        *uint                        `json:"reviewer_id"` // Администратор, рассмотревший заявку
+	ReviewNote        string                       `gorm:"type:text" json:"review_note"`
+	ReviewedAt        *time.Time                   `json:"reviewed_at"`
+	CreatedAt         time.Time                    `json:"created_at"`
+	UpdatedAt         time.Time                    `json:"updated_at"`
+}
+
+type MentorVerificationDocument struct {
+	ID            uint      `gorm:"primaryKey" json:"id"`
+	ApplicationID uint      `gorm:"index;not null" json:"application_id"`
+	Type          string    `gorm:"not null" json:"type"` // Например: diploma, certificate, employment
+	URL           string    `gorm:"type:text;not null" json:"url"`
+	CreatedAt     time.Time `json:"created_at"`
+}
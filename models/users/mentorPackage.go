@@ -0,0 +1,54 @@
+package users
+
+import "time"
+
+// Статусы покупки пакета
+const (
+	PurchaseActive   = "active"
+	PurchaseExpired  = "expired"
+	PurchaseRefunded = "refunded"
+)
+
+// MentorPackage - пакет из нескольких сессий, например 4 × 45 минут за 2 месяца
+type MentorPackage struct {
+	ID             uint      `gorm:"primaryKey" json:"id"`
+	MentorID       uint      `gorm:"index;not null" json:"mentor_id"` // ID профиля ментора
+	Title          string    `gorm:"not null" json:"title"`
+	Description    string    `gorm:"type:text" json:"description"`
+	SessionsCount  int       `gorm:"not null" json:"sessions_count"`
+	SessionMinutes int       `gorm:"not null" json:"session_minutes"`
+	ValidityDays   int       `gorm:"not null" json:"validity_days"`
+	Price          float64   `gorm:"not null" json:"price"`
+	RefundPercent  int       `gorm:"default:0" json:"refund_percent"` // Доля стоимости неиспользованных сессий к возврату
+	IsActive       bool      `gorm:"default:true" json:"is_active"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+type PackagePurchase struct {
+	ID               uint          `gorm:"primaryKey" json:"id"`
+	PackageID        uint          `gorm:"index;not null" json:"package_id"`
+	Package          MentorPackage `gorm:"foreignKey:PackageID" json:"package"`
+	MentorID         uint          `gorm:"index;not null" json:"mentor_id"`
+	UserID           uint          `gorm:"index;not null" json:"user_id"`
+	CreditsTotal     int           `gorm:"not null" json:"credits_total"`
+	CreditsRemaining int           `gorm:"not null" json:"credits_remaining"`
+	PricePaid        float64       `gorm:"not null" json:"price_paid"`
+	Status           string        `gorm:"index;not null;default:active" json:"status"`
+	ExpiresAt        time.Time     `gorm:"index;not null" json:"expires_at"`
+	RefundedAmount   float64       `gorm:"default:0" json:"refunded_amount"`
+	RefundedAt       *time.Time    `json:"refunded_at"`
+	CreditPoolID     *uint         `gorm:"index" json:"credit_pool_id,omitempty"` // Кредиты выданы организацией из OrganizationCreditPool
+	PaymentID        *uint         `gorm:"index" json:"payment_id,omitempty"`     // Оплата пакета (billing.Payment); nil - бесплатный пакет
+	CreatedAt        time.Time     `json:"created_at"`
+	UpdatedAt        time.Time     `json:"updated_at"`
+}
+
+// CreditRedemption - списание кредита пакета на конкретный слот
+type CreditRedemption struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	PurchaseID uint       `gorm:"index;not null" json:"purchase_id"`
+	SlotID     uint       `gorm:"index;not null" json:"slot_id"`
+	ReturnedAt *time.Time `json:"returned_at"` // Кредит возвращен после отмены бронирования
+	CreatedAt  time.Time  `json:"created_at"`
+}
@@ -0,0 +1,26 @@
+package users
+
+import "time"
+
+type MentorReview struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	MentorID   uint       `gorm:"index;not null" json:"mentor_id"`     // ID профиля ментора
+	SlotID     uint       `gorm:"uniqueIndex;not null" json:"slot_id"` // Один отзыв на одно бронирование
+	UserID     uint       `gorm:"index;not null" json:"user_id"`       // Автор отзыва (менти)
+	Rating     int        `gorm:"not null" json:"rating"`              // Оценка от 1 до 5
+	Comment    string     `gorm:"type:text" json:"comment"`
+	Reply      string     `gorm:"type:text" json:"reply"` // Ответ ментора
+	RepliedAt  *time.Time `json:"replied_at"`
+	FlagsCount int        `gorm:"default:0" json:"flags_count"`   // Количество жалоб
+	IsHidden   bool       `gorm:"default:false" json:"is_hidden"` // Скрыт модератором
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+type MentorReviewFlag struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	ReviewID  uint      `gorm:"uniqueIndex:idx_review_flag_user;not null" json:"review_id"`
+	UserID    uint      `gorm:"uniqueIndex:idx_review_flag_user;not null" json:"user_id"`
+	Reason    string    `gorm:"type:text" json:"reason"`
+	CreatedAt time.Time `json:"created_at"`
+}
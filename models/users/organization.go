@@ -0,0 +1,114 @@
+package users
+
+import (
+	"github.com/lib/pq"
+	"time"
+)
+
+// Роли участника организации
+const (
+	OrgRoleAdmin  = "admin"  // Управляет участниками, местами и кредитами
+	OrgRoleMember = "member" // Сотрудник, получает курсы и кредиты от организации
+)
+
+// Статусы приглашения в организацию
+const (
+	OrgInvitationPending  = "pending"
+	OrgInvitationAccepted = "accepted"
+	OrgInvitationDeclined = "declined"
+	OrgInvitationRevoked  = "revoked"
+)
+
+// Organization - аккаунт компании, покупающей обучение и менторство для сотрудников
+type Organization struct {
+	ID           uint           `gorm:"primaryKey" json:"id"`
+	Name         string         `gorm:"not null" json:"name"`
+	EmailDomains pq.StringArray `gorm:"type:text[]" json:"email_domains"` // Заявленные домены; вступление - по подтвержденным OrganizationDomain
+	CreatedByID  uint           `gorm:"not null" json:"created_by_id"`
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+}
+
+// OrganizationDomain - домен email, заявленный организацией; вступить по нему можно только после того,
+// как владение доменом подтверждено TXT-записью с Token
+type OrganizationDomain struct {
+	ID             uint       `gorm:"primaryKey" json:"id"`
+	OrganizationID uint       `gorm:"uniqueIndex:idx_org_domain;not null" json:"organization_id"`
+	Domain         string     `gorm:"uniqueIndex:idx_org_domain;index;not null" json:"domain"`
+	Token          string     `gorm:"not null" json:"-"`
+	VerifiedAt     *time.Time `json:"verified_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// OrganizationMember - участник организации
+type OrganizationMember struct {
+	ID             uint      `gorm:"primaryKey" json:"id"`
+	OrganizationID uint      `gorm:"uniqueIndex:idx_org_member;not null" json:"organization_id"`
+	UserID         uint      `gorm:"uniqueIndex:idx_org_member;index;not null" json:"user_id"`
+	Role           string    `gorm:"not null;default:member" json:"role"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// OrganizationInvitation - приглашение сотрудника в организацию по email
+type OrganizationInvitation struct {
+	ID             uint       `gorm:"primaryKey" json:"id"`
+	OrganizationID uint       `gorm:"index;not null" json:"organization_id"`
+	Email          string     `gorm:"index;not null" json:"email"` // В нижнем регистре
+	Role           string     `gorm:"not null" json:"role"`
+	Token          string     `gorm:"uniqueIndex;not null" json:"-"`
+	InvitedByID    uint       `gorm:"not null" json:"invited_by_id"`
+	Status         string     `gorm:"index;not null;default:pending" json:"status"`
+	ExpiresAt      time.Time  `json:"expires_at"`
+	RespondedAt    *time.Time `json:"responded_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// OrganizationCourseSeats - места на курс, купленные организацией. CourseID - ID первой версии курса
+type OrganizationCourseSeats struct {
+	ID             uint      `gorm:"primaryKey" json:"id"`
+	OrganizationID uint      `gorm:"uniqueIndex:idx_org_course_seats;not null" json:"organization_id"`
+	CourseID       uint      `gorm:"uniqueIndex:idx_org_course_seats;not null" json:"course_id"`
+	SeatsTotal     int       `gorm:"not null" json:"seats_total"`
+	SeatsUsed      int       `gorm:"default:0" json:"seats_used"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// OrganizationSeatPurchase - покупка мест на курс; суммы в минимальных единицах Currency
+type OrganizationSeatPurchase struct {
+	ID             uint      `gorm:"primaryKey" json:"id"`
+	OrganizationID uint      `gorm:"index;not null" json:"organization_id"`
+	CourseID       uint      `gorm:"index;not null" json:"course_id"` // ID первой версии курса
+	PurchasedByID  uint      `gorm:"not null" json:"purchased_by_id"`
+	Seats          int       `gorm:"not null" json:"seats"`
+	Currency       string    `gorm:"size:3;not null" json:"currency"`
+	AmountPaid     int64     `gorm:"not null" json:"amount_paid"`
+	CouponID       *uint     `json:"coupon_id,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// OrganizationSeatAssignment - место на курсе, выданное участнику; EnrollmentID - созданная запись на курс
+type OrganizationSeatAssignment struct {
+	ID             uint      `gorm:"primaryKey" json:"id"`
+	OrganizationID uint      `gorm:"uniqueIndex:idx_org_seat_assignment;not null" json:"organization_id"`
+	CourseID       uint      `gorm:"uniqueIndex:idx_org_seat_assignment;not null" json:"course_id"` // ID первой версии курса
+	UserID         uint      `gorm:"uniqueIndex:idx_org_seat_assignment;index;not null" json:"user_id"`
+	EnrollmentID   uint      `gorm:"not null" json:"enrollment_id"`
+	AssignedByID   uint      `gorm:"not null" json:"assigned_by_id"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// OrganizationCreditPool - сессии пакета ментора, купленные организацией для распределения участникам
+type OrganizationCreditPool struct {
+	ID               uint          `gorm:"primaryKey" json:"id"`
+	OrganizationID   uint          `gorm:"index;not null" json:"organization_id"`
+	PackageID        uint          `gorm:"index;not null" json:"package_id"`
+	Package          MentorPackage `gorm:"foreignKey:PackageID" json:"package"`
+	MentorID         uint          `gorm:"index;not null" json:"mentor_id"` // ID профиля ментора
+	PurchasedByID    uint          `gorm:"not null" json:"purchased_by_id"`
+	CreditsTotal     int           `gorm:"not null" json:"credits_total"`
+	CreditsAllocated int           `gorm:"default:0" json:"credits_allocated"`
+	PricePaid        float64       `gorm:"not null" json:"price_paid"`
+	ExpiresAt        time.Time     `gorm:"index;not null" json:"expires_at"`
+	CreatedAt        time.Time     `json:"created_at"`
+}
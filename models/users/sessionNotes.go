@@ -0,0 +1,35 @@
+package users
+
+import "time"
+
+// Видимость заметки по сессии
+const (
+	NotePrivate = "private" // Видна только автору-ментору
+	NoteShared  = "shared"  // Видна ментору и менти
+)
+
+type SessionNote struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	SlotID     uint      `gorm:"index;not null" json:"slot_id"`
+	MenteeID   uint      `gorm:"index;not null;default:0" json:"mentee_id"` // Менти бронирования: слот после отмены может занять другой
+	AuthorID   uint      `gorm:"index;not null" json:"author_id"`
+	Visibility string    `gorm:"not null;default:shared" json:"visibility"`
+	Content    string    `gorm:"type:text;not null" json:"content"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+type ActionItem struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	SlotID      uint       `gorm:"index;not null" json:"slot_id"`
+	MenteeID    uint       `gorm:"index;not null" json:"mentee_id"`
+	CreatedByID uint       `gorm:"not null" json:"created_by_id"`
+	Title       string     `gorm:"not null" json:"title"`
+	Description string     `gorm:"type:text" json:"description"`
+	DueDate     *time.Time `json:"due_date"`
+	Completed   bool       `gorm:"default:false" json:"completed"`
+	CompletedAt *time.Time `json:"completed_at"`
+	PlanStepID  *uint      `gorm:"index" json:"plan_step_id"` // Шаг карьерного плана, в который перенесена задача
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
@@ -10,6 +10,7 @@ type User struct {
 	ID                 uint          `gorm:"primaryKey"`
 	Name               string        `json:"name"`
 	Email              string        `json:"email" gorm:"unique;not null"`
+	EmailVerifiedAt    *time.Time    `json:"email_verified_at"` // Владение адресом подтверждено письмом или OAuth-провайдером
 	Password           string        `json:"-" gorm:"not null"`
 	Company            string        `json:"company"`
 	Industry           string        `json:"industry"`
@@ -24,7 +25,8 @@ type User struct {
 	AccessToken        string        `json:"accessToken"`
 	RefreshToken       string        `json:"refreshToken"`
 	Provider           string        `json:"provider"`
-	Stories            []story.Story `gorm:"foreignKey:UserID"` // Связь с историями
+	Stories            []story.Story `gorm:"foreignKey:UserID"`                     // Связь с историями
+	Certificates       []Certificate `json:"certificates" gorm:"foreignKey:UserID"` // Сертификаты о прохождении курсов
 	CreatedAt          time.Time
 	UpdatedAt          time.Time
 	DeletedAt          gorm.DeletedAt `gorm:"index"`
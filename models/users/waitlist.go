@@ -0,0 +1,42 @@
+package users
+
+import "time"
+
+// Статусы записи в листе ожидания
+const (
+	WaitlistWaiting   = "waiting"
+	WaitlistOffered   = "offered"
+	WaitlistClaimed   = "claimed"
+	WaitlistExpired   = "expired"
+	WaitlistCancelled = "cancelled"
+)
+
+type WaitlistEntry struct {
+	ID             uint             `gorm:"primaryKey" json:"id"`
+	MentorID       uint             `gorm:"index;not null" json:"mentor_id"` // ID профиля ментора
+	UserID         uint             `gorm:"index;not null" json:"user_id"`
+	Status         string           `gorm:"index;not null;default:waiting" json:"status"`
+	Windows        []WaitlistWindow `gorm:"foreignKey:EntryID" json:"windows"` // Пустой список - подходит любое время
+	OfferedSlotID  *uint            `json:"offered_slot_id"`
+	OfferExpiresAt *time.Time       `json:"offer_expires_at"`
+	CreatedAt      time.Time        `json:"created_at"`
+	UpdatedAt      time.Time        `json:"updated_at"`
+}
+
+type WaitlistWindow struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	EntryID   uint      `gorm:"index;not null" json:"entry_id"`
+	StartTime time.Time `gorm:"not null" json:"start_time"`
+	EndTime   time.Time `gorm:"not null" json:"end_time"`
+}
+
+// BookingCancellation - история отмен бронирований
+type BookingCancellation struct {
+	ID            uint      `gorm:"primaryKey" json:"id"`
+	SlotID        uint      `gorm:"index;not null" json:"slot_id"`
+	MentorID      uint      `gorm:"index;not null" json:"mentor_id"`
+	UserID        uint      `gorm:"index;not null" json:"user_id"` // Менти, чье бронирование отменено
+	CancelledByID uint      `gorm:"not null" json:"cancelled_by_id"`
+	Reason        string    `gorm:"type:text" json:"reason"`
+	CreatedAt     time.Time `json:"created_at"`
+}
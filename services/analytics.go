@@ -0,0 +1,15 @@
+package services
+
+import (
+	"hired-valley-backend/config"
+	"hired-valley-backend/models/analytics"
+	"log"
+)
+
+// RecordView - запись просмотра курса или контента для аналитики авторов
+func RecordView(itemType string, itemID, userID uint) {
+	event := analytics.ViewEvent{ItemType: itemType, ItemID: itemID, UserID: userID}
+	if err := config.DB.Create(&event).Error; err != nil {
+		log.Printf("Ошибка записи просмотра %s %d: %v", itemType, itemID, err)
+	}
+}
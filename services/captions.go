@@ -0,0 +1,320 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"hired-valley-backend/config"
+	"hired-valley-backend/models/courses"
+	"hired-valley-backend/models/courses/videos"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrInvalidCaptions = errors.New("invalid caption file")
+	ErrInvalidLanguage = errors.New("language must be a language code such as en, ru or pt-BR")
+	ErrInvalidChapters = errors.New("invalid chapters")
+)
+
+const (
+	// MaxCaptionFileBytes - максимальный размер файла субтитров
+	MaxCaptionFileBytes = 2 << 20
+	// CaptionFormatVTT и CaptionFormatSRT - поддерживаемые форматы субтитров
+	CaptionFormatVTT = "vtt"
+	CaptionFormatSRT = "srt"
+)
+
+var languageCodePattern = regexp.MustCompile(`^[a-zA-Z]{2,3}(-[a-zA-Z0-9]{2,8})*$`)
+
+// NormalizeLanguage - проверка кода языка; основной код в нижнем регистре (en, pt-BR)
+func NormalizeLanguage(language string) (string, error) {
+	language = strings.TrimSpace(language)
+	if !languageCodePattern.MatchString(language) {
+		return "", ErrInvalidLanguage
+	}
+	parts := strings.SplitN(language, "-", 2)
+	parts[0] = strings.ToLower(parts[0])
+	return strings.Join(parts, "-"), nil
+}
+
+// CaptionCue - одна реплика субтитров
+type CaptionCue struct {
+	Start float64
+	End   float64
+	Text  string
+}
+
+// DetectCaptionFormat - формат по расширению файла, а при неизвестном расширении - по заголовку WEBVTT
+func DetectCaptionFormat(fileName string, data []byte) string {
+	switch strings.ToLower(strings.TrimPrefix(filepath.Ext(fileName), ".")) {
+	case CaptionFormatVTT:
+		return CaptionFormatVTT
+	case CaptionFormatSRT:
+		return CaptionFormatSRT
+	}
+	if strings.HasPrefix(strings.TrimPrefix(string(data), "\ufeff"), "WEBVTT") {
+		return CaptionFormatVTT
+	}
+	return CaptionFormatSRT
+}
+
+// ParseCaptions - разбор файла WebVTT или SRT. Блоки без времени (NOTE, STYLE, REGION) пропускаются,
+// оформление реплик сохраняется
+func ParseCaptions(data []byte, format string) ([]CaptionCue, error) {
+	text := strings.TrimPrefix(string(data), "\ufeff")
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\r", "\n")
+
+	if format == CaptionFormatVTT {
+		if !strings.HasPrefix(text, "WEBVTT") {
+			return nil, fmt.Errorf("%w: WebVTT file must start with WEBVTT", ErrInvalidCaptions)
+		}
+	}
+
+	var cues []CaptionCue
+	for number, block := range strings.Split(text, "\n\n") {
+		block = strings.Trim(block, "\n")
+		if block == "" {
+			continue
+		}
+		lines := strings.Split(block, "\n")
+		timing := -1
+		for i, line := range lines {
+			if strings.Contains(line, "-->") {
+				timing = i
+				break
+			}
+		}
+		if timing < 0 {
+			if format == CaptionFormatVTT {
+				continue // Заголовок и служебные блоки WebVTT
+			}
+			return nil, fmt.Errorf("%w: block %d has no timing line", ErrInvalidCaptions, number+1)
+		}
+		if timing > 1 {
+			return nil, fmt.Errorf("%w: block %d has unexpected lines before the timing", ErrInvalidCaptions, number+1)
+		}
+
+		parts := strings.SplitN(lines[timing], "-->", 2)
+		start, err := parseCaptionTime(parts[0])
+		if err != nil {
+			return nil, fmt.Errorf("%w: block %d: %v", ErrInvalidCaptions, number+1, err)
+		}
+		endField := strings.Fields(parts[1])
+		if len(endField) == 0 {
+			return nil, fmt.Errorf("%w: block %d has no end time", ErrInvalidCaptions, number+1)
+		}
+		end, err := parseCaptionTime(endField[0])
+		if err != nil {
+			return nil, fmt.Errorf("%w: block %d: %v", ErrInvalidCaptions, number+1, err)
+		}
+		if end <= start {
+			return nil, fmt.Errorf("%w: block %d ends before it starts", ErrInvalidCaptions, number+1)
+		}
+
+		cueText := strings.TrimSpace(strings.Join(lines[timing+1:], "\n"))
+		if cueText == "" {
+			continue
+		}
+		cues = append(cues, CaptionCue{Start: start, End: end, Text: cueText})
+	}
+	if len(cues) == 0 {
+		return nil, fmt.Errorf("%w: no captions found", ErrInvalidCaptions)
+	}
+	sort.SliceStable(cues, func(i, j int) bool { return cues[i].Start < cues[j].Start })
+	return cues, nil
+}
+
+// parseCaptionTime - время вида 01:02:03.456, 02:03.456 или 01:02:03,456 (SRT) в секундах
+func parseCaptionTime(value string) (float64, error) {
+	value = strings.ReplaceAll(strings.TrimSpace(value), ",", ".")
+	fields := strings.Split(value, ":")
+	if len(fields) < 2 || len(fields) > 3 {
+		return 0, fmt.Errorf("invalid timestamp %q", value)
+	}
+	var seconds float64
+	for i, field := range fields {
+		number, err := strconv.ParseFloat(field, 64)
+		if err != nil || number < 0 || (i > 0 && number >= 60) {
+			return 0, fmt.Errorf("invalid timestamp %q", value)
+		}
+		seconds = seconds*60 + number
+	}
+	return seconds, nil
+}
+
+// formatCaptionTime - время в формате 00:01:02.345 (separator "," для SRT)
+func formatCaptionTime(seconds float64, separator string) string {
+	millis := int64(seconds*1000 + 0.5)
+	return fmt.Sprintf("%02d:%02d:%02d%s%03d", millis/3600000, millis/60000%60, millis/1000%60, separator, millis%1000)
+}
+
+// RenderWebVTT - субтитры в формате WebVTT
+func RenderWebVTT(cues []CaptionCue) string {
+	var b strings.Builder
+	b.WriteString("WEBVTT\n")
+	for _, cue := range cues {
+		fmt.Fprintf(&b, "\n%s --> %s\n%s\n", formatCaptionTime(cue.Start, "."), formatCaptionTime(cue.End, "."), cue.Text)
+	}
+	return b.String()
+}
+
+// RenderSRT - субтитры в формате SRT
+func RenderSRT(cues []CaptionCue) string {
+	var b strings.Builder
+	for i, cue := range cues {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "%d\n%s --> %s\n%s\n", i+1, formatCaptionTime(cue.Start, ","), formatCaptionTime(cue.End, ","), cue.Text)
+	}
+	return b.String()
+}
+
+var (
+	cueTagPattern    = regexp.MustCompile(`<[^>]*>`)
+	cueEntityReplace = strings.NewReplacer("&amp;", "&", "&lt;", "<", "&gt;", ">", "&nbsp;", " ", "&lrm;", "", "&rlm;", "")
+)
+
+// plainCueText - текст реплики без тегов оформления и голосов
+func plainCueText(text string) string {
+	text = cueEntityReplace.Replace(cueTagPattern.ReplaceAllString(text, " "))
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// SaveCaptionTrack - создание или замена дорожки субтитров языка; расшифровка этого языка
+// строится заново из реплик
+func SaveCaptionTrack(track *videos.CaptionTrack, cues []CaptionCue) error {
+	track.Content = RenderWebVTT(cues)
+	track.CuesCount = len(cues)
+	segments := make([]videos.TranscriptSegment, 0, len(cues))
+	for _, cue := range cues {
+		if text := plainCueText(cue.Text); text != "" {
+			segments = append(segments, videos.TranscriptSegment{StartSeconds: cue.Start, EndSeconds: cue.End, Text: text})
+		}
+	}
+
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		var existing videos.CaptionTrack
+		err := tx.Where("video_id = ? AND language = ?", track.VideoID, track.Language).First(&existing).Error
+		switch {
+		case err == nil:
+			track.ID = existing.ID
+			track.CreatedAt = existing.CreatedAt
+			if err := tx.Save(track).Error; err != nil {
+				return err
+			}
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			if err := tx.Create(track).Error; err != nil {
+				return err
+			}
+		default:
+			return err
+		}
+
+		if track.IsDefault {
+			if err := tx.Model(&videos.CaptionTrack{}).Where("video_id = ? AND id <> ?", track.VideoID, track.ID).
+				Update("is_default", false).Error; err != nil {
+				return err
+			}
+		}
+		return replaceTranscript(tx, track.VideoID, track.Language, segments)
+	})
+}
+
+// DeleteCaptionTrack - удаление дорожки субтитров вместе с расшифровкой этого языка
+func DeleteCaptionTrack(track *videos.CaptionTrack) error {
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(track).Error; err != nil {
+			return err
+		}
+		return replaceTranscript(tx, track.VideoID, track.Language, nil)
+	})
+}
+
+// ReplaceTranscript - замена расшифровки языка (например, полученной от сервиса распознавания речи)
+func ReplaceTranscript(videoID uint, language string, segments []videos.TranscriptSegment) error {
+	for i, segment := range segments {
+		if strings.TrimSpace(segment.Text) == "" {
+			return fmt.Errorf("%w: segment %d has no text", ErrInvalidCaptions, i+1)
+		}
+		if segment.StartSeconds < 0 || (segment.EndSeconds != 0 && segment.EndSeconds < segment.StartSeconds) {
+			return fmt.Errorf("%w: segment %d has invalid timing", ErrInvalidCaptions, i+1)
+		}
+	}
+	sort.SliceStable(segments, func(i, j int) bool { return segments[i].StartSeconds < segments[j].StartSeconds })
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		return replaceTranscript(tx, videoID, language, segments)
+	})
+}
+
+func replaceTranscript(tx *gorm.DB, videoID uint, language string, segments []videos.TranscriptSegment) error {
+	if err := tx.Where("video_id = ? AND language = ?", videoID, language).Delete(&videos.TranscriptSegment{}).Error; err != nil {
+		return err
+	}
+	if len(segments) == 0 {
+		return nil
+	}
+	for i := range segments {
+		segments[i].ID = 0
+		segments[i].VideoID = videoID
+		segments[i].Language = language
+		segments[i].Position = i
+		segments[i].Text = strings.TrimSpace(segments[i].Text)
+	}
+	return tx.CreateInBatches(segments, 500).Error
+}
+
+// ValidateChapters - главы по возрастанию времени начала, без повторов и в пределах длительности видео
+func ValidateChapters(chapters []videos.VideoChapter, durationSeconds float64) error {
+	sort.SliceStable(chapters, func(i, j int) bool { return chapters[i].StartSeconds < chapters[j].StartSeconds })
+	for i := range chapters {
+		chapters[i].Title = strings.TrimSpace(chapters[i].Title)
+		if chapters[i].Title == "" {
+			return fmt.Errorf("%w: chapter %d has no title", ErrInvalidChapters, i+1)
+		}
+		if chapters[i].StartSeconds < 0 || (durationSeconds > 0 && chapters[i].StartSeconds >= durationSeconds) {
+			return fmt.Errorf("%w: %q starts outside the video", ErrInvalidChapters, chapters[i].Title)
+		}
+		if i > 0 && chapters[i].StartSeconds == chapters[i-1].StartSeconds {
+			return fmt.Errorf("%w: %q and %q start at the same time", ErrInvalidChapters, chapters[i-1].Title, chapters[i].Title)
+		}
+		chapters[i].Position = i
+	}
+	return nil
+}
+
+// LessonVideo - опубликованное видео урока. Уроки разных версий курса ссылаются на одно видео
+// через ссылку, поэтому видео ищется по ней, а не по LessonID
+func LessonVideo(lesson courses.Lesson) (videos.Video, error) {
+	var video videos.Video
+	if lesson.VideoLink == "" {
+		return video, gorm.ErrRecordNotFound
+	}
+	err := config.DB.Where("video_link = ? AND status = ?", lesson.VideoLink, videos.VideoReady).
+		Order("id DESC").First(&video).Error
+	return video, err
+}
+
+// searchIndexes - индексы полнотекстового поиска по урокам. Выражения должны совпадать с выражениями
+// в запросах LessonSearchHandler, иначе Postgres не использует индекс
+var searchIndexes = []string{
+	"CREATE INDEX IF NOT EXISTS idx_transcript_segments_search ON transcript_segments " +
+		"USING gin (to_tsvector('simple', text))",
+	"CREATE INDEX IF NOT EXISTS idx_lessons_search ON lessons " +
+		"USING gin (to_tsvector('simple', coalesce(title, '') || ' ' || coalesce(content, '')))",
+}
+
+// EnsureSearchIndexes - создание GIN-индексов поиска по урокам и расшифровкам (после миграции)
+func EnsureSearchIndexes() error {
+	for _, statement := range searchIndexes {
+		if err := config.DB.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package services
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"hired-valley-backend/config"
+	"hired-valley-backend/models/courses"
+	"hired-valley-backend/models/users"
+	"os"
+	"strings"
+	"time"
+)
+
+// newVerificationID - случайный идентификатор для публичной проверки сертификата
+func newVerificationID() (string, error) {
+	buf := make([]byte, 12)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return strings.ToUpper(hex.EncodeToString(buf)), nil
+}
+
+// CertificateVerifyURL - публичная ссылка на проверку сертификата (CERTIFICATE_VERIFY_BASE_URL)
+func CertificateVerifyURL(verificationID string) string {
+	base := strings.TrimRight(os.Getenv("CERTIFICATE_VERIFY_BASE_URL"), "/")
+	return base + "/certificates/verify?id=" + verificationID
+}
+
+// IssueCertificateIfCompleted - выдача сертификата, если все уроки курса пройдены.
+// Повторно сертификат не выдается, в том числе после отзыва.
+func IssueCertificateIfCompleted(userID uint, course courses.Course) (*users.Certificate, error) {
+	progress, err := GetCourseProgress(userID, course)
+	if err != nil {
+		return nil, err
+	}
+	if progress.TotalLessons == 0 || progress.CompletedLessons < progress.TotalLessons {
+		return nil, nil
+	}
+
+	var existing users.Certificate
+	err = config.DB.Where("user_id = ? AND course_id = ?", userID, course.ID).First(&existing).Error
+	if err == nil {
+		return &existing, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	if !IsEnrolled(userID, &course) {
+		return nil, nil
+	}
+
+	var recipient, instructor users.User
+	if err := config.DB.First(&recipient, userID).Error; err != nil {
+		return nil, err
+	}
+	config.DB.First(&instructor, course.InstructorID)
+
+	verificationID, err := newVerificationID()
+	if err != nil {
+		return nil, err
+	}
+	certificate := users.Certificate{
+		VerificationID: verificationID,
+		UserID:         userID,
+		CourseID:       course.ID,
+		RecipientName:  recipient.Name,
+		CourseTitle:    course.Title,
+		InstructorName: instructor.Name,
+		IssuedAt:       time.Now(),
+	}
+	if err := config.DB.Create(&certificate).Error; err != nil {
+		return nil, err
+	}
+
+	config.DB.Create(&users.NotificationMentor{
+		UserID:  userID,
+		Message: fmt.Sprintf("Congratulations! You completed \"%s\" and earned a certificate.", course.Title),
+	})
+	return &certificate, nil
+}
+
+// RenderCertificatePDF - одностраничный PDF сертификата (A4, альбомная ориентация).
+// Шрифты встраиваются целиком, поэтому имена и названия курсов выводятся на любом языке, который есть в шрифте.
+func RenderCertificatePDF(certificate users.Certificate) []byte {
+	type line struct {
+		font string
+		size int
+		y    int
+		text string
+	}
+	lines := []line{
+		{"F2", 36, 470, "Certificate of Completion"},
+		{"F1", 16, 410, "This certifies that"},
+		{"F2", 28, 365, certificate.RecipientName},
+		{"F1", 16, 320, "has successfully completed the course"},
+		{"F2", 22, 280, certificate.CourseTitle},
+		{"F1", 14, 220, "Instructor: " + certificate.InstructorName},
+		{"F1", 14, 195, "Issued: " + certificate.IssuedAt.Format("January 2, 2006")},
+		{"F1", 11, 110, "Verification ID: " + certificate.VerificationID},
+		{"F1", 11, 92, "Verify at: " + CertificateVerifyURL(certificate.VerificationID)},
+	}
+	if certificate.RevokedAt != nil {
+		lines = append(lines, line{"F2", 18, 140, "REVOKED " + certificate.RevokedAt.Format("January 2, 2006")})
+	}
+
+	const pageWidth, pageHeight = 842, 595
+	regularSource, boldSource := certificateFonts()
+	fonts := map[string]*pdfFont{"F1": newPDFFont(regularSource), "F2": newPDFFont(boldSource)}
+
+	var content bytes.Buffer
+	// Рамка
+	content.WriteString("2 w 30 30 782 535 re S 0.5 w 40 40 762 515 re S\n")
+	for _, item := range lines {
+		text, width := fonts[item.font].encode(item.text, item.size)
+		x := (float64(pageWidth) - width) / 2
+		if x < 50 {
+			x = 50
+		}
+		fmt.Fprintf(&content, "BT /%s %d Tf %.1f %d Td %s Tj ET\n", item.font, item.size, x, item.y, text)
+	}
+
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 5 0 R /F2 10 0 R >> >> /Contents 4 0 R >>", pageWidth, pageHeight),
+		pdfStream(content.Bytes(), ""),
+	}
+	objects = append(objects, fonts["F1"].objects(5)...)
+	objects = append(objects, fonts["F2"].objects(10)...)
+
+	var pdf bytes.Buffer
+	pdf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = pdf.Len()
+		fmt.Fprintf(&pdf, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+	xref := pdf.Len()
+	fmt.Fprintf(&pdf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&pdf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&pdf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return pdf.Bytes()
+}
@@ -0,0 +1,96 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"hired-valley-backend/models/users"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// objectHeader - заголовок объекта сразу после заголовка файла или конца предыдущего объекта;
+// так совпадения внутри бинарных потоков шрифтов не считаются объектами
+var objectHeader = regexp.MustCompile(`(?:%PDF-1\.\d\n|endobj\n)(\d+) 0 obj\n`)
+
+func TestRenderCertificatePDF(t *testing.T) {
+	issued := time.Date(2025, time.March, 14, 10, 0, 0, 0, time.UTC)
+	revoked := issued.AddDate(0, 1, 0)
+	cases := map[string]users.Certificate{
+		"active": {VerificationID: "abc123", RecipientName: "Ann Smith", CourseTitle: "Go Basics",
+			InstructorName: "Bob Brown", IssuedAt: issued},
+		"revoked non-latin": {VerificationID: "def456", RecipientName: "Анна Смирнова", CourseTitle: "Основы Go",
+			InstructorName: "Борис Браун", IssuedAt: issued, RevokedAt: &revoked},
+	}
+	for name, certificate := range cases {
+		pdf := RenderCertificatePDF(certificate)
+		if !bytes.HasPrefix(pdf, []byte("%PDF-1.")) {
+			t.Errorf("%s: missing PDF header", name)
+			continue
+		}
+		if !bytes.HasSuffix(pdf, []byte("%%EOF\n")) {
+			t.Errorf("%s: missing EOF marker", name)
+		}
+
+		// startxref указывает на начало таблицы xref
+		marker := bytes.LastIndex(pdf, []byte("startxref\n"))
+		if marker < 0 {
+			t.Errorf("%s: startxref not found", name)
+			continue
+		}
+		var xref int
+		if _, err := fmt.Sscanf(string(pdf[marker:]), "startxref\n%d\n", &xref); err != nil {
+			t.Errorf("%s: parse startxref: %v", name, err)
+			continue
+		}
+		if xref <= 0 || xref >= marker || !bytes.HasPrefix(pdf[xref:], []byte("xref\n")) {
+			t.Errorf("%s: startxref %d does not point at the xref table", name, xref)
+			continue
+		}
+
+		var first, size int
+		if _, err := fmt.Sscanf(string(pdf[xref:marker]), "xref\n%d %d\n", &first, &size); err != nil || first != 0 {
+			t.Errorf("%s: parse xref subsection: first %d, error %v", name, first, err)
+			continue
+		}
+		table := pdf[xref+len(fmt.Sprintf("xref\n0 %d\n", size)) : marker]
+		if len(table) < size*20 {
+			t.Errorf("%s: xref table of %d entries is truncated", name, size)
+			continue
+		}
+		if entry := string(table[:20]); entry != "0000000000 65535 f \n" {
+			t.Errorf("%s: unexpected free entry %q", name, entry)
+		}
+		for number := 1; number < size; number++ {
+			entry := string(table[number*20 : (number+1)*20])
+			offset, err := strconv.Atoi(entry[:10])
+			if err != nil || entry[10:] != " 00000 n \n" {
+				t.Errorf("%s: malformed xref entry %d: %q", name, number, entry)
+				continue
+			}
+			if header := fmt.Sprintf("%d 0 obj\n", number); offset >= xref || !bytes.HasPrefix(pdf[offset:], []byte(header)) {
+				t.Errorf("%s: xref entry %d (offset %d) does not point at %q", name, number, offset, header)
+			}
+		}
+
+		// Объекты пронумерованы подряд, /Size на единицу больше их числа (с учетом объекта 0)
+		objects := objectHeader.FindAllSubmatch(pdf[:xref], -1)
+		for i, match := range objects {
+			if number, _ := strconv.Atoi(string(match[1])); number != i+1 {
+				t.Errorf("%s: object %d numbered %d", name, i+1, number)
+			}
+		}
+		if size != len(objects)+1 {
+			t.Errorf("%s: xref has %d entries for %d objects", name, size, len(objects))
+		}
+		trailer := regexp.MustCompile(`trailer\n<< /Size (\d+) /Root 1 0 R >>\n`).FindSubmatch(table[size*20:])
+		if trailer == nil {
+			t.Errorf("%s: trailer not found after the xref table", name)
+			continue
+		}
+		if trailerSize, _ := strconv.Atoi(string(trailer[1])); trailerSize != len(objects)+1 {
+			t.Errorf("%s: trailer /Size %d, want %d", name, trailerSize, len(objects)+1)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"hired-valley-backend/config"
+	"hired-valley-backend/models/courses"
+	"hired-valley-backend/models/users"
+	"os"
+	"strings"
+	"time"
+)
+
+// CourseInvitationTTL - срок действия приглашения в команду курса
+const CourseInvitationTTL = 14 * 24 * time.Hour
+
+// CoursePermissions - права пользователя в курсе: у владельца все, у участника команды -
+// назначенные ему, у остальных - нет прав
+func CoursePermissions(userID uint, course *courses.Course) []string {
+	if userID == 0 {
+		return nil
+	}
+	if course.InstructorID == userID {
+		return courses.AllCoursePermissions
+	}
+	var collaborator courses.CourseCollaborator
+	if err := config.DB.Where("course_id = ? AND user_id = ?", CourseOriginID(course), userID).
+		First(&collaborator).Error; err != nil {
+		return nil
+	}
+	return collaborator.Permissions
+}
+
+// HasCoursePermission - есть ли у пользователя право в курсе; администратору доступно все
+func HasCoursePermission(user *users.User, course *courses.Course, permission string) bool {
+	if user == nil {
+		return false
+	}
+	if user.Role == "admin" {
+		return true
+	}
+	for _, granted := range CoursePermissions(user.ID, course) {
+		if granted == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// IsCourseTeamMember - владелец курса или участник его команды
+func IsCourseTeamMember(userID uint, course *courses.Course) bool {
+	if userID == 0 {
+		return false
+	}
+	if course.InstructorID == userID {
+		return true
+	}
+	var count int64
+	config.DB.Model(&courses.CourseCollaborator{}).
+		Where("course_id = ? AND user_id = ?", CourseOriginID(course), userID).Count(&count)
+	return count > 0
+}
+
+// NormalizeCoursePermissions - права участника команды: по умолчанию для роли или явно заданный набор
+func NormalizeCoursePermissions(role string, permissions []string) ([]string, error) {
+	defaults, ok := courses.DefaultRolePermissions[role]
+	if !ok || role == courses.CourseRoleOwner {
+		return nil, fmt.Errorf("role must be %q or %q", courses.CourseRoleCoInstructor, courses.CourseRoleAssistant)
+	}
+	if permissions == nil {
+		return defaults, nil
+	}
+
+	known := map[string]bool{}
+	for _, permission := range courses.AllCoursePermissions {
+		known[permission] = true
+	}
+	seen := map[string]bool{}
+	result := []string{}
+	for _, permission := range permissions {
+		permission = strings.TrimSpace(permission)
+		if !known[permission] {
+			return nil, fmt.Errorf("unknown permission %q", permission)
+		}
+		if !seen[permission] {
+			seen[permission] = true
+			result = append(result, permission)
+		}
+	}
+	return result, nil
+}
+
+// NewInvitationToken - случайный секрет для ссылки-приглашения
+func NewInvitationToken() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// CourseInvitationURL - ссылка для принятия приглашения (COURSE_INVITATION_BASE_URL)
+func CourseInvitationURL(token string) string {
+	base := strings.TrimRight(os.Getenv("COURSE_INVITATION_BASE_URL"), "/")
+	return base + "/courses/team/invitations/accept?token=" + token
+}
+
+// SendCourseInvitation - письмо с приглашением в команду курса
+func SendCourseInvitation(invitation courses.CourseInvitation, course courses.Course, inviter users.User) error {
+	roleNames := map[string]string{
+		courses.CourseRoleCoInstructor: "co-instructor",
+		courses.CourseRoleAssistant:    "teaching assistant",
+	}
+	subject := fmt.Sprintf("Invitation to teach \"%s\"", course.Title)
+	body := fmt.Sprintf("%s invited you to join the course \"%s\" as a %s.\n\nAccept the invitation: %s\n\nThe invitation expires on %s.",
+		inviter.Name, course.Title, roleNames[invitation.Role], CourseInvitationURL(invitation.Token),
+		invitation.ExpiresAt.Format("January 2, 2006"))
+	return GetMailer().Send(invitation.Email, subject, body)
+}
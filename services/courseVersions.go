@@ -0,0 +1,112 @@
+package services
+
+import (
+	"gorm.io/gorm"
+	"hired-valley-backend/models/courses"
+	"time"
+)
+
+// copyCourseVersion - черновик следующей версии курса: копия карточки, разделов, уроков,
+// пререквизитов и тестов. Прогресс, записи и обсуждения остаются у исходной версии.
+func copyCourseVersion(tx *gorm.DB, course *courses.Course) (*courses.Course, error) {
+	originID := CourseOriginID(course)
+
+	var lastVersion int
+	if err := tx.Model(&courses.Course{}).Where("id = ? OR origin_id = ?", originID, originID).
+		Select("COALESCE(MAX(version), 1)").Scan(&lastVersion).Error; err != nil {
+		return nil, err
+	}
+
+	// Рейтинг и число отзывов переносятся, чтобы новая версия не теряла их в каталоге
+	draft := &courses.Course{
+		Title:         course.Title,
+		Description:   course.Description,
+		Price:         course.Price,
+		InstructorID:  course.InstructorID,
+		Tags:          course.Tags,
+		AverageRating: course.AverageRating,
+		ReviewsCount:  course.ReviewsCount,
+		Status:        courses.CourseStatusDraft,
+		Version:       lastVersion + 1,
+		OriginID:      &originID,
+	}
+	if err := tx.Omit("Lessons", "Modules").Create(draft).Error; err != nil {
+		return nil, err
+	}
+
+	var modules []courses.CourseModule
+	if err := tx.Where("course_id = ?", course.ID).Find(&modules).Error; err != nil {
+		return nil, err
+	}
+	moduleIDs := map[uint]uint{}
+	for _, module := range modules {
+		copied := courses.CourseModule{CourseID: draft.ID, Title: module.Title, Position: module.Position}
+		if err := tx.Omit("Lessons").Create(&copied).Error; err != nil {
+			return nil, err
+		}
+		moduleIDs[module.ID] = copied.ID
+	}
+
+	var lessons []courses.Lesson
+	if err := tx.Where("course_id = ?", course.ID).Find(&lessons).Error; err != nil {
+		return nil, err
+	}
+	lessonIDs := map[uint]uint{}
+	for _, lesson := range lessons {
+		copied := lesson
+		copied.ID = 0
+		copied.CreatedAt, copied.UpdatedAt = time.Time{}, time.Time{}
+		copied.CourseID = draft.ID
+		if lesson.ModuleID != nil {
+			moduleID := moduleIDs[*lesson.ModuleID]
+			copied.ModuleID = &moduleID
+		}
+		if err := tx.Create(&copied).Error; err != nil {
+			return nil, err
+		}
+		lessonIDs[lesson.ID] = copied.ID
+	}
+	if len(lessons) == 0 {
+		return draft, nil
+	}
+
+	oldLessonIDs := make([]uint, 0, len(lessons))
+	for _, lesson := range lessons {
+		oldLessonIDs = append(oldLessonIDs, lesson.ID)
+	}
+
+	var links []courses.LessonPrerequisite
+	if err := tx.Where("lesson_id IN ?", oldLessonIDs).Find(&links).Error; err != nil {
+		return nil, err
+	}
+	for _, link := range links {
+		copied := courses.LessonPrerequisite{LessonID: lessonIDs[link.LessonID], PrerequisiteID: lessonIDs[link.PrerequisiteID]}
+		if err := tx.Create(&copied).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	var quizzes []courses.Quiz
+	if err := tx.Preload("Questions").Where("lesson_id IN ?", oldLessonIDs).Find(&quizzes).Error; err != nil {
+		return nil, err
+	}
+	for _, quiz := range quizzes {
+		copied := quiz
+		copied.ID = 0
+		copied.CreatedAt, copied.UpdatedAt = time.Time{}, time.Time{}
+		copied.LessonID = lessonIDs[quiz.LessonID]
+		copied.Questions = nil
+		if err := tx.Create(&copied).Error; err != nil {
+			return nil, err
+		}
+		for _, question := range quiz.Questions {
+			question.ID = 0
+			question.QuizID = copied.ID
+			if err := tx.Create(&question).Error; err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return draft, nil
+}
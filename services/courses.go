@@ -0,0 +1,449 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+	"hired-valley-backend/config"
+	"hired-valley-backend/models/courses"
+	"hired-valley-backend/models/users"
+	"strings"
+	"time"
+)
+
+var (
+	ErrCourseNotFound  = errors.New("course not found")
+	ErrCourseForbidden = errors.New("permission denied")
+	ErrMentorOnly      = errors.New("only mentors can create courses")
+	ErrInvalidCourse   = errors.New("invalid course")
+	ErrCourseLocked    = errors.New("course is locked")
+)
+
+// CourseStore - хранилище курсов
+type CourseStore interface {
+	FindCourse(id uint) (*courses.Course, error) // ErrCourseNotFound, если курса нет
+	InstructorCourses(instructorID uint) ([]courses.Course, error)
+	CreateCourse(course *courses.Course) error
+	SaveCourse(course *courses.Course) error
+	DeleteCourse(course *courses.Course) error
+	IsEnrolled(userID uint, course *courses.Course) bool   // Запись на любую версию курса
+	IsTeamMember(userID uint, course *courses.Course) bool // Владелец или участник команды курса
+	HasPermission(user *users.User, course *courses.Course, permission string) bool
+	CourseVersions(originID uint) ([]courses.Course, error)
+	CreateDraftVersion(course *courses.Course) (*courses.Course, error)   // Копия курса с программой и тестами
+	PublishCourse(course *courses.Course, previous *courses.Course) error // previous - заменяемая версия или nil
+}
+
+// databaseCourseStore - курсы в базе данных приложения (config.DB)
+type databaseCourseStore struct{}
+
+func (databaseCourseStore) FindCourse(id uint) (*courses.Course, error) {
+	var course courses.Course
+	if err := config.DB.First(&course, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCourseNotFound
+		}
+		return nil, err
+	}
+	return &course, nil
+}
+
+func (databaseCourseStore) InstructorCourses(instructorID uint) ([]courses.Course, error) {
+	var list []courses.Course
+	err := config.DB.Where("instructor_id = ?", instructorID).Order("created_at DESC").Find(&list).Error
+	return list, err
+}
+
+func (databaseCourseStore) CreateCourse(course *courses.Course) error {
+	return config.DB.Create(course).Error
+}
+
+func (databaseCourseStore) SaveCourse(course *courses.Course) error {
+	return config.DB.Save(course).Error
+}
+
+func (databaseCourseStore) DeleteCourse(course *courses.Course) error {
+	return config.DB.Delete(course).Error
+}
+
+func (databaseCourseStore) IsEnrolled(userID uint, course *courses.Course) bool {
+	return IsEnrolled(userID, course)
+}
+
+func (databaseCourseStore) IsTeamMember(userID uint, course *courses.Course) bool {
+	return IsCourseTeamMember(userID, course)
+}
+
+func (databaseCourseStore) HasPermission(user *users.User, course *courses.Course, permission string) bool {
+	return HasCoursePermission(user, course, permission)
+}
+
+func (databaseCourseStore) CourseVersions(originID uint) ([]courses.Course, error) {
+	var list []courses.Course
+	err := config.DB.Where("id = ? OR origin_id = ?", originID, originID).Order("version").Find(&list).Error
+	return list, err
+}
+
+func (databaseCourseStore) CreateDraftVersion(course *courses.Course) (*courses.Course, error) {
+	var draft *courses.Course
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		draft, err = copyCourseVersion(tx, course)
+		return err
+	})
+	return draft, err
+}
+
+func (databaseCourseStore) PublishCourse(course *courses.Course, previous *courses.Course) error {
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		if previous != nil {
+			if err := tx.Model(previous).Updates(map[string]interface{}{
+				"status":        courses.CourseStatusArchived,
+				"superseded_by": course.ID,
+			}).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Model(course).Updates(map[string]interface{}{
+			"status":       course.Status,
+			"published_at": course.PublishedAt,
+			"review_note":  "",
+		}).Error
+	})
+}
+
+// CourseInput - данные курса от автора; при обновлении nil-поля не меняются
+type CourseInput struct {
+	Title       *string   `json:"title"`
+	Description *string   `json:"description"`
+	Price       *float64  `json:"price"`
+	Tags        *[]string `json:"tags"`
+}
+
+// CourseService - управление курсами независимо от способа аутентификации.
+// Пользователь запроса определяется до вызова сервиса (JWT или Google OAuth).
+type CourseService struct {
+	store CourseStore
+}
+
+// NewCourseService - сервис курсов поверх хранилища; nil - база данных приложения
+func NewCourseService(store CourseStore) *CourseService {
+	if store == nil {
+		store = databaseCourseStore{}
+	}
+	return &CourseService{store: store}
+}
+
+// CanManageCourse - редактировать и удалять курс могут его инструктор и администратор
+func CanManageCourse(user *users.User, course *courses.Course) bool {
+	if user == nil {
+		return false
+	}
+	return user.Role == "admin" || course.InstructorID == user.ID
+}
+
+// CourseOriginID - ID первой версии курса, объединяющий все его версии
+func CourseOriginID(course *courses.Course) uint {
+	if course.OriginID != nil {
+		return *course.OriginID
+	}
+	return course.ID
+}
+
+// CourseVersionIDs - подзапрос ID всех версий курса
+func CourseVersionIDs(db *gorm.DB, course *courses.Course) *gorm.DB {
+	originID := CourseOriginID(course)
+	return db.Model(&courses.Course{}).Select("id").Where("id = ? OR origin_id = ?", originID, originID)
+}
+
+// IsEnrolled - записан ли пользователь на курс; запись на прежнюю версию курса тоже считается
+func IsEnrolled(userID uint, course *courses.Course) bool {
+	var count int64
+	config.DB.Model(&courses.Enrollment{}).
+		Where("user_id = ? AND course_id IN (?)", userID, CourseVersionIDs(config.DB, course)).Count(&count)
+	return count > 0
+}
+
+// canViewCourse - опубликованный курс виден всем, архивный - еще и записанным ученикам,
+// черновик и курс на проверке - только команде курса и администратору
+func (s *CourseService) canViewCourse(user *users.User, course *courses.Course) bool {
+	if course.Status == courses.CourseStatusPublished {
+		return true
+	}
+	if CanManageCourse(user, course) || (user != nil && s.store.IsTeamMember(user.ID, course)) {
+		return true
+	}
+	return course.Status == courses.CourseStatusArchived && user != nil && s.store.IsEnrolled(user.ID, course)
+}
+
+// List - курсы инструктора; черновики и архив видны только ему самому и администратору
+func (s *CourseService) List(user *users.User, instructorID uint) ([]courses.Course, error) {
+	list, err := s.store.InstructorCourses(instructorID)
+	if err != nil {
+		return nil, err
+	}
+	if user != nil && (user.ID == instructorID || user.Role == "admin") {
+		return list, nil
+	}
+	published := []courses.Course{}
+	for _, course := range list {
+		if course.Status == courses.CourseStatusPublished {
+			published = append(published, course)
+		}
+	}
+	return published, nil
+}
+
+// Get - курс по ID с учетом статуса; user может быть nil для гостя
+func (s *CourseService) Get(user *users.User, id uint) (*courses.Course, error) {
+	course, err := s.store.FindCourse(id)
+	if err != nil {
+		return nil, err
+	}
+	if !s.canViewCourse(user, course) {
+		return nil, ErrCourseNotFound
+	}
+	return course, nil
+}
+
+// Create - новый курс; автор становится инструктором курса
+func (s *CourseService) Create(user *users.User, input CourseInput) (*courses.Course, error) {
+	if user == nil || user.Role != "mentor" {
+		return nil, ErrMentorOnly
+	}
+
+	course := &courses.Course{InstructorID: user.ID, Status: courses.CourseStatusDraft, Version: 1}
+	if err := applyCourseInput(course, input); err != nil {
+		return nil, err
+	}
+	if course.Title == "" {
+		return nil, fmt.Errorf("%w: title is required", ErrInvalidCourse)
+	}
+	if len(course.Tags) == 0 {
+		return nil, fmt.Errorf("%w: tags are required", ErrInvalidCourse)
+	}
+
+	if err := s.store.CreateCourse(course); err != nil {
+		return nil, err
+	}
+	return course, nil
+}
+
+// Update - частичное обновление курса его инструктором или администратором
+func (s *CourseService) Update(user *users.User, id uint, input CourseInput) (*courses.Course, error) {
+	course, err := s.store.FindCourse(id)
+	if err != nil {
+		return nil, err
+	}
+	if !CanManageCourse(user, course) {
+		return nil, ErrCourseForbidden
+	}
+	if course.Status != courses.CourseStatusDraft {
+		return nil, fmt.Errorf("%w: only drafts can be edited, create a draft version of the course", ErrCourseLocked)
+	}
+	if err := applyCourseInput(course, input); err != nil {
+		return nil, err
+	}
+	if err := s.store.SaveCourse(course); err != nil {
+		return nil, err
+	}
+	return course, nil
+}
+
+// Delete - удаление курса его инструктором или администратором
+func (s *CourseService) Delete(user *users.User, id uint) error {
+	course, err := s.store.FindCourse(id)
+	if err != nil {
+		return err
+	}
+	if !CanManageCourse(user, course) {
+		return ErrCourseForbidden
+	}
+	// Опубликованные версии могут быть у записанных учеников, их можно только архивировать
+	if course.Status == courses.CourseStatusPublished || course.Status == courses.CourseStatusArchived {
+		return fmt.Errorf("%w: published courses cannot be deleted, archive the course instead", ErrCourseLocked)
+	}
+	return s.store.DeleteCourse(course)
+}
+
+// managedCourse - курс, которым управляет пользователь
+func (s *CourseService) managedCourse(user *users.User, id uint) (*courses.Course, error) {
+	course, err := s.store.FindCourse(id)
+	if err != nil {
+		return nil, err
+	}
+	if !CanManageCourse(user, course) {
+		return nil, ErrCourseForbidden
+	}
+	return course, nil
+}
+
+// teamCourse - курс, в команде которого у пользователя есть право permission
+func (s *CourseService) teamCourse(user *users.User, id uint, permission string) (*courses.Course, error) {
+	course, err := s.store.FindCourse(id)
+	if err != nil {
+		return nil, err
+	}
+	if !CanManageCourse(user, course) && !s.store.HasPermission(user, course, permission) {
+		return nil, ErrCourseForbidden
+	}
+	return course, nil
+}
+
+// Versions - все версии курса по порядку; доступны команде курса с правом на редактирование программы
+func (s *CourseService) Versions(user *users.User, id uint) ([]courses.Course, error) {
+	course, err := s.teamCourse(user, id, courses.PermissionEditLessons)
+	if err != nil {
+		return nil, err
+	}
+	return s.store.CourseVersions(CourseOriginID(course))
+}
+
+// CreateDraft - черновик новой версии опубликованного курса; если черновик уже есть, возвращается он.
+// Черновик может создать и соавтор с правом на редактирование программы
+func (s *CourseService) CreateDraft(user *users.User, id uint) (*courses.Course, bool, error) {
+	course, err := s.teamCourse(user, id, courses.PermissionEditLessons)
+	if err != nil {
+		return nil, false, err
+	}
+	if course.Status != courses.CourseStatusPublished {
+		return nil, false, fmt.Errorf("%w: draft versions are created from the published version", ErrCourseLocked)
+	}
+
+	versions, err := s.store.CourseVersions(CourseOriginID(course))
+	if err != nil {
+		return nil, false, err
+	}
+	for i := range versions {
+		if versions[i].Status == courses.CourseStatusDraft || versions[i].Status == courses.CourseStatusReview {
+			return &versions[i], false, nil
+		}
+	}
+
+	draft, err := s.store.CreateDraftVersion(course)
+	if err != nil {
+		return nil, false, err
+	}
+	return draft, true, nil
+}
+
+// Submit - отправка черновика на проверку
+func (s *CourseService) Submit(user *users.User, id uint) (*courses.Course, error) {
+	course, err := s.managedCourse(user, id)
+	if err != nil {
+		return nil, err
+	}
+	if course.Status != courses.CourseStatusDraft {
+		return nil, fmt.Errorf("%w: only drafts can be submitted for review", ErrCourseLocked)
+	}
+	course.Status = courses.CourseStatusReview
+	if err := s.store.SaveCourse(course); err != nil {
+		return nil, err
+	}
+	return course, nil
+}
+
+// Reject - возврат курса с проверки на доработку (администратор)
+func (s *CourseService) Reject(user *users.User, id uint, note string) (*courses.Course, error) {
+	if user == nil || user.Role != "admin" {
+		return nil, ErrCourseForbidden
+	}
+	course, err := s.store.FindCourse(id)
+	if err != nil {
+		return nil, err
+	}
+	if course.Status != courses.CourseStatusReview {
+		return nil, fmt.Errorf("%w: course is not under review", ErrCourseLocked)
+	}
+	course.Status = courses.CourseStatusDraft
+	course.ReviewNote = strings.TrimSpace(note)
+	if err := s.store.SaveCourse(course); err != nil {
+		return nil, err
+	}
+	return course, nil
+}
+
+// Publish - публикация проверенной версии (администратор). Предыдущая опубликованная версия
+// атомарно уходит в архив: записанные на нее ученики продолжают курс без изменений.
+func (s *CourseService) Publish(user *users.User, id uint) (*courses.Course, error) {
+	if user == nil || user.Role != "admin" {
+		return nil, ErrCourseForbidden
+	}
+	course, err := s.store.FindCourse(id)
+	if err != nil {
+		return nil, err
+	}
+	if course.Status != courses.CourseStatusReview {
+		return nil, fmt.Errorf("%w: only courses under review can be published", ErrCourseLocked)
+	}
+
+	versions, err := s.store.CourseVersions(CourseOriginID(course))
+	if err != nil {
+		return nil, err
+	}
+	var previous *courses.Course
+	for i := range versions {
+		if versions[i].ID != course.ID && versions[i].Status == courses.CourseStatusPublished {
+			previous = &versions[i]
+		}
+	}
+
+	now := time.Now()
+	course.Status = courses.CourseStatusPublished
+	course.PublishedAt = &now
+	course.ReviewNote = ""
+	if err := s.store.PublishCourse(course, previous); err != nil {
+		return nil, err
+	}
+	return course, nil
+}
+
+// Archive - снятие опубликованного курса из каталога; записанные ученики сохраняют доступ
+func (s *CourseService) Archive(user *users.User, id uint) (*courses.Course, error) {
+	course, err := s.managedCourse(user, id)
+	if err != nil {
+		return nil, err
+	}
+	if course.Status != courses.CourseStatusPublished {
+		return nil, fmt.Errorf("%w: only published courses can be archived", ErrCourseLocked)
+	}
+	course.Status = courses.CourseStatusArchived
+	if err := s.store.SaveCourse(course); err != nil {
+		return nil, err
+	}
+	return course, nil
+}
+
+// applyCourseInput - перенос переданных полей в курс с проверкой значений
+func applyCourseInput(course *courses.Course, input CourseInput) error {
+	if input.Title != nil {
+		title := strings.TrimSpace(*input.Title)
+		if title == "" {
+			return fmt.Errorf("%w: title cannot be empty", ErrInvalidCourse)
+		}
+		course.Title = title
+	}
+	if input.Description != nil {
+		course.Description = *input.Description
+	}
+	if input.Price != nil {
+		if *input.Price < 0 {
+			return fmt.Errorf("%w: price cannot be negative", ErrInvalidCourse)
+		}
+		course.Price = *input.Price
+	}
+	if input.Tags != nil {
+		var tags pq.StringArray
+		for _, tag := range *input.Tags {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				tags = append(tags, tag)
+			}
+		}
+		if len(tags) == 0 {
+			return fmt.Errorf("%w: tags are required", ErrInvalidCourse)
+		}
+		course.Tags = tags
+	}
+	return nil
+}
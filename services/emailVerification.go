@@ -0,0 +1,76 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"hired-valley-backend/config"
+	"hired-valley-backend/models/users"
+	"os"
+	"strings"
+	"time"
+)
+
+var ErrVerificationInvalid = errors.New("verification link is invalid or has expired")
+
+// EmailVerificationTTL - срок действия ссылки подтверждения email
+const EmailVerificationTTL = 48 * time.Hour
+
+// EmailVerificationURL - ссылка подтверждения email (EMAIL_VERIFICATION_BASE_URL)
+func EmailVerificationURL(token string) string {
+	base := strings.TrimRight(os.Getenv("EMAIL_VERIFICATION_BASE_URL"), "/")
+	return base + "/email/verify/confirm?token=" + token
+}
+
+// SendEmailVerification - письмо со ссылкой подтверждения текущего адреса пользователя
+func SendEmailVerification(user *users.User) error {
+	token, err := NewInvitationToken()
+	if err != nil {
+		return err
+	}
+	verification := users.EmailVerification{
+		UserID:    user.ID,
+		Email:     strings.ToLower(strings.TrimSpace(user.Email)),
+		Token:     token,
+		ExpiresAt: time.Now().Add(EmailVerificationTTL),
+	}
+	if err := config.DB.Create(&verification).Error; err != nil {
+		return err
+	}
+
+	subject := "Confirm your email on Hired Valley"
+	body := fmt.Sprintf("Hi %s,\n\nConfirm that %s is your email address: %s\n\nThe link expires on %s.",
+		user.Name, user.Email, EmailVerificationURL(token), verification.ExpiresAt.Format("January 2, 2006"))
+	return GetMailer().Send(user.Email, subject, body)
+}
+
+// ConfirmEmailVerification - подтверждение адреса по ссылке; ссылка одноразовая и недействительна,
+// если пользователь сменил адрес после ее отправки
+func ConfirmEmailVerification(token string) (*users.User, error) {
+	var user users.User
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var verification users.EmailVerification
+		if err := tx.Where("token = ? AND expires_at > ?", token, time.Now()).First(&verification).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrVerificationInvalid
+			}
+			return err
+		}
+		if err := tx.First(&user, verification.UserID).Error; err != nil {
+			return err
+		}
+		if !strings.EqualFold(strings.TrimSpace(user.Email), verification.Email) {
+			return ErrVerificationInvalid
+		}
+		now := time.Now()
+		if err := tx.Model(&user).Update("email_verified_at", now).Error; err != nil {
+			return err
+		}
+		user.EmailVerifiedAt = &now
+		return tx.Where("user_id = ?", user.ID).Delete(&users.EmailVerification{}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
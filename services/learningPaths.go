@@ -0,0 +1,456 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+	"hired-valley-backend/config"
+	"hired-valley-backend/models/analytics"
+	"hired-valley-backend/models/career"
+	"hired-valley-backend/models/content"
+	"hired-valley-backend/models/courses"
+	"hired-valley-backend/models/learning"
+	"hired-valley-backend/models/users"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// ErrNothingToLearn - по карьерному плану не нашлось ни курсов, ни контента, ни менторов
+var ErrNothingToLearn = errors.New("no courses, content or mentors match this career plan")
+
+// maxPathKeywords - ограничение ключевых слов из текста шага карьерного плана
+const maxPathKeywords = 12
+
+// CanViewPath - опубликованный путь виден всем, черновик - автору, личный путь - только владельцу;
+// администратору доступны все пути
+func CanViewPath(user *users.User, path *learning.Path) bool {
+	if user != nil && user.Role == "admin" {
+		return true
+	}
+	if path.OwnerID != nil {
+		return user != nil && *path.OwnerID == user.ID
+	}
+	if path.Status != learning.PathStatusDraft {
+		return true
+	}
+	return user != nil && path.AuthorID == user.ID
+}
+
+// CanEditPath - редактировать путь могут автор и администратор
+func CanEditPath(user *users.User, path *learning.Path) bool {
+	if user == nil {
+		return false
+	}
+	return user.Role == "admin" || path.AuthorID == user.ID
+}
+
+// ValidatePathStep - проверка ссылки шага на курс, контент, тест или навык; пустое название
+// заполняется из связанного объекта, лишние ссылки сбрасываются
+func ValidatePathStep(step *learning.PathStep) error {
+	step.Title = strings.TrimSpace(step.Title)
+	step.Skill = strings.TrimSpace(step.Skill)
+	switch step.Type {
+	case learning.StepCourse:
+		var course courses.Course
+		if step.CourseID == nil || config.DB.First(&course, *step.CourseID).Error != nil {
+			return errors.New("course step requires an existing course_id")
+		}
+		originID := CourseOriginID(&course)
+		step.CourseID = &originID
+		step.ContentID, step.QuizID, step.Skill = nil, nil, ""
+		if step.Title == "" {
+			step.Title = course.Title
+		}
+	case learning.StepContent:
+		var item content.Content
+		if step.ContentID == nil || config.DB.First(&item, *step.ContentID).Error != nil {
+			return errors.New("content step requires an existing content_id")
+		}
+		step.CourseID, step.QuizID, step.Skill = nil, nil, ""
+		if step.Title == "" {
+			step.Title = item.Title
+		}
+	case learning.StepQuiz:
+		var quiz courses.Quiz
+		if step.QuizID == nil || config.DB.First(&quiz, *step.QuizID).Error != nil {
+			return errors.New("quiz step requires an existing quiz_id")
+		}
+		step.CourseID, step.ContentID, step.Skill = nil, nil, ""
+		if step.Title == "" {
+			step.Title = quiz.Title
+		}
+	case learning.StepMentorSession:
+		if step.Skill == "" {
+			return errors.New("mentor_session step requires a skill")
+		}
+		step.CourseID, step.ContentID, step.QuizID = nil, nil, nil
+		if step.Title == "" {
+			step.Title = "Book a session with a mentor in " + step.Skill
+		}
+	default:
+		return fmt.Errorf("step type must be one of %q, %q, %q, %q",
+			learning.StepCourse, learning.StepContent, learning.StepQuiz, learning.StepMentorSession)
+	}
+	return nil
+}
+
+// StepProgress - шаг пути с отметкой о выполнении
+type StepProgress struct {
+	learning.PathStep
+	Completed        bool       `json:"completed"`
+	CompletedAt      *time.Time `json:"completed_at"`
+	Manual           bool       `json:"manual"`
+	Percent          float64    `json:"percent,omitempty"`           // Прохождение курса для шага course
+	SuggestedMentors []uint     `json:"suggested_mentors,omitempty"` // Профили менторов с нужным навыком
+}
+
+// PathProgress - прогресс пользователя по учебному пути; процент считается по обязательным шагам
+type PathProgress struct {
+	PathID         uint           `json:"path_id"`
+	EnrollmentID   uint           `json:"enrollment_id"`
+	TotalSteps     int            `json:"total_steps"`
+	CompletedSteps int            `json:"completed_steps"`
+	Percent        float64        `json:"percent"`
+	NextStepID     *uint          `json:"next_step_id"` // Первый невыполненный шаг, nil - путь пройден
+	CompletedAt    *time.Time     `json:"completed_at"`
+	Steps          []StepProgress `json:"steps"`
+}
+
+// courseStepPercent - лучший прогресс пользователя среди версий курса, на которые он записан
+func courseStepPercent(userID, originID uint) (float64, bool, error) {
+	var enrolled []courses.Course
+	if err := config.DB.Joins("JOIN enrollments ON enrollments.course_id = courses.id").
+		Where("enrollments.user_id = ? AND (courses.id = ? OR courses.origin_id = ?)", userID, originID, originID).
+		Find(&enrolled).Error; err != nil {
+		return 0, false, err
+	}
+	best, done := 0.0, false
+	for _, course := range enrolled {
+		progress, err := GetCourseProgress(userID, course)
+		if err != nil {
+			return 0, false, err
+		}
+		if progress.Percent > best {
+			best = progress.Percent
+		}
+		if progress.TotalLessons > 0 && progress.CompletedLessons == progress.TotalLessons {
+			done = true
+		}
+	}
+	return best, done, nil
+}
+
+// stepDone - выполнен ли шаг автоматически: курс пройден, контент просмотрен, тест сдан,
+// сессия с ментором нужного навыка проведена после записи на путь (since)
+func stepDone(userID uint, step learning.PathStep, since time.Time) (bool, error) {
+	var count int64
+	switch step.Type {
+	case learning.StepContent:
+		err := config.DB.Model(&analytics.ViewEvent{}).
+			Where("item_type = ? AND item_id = ? AND user_id = ?", analytics.ItemContent, *step.ContentID, userID).
+			Count(&count).Error
+		return count > 0, err
+	case learning.StepQuiz:
+		err := config.DB.Model(&courses.QuizAttempt{}).
+			Where("quiz_id = ? AND user_id = ? AND passed = ?", *step.QuizID, userID, true).Count(&count).Error
+		return count > 0, err
+	case learning.StepMentorSession:
+		err := config.DB.Model(&users.Slot{}).
+			Joins("JOIN mentor_skills ms ON ms.mentor_profile_id = slots.mentor_id").
+			Joins("JOIN skills s ON s.id = ms.skill_id").
+			Where("slots.user_id = ? AND slots.is_booked = ? AND slots.end_time < ? AND slots.start_time >= ? AND LOWER(s.name) = ?",
+				userID, true, time.Now(), since, strings.ToLower(step.Skill)).
+			Count(&count).Error
+		return count > 0, err
+	}
+	return false, nil
+}
+
+// MentorsForSkill - активные менторы с навыком по убыванию рейтинга
+func MentorsForSkill(skill string, limit int) []uint {
+	var ids []uint
+	config.DB.Model(&users.MentorProfile{}).
+		Joins("JOIN mentor_skills ms ON ms.mentor_profile_id = mentor_profiles.id").
+		Joins("JOIN skills s ON s.id = ms.skill_id").
+		Where("mentor_profiles.status = ? AND LOWER(s.name) = ?", users.MentorActive, strings.ToLower(skill)).
+		Order("mentor_profiles.average_rating DESC").Limit(limit).
+		Pluck("mentor_profiles.id", &ids)
+	return ids
+}
+
+// SyncPathProgress - прогресс по пути: выполненные автоматически шаги сохраняются, при выполнении
+// всех обязательных шагов путь отмечается пройденным. steps - шаги пути по порядку
+func SyncPathProgress(enrollment *learning.PathEnrollment, steps []learning.PathStep) (PathProgress, error) {
+	result := PathProgress{PathID: enrollment.PathID, EnrollmentID: enrollment.ID, Steps: []StepProgress{}}
+
+	var completions []learning.StepCompletion
+	if err := config.DB.Where("enrollment_id = ?", enrollment.ID).Find(&completions).Error; err != nil {
+		return result, err
+	}
+	done := map[uint]learning.StepCompletion{}
+	for _, completion := range completions {
+		done[completion.StepID] = completion
+	}
+
+	complete := func(step learning.PathStep) {
+		completion := learning.StepCompletion{EnrollmentID: enrollment.ID, StepID: step.ID, CompletedAt: time.Now()}
+		config.DB.Create(&completion)
+		done[step.ID] = completion
+	}
+
+	for _, step := range steps {
+		item := StepProgress{PathStep: step}
+		_, completed := done[step.ID]
+		if step.Type == learning.StepCourse {
+			percent, finished, err := courseStepPercent(enrollment.UserID, *step.CourseID)
+			if err != nil {
+				return result, err
+			}
+			item.Percent = percent
+			if finished && !completed {
+				complete(step)
+			}
+		} else if !completed {
+			finished, err := stepDone(enrollment.UserID, step, enrollment.CreatedAt)
+			if err != nil {
+				return result, err
+			}
+			if finished {
+				complete(step)
+			}
+		}
+
+		if completion, ok := done[step.ID]; ok {
+			item.Completed = true
+			item.CompletedAt = &completion.CompletedAt
+			item.Manual = completion.Manual
+		} else if step.Type == learning.StepMentorSession {
+			item.SuggestedMentors = MentorsForSkill(step.Skill, 3)
+		}
+
+		if !step.Optional {
+			result.TotalSteps++
+			if item.Completed {
+				result.CompletedSteps++
+			} else if result.NextStepID == nil {
+				id := step.ID
+				result.NextStepID = &id
+			}
+		}
+		result.Steps = append(result.Steps, item)
+	}
+
+	if result.TotalSteps > 0 {
+		result.Percent = float64(result.CompletedSteps) * 100 / float64(result.TotalSteps)
+	}
+	if result.TotalSteps > 0 && result.CompletedSteps == result.TotalSteps {
+		if enrollment.CompletedAt == nil {
+			now := time.Now()
+			enrollment.CompletedAt = &now
+			config.DB.Model(enrollment).Update("completed_at", now)
+		}
+	} else if enrollment.CompletedAt != nil {
+		// В путь добавили новые шаги после завершения
+		enrollment.CompletedAt = nil
+		config.DB.Model(enrollment).Update("completed_at", nil)
+	}
+	result.CompletedAt = enrollment.CompletedAt
+	return result, nil
+}
+
+// pathStopWords - слова, не несущие смысла для подбора материалов
+var pathStopWords = map[string]bool{
+	"the": true, "and": true, "for": true, "with": true, "into": true, "from": true, "your": true, "that": true,
+	"this": true, "will": true, "want": true, "become": true, "learn": true, "get": true, "year": true, "years": true,
+	"work": true, "job": true, "role": true, "skills": true, "skill": true, "more": true, "how": true, "about": true,
+	"для": true, "как": true, "что": true, "это": true, "или": true, "при": true, "хочу": true, "стать": true,
+}
+
+// pathKeywords - ключевые слова текста в нижнем регистре без повторов и служебных слов
+func pathKeywords(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '+' && r != '#'
+	})
+	seen := map[string]bool{}
+	result := []string{}
+	for _, word := range words {
+		if len([]rune(word)) < 2 || pathStopWords[word] || seen[word] {
+			continue
+		}
+		seen[word] = true
+		result = append(result, word)
+		if len(result) == maxPathKeywords {
+			break
+		}
+	}
+	return result
+}
+
+// keywordScore - совпадения ключевых слов с тегами (вес 2), названием и описанием
+func keywordScore(keywords []string, tags []string, title, description string) int {
+	title, description = strings.ToLower(title), strings.ToLower(description)
+	tagSet := map[string]bool{}
+	for _, tag := range tags {
+		tagSet[strings.ToLower(tag)] = true
+	}
+	score := 0
+	for _, keyword := range keywords {
+		if tagSet[keyword] {
+			score += 2
+		}
+		if strings.Contains(title, keyword) {
+			score++
+		}
+		if strings.Contains(description, keyword) {
+			score++
+		}
+	}
+	return score
+}
+
+// keywordFilter - условие выборки кандидатов: совпадение тега или вхождение слова в название
+func keywordFilter(db *gorm.DB, keywords []string) *gorm.DB {
+	condition := config.DB.Where("tags && ?", pq.StringArray(keywords))
+	for _, keyword := range keywords {
+		condition = condition.Or("title ILIKE ?", "%"+keyword+"%")
+	}
+	return db.Where(condition)
+}
+
+// bestCourse - наиболее подходящий опубликованный курс, еще не включенный в путь
+func bestCourse(keywords []string, used map[uint]bool) *courses.Course {
+	var candidates []courses.Course
+	keywordFilter(config.DB.Where("status = ?", courses.CourseStatusPublished), keywords).
+		Order("average_rating DESC").Limit(50).Find(&candidates)
+	var best *courses.Course
+	bestScore := 0
+	for i := range candidates {
+		if used[CourseOriginID(&candidates[i])] {
+			continue
+		}
+		if score := keywordScore(keywords, candidates[i].Tags, candidates[i].Title, candidates[i].Description); score > bestScore {
+			best, bestScore = &candidates[i], score
+		}
+	}
+	return best
+}
+
+// bestContent - наиболее подходящее видео из раздела контента, еще не включенное в путь
+func bestContent(keywords []string, used map[uint]bool) *content.Content {
+	var candidates []content.Content
+	keywordFilter(config.DB.Model(&content.Content{}), keywords).Order("created_at DESC").Limit(50).Find(&candidates)
+	var best *content.Content
+	bestScore := 0
+	for i := range candidates {
+		if used[candidates[i].ID] {
+			continue
+		}
+		if score := keywordScore(keywords, candidates[i].Tags, candidates[i].Title, candidates[i].Description); score > bestScore {
+			best, bestScore = &candidates[i], score
+		}
+	}
+	return best
+}
+
+// GeneratePathFromPlan - личный учебный путь по карьерному плану: для каждого шага плана
+// подбираются курс, видео и навык для сессии с ментором по ключевым словам шага
+func GeneratePathFromPlan(plan career.PlanCareer) (*learning.Path, error) {
+	type chunk struct{ title, text string }
+	chunks := []chunk{}
+	planSteps := append([]career.PlanStep(nil), plan.PlanSteps...)
+	sort.Slice(planSteps, func(i, j int) bool { return planSteps[i].ID < planSteps[j].ID })
+	for _, step := range planSteps {
+		if !step.Completed {
+			chunks = append(chunks, chunk{step.Title, step.Title + " " + step.Description})
+		}
+	}
+	if len(chunks) == 0 {
+		chunks = append(chunks,
+			chunk{plan.ShortTermGoals, plan.ShortTermGoals},
+			chunk{plan.LongTermGoals, plan.LongTermGoals + " " + plan.Steps})
+	}
+
+	var skillNames []string
+	config.DB.Model(&users.Skill{}).Pluck("name", &skillNames)
+
+	usedCourses, usedContent, usedSkills := map[uint]bool{}, map[uint]bool{}, map[string]bool{}
+	steps := []learning.PathStep{}
+	for _, part := range chunks {
+		keywords := pathKeywords(part.text)
+		if len(keywords) == 0 {
+			continue
+		}
+		note := ""
+		if strings.TrimSpace(part.title) != "" {
+			note = "Career plan: " + strings.TrimSpace(part.title)
+		}
+
+		if course := bestCourse(keywords, usedCourses); course != nil {
+			originID := CourseOriginID(course)
+			usedCourses[originID] = true
+			steps = append(steps, learning.PathStep{Type: learning.StepCourse, Title: course.Title, Description: note, CourseID: &originID})
+		}
+		if item := bestContent(keywords, usedContent); item != nil {
+			usedContent[item.ID] = true
+			contentID := item.ID
+			steps = append(steps, learning.PathStep{Type: learning.StepContent, Title: item.Title, Description: note, ContentID: &contentID})
+		}
+
+		text := " " + strings.Join(keywords, " ") + " "
+		for _, name := range skillNames {
+			lower := strings.ToLower(name)
+			if usedSkills[lower] || !strings.Contains(text, " "+lower+" ") {
+				continue
+			}
+			usedSkills[lower] = true
+			steps = append(steps, learning.PathStep{
+				Type:        learning.StepMentorSession,
+				Title:       "Book a session with a mentor in " + name,
+				Description: note,
+				Skill:       name,
+			})
+			break
+		}
+	}
+	if len(steps) == 0 {
+		return nil, ErrNothingToLearn
+	}
+
+	title := strings.TrimSpace(plan.ShortTermGoals)
+	if len([]rune(title)) > 80 {
+		title = string([]rune(title)[:80]) + "…"
+	}
+	if title == "" {
+		title = fmt.Sprintf("Career plan #%d", plan.ID)
+	}
+	ownerID, planID := plan.UserID, plan.ID
+	path := learning.Path{
+		Title:        "Learning path: " + title,
+		Description:  strings.TrimSpace(plan.LongTermGoals),
+		AuthorID:     plan.UserID,
+		Status:       learning.PathStatusPublished,
+		OwnerID:      &ownerID,
+		CareerPlanID: &planID,
+	}
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&path).Error; err != nil {
+			return err
+		}
+		for i := range steps {
+			steps[i].PathID = path.ID
+			steps[i].Position = i + 1
+		}
+		if err := tx.Create(&steps).Error; err != nil {
+			return err
+		}
+		return tx.Create(&learning.PathEnrollment{PathID: path.ID, UserID: plan.UserID}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	path.Steps = steps
+	return &path, nil
+}
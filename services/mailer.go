@@ -0,0 +1,77 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net/smtp"
+	"os"
+	"strings"
+)
+
+// Mailer - отправка писем пользователям
+type Mailer interface {
+	Send(to, subject, body string) error
+}
+
+// SMTPMailer - отправка через SMTP-сервер с PLAIN-аутентификацией
+type SMTPMailer struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+var (
+	ErrMailerNotConfigured = errors.New("email delivery is not configured")
+	ErrInvalidEmailHeader  = errors.New("email address or subject contains line breaks")
+)
+
+func (m SMTPMailer) Send(to, subject, body string) error {
+	// Перевод строки в заголовке позволил бы дописать в письмо чужие заголовки
+	if strings.ContainsAny(to, "\r\n") || strings.ContainsAny(subject, "\r\n") {
+		return ErrInvalidEmailHeader
+	}
+	var auth smtp.Auth
+	if m.Username != "" {
+		auth = smtp.PlainAuth("", m.Username, m.Password, m.Host)
+	}
+	message := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\nMIME-Version: 1.0\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\n%s",
+		m.From, to, mime.QEncoding.Encode("UTF-8", subject), strings.ReplaceAll(body, "\n", "\r\n"))
+	return smtp.SendMail(m.Host+":"+m.Port, auth, m.From, []string{to}, []byte(message))
+}
+
+// logMailer - SMTP не настроен: письмо не отправляется, в лог пишется только адресат и тема,
+// потому что текст письма содержит ссылки с секретными токенами
+type logMailer struct{}
+
+func (logMailer) Send(to, subject, body string) error {
+	log.Printf("Email to %s not sent (%q): SMTP is not configured", to, subject)
+	return ErrMailerNotConfigured
+}
+
+// GetMailer - SMTP из переменных SMTP_HOST, SMTP_PORT, SMTP_USERNAME, SMTP_PASSWORD, SMTP_FROM;
+// без SMTP_HOST письма не отправляются и Send возвращает ErrMailerNotConfigured
+func GetMailer() Mailer {
+	host := os.Getenv("SMTP_HOST")
+	if host == "" {
+		return logMailer{}
+	}
+	port := os.Getenv("SMTP_PORT")
+	if port == "" {
+		port = "587"
+	}
+	from := os.Getenv("SMTP_FROM")
+	if from == "" {
+		from = os.Getenv("SMTP_USERNAME")
+	}
+	return SMTPMailer{
+		Host:     host,
+		Port:     port,
+		Username: os.Getenv("SMTP_USERNAME"),
+		Password: os.Getenv("SMTP_PASSWORD"),
+		From:     from,
+	}
+}
@@ -0,0 +1,78 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultMeetingBaseURL = "https://meet.jit.si"
+
+// MeetingRoom - комната видеовстречи для забронированной сессии
+type MeetingRoom struct {
+	Provider string
+	RoomID   string
+	JoinURL  string
+}
+
+// MeetingProvider - провайдер видеовстреч (встроенный, Zoom, Google Meet и т.д.)
+type MeetingProvider interface {
+	Name() string
+	CreateRoom(slotID uint, startTime, endTime time.Time) (MeetingRoom, error)
+}
+
+// BuiltinMeetingProvider - генерирует уникальные ссылки на комнаты от базового URL
+type BuiltinMeetingProvider struct {
+	BaseURL string
+}
+
+func (p BuiltinMeetingProvider) Name() string {
+	return "builtin"
+}
+
+func (p BuiltinMeetingProvider) CreateRoom(slotID uint, startTime, endTime time.Time) (MeetingRoom, error) {
+	buf := make([]byte, 12)
+	if _, err := rand.Read(buf); err != nil {
+		return MeetingRoom{}, fmt.Errorf("failed to generate room id: %w", err)
+	}
+
+	roomID := fmt.Sprintf("hv-%d-%s", slotID, hex.EncodeToString(buf))
+	return MeetingRoom{
+		Provider: p.Name(),
+		RoomID:   roomID,
+		JoinURL:  strings.TrimRight(p.BaseURL, "/") + "/" + roomID,
+	}, nil
+}
+
+var meetingProviders = map[string]MeetingProvider{}
+
+// RegisterMeetingProvider - регистрация дополнительного провайдера по имени
+func RegisterMeetingProvider(provider MeetingProvider) {
+	meetingProviders[provider.Name()] = provider
+}
+
+// GetMeetingProvider - провайдер из переменной MEETING_PROVIDER, по умолчанию встроенный
+func GetMeetingProvider() MeetingProvider {
+	if provider, ok := meetingProviders[os.Getenv("MEETING_PROVIDER")]; ok {
+		return provider
+	}
+
+	baseURL := os.Getenv("MEETING_BASE_URL")
+	if baseURL == "" {
+		baseURL = defaultMeetingBaseURL
+	}
+	return BuiltinMeetingProvider{BaseURL: baseURL}
+}
+
+// MeetingJoinWindow - за сколько до начала сессии открывается ссылка (MEETING_JOIN_WINDOW_MINUTES)
+func MeetingJoinWindow() time.Duration {
+	minutes, err := strconv.Atoi(os.Getenv("MEETING_JOIN_WINDOW_MINUTES"))
+	if err != nil || minutes <= 0 {
+		minutes = 10
+	}
+	return time.Duration(minutes) * time.Minute
+}
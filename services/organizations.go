@@ -0,0 +1,421 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"hired-valley-backend/config"
+	"hired-valley-backend/models/courses"
+	"hired-valley-backend/models/users"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNoSeatsAvailable  = errors.New("no seats available for this course")
+	ErrAlreadyEnrolled   = errors.New("user is already enrolled in this course")
+	ErrNotOrgMember      = errors.New("user is not a member of the organization")
+	ErrNoCreditsLeft     = errors.New("not enough credits left in the pool")
+	ErrDomainNotVerified = errors.New("verification TXT record was not found for the domain")
+	ErrDomainClaimed     = errors.New("domain is already verified by another organization")
+)
+
+// OrganizationInvitationTTL - срок действия приглашения в организацию
+const OrganizationInvitationTTL = 14 * 24 * time.Hour
+
+// OrganizationRole - роль пользователя в организации или "", если он не участник
+func OrganizationRole(userID, organizationID uint) string {
+	var member users.OrganizationMember
+	if err := config.DB.Where("organization_id = ? AND user_id = ?", organizationID, userID).First(&member).Error; err != nil {
+		return ""
+	}
+	return member.Role
+}
+
+// CanManageOrganization - администратор организации или администратор платформы
+func CanManageOrganization(user *users.User, organizationID uint) bool {
+	if user == nil {
+		return false
+	}
+	return user.Role == "admin" || OrganizationRole(user.ID, organizationID) == users.OrgRoleAdmin
+}
+
+// EmailDomain - домен адреса в нижнем регистре или "", если адрес некорректен
+func EmailDomain(email string) string {
+	at := strings.LastIndex(email, "@")
+	if at < 0 || at == len(email)-1 {
+		return ""
+	}
+	return strings.ToLower(strings.TrimSpace(email[at+1:]))
+}
+
+// NormalizeEmailDomains - домены организации без повторов; публичные почтовые сервисы не допускаются
+func NormalizeEmailDomains(domains []string) ([]string, error) {
+	public := map[string]bool{"gmail.com": true, "yahoo.com": true, "outlook.com": true, "hotmail.com": true,
+		"icloud.com": true, "mail.ru": true, "yandex.ru": true, "proton.me": true}
+	seen := map[string]bool{}
+	result := []string{}
+	for _, domain := range domains {
+		domain = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(domain), "@"))
+		if domain == "" || seen[domain] {
+			continue
+		}
+		if !strings.Contains(domain, ".") || strings.ContainsAny(domain, " @/") {
+			return nil, fmt.Errorf("invalid email domain %q", domain)
+		}
+		if public[domain] {
+			return nil, fmt.Errorf("public email domain %q cannot be used", domain)
+		}
+		seen[domain] = true
+		result = append(result, domain)
+	}
+	return result, nil
+}
+
+// SyncOrganizationDomains - заявленные домены организации в транзакции tx: новые ожидают подтверждения,
+// убранные из списка удаляются вместе с подтверждением
+func SyncOrganizationDomains(tx *gorm.DB, organizationID uint, domains []string) error {
+	removed := tx.Where("organization_id = ?", organizationID)
+	if len(domains) > 0 {
+		removed = removed.Where("domain NOT IN ?", domains)
+	}
+	if err := removed.Delete(&users.OrganizationDomain{}).Error; err != nil {
+		return err
+	}
+	for _, domain := range domains {
+		var existing int64
+		if err := tx.Model(&users.OrganizationDomain{}).Where("organization_id = ? AND domain = ?", organizationID, domain).
+			Count(&existing).Error; err != nil {
+			return err
+		}
+		if existing > 0 {
+			continue
+		}
+		token, err := NewInvitationToken()
+		if err != nil {
+			return err
+		}
+		if err := tx.Create(&users.OrganizationDomain{OrganizationID: organizationID, Domain: domain, Token: token}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DomainVerificationRecord - значение TXT-записи, которую организация добавляет в DNS домена
+func DomainVerificationRecord(domain *users.OrganizationDomain) string {
+	return "hired-valley-verification=" + domain.Token
+}
+
+// hasVerificationRecord - есть ли среди TXT-записей домена запись подтверждения
+func hasVerificationRecord(records []string, expected string) bool {
+	for _, record := range records {
+		if strings.TrimSpace(record) == expected {
+			return true
+		}
+	}
+	return false
+}
+
+// VerifyOrganizationDomain - подтверждение владения доменом по TXT-записи. Подтвержденный домен
+// принадлежит только одной организации
+func VerifyOrganizationDomain(domain *users.OrganizationDomain) error {
+	if domain.VerifiedAt != nil {
+		return nil
+	}
+	var claimed int64
+	if err := config.DB.Model(&users.OrganizationDomain{}).
+		Where("domain = ? AND organization_id <> ? AND verified_at IS NOT NULL", domain.Domain, domain.OrganizationID).
+		Count(&claimed).Error; err != nil {
+		return err
+	}
+	if claimed > 0 {
+		return ErrDomainClaimed
+	}
+
+	records, err := net.LookupTXT(domain.Domain)
+	if err != nil || !hasVerificationRecord(records, DomainVerificationRecord(domain)) {
+		return ErrDomainNotVerified
+	}
+	now := time.Now()
+	if err := config.DB.Model(domain).Update("verified_at", now).Error; err != nil {
+		return err
+	}
+	domain.VerifiedAt = &now
+	return nil
+}
+
+// JoinableDomain - домен email пользователя, по которому можно вступать в организации, или "",
+// если адрес не подтвержден
+func JoinableDomain(user *users.User) string {
+	if user == nil || user.EmailVerifiedAt == nil {
+		return ""
+	}
+	return EmailDomain(user.Email)
+}
+
+// CanJoinByDomain - подтвержден ли email пользователя и домен его адреса у организации
+func CanJoinByDomain(organizationID uint, user *users.User) bool {
+	domain := JoinableDomain(user)
+	if domain == "" {
+		return false
+	}
+	var count int64
+	config.DB.Model(&users.OrganizationDomain{}).
+		Where("organization_id = ? AND domain = ? AND verified_at IS NOT NULL", organizationID, domain).Count(&count)
+	return count > 0
+}
+
+// PublishedCourseVersion - текущая опубликованная версия курса по ID первой версии
+func PublishedCourseVersion(originID uint) (*courses.Course, error) {
+	var course courses.Course
+	err := config.DB.Where("(id = ? OR origin_id = ?) AND status = ?", originID, originID, courses.CourseStatusPublished).
+		Order("version DESC").First(&course).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrCourseNotFound
+	}
+	return &course, err
+}
+
+// AssignCourseSeat - выдача участнику места на опубликованной версии курса в транзакции tx.
+// Места курса блокируются, чтобы параллельные назначения не превысили купленное количество
+func AssignCourseSeat(tx *gorm.DB, organizationID uint, course *courses.Course, userID, assignedByID uint) (*users.OrganizationSeatAssignment, error) {
+	originID := CourseOriginID(course)
+
+	var member users.OrganizationMember
+	if err := tx.Where("organization_id = ? AND user_id = ?", organizationID, userID).First(&member).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotOrgMember
+		}
+		return nil, err
+	}
+
+	var enrolled int64
+	tx.Model(&courses.Enrollment{}).
+		Where("user_id = ? AND course_id IN (?)", userID, tx.Model(&courses.Course{}).Select("id").Where("id = ? OR origin_id = ?", originID, originID)).
+		Count(&enrolled)
+	if enrolled > 0 {
+		return nil, ErrAlreadyEnrolled
+	}
+
+	var seats users.OrganizationCourseSeats
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("organization_id = ? AND course_id = ?", organizationID, originID).First(&seats).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNoSeatsAvailable
+		}
+		return nil, err
+	}
+	if seats.SeatsUsed >= seats.SeatsTotal {
+		return nil, ErrNoSeatsAvailable
+	}
+
+	enrollment := courses.Enrollment{UserID: userID, CourseID: course.ID, Source: courses.EnrollmentOrganization}
+	if err := tx.Create(&enrollment).Error; err != nil {
+		return nil, err
+	}
+	assignment := users.OrganizationSeatAssignment{
+		OrganizationID: organizationID,
+		CourseID:       originID,
+		UserID:         userID,
+		EnrollmentID:   enrollment.ID,
+		AssignedByID:   assignedByID,
+	}
+	if err := tx.Create(&assignment).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Model(&seats).UpdateColumn("seats_used", gorm.Expr("seats_used + 1")).Error; err != nil {
+		return nil, err
+	}
+	return &assignment, nil
+}
+
+// ReleaseCourseSeat - отзыв места: запись на курс удаляется, место возвращается организации.
+// Прогресс урока сохраняется и учитывается, если место будет выдано снова
+func ReleaseCourseSeat(tx *gorm.DB, assignment *users.OrganizationSeatAssignment) error {
+	if err := tx.Delete(&courses.Enrollment{}, assignment.EnrollmentID).Error; err != nil {
+		return err
+	}
+	if err := tx.Delete(assignment).Error; err != nil {
+		return err
+	}
+	return tx.Model(&users.OrganizationCourseSeats{}).
+		Where("organization_id = ? AND course_id = ? AND seats_used > 0", assignment.OrganizationID, assignment.CourseID).
+		UpdateColumn("seats_used", gorm.Expr("seats_used - 1")).Error
+}
+
+// AllocateOrganizationCredits - выдача участнику кредитов из пула организации: создается пакет
+// участника с тем же ментором и сроком действия, что у пула
+func AllocateOrganizationCredits(tx *gorm.DB, pool *users.OrganizationCreditPool, userID uint, credits int) (*users.PackagePurchase, error) {
+	var member users.OrganizationMember
+	if err := tx.Where("organization_id = ? AND user_id = ?", pool.OrganizationID, userID).First(&member).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotOrgMember
+		}
+		return nil, err
+	}
+
+	var locked users.OrganizationCreditPool
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&locked, pool.ID).Error; err != nil {
+		return nil, err
+	}
+	if locked.CreditsTotal-locked.CreditsAllocated < credits {
+		return nil, ErrNoCreditsLeft
+	}
+
+	purchase := users.PackagePurchase{
+		PackageID:        locked.PackageID,
+		MentorID:         locked.MentorID,
+		UserID:           userID,
+		CreditsTotal:     credits,
+		CreditsRemaining: credits,
+		Status:           users.PurchaseActive,
+		ExpiresAt:        locked.ExpiresAt,
+		CreditPoolID:     &locked.ID,
+	}
+	if err := tx.Create(&purchase).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Model(&locked).UpdateColumn("credits_allocated", gorm.Expr("credits_allocated + ?", credits)).Error; err != nil {
+		return nil, err
+	}
+	pool.CreditsAllocated = locked.CreditsAllocated + credits
+	return &purchase, nil
+}
+
+// ReclaimOrganizationCredits - возврат неиспользованных кредитов участника в пул организации
+func ReclaimOrganizationCredits(tx *gorm.DB, purchase *users.PackagePurchase) (int, error) {
+	unused := purchase.CreditsRemaining
+	if purchase.CreditPoolID == nil || unused <= 0 {
+		return 0, nil
+	}
+	if err := tx.Model(purchase).Updates(map[string]interface{}{
+		"credits_total":     purchase.CreditsTotal - unused,
+		"credits_remaining": 0,
+	}).Error; err != nil {
+		return 0, err
+	}
+	err := tx.Model(&users.OrganizationCreditPool{}).Where("id = ?", *purchase.CreditPoolID).
+		UpdateColumn("credits_allocated", gorm.Expr("credits_allocated - ?", unused)).Error
+	return unused, err
+}
+
+// OrganizationInvitationURL - ссылка для принятия приглашения в организацию (ORGANIZATION_INVITATION_BASE_URL)
+func OrganizationInvitationURL(token string) string {
+	base := strings.TrimRight(os.Getenv("ORGANIZATION_INVITATION_BASE_URL"), "/")
+	return base + "/organizations/invitations/accept?token=" + token
+}
+
+// SendOrganizationInvitation - письмо с приглашением в организацию
+func SendOrganizationInvitation(invitation users.OrganizationInvitation, organization users.Organization, inviter users.User) error {
+	subject := fmt.Sprintf("Join %s on Hired Valley", organization.Name)
+	body := fmt.Sprintf("%s invited you to join %s. The organization gives its members access to courses and mentoring sessions.\n\nAccept the invitation: %s\n\nThe invitation expires on %s.",
+		inviter.Name, organization.Name, OrganizationInvitationURL(invitation.Token),
+		invitation.ExpiresAt.Format("January 2, 2006"))
+	return GetMailer().Send(invitation.Email, subject, body)
+}
+
+// MemberCourseProgress - прогресс участника организации по выданному курсу
+type MemberCourseProgress struct {
+	UserID           uint       `json:"user_id"`
+	Name             string     `json:"name"`
+	Email            string     `json:"email"`
+	CourseID         uint       `json:"course_id"` // ID первой версии курса
+	CourseTitle      string     `json:"course_title"`
+	AssignedAt       time.Time  `json:"assigned_at"`
+	TotalLessons     int64      `json:"total_lessons"`
+	CompletedLessons int64      `json:"completed_lessons"`
+	Percent          float64    `json:"percent"`
+	Completed        bool       `json:"completed"`
+	LastActivityAt   *time.Time `json:"last_activity_at"`
+}
+
+// OrganizationCourseSummary - сводка по курсу организации
+type OrganizationCourseSummary struct {
+	CourseID       uint    `json:"course_id"`
+	CourseTitle    string  `json:"course_title"`
+	SeatsTotal     int     `json:"seats_total"`
+	SeatsUsed      int     `json:"seats_used"`
+	Started        int     `json:"started"`
+	Completed      int     `json:"completed"`
+	AveragePercent float64 `json:"average_percent"`
+}
+
+// OrganizationProgressReport - прогресс участников по выданным курсам; courseID = 0 - по всем курсам
+func OrganizationProgressReport(organizationID, courseID uint) ([]MemberCourseProgress, []OrganizationCourseSummary, error) {
+	query := config.DB.Where("organization_id = ?", organizationID)
+	if courseID != 0 {
+		query = query.Where("course_id = ?", courseID)
+	}
+	var assignments []users.OrganizationSeatAssignment
+	if err := query.Order("course_id, created_at").Find(&assignments).Error; err != nil {
+		return nil, nil, err
+	}
+
+	rows := []MemberCourseProgress{}
+	summaries := map[uint]*OrganizationCourseSummary{}
+	counts := map[uint]int{}
+	order := []uint{}
+	for _, assignment := range assignments {
+		var enrollment courses.Enrollment
+		var course courses.Course
+		if err := config.DB.First(&enrollment, assignment.EnrollmentID).Error; err != nil {
+			continue
+		}
+		if err := config.DB.Unscoped().First(&course, enrollment.CourseID).Error; err != nil {
+			continue
+		}
+		progress, err := GetCourseProgress(assignment.UserID, course)
+		if err != nil {
+			return nil, nil, err
+		}
+		var member users.User
+		config.DB.First(&member, assignment.UserID)
+
+		row := MemberCourseProgress{
+			UserID:           assignment.UserID,
+			Name:             member.Name,
+			Email:            member.Email,
+			CourseID:         assignment.CourseID,
+			CourseTitle:      course.Title,
+			AssignedAt:       assignment.CreatedAt,
+			TotalLessons:     progress.TotalLessons,
+			CompletedLessons: progress.CompletedLessons,
+			Percent:          progress.Percent,
+			Completed:        progress.TotalLessons > 0 && progress.CompletedLessons == progress.TotalLessons,
+			LastActivityAt:   progress.LastActivityAt,
+		}
+		rows = append(rows, row)
+
+		summary, ok := summaries[assignment.CourseID]
+		if !ok {
+			summary = &OrganizationCourseSummary{CourseID: assignment.CourseID, CourseTitle: course.Title}
+			summaries[assignment.CourseID] = summary
+			order = append(order, assignment.CourseID)
+		}
+		if row.LastActivityAt != nil {
+			summary.Started++
+		}
+		if row.Completed {
+			summary.Completed++
+		}
+		summary.AveragePercent += row.Percent
+		counts[assignment.CourseID]++
+	}
+
+	result := []OrganizationCourseSummary{}
+	for _, id := range order {
+		summary := summaries[id]
+		var seats users.OrganizationCourseSeats
+		if err := config.DB.Where("organization_id = ? AND course_id = ?", organizationID, id).First(&seats).Error; err == nil {
+			summary.SeatsTotal = seats.SeatsTotal
+			summary.SeatsUsed = seats.SeatsUsed
+		}
+		summary.AveragePercent /= float64(counts[id])
+		result = append(result, *summary)
+	}
+	return rows, result, nil
+}
@@ -0,0 +1,60 @@
+package services
+
+import (
+	"hired-valley-backend/models/users"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNormalizeEmailDomains(t *testing.T) {
+	domains, err := NormalizeEmailDomains([]string{" Acme.COM ", "@acme.com", "", "eu.acme.com"})
+	if err != nil {
+		t.Fatalf("normalize: %v", err)
+	}
+	if want := []string{"acme.com", "eu.acme.com"}; !reflect.DeepEqual(domains, want) {
+		t.Fatalf("domains %v, want %v", domains, want)
+	}
+
+	for _, invalid := range []string{"localhost", "acme .com", "user@acme.com", "acme.com/path", "Gmail.com"} {
+		if _, err := NormalizeEmailDomains([]string{invalid}); err == nil {
+			t.Errorf("domain %q accepted", invalid)
+		}
+	}
+}
+
+func TestHasVerificationRecord(t *testing.T) {
+	domain := &users.OrganizationDomain{Domain: "acme.com", Token: "abc123"}
+	expected := DomainVerificationRecord(domain)
+	if expected != "hired-valley-verification=abc123" {
+		t.Fatalf("unexpected record %q", expected)
+	}
+
+	if !hasVerificationRecord([]string{"v=spf1 -all", " " + expected + " "}, expected) {
+		t.Fatal("verification record not found")
+	}
+	for _, records := range [][]string{nil, {"v=spf1 -all"}, {"hired-valley-verification=abc1234"}, {"hired-valley-verification="}} {
+		if hasVerificationRecord(records, expected) {
+			t.Errorf("records %q accepted", records)
+		}
+	}
+}
+
+func TestJoinableDomain(t *testing.T) {
+	verified := time.Now()
+	cases := []struct {
+		name string
+		user *users.User
+		want string
+	}{
+		{"verified email", &users.User{Email: "Ann@Acme.com", EmailVerifiedAt: &verified}, "acme.com"},
+		{"unverified email", &users.User{Email: "ann@acme.com"}, ""},
+		{"email without domain", &users.User{Email: "ann@", EmailVerifiedAt: &verified}, ""},
+		{"no user", nil, ""},
+	}
+	for _, c := range cases {
+		if got := JoinableDomain(c.user); got != c.want {
+			t.Errorf("%s: got %q, want %q", c.name, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,280 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"hired-valley-backend/models/billing"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrPaymentsUnavailable = errors.New("payments are not configured")
+	ErrPaymentNotVerified  = errors.New("payment is not completed or does not match the order")
+	ErrPaymentAlreadyUsed  = errors.New("payment has already been used")
+	ErrRefundNotAllowed    = errors.New("payment cannot be refunded for this amount")
+)
+
+// PaymentOrder - оплачиваемая покупка: сумма в минимальных единицах Currency, назначение и покупатель
+type PaymentOrder struct {
+	Purpose     string
+	ItemID      uint
+	UserID      uint
+	Amount      int64
+	Currency    string
+	Description string
+}
+
+// metadata - метаданные платежа, по которым он привязывается к покупке и покупателю
+func (o PaymentOrder) metadata() map[string]string {
+	return map[string]string{
+		"purpose": o.Purpose,
+		"item_id": strconv.FormatUint(uint64(o.ItemID), 10),
+		"user_id": strconv.FormatUint(uint64(o.UserID), 10),
+	}
+}
+
+// PaymentIntent - платеж, созданный у платежной системы; клиент оплачивает его по ClientSecret
+type PaymentIntent struct {
+	Reference    string `json:"payment_reference"`
+	ClientSecret string `json:"client_secret,omitempty"`
+	Amount       int64  `json:"amount"`
+	Currency     string `json:"currency"`
+}
+
+// PaymentReceipt - состояние платежа у платежной системы
+type PaymentReceipt struct {
+	Reference string
+	Succeeded bool
+	Amount    int64
+	Currency  string
+	Metadata  map[string]string
+}
+
+// PaymentProvider - платежная система
+type PaymentProvider interface {
+	Name() string
+	CreatePayment(ctx context.Context, order PaymentOrder) (*PaymentIntent, error)
+	// FetchPayment - платеж по ID; неизвестный платеж - ErrPaymentNotVerified
+	FetchPayment(ctx context.Context, reference string) (*PaymentReceipt, error)
+	// RefundPayment - возврат amount по платежу reference; повтор с тем же key не создает второй возврат.
+	// Возвращает ID возврата
+	RefundPayment(ctx context.Context, reference string, amount int64, key string) (string, error)
+}
+
+// GetPaymentProvider - Stripe из переменной STRIPE_SECRET_KEY; nil, если платежи не настроены
+func GetPaymentProvider() PaymentProvider {
+	key := os.Getenv("STRIPE_SECRET_KEY")
+	if key == "" {
+		return nil
+	}
+	return StripePayments{SecretKey: key}
+}
+
+// VerifyPayment - проверка, что платеж завершен и оплачивает именно эту покупку этого пользователя
+func VerifyPayment(ctx context.Context, provider PaymentProvider, reference string, order PaymentOrder) (*PaymentReceipt, error) {
+	if provider == nil {
+		return nil, ErrPaymentsUnavailable
+	}
+	reference = strings.TrimSpace(reference)
+	if reference == "" {
+		return nil, ErrPaymentNotVerified
+	}
+	receipt, err := provider.FetchPayment(ctx, reference)
+	if err != nil {
+		return nil, err
+	}
+	if !receipt.Succeeded || receipt.Reference != reference || receipt.Amount != order.Amount ||
+		!strings.EqualFold(receipt.Currency, order.Currency) {
+		return nil, ErrPaymentNotVerified
+	}
+	for key, value := range order.metadata() {
+		if receipt.Metadata[key] != value {
+			return nil, ErrPaymentNotVerified
+		}
+	}
+	return receipt, nil
+}
+
+// RecordPayment - сохранение проверенного платежа в транзакции покупки; один платеж засчитывается один раз
+func RecordPayment(tx *gorm.DB, provider PaymentProvider, receipt *PaymentReceipt, order PaymentOrder) (*billing.Payment, error) {
+	var used int64
+	if err := tx.Model(&billing.Payment{}).Where("reference = ?", receipt.Reference).Count(&used).Error; err != nil {
+		return nil, err
+	}
+	if used > 0 {
+		return nil, ErrPaymentAlreadyUsed
+	}
+	payment := billing.Payment{
+		Provider:  provider.Name(),
+		Reference: receipt.Reference,
+		Purpose:   order.Purpose,
+		ItemID:    order.ItemID,
+		UserID:    order.UserID,
+		Amount:    order.Amount,
+		Currency:  strings.ToUpper(order.Currency),
+	}
+	if err := tx.Create(&payment).Error; err != nil {
+		return nil, err
+	}
+	return &payment, nil
+}
+
+// RefundPayment - возврат amount по записанному платежу через платежную систему, которой он проведен,
+// и сохранение возврата в транзакции tx. Сумма возвратов не превышает сумму платежа; key защищает
+// от повторного возврата при повторе запроса
+func RefundPayment(ctx context.Context, tx *gorm.DB, provider PaymentProvider, payment *billing.Payment, amount int64,
+	key string) (*billing.Refund, error) {
+	if provider == nil || provider.Name() != payment.Provider {
+		return nil, ErrPaymentsUnavailable
+	}
+	var refunded int64
+	if err := tx.Model(&billing.Refund{}).Where("payment_id = ?", payment.ID).
+		Select("COALESCE(SUM(amount), 0)").Scan(&refunded).Error; err != nil {
+		return nil, err
+	}
+	if amount <= 0 || refunded+amount > payment.Amount {
+		return nil, ErrRefundNotAllowed
+	}
+
+	reference, err := provider.RefundPayment(ctx, payment.Reference, amount, key)
+	if err != nil {
+		return nil, err
+	}
+	refund := billing.Refund{PaymentID: payment.ID, Reference: reference, Amount: amount}
+	if err := tx.Create(&refund).Error; err != nil {
+		return nil, err
+	}
+	return &refund, nil
+}
+
+const stripeAPI = "https://api.stripe.com/v1"
+
+// StripePayments - платежи через Stripe Payment Intents
+type StripePayments struct {
+	SecretKey string
+}
+
+func (StripePayments) Name() string {
+	return "stripe"
+}
+
+// stripePaymentIntent - поля Payment Intent, которые используются при проверке
+type stripePaymentIntent struct {
+	ID           string            `json:"id"`
+	ClientSecret string            `json:"client_secret"`
+	Status       string            `json:"status"`
+	Amount       int64             `json:"amount"`
+	Currency     string            `json:"currency"`
+	Metadata     map[string]string `json:"metadata"`
+}
+
+func (s StripePayments) CreatePayment(ctx context.Context, order PaymentOrder) (*PaymentIntent, error) {
+	form := url.Values{}
+	form.Set("amount", strconv.FormatInt(order.Amount, 10))
+	form.Set("currency", strings.ToLower(order.Currency))
+	form.Set("automatic_payment_methods[enabled]", "true")
+	if order.Description != "" {
+		form.Set("description", order.Description)
+	}
+	for key, value := range order.metadata() {
+		form.Set("metadata["+key+"]", value)
+	}
+
+	var intent stripePaymentIntent
+	if _, err := s.call(ctx, http.MethodPost, "/payment_intents", form, "", &intent); err != nil {
+		return nil, err
+	}
+	return &PaymentIntent{
+		Reference:    intent.ID,
+		ClientSecret: intent.ClientSecret,
+		Amount:       intent.Amount,
+		Currency:     strings.ToUpper(intent.Currency),
+	}, nil
+}
+
+func (s StripePayments) FetchPayment(ctx context.Context, reference string) (*PaymentReceipt, error) {
+	var intent stripePaymentIntent
+	status, err := s.call(ctx, http.MethodGet, "/payment_intents/"+url.PathEscape(reference), nil, "", &intent)
+	if status == http.StatusNotFound {
+		return nil, ErrPaymentNotVerified
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &PaymentReceipt{
+		Reference: intent.ID,
+		Succeeded: intent.Status == "succeeded",
+		Amount:    intent.Amount,
+		Currency:  strings.ToUpper(intent.Currency),
+		Metadata:  intent.Metadata,
+	}, nil
+}
+
+func (s StripePayments) RefundPayment(ctx context.Context, reference string, amount int64, key string) (string, error) {
+	form := url.Values{}
+	form.Set("payment_intent", reference)
+	form.Set("amount", strconv.FormatInt(amount, 10))
+
+	var refund struct {
+		ID     string `json:"id"`
+		Status string `json:"status"`
+	}
+	if _, err := s.call(ctx, http.MethodPost, "/refunds", form, key, &refund); err != nil {
+		return "", err
+	}
+	if refund.Status == "failed" || refund.Status == "canceled" {
+		return "", fmt.Errorf("stripe refund %s is %s", refund.ID, refund.Status)
+	}
+	return refund.ID, nil
+}
+
+// call - запрос к API Stripe (idempotencyKey - ключ идемпотентности, "" - без него); возвращает HTTP-статус ответа
+func (s StripePayments) call(ctx context.Context, method, path string, form url.Values, idempotencyKey string,
+	out interface{}) (int, error) {
+	var body io.Reader
+	if form != nil {
+		body = strings.NewReader(form.Encode())
+	}
+	req, err := http.NewRequestWithContext(ctx, method, stripeAPI+path, body)
+	if err != nil {
+		return 0, err
+	}
+	req.SetBasicAuth(s.SecretKey, "")
+	if form != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	if idempotencyKey != "" {
+		req.Header.Set("Idempotency-Key", idempotencyKey)
+	}
+
+	client := &http.Client{Timeout: 20 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("stripe request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return resp.StatusCode, fmt.Errorf("failed to read stripe response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		var failure struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		json.Unmarshal(data, &failure)
+		return resp.StatusCode, fmt.Errorf("stripe returned %d: %s", resp.StatusCode, failure.Error.Message)
+	}
+	return resp.StatusCode, json.Unmarshal(data, out)
+}
@@ -0,0 +1,73 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"hired-valley-backend/models/billing"
+	"testing"
+)
+
+// fakePayments - платежная система в памяти для тестов
+type fakePayments struct {
+	receipts map[string]PaymentReceipt
+}
+
+func (fakePayments) Name() string {
+	return "fake"
+}
+
+func (f fakePayments) CreatePayment(ctx context.Context, order PaymentOrder) (*PaymentIntent, error) {
+	return &PaymentIntent{Reference: "pi_new", Amount: order.Amount, Currency: order.Currency}, nil
+}
+
+func (f fakePayments) FetchPayment(ctx context.Context, reference string) (*PaymentReceipt, error) {
+	receipt, ok := f.receipts[reference]
+	if !ok {
+		return nil, errors.New("payment not found")
+	}
+	return &receipt, nil
+}
+
+func (f fakePayments) RefundPayment(ctx context.Context, reference string, amount int64, key string) (string, error) {
+	return "re_" + key, nil
+}
+
+func TestVerifyPayment(t *testing.T) {
+	order := PaymentOrder{Purpose: billing.PurposeCourseEnrollment, ItemID: 7, UserID: 3, Amount: 4900, Currency: "USD"}
+	paid := map[string]string{"purpose": billing.PurposeCourseEnrollment, "item_id": "7", "user_id": "3"}
+	provider := fakePayments{receipts: map[string]PaymentReceipt{
+		"pi_paid":     {Reference: "pi_paid", Succeeded: true, Amount: 4900, Currency: "usd", Metadata: paid},
+		"pi_pending":  {Reference: "pi_pending", Succeeded: false, Amount: 4900, Currency: "usd", Metadata: paid},
+		"pi_partial":  {Reference: "pi_partial", Succeeded: true, Amount: 100, Currency: "usd", Metadata: paid},
+		"pi_currency": {Reference: "pi_currency", Succeeded: true, Amount: 4900, Currency: "jpy", Metadata: paid},
+		"pi_other": {Reference: "pi_other", Succeeded: true, Amount: 4900, Currency: "usd",
+			Metadata: map[string]string{"purpose": billing.PurposeCourseEnrollment, "item_id": "7", "user_id": "4"}},
+	}}
+
+	if receipt, err := VerifyPayment(context.Background(), provider, " pi_paid ", order); err != nil || receipt.Reference != "pi_paid" {
+		t.Fatalf("paid order: receipt %+v, error %v", receipt, err)
+	}
+
+	cases := []struct {
+		reference string
+		want      error
+	}{
+		{"", ErrPaymentNotVerified},
+		{"pi_pending", ErrPaymentNotVerified},
+		{"pi_partial", ErrPaymentNotVerified},
+		{"pi_currency", ErrPaymentNotVerified},
+		{"pi_other", ErrPaymentNotVerified}, // платеж другого пользователя
+	}
+	for _, c := range cases {
+		if _, err := VerifyPayment(context.Background(), provider, c.reference, order); !errors.Is(err, c.want) {
+			t.Errorf("reference %q: error %v, want %v", c.reference, err, c.want)
+		}
+	}
+
+	if _, err := VerifyPayment(context.Background(), provider, "pi_missing", order); err == nil {
+		t.Fatal("unknown payment verified")
+	}
+	if _, err := VerifyPayment(context.Background(), nil, "pi_paid", order); !errors.Is(err, ErrPaymentsUnavailable) {
+		t.Fatalf("no provider: error %v", err)
+	}
+}
@@ -0,0 +1,209 @@
+package services
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf16"
+)
+
+// truetypeFont - разобранный шрифт TrueType и его исходный файл для встраивания в PDF
+type truetypeFont struct {
+	data []byte
+	font *sfnt.Font
+}
+
+var (
+	certificateFontsOnce sync.Once
+	certificateRegular   truetypeFont
+	certificateBold      truetypeFont
+)
+
+// certificateFonts - шрифты сертификата: CERTIFICATE_FONT_PATH и CERTIFICATE_BOLD_FONT_PATH (например,
+// Noto Sans CJK для иероглифов), по умолчанию - встроенные шрифты Go с латиницей, кириллицей и греческим
+func certificateFonts() (truetypeFont, truetypeFont) {
+	certificateFontsOnce.Do(func() {
+		certificateRegular = loadTrueTypeFont(os.Getenv("CERTIFICATE_FONT_PATH"), goregular.TTF)
+		certificateBold = loadTrueTypeFont(os.Getenv("CERTIFICATE_BOLD_FONT_PATH"), gobold.TTF)
+	})
+	return certificateRegular, certificateBold
+}
+
+// loadTrueTypeFont - шрифт из файла path, а если он не задан или не читается - из fallback
+func loadTrueTypeFont(path string, fallback []byte) truetypeFont {
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err == nil {
+			var parsed *sfnt.Font
+			if parsed, err = sfnt.Parse(data); err == nil {
+				return truetypeFont{data: data, font: parsed}
+			}
+		}
+		log.Printf("Шрифт сертификата %s не загружен, используется встроенный: %v", path, err)
+	}
+	parsed, err := sfnt.Parse(fallback)
+	if err != nil {
+		panic(fmt.Sprintf("built-in certificate font: %v", err))
+	}
+	return truetypeFont{data: fallback, font: parsed}
+}
+
+// pdfFont - шрифт TrueType, встраиваемый в PDF как составной (Type0, Identity-H). Текст записывается
+// номерами глифов, поэтому доступны все символы шрифта; ToUnicode сохраняет копирование и поиск текста
+type pdfFont struct {
+	truetypeFont
+	buf    sfnt.Buffer
+	ppem   fixed.Int26_6
+	units  int
+	widths map[sfnt.GlyphIndex]int  // Ширина глифа в 1/1000 кегля
+	runes  map[sfnt.GlyphIndex]rune // Символ, которым глиф попал в документ
+}
+
+func newPDFFont(source truetypeFont) *pdfFont {
+	units := int(source.font.UnitsPerEm())
+	return &pdfFont{
+		truetypeFont: source,
+		ppem:         fixed.Int26_6(units << 6),
+		units:        units,
+		widths:       map[sfnt.GlyphIndex]int{},
+		runes:        map[sfnt.GlyphIndex]rune{},
+	}
+}
+
+// scale - значение в единицах шрифта (26.6) в 1/1000 кегля
+func (f *pdfFont) scale(value fixed.Int26_6) int {
+	return int(value) * 1000 / (f.units << 6)
+}
+
+// encode - строка PDF из номеров глифов и ширина текста в пунктах при кегле size.
+// Символы, которых нет в шрифте, выводятся пустым глифом
+func (f *pdfFont) encode(text string, size int) (string, float64) {
+	var hexText strings.Builder
+	hexText.WriteByte('<')
+	width := 0
+	for _, r := range text {
+		glyph, err := f.font.GlyphIndex(&f.buf, r)
+		if err != nil {
+			glyph = 0
+		}
+		if _, ok := f.widths[glyph]; !ok {
+			advance, err := f.font.GlyphAdvance(&f.buf, glyph, f.ppem, font.HintingNone)
+			if err != nil {
+				advance = 0
+			}
+			f.widths[glyph] = f.scale(advance)
+		}
+		if _, ok := f.runes[glyph]; !ok && glyph != 0 {
+			f.runes[glyph] = r
+		}
+		width += f.widths[glyph]
+		fmt.Fprintf(&hexText, "%04X", uint16(glyph))
+	}
+	hexText.WriteByte('>')
+	return hexText.String(), float64(width*size) / 1000
+}
+
+// postScriptName - имя шрифта для PDF без пробелов и служебных символов
+func (f *pdfFont) postScriptName() string {
+	name, err := f.font.Name(&f.buf, sfnt.NameIDPostScript)
+	if err != nil || name == "" {
+		return "EmbeddedFont"
+	}
+	return strings.Map(func(r rune) rune {
+		if r > ' ' && r < 127 && !strings.ContainsRune("()<>[]{}/%#", r) {
+			return r
+		}
+		return -1
+	}, name)
+}
+
+// objects - объекты PDF шрифта с номерами first..first+4: Type0, CIDFontType2, FontDescriptor,
+// FontFile2 и ToUnicode. Вызывается после encode всех строк документа
+func (f *pdfFont) objects(first int) []string {
+	name := f.postScriptName()
+	glyphs := make([]sfnt.GlyphIndex, 0, len(f.widths))
+	for glyph := range f.widths {
+		glyphs = append(glyphs, glyph)
+	}
+	sort.Slice(glyphs, func(i, j int) bool { return glyphs[i] < glyphs[j] })
+
+	var widths strings.Builder
+	for _, glyph := range glyphs {
+		fmt.Fprintf(&widths, "%d [%d] ", glyph, f.widths[glyph])
+	}
+
+	metrics, _ := f.font.Metrics(&f.buf, f.ppem, font.HintingNone)
+	bounds, _ := f.font.Bounds(&f.buf, f.ppem, font.HintingNone)
+	capHeight := f.scale(metrics.CapHeight)
+	if capHeight == 0 {
+		capHeight = f.scale(metrics.Ascent)
+	}
+
+	return []string{
+		fmt.Sprintf("<< /Type /Font /Subtype /Type0 /BaseFont /%s /Encoding /Identity-H /DescendantFonts [%d 0 R] /ToUnicode %d 0 R >>",
+			name, first+1, first+4),
+		fmt.Sprintf("<< /Type /Font /Subtype /CIDFontType2 /BaseFont /%s /CIDSystemInfo << /Registry (Adobe) /Ordering (Identity) /Supplement 0 >> /FontDescriptor %d 0 R /DW 1000 /W [%s] /CIDToGIDMap /Identity >>",
+			name, first+2, strings.TrimSpace(widths.String())),
+		fmt.Sprintf("<< /Type /FontDescriptor /FontName /%s /Flags 32 /FontBBox [%d %d %d %d] /ItalicAngle 0 /Ascent %d /Descent %d /CapHeight %d /StemV 80 /FontFile2 %d 0 R >>",
+			name, f.scale(bounds.Min.X), -f.scale(bounds.Max.Y), f.scale(bounds.Max.X), -f.scale(bounds.Min.Y),
+			f.scale(metrics.Ascent), -f.scale(metrics.Descent), capHeight, first+3),
+		pdfStream(f.data, fmt.Sprintf("/Length1 %d", len(f.data))),
+		pdfStream([]byte(f.toUnicode(glyphs)), ""),
+	}
+}
+
+// toUnicode - CMap соответствия глифов символам Unicode
+func (f *pdfFont) toUnicode(glyphs []sfnt.GlyphIndex) string {
+	var mapped []sfnt.GlyphIndex
+	for _, glyph := range glyphs {
+		if _, ok := f.runes[glyph]; ok {
+			mapped = append(mapped, glyph)
+		}
+	}
+
+	var cmap strings.Builder
+	cmap.WriteString("/CIDInit /ProcSet findresource begin\n12 dict begin\nbegincmap\n" +
+		"/CIDSystemInfo << /Registry (Adobe) /Ordering (UCS) /Supplement 0 >> def\n" +
+		"/CMapName /Adobe-Identity-UCS def\n/CMapType 2 def\n" +
+		"1 begincodespacerange\n<0000> <FFFF>\nendcodespacerange\n")
+	// В одном блоке bfchar допускается не больше 100 записей
+	for start := 0; start < len(mapped); start += 100 {
+		end := start + 100
+		if end > len(mapped) {
+			end = len(mapped)
+		}
+		fmt.Fprintf(&cmap, "%d beginbfchar\n", end-start)
+		for _, glyph := range mapped[start:end] {
+			fmt.Fprintf(&cmap, "<%04X> <", uint16(glyph))
+			for _, unit := range utf16.Encode([]rune{f.runes[glyph]}) {
+				fmt.Fprintf(&cmap, "%04X", unit)
+			}
+			cmap.WriteString(">\n")
+		}
+		cmap.WriteString("endbfchar\n")
+	}
+	cmap.WriteString("endcmap\nCMapName currentdict /defineresource pop\nend\nend\n")
+	return cmap.String()
+}
+
+// pdfStream - сжатый поток PDF; extra - дополнительные ключи словаря потока
+func pdfStream(data []byte, extra string) string {
+	var compressed bytes.Buffer
+	writer := zlib.NewWriter(&compressed)
+	writer.Write(data)
+	writer.Close()
+	if extra != "" {
+		extra = " " + extra
+	}
+	return fmt.Sprintf("<< /Length %d /Filter /FlateDecode%s >>\nstream\n%s\nendstream", compressed.Len(), extra, compressed.String())
+}
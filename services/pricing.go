@@ -0,0 +1,281 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"hired-valley-backend/config"
+	"hired-valley-backend/models/courses"
+	"math"
+	"os"
+	"strings"
+	"time"
+)
+
+var (
+	ErrCurrencyUnavailable = errors.New("course is not sold in this currency")
+	ErrCouponNotFound      = errors.New("coupon not found")
+	ErrCouponInactive      = errors.New("coupon is not active yet or has expired")
+	ErrCouponExhausted     = errors.New("coupon usage limit reached")
+	ErrCouponNotApplicable = errors.New("coupon does not apply to this course")
+)
+
+// MaxSeatsPerPurchase - ограничение количества мест в одной покупке
+const MaxSeatsPerPurchase = 1000
+
+// zeroDecimalCurrencies - валюты без дробных единиц
+var zeroDecimalCurrencies = map[string]bool{"JPY": true, "KRW": true, "VND": true, "CLP": true, "ISK": true}
+
+// DefaultCurrency - валюта Course.Price и каталога (COURSE_DEFAULT_CURRENCY, по умолчанию USD)
+func DefaultCurrency() string {
+	if currency := NormalizeCurrency(os.Getenv("COURSE_DEFAULT_CURRENCY")); currency != "" {
+		return currency
+	}
+	return "USD"
+}
+
+// NormalizeCurrency - код валюты ISO 4217 в верхнем регистре или "", если код некорректен
+func NormalizeCurrency(code string) string {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if len(code) != 3 {
+		return ""
+	}
+	for _, c := range code {
+		if c < 'A' || c > 'Z' {
+			return ""
+		}
+	}
+	return code
+}
+
+// MinorUnitsFactor - число минимальных единиц в основной единице валюты (100 для USD, 1 для JPY)
+func MinorUnitsFactor(currency string) int64 {
+	if zeroDecimalCurrencies[currency] {
+		return 1
+	}
+	return 100
+}
+
+// ToMinorUnits - сумма в основных единицах в минимальных
+func ToMinorUnits(amount float64, currency string) int64 {
+	return int64(math.Round(amount * float64(MinorUnitsFactor(currency))))
+}
+
+// FromMinorUnits - сумма в минимальных единицах в основных (для Course.Price и Enrollment.PricePaid)
+func FromMinorUnits(amount int64, currency string) float64 {
+	return float64(amount) / float64(MinorUnitsFactor(currency))
+}
+
+// PriceQuote - расчет стоимости курса перед оплатой; все суммы в минимальных единицах Currency
+type PriceQuote struct {
+	CourseID            uint       `json:"course_id"`
+	Currency            string     `json:"currency"`
+	Seats               int        `json:"seats"`
+	ListPrice           int64      `json:"list_price"` // Цена одного места без скидок
+	UnitPrice           int64      `json:"unit_price"` // Цена одного места с учетом распродажи
+	OnSale              bool       `json:"on_sale"`
+	SaleEndsAt          *time.Time `json:"sale_ends_at,omitempty"`
+	SeatDiscountPercent int        `json:"seat_discount_percent"`
+	Subtotal            int64      `json:"subtotal"` // UnitPrice × Seats за вычетом скидки за количество
+	CouponCode          string     `json:"coupon_code,omitempty"`
+	CouponID            *uint      `json:"-"`
+	CouponDiscount      int64      `json:"coupon_discount"`
+	CouponError         string     `json:"coupon_error,omitempty"` // Купон не применен, причина
+	Total               int64      `json:"total"`
+	FreePreviewLessons  int64      `json:"free_preview_lessons"`
+}
+
+// CoursePrices - цены курса во всех валютах. Если цены не заданы, цена курса в валюте
+// по умолчанию берется из Course.Price
+func CoursePrices(course *courses.Course) ([]courses.CoursePrice, error) {
+	var prices []courses.CoursePrice
+	if err := config.DB.Where("course_id = ?", CourseOriginID(course)).Order("currency").Find(&prices).Error; err != nil {
+		return nil, err
+	}
+	if len(prices) == 0 {
+		currency := DefaultCurrency()
+		prices = append(prices, courses.CoursePrice{
+			CourseID: CourseOriginID(course),
+			Currency: currency,
+			Amount:   ToMinorUnits(course.Price, currency),
+		})
+	}
+	return prices, nil
+}
+
+// SeatPriceTiers - скидки за количество мест по возрастанию порога
+func SeatPriceTiers(course *courses.Course) ([]courses.SeatPriceTier, error) {
+	var tiers []courses.SeatPriceTier
+	err := config.DB.Where("course_id = ?", CourseOriginID(course)).Order("min_seats").Find(&tiers).Error
+	return tiers, err
+}
+
+// FindCoupon - купон по коду без учета регистра
+func FindCoupon(code string) (*courses.Coupon, error) {
+	var coupon courses.Coupon
+	if err := config.DB.Where("code = ?", strings.ToUpper(strings.TrimSpace(code))).First(&coupon).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCouponNotFound
+		}
+		return nil, err
+	}
+	return &coupon, nil
+}
+
+// CheckCoupon - можно ли применить купон к курсу пользователем в момент at
+func CheckCoupon(coupon *courses.Coupon, course *courses.Course, userID uint, at time.Time) error {
+	if !coupon.IsActive || (coupon.StartsAt != nil && at.Before(*coupon.StartsAt)) ||
+		(coupon.ExpiresAt != nil && !at.Before(*coupon.ExpiresAt)) {
+		return ErrCouponInactive
+	}
+	if coupon.CourseID != nil && *coupon.CourseID != CourseOriginID(course) {
+		return ErrCouponNotApplicable
+	}
+	if coupon.MaxRedemptions > 0 && coupon.RedemptionsCount >= coupon.MaxRedemptions {
+		return ErrCouponExhausted
+	}
+	if coupon.MaxPerUser > 0 && userID != 0 {
+		var used int64
+		config.DB.Model(&courses.CouponRedemption{}).Where("coupon_id = ? AND user_id = ?", coupon.ID, userID).Count(&used)
+		if int(used) >= coupon.MaxPerUser {
+			return ErrCouponExhausted
+		}
+	}
+	return nil
+}
+
+// CalculatePrice - цена мест с учетом распродажи, скидки за количество и купона (coupon может быть nil)
+func CalculatePrice(price courses.CoursePrice, tiers []courses.SeatPriceTier, coupon *courses.Coupon, seats int, at time.Time) PriceQuote {
+	quote := PriceQuote{
+		CourseID:  price.CourseID,
+		Currency:  price.Currency,
+		Seats:     seats,
+		ListPrice: price.Amount,
+		UnitPrice: price.Amount,
+	}
+
+	if price.SaleAmount != nil && *price.SaleAmount < price.Amount &&
+		(price.SaleStartsAt == nil || !at.Before(*price.SaleStartsAt)) &&
+		(price.SaleEndsAt == nil || at.Before(*price.SaleEndsAt)) {
+		quote.UnitPrice = *price.SaleAmount
+		quote.OnSale = true
+		quote.SaleEndsAt = price.SaleEndsAt
+	}
+
+	for _, tier := range tiers {
+		if seats >= tier.MinSeats && tier.DiscountPercent > quote.SeatDiscountPercent {
+			quote.SeatDiscountPercent = tier.DiscountPercent
+		}
+	}
+	gross := quote.UnitPrice * int64(seats)
+	quote.Subtotal = gross - gross*int64(quote.SeatDiscountPercent)/100
+
+	if coupon != nil {
+		switch coupon.DiscountType {
+		case courses.CouponPercent:
+			quote.CouponDiscount = quote.Subtotal * int64(coupon.PercentOff) / 100
+		case courses.CouponAmount:
+			if coupon.Currency == price.Currency {
+				quote.CouponDiscount = coupon.AmountOff
+			} else {
+				quote.CouponError = fmt.Sprintf("coupon is valid only for payments in %s", coupon.Currency)
+			}
+		}
+		if quote.CouponDiscount > quote.Subtotal {
+			quote.CouponDiscount = quote.Subtotal
+		}
+		if quote.CouponError == "" {
+			quote.CouponCode = coupon.Code
+			quote.CouponID = &coupon.ID
+		}
+	}
+
+	quote.Total = quote.Subtotal - quote.CouponDiscount
+	return quote
+}
+
+// QuoteCoursePrice - расчет стоимости курса: currency "" - валюта по умолчанию, couponCode "" - без купона.
+// Ошибка купона не прерывает расчет и возвращается в CouponError
+func QuoteCoursePrice(course *courses.Course, userID uint, currency string, seats int, couponCode string) (*PriceQuote, error) {
+	if currency == "" {
+		currency = DefaultCurrency()
+	}
+	prices, err := CoursePrices(course)
+	if err != nil {
+		return nil, err
+	}
+	var price *courses.CoursePrice
+	for i := range prices {
+		if prices[i].Currency == currency {
+			price = &prices[i]
+		}
+	}
+	if price == nil {
+		return nil, ErrCurrencyUnavailable
+	}
+	tiers, err := SeatPriceTiers(course)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var coupon *courses.Coupon
+	couponError := ""
+	if strings.TrimSpace(couponCode) != "" {
+		found, err := FindCoupon(couponCode)
+		if err == nil {
+			err = CheckCoupon(found, course, userID, now)
+		}
+		switch {
+		case err == nil:
+			coupon = found
+		case errors.Is(err, ErrCouponNotFound), errors.Is(err, ErrCouponInactive),
+			errors.Is(err, ErrCouponExhausted), errors.Is(err, ErrCouponNotApplicable):
+			couponError = err.Error()
+		default:
+			return nil, err
+		}
+	}
+
+	quote := CalculatePrice(*price, tiers, coupon, seats, now)
+	quote.CourseID = course.ID
+	if couponError != "" {
+		quote.CouponError = couponError
+	}
+	config.DB.Model(&courses.Lesson{}).Where("course_id = ? AND is_preview = ?", course.ID, true).Count(&quote.FreePreviewLessons)
+	return &quote, nil
+}
+
+// RedeemCoupon - учет использования купона в транзакции записи на курс; лимиты проверяются
+// повторно под блокировкой, чтобы параллельные покупки не превысили их
+func RedeemCoupon(tx *gorm.DB, quote *PriceQuote, userID uint) error {
+	if quote.CouponID == nil {
+		return nil
+	}
+	var coupon courses.Coupon
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&coupon, *quote.CouponID).Error; err != nil {
+		return err
+	}
+	if coupon.MaxRedemptions > 0 && coupon.RedemptionsCount >= coupon.MaxRedemptions {
+		return ErrCouponExhausted
+	}
+	if coupon.MaxPerUser > 0 {
+		var used int64
+		tx.Model(&courses.CouponRedemption{}).Where("coupon_id = ? AND user_id = ?", coupon.ID, userID).Count(&used)
+		if int(used) >= coupon.MaxPerUser {
+			return ErrCouponExhausted
+		}
+	}
+
+	if err := tx.Model(&coupon).Update("redemptions_count", gorm.Expr("redemptions_count + 1")).Error; err != nil {
+		return err
+	}
+	return tx.Create(&courses.CouponRedemption{
+		CouponID:       coupon.ID,
+		UserID:         userID,
+		CourseID:       quote.CourseID,
+		Currency:       quote.Currency,
+		DiscountAmount: quote.CouponDiscount,
+	}).Error
+}
@@ -0,0 +1,111 @@
+package services
+
+import (
+	"errors"
+	"hired-valley-backend/models/courses"
+	"testing"
+	"time"
+)
+
+func TestMinorUnits(t *testing.T) {
+	cases := []struct {
+		amount   float64
+		currency string
+		minor    int64
+	}{
+		{19.99, "USD", 1999},
+		{0.1 + 0.2, "EUR", 30}, // округление, а не отбрасывание дробной части
+		{1500, "JPY", 1500},
+	}
+	for _, c := range cases {
+		if got := ToMinorUnits(c.amount, c.currency); got != c.minor {
+			t.Errorf("ToMinorUnits(%v, %s) = %d, want %d", c.amount, c.currency, got, c.minor)
+		}
+		if got := FromMinorUnits(c.minor, c.currency); ToMinorUnits(got, c.currency) != c.minor {
+			t.Errorf("FromMinorUnits(%d, %s) = %v does not round-trip", c.minor, c.currency, got)
+		}
+	}
+
+	for code, want := range map[string]string{" usd ": "USD", "Eur": "EUR", "US": "", "US1": "", "": ""} {
+		if got := NormalizeCurrency(code); got != want {
+			t.Errorf("NormalizeCurrency(%q) = %q, want %q", code, got, want)
+		}
+	}
+}
+
+func TestCalculatePrice(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	sale := int64(6000)
+	saleEnds := now.Add(24 * time.Hour)
+	price := courses.CoursePrice{CourseID: 7, Currency: "USD", Amount: 10000, SaleAmount: &sale, SaleEndsAt: &saleEnds}
+	tiers := []courses.SeatPriceTier{{MinSeats: 5, DiscountPercent: 10}, {MinSeats: 20, DiscountPercent: 25}}
+
+	t.Run("sale and seat discount", func(t *testing.T) {
+		quote := CalculatePrice(price, tiers, nil, 5, now)
+		if !quote.OnSale || quote.UnitPrice != 6000 || quote.ListPrice != 10000 {
+			t.Fatalf("unexpected sale pricing %+v", quote)
+		}
+		if quote.SeatDiscountPercent != 10 || quote.Subtotal != 27000 || quote.Total != 27000 {
+			t.Fatalf("unexpected seat discount %+v", quote)
+		}
+	})
+
+	t.Run("expired sale", func(t *testing.T) {
+		quote := CalculatePrice(price, tiers, nil, 1, saleEnds)
+		if quote.OnSale || quote.UnitPrice != 10000 || quote.Total != 10000 {
+			t.Fatalf("sale applied after it ended %+v", quote)
+		}
+	})
+
+	t.Run("percent coupon", func(t *testing.T) {
+		coupon := &courses.Coupon{ID: 3, Code: "SPRING", DiscountType: courses.CouponPercent, PercentOff: 15}
+		quote := CalculatePrice(price, nil, coupon, 1, now)
+		if quote.CouponDiscount != 900 || quote.Total != 5100 || quote.CouponID == nil || *quote.CouponID != 3 {
+			t.Fatalf("unexpected percent coupon %+v", quote)
+		}
+	})
+
+	t.Run("amount coupon is capped by subtotal", func(t *testing.T) {
+		coupon := &courses.Coupon{ID: 4, Code: "BIG", DiscountType: courses.CouponAmount, AmountOff: 50000, Currency: "USD"}
+		quote := CalculatePrice(price, nil, coupon, 1, now)
+		if quote.CouponDiscount != 6000 || quote.Total != 0 {
+			t.Fatalf("unexpected amount coupon %+v", quote)
+		}
+	})
+
+	t.Run("amount coupon in another currency", func(t *testing.T) {
+		coupon := &courses.Coupon{ID: 5, Code: "EURO", DiscountType: courses.CouponAmount, AmountOff: 1000, Currency: "EUR"}
+		quote := CalculatePrice(price, nil, coupon, 1, now)
+		if quote.CouponDiscount != 0 || quote.CouponError == "" || quote.CouponID != nil || quote.Total != 6000 {
+			t.Fatalf("coupon applied in a foreign currency %+v", quote)
+		}
+	})
+}
+
+func TestCheckCoupon(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	later := now.Add(time.Hour)
+	originID := uint(7)
+	otherID := uint(8)
+	course := &courses.Course{ID: 12, OriginID: &originID}
+
+	// MaxPerUser = 0, чтобы проверка не обращалась к базе данных
+	cases := []struct {
+		name   string
+		coupon courses.Coupon
+		want   error
+	}{
+		{"valid for every course", courses.Coupon{IsActive: true}, nil},
+		{"valid for the course origin", courses.Coupon{IsActive: true, CourseID: &originID}, nil},
+		{"disabled", courses.Coupon{IsActive: false}, ErrCouponInactive},
+		{"not started", courses.Coupon{IsActive: true, StartsAt: &later}, ErrCouponInactive},
+		{"expired", courses.Coupon{IsActive: true, ExpiresAt: &now}, ErrCouponInactive},
+		{"another course", courses.Coupon{IsActive: true, CourseID: &otherID}, ErrCouponNotApplicable},
+		{"limit reached", courses.Coupon{IsActive: true, MaxRedemptions: 2, RedemptionsCount: 2}, ErrCouponExhausted},
+	}
+	for _, c := range cases {
+		if err := CheckCoupon(&c.coupon, course, 3, now); !errors.Is(err, c.want) {
+			t.Errorf("%s: error %v, want %v", c.name, err, c.want)
+		}
+	}
+}
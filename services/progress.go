@@ -0,0 +1,119 @@
+package services
+
+import (
+	"errors"
+	"gorm.io/gorm"
+	"hired-valley-backend/config"
+	"hired-valley-backend/models/courses"
+	"time"
+)
+
+// ResumePoint - урок и позиция видео, с которых стоит продолжить курс
+type ResumePoint struct {
+	LessonID        uint   `json:"lesson_id"`
+	LessonTitle     string `json:"lesson_title"`
+	PositionSeconds int    `json:"position_seconds"`
+}
+
+// CourseProgress - прохождение курса пользователем
+type CourseProgress struct {
+	CourseID         uint         `json:"course_id"`
+	CourseTitle      string       `json:"course_title"`
+	TotalLessons     int64        `json:"total_lessons"`
+	CompletedLessons int64        `json:"completed_lessons"`
+	Percent          float64      `json:"percent"`
+	Resume           *ResumePoint `json:"resume"` // nil, если курс пройден
+	LastActivityAt   *time.Time   `json:"last_activity_at"`
+}
+
+// GetCourseProgress - процент прохождения курса и точка продолжения
+func GetCourseProgress(userID uint, course courses.Course) (CourseProgress, error) {
+	result := CourseProgress{CourseID: course.ID, CourseTitle: course.Title}
+
+	if err := config.DB.Model(&courses.Lesson{}).Where("course_id = ?", course.ID).Count(&result.TotalLessons).Error; err != nil {
+		return result, err
+	}
+	if err := config.DB.Model(&courses.Progress{}).
+		Where("user_id = ? AND course_id = ? AND completed = ?", userID, course.ID, true).
+		Where("lesson_id IN (?)", config.DB.Model(&courses.Lesson{}).Select("id").Where("course_id = ?", course.ID)).
+		Count(&result.CompletedLessons).Error; err != nil {
+		return result, err
+	}
+	if result.TotalLessons > 0 {
+		result.Percent = float64(result.CompletedLessons) * 100 / float64(result.TotalLessons)
+	}
+
+	// Последний незавершенный урок, который пользователь открывал
+	var last courses.Progress
+	err := config.DB.Where("user_id = ? AND course_id = ?", userID, course.ID).Order("updated_at DESC").First(&last).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return result, err
+	}
+	if err == nil {
+		result.LastActivityAt = &last.UpdatedAt
+	}
+
+	var lesson courses.Lesson
+	if err == nil && !last.Completed {
+		if err := config.DB.First(&lesson, last.LessonID).Error; err == nil {
+			result.Resume = &ResumePoint{LessonID: lesson.ID, LessonTitle: lesson.Title, PositionSeconds: last.PositionSeconds}
+			return result, nil
+		}
+	}
+
+	// Иначе - первый урок курса, который еще не пройден
+	err = config.DB.Scopes(CurriculumOrder).Where("lessons.course_id = ?", course.ID).
+		Where("lessons.id NOT IN (?)", config.DB.Model(&courses.Progress{}).Select("lesson_id").
+			Where("user_id = ? AND course_id = ? AND completed = ?", userID, course.ID, true)).
+		Take(&lesson).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return result, err
+	}
+	if err == nil {
+		resume := ResumePoint{LessonID: lesson.ID, LessonTitle: lesson.Title}
+		var progress courses.Progress
+		if config.DB.Where("user_id = ? AND lesson_id = ?", userID, lesson.ID).First(&progress).Error == nil {
+			resume.PositionSeconds = progress.PositionSeconds
+		}
+		result.Resume = &resume
+	}
+	return result, nil
+}
+
+// CurriculumOrder - уроки в порядке программы: разделы по порядку, затем уроки вне разделов
+func CurriculumOrder(db *gorm.DB) *gorm.DB {
+	return db.Model(&courses.Lesson{}).
+		Joins("LEFT JOIN course_modules ON course_modules.id = lessons.module_id").
+		Order("course_modules.position NULLS LAST, course_modules.id, lessons.position, lessons.id")
+}
+
+// ContinueLearning - прогресс по всем курсам, на которые записан пользователь, начиная с последних активных
+func ContinueLearning(userID uint) ([]CourseProgress, error) {
+	var enrolled []courses.Course
+	if err := config.DB.Joins("JOIN enrollments ON enrollments.course_id = courses.id").
+		Where("enrollments.user_id = ?", userID).
+		Order("(SELECT MAX(p.updated_at) FROM progresses p WHERE p.user_id = enrollments.user_id AND p.course_id = courses.id) DESC NULLS LAST").
+		Order("enrollments.created_at DESC").
+		Find(&enrolled).Error; err != nil {
+		return nil, err
+	}
+
+	result := make([]CourseProgress, 0, len(enrolled))
+	for _, course := range enrolled {
+		progress, err := GetCourseProgress(userID, course)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, progress)
+	}
+	return result, nil
+}
+
+// LearningTags - теги курсов, которые пользователь проходит; используются в рекомендациях
+func LearningTags(userID uint) ([]string, error) {
+	var tags []string
+	err := config.DB.Model(&courses.Course{}).
+		Where("id IN (?)", config.DB.Model(&courses.Progress{}).Select("course_id").Where("user_id = ?", userID)).
+		Distinct().Pluck("unnest(tags)", &tags).Error
+	return tags, err
+}
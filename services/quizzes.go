@@ -0,0 +1,75 @@
+package services
+
+import (
+	"hired-valley-backend/models/courses"
+	"strings"
+)
+
+// GradeQuizAttempt - проверка ответов попытки по вопросам, которые в ней выпали. Вопросы без ответа
+// засчитываются как неверные; попытка пройдена, если процент не ниже passingScore
+func GradeQuizAttempt(attempt *courses.QuizAttempt, questions []courses.QuizQuestion, given map[uint]courses.QuizAnswer, passingScore int) {
+	attempt.Score = 0
+	attempt.Percent = 0
+	attempt.Answers = nil
+	for _, question := range questions {
+		answer := given[question.ID]
+		answer.QuestionID = question.ID
+		answer.PointsAwarded = 0
+		answer.IsCorrect = IsCorrectAnswer(question, answer)
+		if answer.IsCorrect {
+			answer.PointsAwarded = question.Points
+			attempt.Score += question.Points
+		}
+		attempt.Answers = append(attempt.Answers, answer)
+	}
+	if attempt.MaxScore > 0 {
+		attempt.Percent = float64(attempt.Score) * 100 / float64(attempt.MaxScore)
+	}
+	attempt.Passed = attempt.Percent >= float64(passingScore)
+}
+
+// IsCorrectAnswer - проверка ответа; множественный выбор засчитывается только при полном совпадении
+func IsCorrectAnswer(question courses.QuizQuestion, answer courses.QuizAnswer) bool {
+	switch question.Type {
+	case courses.QuestionSingleChoice, courses.QuestionMultiSelect:
+		if len(answer.SelectedOptions) != len(question.CorrectOptions) {
+			return false
+		}
+		correct := make(map[int64]bool, len(question.CorrectOptions))
+		for _, option := range question.CorrectOptions {
+			correct[option] = true
+		}
+		for _, option := range answer.SelectedOptions {
+			if !correct[option] {
+				return false
+			}
+			delete(correct, option)
+		}
+		return len(correct) == 0
+	case courses.QuestionShortAnswer:
+		given := normalizeAnswerText(answer.TextAnswer)
+		for _, accepted := range question.AcceptedAnswers {
+			if given != "" && given == normalizeAnswerText(accepted) {
+				return true
+			}
+		}
+	case courses.QuestionCodeSnippet:
+		given := normalizeAnswerCode(answer.TextAnswer)
+		for _, accepted := range question.AcceptedAnswers {
+			if given != "" && given == normalizeAnswerCode(accepted) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// normalizeAnswerText - сравнение коротких ответов без учета регистра и лишних пробелов
+func normalizeAnswerText(value string) string {
+	return strings.ToLower(strings.Join(strings.Fields(value), " "))
+}
+
+// normalizeAnswerCode - сравнение кода без учета пробелов и переносов строк
+func normalizeAnswerCode(value string) string {
+	return strings.Join(strings.Fields(value), "")
+}
@@ -0,0 +1,67 @@
+package services
+
+import (
+	"hired-valley-backend/models/courses"
+	"testing"
+)
+
+func TestIsCorrectAnswer(t *testing.T) {
+	single := courses.QuizQuestion{Type: courses.QuestionSingleChoice, CorrectOptions: []int64{2}}
+	multi := courses.QuizQuestion{Type: courses.QuestionMultiSelect, CorrectOptions: []int64{0, 3}}
+	short := courses.QuizQuestion{Type: courses.QuestionShortAnswer, AcceptedAnswers: []string{"Goroutine", "green thread"}}
+	code := courses.QuizQuestion{Type: courses.QuestionCodeSnippet, AcceptedAnswers: []string{"for i := range items {\n\tsum += i\n}"}}
+
+	cases := []struct {
+		name     string
+		question courses.QuizQuestion
+		answer   courses.QuizAnswer
+		want     bool
+	}{
+		{"single correct", single, courses.QuizAnswer{SelectedOptions: []int64{2}}, true},
+		{"single wrong", single, courses.QuizAnswer{SelectedOptions: []int64{1}}, false},
+		{"single with extra option", single, courses.QuizAnswer{SelectedOptions: []int64{2, 1}}, false},
+		{"multi in any order", multi, courses.QuizAnswer{SelectedOptions: []int64{3, 0}}, true},
+		{"multi partial", multi, courses.QuizAnswer{SelectedOptions: []int64{0}}, false},
+		{"multi duplicated option", multi, courses.QuizAnswer{SelectedOptions: []int64{0, 0}}, false},
+		{"short ignores case and spaces", short, courses.QuizAnswer{TextAnswer: "  Green   THREAD "}, true},
+		{"short wrong", short, courses.QuizAnswer{TextAnswer: "thread"}, false},
+		{"short empty", short, courses.QuizAnswer{TextAnswer: "   "}, false},
+		{"code ignores whitespace", code, courses.QuizAnswer{TextAnswer: "for i:=range items{sum+=i}"}, true},
+		{"code wrong", code, courses.QuizAnswer{TextAnswer: "for i := range items { sum -= i }"}, false},
+		{"unknown type", courses.QuizQuestion{Type: "essay"}, courses.QuizAnswer{TextAnswer: "text"}, false},
+	}
+	for _, c := range cases {
+		if got := IsCorrectAnswer(c.question, c.answer); got != c.want {
+			t.Errorf("%s: got %v, want %v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestGradeQuizAttempt(t *testing.T) {
+	questions := []courses.QuizQuestion{
+		{ID: 1, Type: courses.QuestionSingleChoice, CorrectOptions: []int64{0}, Points: 1},
+		{ID: 2, Type: courses.QuestionShortAnswer, AcceptedAnswers: []string{"channel"}, Points: 2},
+		{ID: 3, Type: courses.QuestionMultiSelect, CorrectOptions: []int64{1, 2}, Points: 1},
+	}
+	attempt := courses.QuizAttempt{MaxScore: 4}
+
+	// На третий вопрос ответа нет, ответ на вопрос не из попытки игнорируется
+	given := map[uint]courses.QuizAnswer{
+		1:  {SelectedOptions: []int64{0}},
+		2:  {TextAnswer: "Channel", PointsAwarded: 100},
+		99: {TextAnswer: "extra"},
+	}
+	GradeQuizAttempt(&attempt, questions, given, 75)
+	if attempt.Score != 3 || attempt.Percent != 75 || !attempt.Passed || len(attempt.Answers) != 3 {
+		t.Fatalf("unexpected grading %+v", attempt)
+	}
+	if missing := attempt.Answers[2]; missing.QuestionID != 3 || missing.IsCorrect || missing.PointsAwarded != 0 {
+		t.Fatalf("unanswered question graded as %+v", missing)
+	}
+
+	// Повторная проверка пересчитывает результат с нуля
+	GradeQuizAttempt(&attempt, questions, map[uint]courses.QuizAnswer{2: {TextAnswer: "channel"}}, 75)
+	if attempt.Score != 2 || attempt.Percent != 50 || attempt.Passed || len(attempt.Answers) != 3 {
+		t.Fatalf("unexpected regrading %+v", attempt)
+	}
+}
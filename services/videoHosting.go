@@ -0,0 +1,253 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hired-valley-backend/models/courses/videos"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrUnknownVideoHost     = errors.New("unknown video host")
+	ErrInvalidVideoLink     = errors.New("video link is invalid or has expired")
+	ErrVideoFileUnavailable = errors.New("video file is not available")
+)
+
+const (
+	// VideoStreamPath - обработчик просмотра видео из собственного хранилища
+	VideoStreamPath = "/videos/stream"
+	// VideoLinkTTL - срок действия подписанной ссылки на видео
+	VideoLinkTTL = 4 * time.Hour
+)
+
+// VideoHost - площадка размещения видео. Publish заполняет поля видео, по которым его можно найти
+// на площадке (YouTubeID или StorageKey), и VideoLink - ссылку, сохраняемую в уроке или контенте
+type VideoHost interface {
+	// Publish - загрузка файла; ошибки, обернутые в ErrVideoRejected, не повторяются
+	Publish(ctx context.Context, video *videos.Video, file io.Reader) error
+	// Update - название и описание видео на площадке
+	Update(ctx context.Context, video *videos.Video) error
+	Delete(ctx context.Context, video *videos.Video) error
+}
+
+var (
+	videoHostsMu sync.RWMutex
+	videoHosts   = map[string]VideoHost{}
+)
+
+// RegisterVideoHost - подключение площадки; вызывается при запуске сервера
+func RegisterVideoHost(name string, host VideoHost) {
+	videoHostsMu.Lock()
+	defer videoHostsMu.Unlock()
+	videoHosts[name] = host
+}
+
+// GetVideoHost - площадка по названию (videos.HostYouTube, videos.HostSelf)
+func GetVideoHost(name string) (VideoHost, error) {
+	videoHostsMu.RLock()
+	defer videoHostsMu.RUnlock()
+	host, ok := videoHosts[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownVideoHost, name)
+	}
+	return host, nil
+}
+
+// DefaultVideoHost - площадка для новых загрузок, если клиент ее не указал (VIDEO_DEFAULT_HOST, по умолчанию YouTube)
+func DefaultVideoHost() string {
+	if host := os.Getenv("VIDEO_DEFAULT_HOST"); host != "" {
+		return host
+	}
+	return videos.HostYouTube
+}
+
+// VideoStorage - хранилище файлов собственного видеохостинга. Для облачного хранилища Open должен
+// возвращать файл с поддержкой Seek (чтение диапазонами), чтобы работали Range-запросы
+type VideoStorage interface {
+	Save(ctx context.Context, key string, file io.Reader) error
+	Open(key string) (io.ReadSeekCloser, time.Time, error)
+	Remove(key string) error
+}
+
+// LocalVideoStorage - хранение видео в каталоге на диске сервера
+type LocalVideoStorage struct {
+	Dir string
+}
+
+func (s LocalVideoStorage) path(key string) string {
+	return filepath.Join(s.Dir, filepath.Base(key))
+}
+
+// Save - запись во временный файл и переименование, чтобы недописанный файл не отдавался зрителям
+func (s LocalVideoStorage) Save(ctx context.Context, key string, file io.Reader) error {
+	if err := os.MkdirAll(s.Dir, 0o700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(s.Dir, ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, file); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path(key))
+}
+
+func (s LocalVideoStorage) Open(key string) (io.ReadSeekCloser, time.Time, error) {
+	file, err := os.Open(s.path(key))
+	if err != nil {
+		return nil, time.Time{}, ErrVideoFileUnavailable
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, time.Time{}, err
+	}
+	return file, info.ModTime(), nil
+}
+
+func (s LocalVideoStorage) Remove(key string) error {
+	if err := os.Remove(s.path(key)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// GetVideoStorage - хранилище собственного видеохостинга (VIDEO_STORAGE_DIR)
+func GetVideoStorage() VideoStorage {
+	dir := os.Getenv("VIDEO_STORAGE_DIR")
+	if dir == "" {
+		dir = filepath.Join(os.TempDir(), "hired-valley-videos")
+	}
+	return LocalVideoStorage{Dir: dir}
+}
+
+// SelfVideoHost - собственный видеохостинг: файлы в VideoStorage, просмотр через VideoStreamPath
+// по подписанным ссылкам с ограниченным сроком действия
+type SelfVideoHost struct {
+	Storage VideoStorage
+}
+
+func (h SelfVideoHost) Publish(ctx context.Context, video *videos.Video, file io.Reader) error {
+	key := strconv.FormatUint(uint64(video.ID), 10) + strings.ToLower(filepath.Ext(video.FileName))
+	if err := h.Storage.Save(ctx, key, file); err != nil {
+		return fmt.Errorf("failed to store video: %v", err)
+	}
+	video.StorageKey = key
+	video.VideoLink = SelfHostedVideoLink(video.ID)
+	return nil
+}
+
+// Update - название и описание хранятся только в базе данных
+func (h SelfVideoHost) Update(ctx context.Context, video *videos.Video) error {
+	return nil
+}
+
+func (h SelfVideoHost) Delete(ctx context.Context, video *videos.Video) error {
+	if video.StorageKey == "" {
+		return nil
+	}
+	return h.Storage.Remove(video.StorageKey)
+}
+
+// OpenHostedVideo - файл опубликованного видео из собственного хранилища
+func OpenHostedVideo(video *videos.Video) (io.ReadSeekCloser, time.Time, error) {
+	if video.Host != videos.HostSelf || video.Status != videos.VideoReady || video.StorageKey == "" {
+		return nil, time.Time{}, ErrVideoFileUnavailable
+	}
+	host, err := GetVideoHost(videos.HostSelf)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	self, ok := host.(SelfVideoHost)
+	if !ok {
+		return nil, time.Time{}, ErrVideoFileUnavailable
+	}
+	return self.Storage.Open(video.StorageKey)
+}
+
+// SelfHostedVideoLink - постоянная ссылка на видео из собственного хранилища. Она сохраняется в уроке
+// и контенте, но сама по себе не открывает видео: перед выдачей зрителю ее подписывает SignVideoLink
+func SelfHostedVideoLink(videoID uint) string {
+	return fmt.Sprintf("%s?id=%d", VideoStreamPath, videoID)
+}
+
+// MinVideoSigningKeyLength - минимальная длина ключа подписи ссылок на видео
+const MinVideoSigningKeyLength = 32
+
+// videoSigningKey - ключ подписи ссылок; задается при запуске сервера через SetVideoSigningKey
+var videoSigningKey []byte
+
+// SetVideoSigningKey - ключ подписи ссылок на видео (VIDEO_SIGNING_KEY). Сервер не запускается без него,
+// иначе выданные ссылки не проверялись бы после перезапуска
+func SetVideoSigningKey(key []byte) error {
+	if len(key) < MinVideoSigningKeyLength {
+		return fmt.Errorf("video signing key must be at least %d bytes", MinVideoSigningKeyLength)
+	}
+	videoSigningKey = key
+	return nil
+}
+
+func videoSignature(videoID uint, expires int64) string {
+	mac := hmac.New(sha256.New, videoSigningKey)
+	fmt.Fprintf(mac, "%d:%d", videoID, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// SignVideoLink - подписанная ссылка для просмотра видео из собственного хранилища, действующая VideoLinkTTL
+// (VIDEO_STREAM_BASE_URL - адрес API для абсолютных ссылок). Ссылки на другие площадки возвращаются без изменений
+func SignVideoLink(link string) string {
+	if !strings.HasPrefix(link, VideoStreamPath+"?") {
+		return link
+	}
+	params, err := url.ParseQuery(strings.TrimPrefix(link, VideoStreamPath+"?"))
+	if err != nil {
+		return link
+	}
+	videoID, err := strconv.ParseUint(params.Get("id"), 10, 64)
+	if err != nil || len(videoSigningKey) == 0 {
+		return link
+	}
+	expires := time.Now().Add(VideoLinkTTL).Unix()
+	base := strings.TrimRight(os.Getenv("VIDEO_STREAM_BASE_URL"), "/")
+	return fmt.Sprintf("%s%s?id=%d&expires=%d&signature=%s",
+		base, VideoStreamPath, videoID, expires, videoSignature(uint(videoID), expires))
+}
+
+// IsSelfHostedVideoLink - ссылка на видео из собственного хранилища (не переносится между платформами)
+func IsSelfHostedVideoLink(link string) bool {
+	return strings.HasPrefix(link, VideoStreamPath+"?")
+}
+
+// VerifyVideoLink - проверка подписи и срока действия ссылки; возвращает ID видео
+func VerifyVideoLink(params url.Values) (uint, time.Time, error) {
+	videoID, err := strconv.ParseUint(params.Get("id"), 10, 64)
+	if err != nil || len(videoSigningKey) == 0 {
+		return 0, time.Time{}, ErrInvalidVideoLink
+	}
+	expires, err := strconv.ParseInt(params.Get("expires"), 10, 64)
+	if err != nil || time.Now().Unix() > expires {
+		return 0, time.Time{}, ErrInvalidVideoLink
+	}
+	expected := videoSignature(uint(videoID), expires)
+	if !hmac.Equal([]byte(expected), []byte(params.Get("signature"))) {
+		return 0, time.Time{}, ErrInvalidVideoLink
+	}
+	return uint(videoID), time.Unix(expires, 0), nil
+}
@@ -0,0 +1,115 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// withVideoSigningKey - ключ подписи на время теста
+func withVideoSigningKey(t *testing.T, key string) {
+	t.Helper()
+	previous := videoSigningKey
+	t.Cleanup(func() { videoSigningKey = previous })
+	if err := SetVideoSigningKey([]byte(key)); err != nil {
+		t.Fatalf("set signing key: %v", err)
+	}
+}
+
+// signedParams - параметры подписанной ссылки
+func signedParams(t *testing.T, link string) url.Values {
+	t.Helper()
+	parsed, err := url.Parse(link)
+	if err != nil {
+		t.Fatalf("parse link %q: %v", link, err)
+	}
+	return parsed.Query()
+}
+
+func TestSetVideoSigningKey(t *testing.T) {
+	previous := videoSigningKey
+	t.Cleanup(func() { videoSigningKey = previous })
+
+	for _, key := range []string{"", "short-key", strings.Repeat("k", MinVideoSigningKeyLength-1)} {
+		if err := SetVideoSigningKey([]byte(key)); err == nil {
+			t.Errorf("key of %d bytes accepted", len(key))
+		}
+	}
+	if err := SetVideoSigningKey([]byte(strings.Repeat("k", MinVideoSigningKeyLength))); err != nil {
+		t.Fatalf("key of minimal length rejected: %v", err)
+	}
+}
+
+func TestSignAndVerifyVideoLink(t *testing.T) {
+	withVideoSigningKey(t, strings.Repeat("a", MinVideoSigningKeyLength))
+	t.Setenv("VIDEO_STREAM_BASE_URL", "https://api.example.com/")
+
+	link := SignVideoLink(VideoStreamPath + "?id=42")
+	if !strings.HasPrefix(link, "https://api.example.com"+VideoStreamPath+"?") {
+		t.Fatalf("unexpected signed link %q", link)
+	}
+	params := signedParams(t, link)
+	videoID, expires, err := VerifyVideoLink(params)
+	if err != nil || videoID != 42 {
+		t.Fatalf("verify: video %d, error %v", videoID, err)
+	}
+	if ttl := time.Until(expires); ttl <= 0 || ttl > VideoLinkTTL {
+		t.Fatalf("unexpected expiry %v", expires)
+	}
+
+	// Ссылки на другие площадки не подписываются
+	if external := "https://www.youtube.com/watch?v=abc"; SignVideoLink(external) != external {
+		t.Fatal("external link changed")
+	}
+
+	tampered := []func(url.Values){
+		func(v url.Values) { v.Set("id", "43") },
+		func(v url.Values) { v.Set("expires", fmt.Sprint(time.Now().Add(48*time.Hour).Unix())) },
+		func(v url.Values) { v.Set("signature", strings.Repeat("0", 64)) },
+		func(v url.Values) { v.Del("signature") },
+		func(v url.Values) { v.Set("expires", "soon") },
+	}
+	for i, change := range tampered {
+		changed := signedParams(t, link)
+		change(changed)
+		if _, _, err := VerifyVideoLink(changed); !errors.Is(err, ErrInvalidVideoLink) {
+			t.Errorf("tampered link %d: error %v", i, err)
+		}
+	}
+
+	// Просроченная ссылка с верной подписью
+	expired := time.Now().Add(-time.Minute).Unix()
+	params = url.Values{
+		"id":        {"42"},
+		"expires":   {fmt.Sprint(expired)},
+		"signature": {videoSignature(42, expired)},
+	}
+	if _, _, err := VerifyVideoLink(params); !errors.Is(err, ErrInvalidVideoLink) {
+		t.Fatalf("expired link: error %v", err)
+	}
+
+	// Подпись другим ключом недействительна
+	withVideoSigningKey(t, strings.Repeat("b", MinVideoSigningKeyLength))
+	if _, _, err := VerifyVideoLink(signedParams(t, link)); !errors.Is(err, ErrInvalidVideoLink) {
+		t.Fatalf("link signed with another key: error %v", err)
+	}
+}
+
+func TestVideoLinksWithoutKey(t *testing.T) {
+	previous := videoSigningKey
+	t.Cleanup(func() { videoSigningKey = previous })
+	videoSigningKey = nil
+
+	link := VideoStreamPath + "?id=42"
+	if SignVideoLink(link) != link {
+		t.Fatal("link signed without a key")
+	}
+	expires := time.Now().Add(time.Hour).Unix()
+	params := url.Values{"id": {"42"}, "expires": {fmt.Sprint(expires)}, "signature": {videoSignature(42, expires)}}
+	if _, _, err := VerifyVideoLink(params); !errors.Is(err, ErrInvalidVideoLink) {
+		t.Fatalf("link verified without a key: error %v", err)
+	}
+}
@@ -0,0 +1,356 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"hired-valley-backend/config"
+	"hired-valley-backend/models/content"
+	"hired-valley-backend/models/courses"
+	"hired-valley-backend/models/courses/videos"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrUploadTooLarge       = errors.New("video file is too large")
+	ErrUploadOffsetMismatch = errors.New("chunk offset does not match the uploaded size")
+	ErrUploadNotActive      = errors.New("upload is not accepting data")
+	ErrUnsupportedFileType  = errors.New("unsupported file type, upload .mp4, .mov, .webm or .mkv")
+	// ErrVideoRejected - публикация невозможна без действий пользователя (например, истек токен Google);
+	// такие ошибки не повторяются автоматически
+	ErrVideoRejected = errors.New("video was rejected by the video host")
+)
+
+const (
+	// MaxVideoChunkBytes - максимальный размер одной части загрузки
+	MaxVideoChunkBytes = 64 << 20
+	// MaxVideoPublishAttempts - попытки публикации до перевода видео в failed
+	MaxVideoPublishAttempts = 5
+	// abandonedUploadTTL - незавершенная загрузка удаляется через сутки без новых частей
+	abandonedUploadTTL = 24 * time.Hour
+	// stalePublishTTL - публикация, прерванная перезапуском сервера, возвращается в очередь
+	stalePublishTTL = 2 * time.Hour
+)
+
+// allowedVideoExtensions - расширения файлов, принимаемых на загрузку
+var allowedVideoExtensions = map[string]bool{".mp4": true, ".m4v": true, ".mov": true, ".webm": true, ".mkv": true}
+
+// MaxVideoUploadBytes - ограничение размера видео (VIDEO_MAX_UPLOAD_BYTES, по умолчанию 5 ГБ)
+func MaxVideoUploadBytes() int64 {
+	if value, err := strconv.ParseInt(os.Getenv("VIDEO_MAX_UPLOAD_BYTES"), 10, 64); err == nil && value > 0 {
+		return value
+	}
+	return 5 << 30
+}
+
+// VideoStagingDir - каталог временных файлов загрузки (VIDEO_STAGING_DIR)
+func VideoStagingDir() string {
+	if dir := os.Getenv("VIDEO_STAGING_DIR"); dir != "" {
+		return dir
+	}
+	return filepath.Join(os.TempDir(), "hired-valley-uploads")
+}
+
+// CreateVideoUpload - новая загрузка: проверка имени и размера файла, создание записи и пустого
+// временного файла. Заполненные поля video (назначение, описание, токен) сохраняются
+func CreateVideoUpload(video *videos.Video) error {
+	video.FileName = filepath.Base(strings.TrimSpace(video.FileName))
+	if !allowedVideoExtensions[strings.ToLower(filepath.Ext(video.FileName))] {
+		return ErrUnsupportedFileType
+	}
+	if video.SizeBytes <= 0 || video.SizeBytes > MaxVideoUploadBytes() {
+		return fmt.Errorf("%w: size must be between 1 and %d bytes", ErrUploadTooLarge, MaxVideoUploadBytes())
+	}
+	if strings.TrimSpace(video.Title) == "" {
+		video.Title = video.FileName
+	}
+	if video.Host == "" {
+		video.Host = DefaultVideoHost()
+	}
+	if _, err := GetVideoHost(video.Host); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(VideoStagingDir(), 0o700); err != nil {
+		return err
+	}
+
+	video.Status = videos.VideoUploading
+	video.ReceivedBytes = 0
+	if err := config.DB.Create(video).Error; err != nil {
+		return err
+	}
+	video.StagingPath = filepath.Join(VideoStagingDir(), fmt.Sprintf("%d.part", video.ID))
+	file, err := os.OpenFile(video.StagingPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	file.Close()
+	return config.DB.Model(video).Update("staging_path", video.StagingPath).Error
+}
+
+// WriteVideoChunk - запись части файла со смещения offset; offset должен совпадать с уже
+// полученным объемом, чтобы повторная отправка части после обрыва не испортила файл.
+// После последней части файл проверяется и ставится в очередь публикации
+func WriteVideoChunk(video *videos.Video, offset int64, chunk io.Reader) error {
+	if video.Status != videos.VideoUploading {
+		return ErrUploadNotActive
+	}
+	if offset != video.ReceivedBytes {
+		return ErrUploadOffsetMismatch
+	}
+
+	file, err := os.OpenFile(video.StagingPath, os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	remaining := video.SizeBytes - offset
+	written, err := io.Copy(io.NewOffsetWriter(file, offset), io.LimitReader(chunk, remaining+1))
+	file.Close()
+	if err != nil {
+		return err
+	}
+	if written > remaining {
+		return fmt.Errorf("%w: chunk exceeds the declared file size", ErrUploadTooLarge)
+	}
+
+	// Условное обновление защищает от параллельной отправки одной и той же части
+	result := config.DB.Model(&videos.Video{}).Where("id = ? AND received_bytes = ?", video.ID, offset).
+		Update("received_bytes", offset+written)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrUploadOffsetMismatch
+	}
+	video.ReceivedBytes = offset + written
+
+	if video.ReceivedBytes == video.SizeBytes {
+		return finishVideoUpload(video)
+	}
+	return nil
+}
+
+// StageVideoFile - загрузка файла целиком одним запросом (multipart) через тот же конвейер
+func StageVideoFile(video *videos.Video, file io.Reader) error {
+	if err := CreateVideoUpload(video); err != nil {
+		return err
+	}
+	if err := WriteVideoChunk(video, 0, file); err != nil {
+		return err
+	}
+	if video.Status == videos.VideoUploading {
+		failVideo(video, "file is smaller than the declared size")
+		return fmt.Errorf("%w: file is smaller than the declared size", ErrUploadOffsetMismatch)
+	}
+	return nil
+}
+
+// finishVideoUpload - проверка контейнера и кодека загруженного файла и постановка в очередь
+func finishVideoUpload(video *videos.Video) error {
+	probe, err := ProbeVideo(video.StagingPath)
+	if err != nil {
+		failVideo(video, err.Error())
+		os.Remove(video.StagingPath)
+		return nil
+	}
+
+	now := time.Now()
+	video.Status = videos.VideoQueued
+	video.Container = probe.Container
+	video.VideoCodec = probe.VideoCodec
+	video.AudioCodec = probe.AudioCodec
+	video.DurationSeconds = probe.DurationSeconds
+	video.NextAttemptAt = &now
+	return config.DB.Model(video).Updates(map[string]interface{}{
+		"status":           video.Status,
+		"container":        video.Container,
+		"video_codec":      video.VideoCodec,
+		"audio_codec":      video.AudioCodec,
+		"duration_seconds": video.DurationSeconds,
+		"next_attempt_at":  now,
+		"last_error":       "",
+	}).Error
+}
+
+// failVideo - перевод видео в failed с причиной
+func failVideo(video *videos.Video, reason string) {
+	video.Status = videos.VideoFailed
+	video.LastError = reason
+	video.NextAttemptAt = nil
+	config.DB.Model(video).Updates(map[string]interface{}{
+		"status":          video.Status,
+		"last_error":      reason,
+		"next_attempt_at": nil,
+	})
+}
+
+// CancelVideoUpload - отмена загрузки или ожидающей публикации; временный файл удаляется
+func CancelVideoUpload(video *videos.Video) error {
+	if video.Status != videos.VideoUploading && video.Status != videos.VideoQueued && video.Status != videos.VideoFailed {
+		return ErrUploadNotActive
+	}
+	os.Remove(video.StagingPath)
+	video.Status = videos.VideoCancelled
+	return config.DB.Model(video).Updates(map[string]interface{}{"status": video.Status, "next_attempt_at": nil}).Error
+}
+
+// RetryVideoPublish - повторная публикация видео после ошибки; googleToken - новый токен доступа или ""
+func RetryVideoPublish(video *videos.Video, googleToken string) error {
+	if video.Status != videos.VideoFailed {
+		return ErrUploadNotActive
+	}
+	if _, err := os.Stat(video.StagingPath); err != nil {
+		return errors.New("uploaded file is no longer available, upload the video again")
+	}
+	updates := map[string]interface{}{"status": videos.VideoQueued, "attempts": 0, "next_attempt_at": time.Now(), "last_error": ""}
+	if googleToken != "" {
+		updates["google_token"] = googleToken
+	}
+	if err := config.DB.Model(video).Updates(updates).Error; err != nil {
+		return err
+	}
+	return config.DB.First(video, video.ID).Error
+}
+
+// RunVideoWorker - фоновая публикация загруженных видео на их площадки с повторами
+func RunVideoWorker(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if err := cleanupVideoUploads(); err != nil {
+			log.Printf("Ошибка очистки загрузок видео: %v", err)
+		}
+		for {
+			processed, err := publishNextVideo()
+			if err != nil {
+				log.Printf("Ошибка публикации видео: %v", err)
+			}
+			if !processed {
+				break
+			}
+		}
+	}
+}
+
+// cleanupVideoUploads - брошенные загрузки и публикации, прерванные перезапуском
+func cleanupVideoUploads() error {
+	var abandoned []videos.Video
+	if err := config.DB.Where("status = ? AND updated_at < ?", videos.VideoUploading, time.Now().Add(-abandonedUploadTTL)).
+		Find(&abandoned).Error; err != nil {
+		return err
+	}
+	for i := range abandoned {
+		os.Remove(abandoned[i].StagingPath)
+		failVideo(&abandoned[i], "upload expired before all chunks were received")
+	}
+	return config.DB.Model(&videos.Video{}).
+		Where("status = ? AND updated_at < ?", videos.VideoPublishing, time.Now().Add(-stalePublishTTL)).
+		Updates(map[string]interface{}{"status": videos.VideoQueued, "next_attempt_at": time.Now()}).Error
+}
+
+// publishNextVideo - публикация одного видео из очереди; false - очередь пуста
+func publishNextVideo() (bool, error) {
+	var video videos.Video
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", videos.VideoQueued, time.Now()).
+			Order("next_attempt_at").First(&video).Error; err != nil {
+			return err
+		}
+		video.Status = videos.VideoPublishing
+		video.Attempts++
+		return tx.Model(&video).Updates(map[string]interface{}{"status": video.Status, "attempts": video.Attempts}).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if err := publishStagedVideo(&video); err != nil {
+		if errors.Is(err, ErrVideoRejected) || video.Attempts >= MaxVideoPublishAttempts {
+			failVideo(&video, err.Error())
+			return true, nil
+		}
+		// Экспоненциальная задержка: 1, 2, 4, 8 минут
+		next := time.Now().Add(time.Minute << (video.Attempts - 1))
+		return true, config.DB.Model(&video).Updates(map[string]interface{}{
+			"status":          videos.VideoQueued,
+			"next_attempt_at": next,
+			"last_error":      err.Error(),
+		}).Error
+	}
+
+	if err := completeVideoPublish(&video); err != nil {
+		return true, err
+	}
+	os.Remove(video.StagingPath)
+	return true, nil
+}
+
+// publishStagedVideo - загрузка временного файла на площадку видео
+func publishStagedVideo(video *videos.Video) error {
+	host, err := GetVideoHost(video.Host)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrVideoRejected, err)
+	}
+	file, err := os.Open(video.StagingPath)
+	if err != nil {
+		return fmt.Errorf("%w: uploaded file is missing", ErrVideoRejected)
+	}
+	defer file.Close()
+	return host.Publish(context.Background(), video, file)
+}
+
+// completeVideoPublish - ссылка на опубликованное видео в уроке или новая запись контента
+func completeVideoPublish(video *videos.Video) error {
+	now := time.Now()
+	video.Status = videos.VideoReady
+	video.PublishedAt = &now
+	video.NextAttemptAt = nil
+	video.LastError = ""
+
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		switch video.TargetType {
+		case videos.TargetLesson:
+			if err := tx.Model(&courses.Lesson{}).Where("id = ?", video.LessonID).
+				Update("video_link", video.VideoLink).Error; err != nil {
+				return err
+			}
+		case videos.TargetContent:
+			item := content.Content{
+				Title:       video.Title,
+				Description: video.Description,
+				Category:    video.Category,
+				Tags:        video.Tags,
+				VideoLink:   video.VideoLink,
+				YouTubeID:   video.YouTubeID,
+				AuthorID:    video.UploadedBy,
+			}
+			if err := tx.Create(&item).Error; err != nil {
+				return err
+			}
+			video.ContentID = &item.ID
+		}
+		return tx.Model(video).Updates(map[string]interface{}{
+			"you_tube_id":     video.YouTubeID,
+			"storage_key":     video.StorageKey,
+			"video_link":      video.VideoLink,
+			"status":          video.Status,
+			"published_at":    now,
+			"next_attempt_at": nil,
+			"last_error":      "",
+			"content_id":      video.ContentID,
+			"google_token":    "",
+		}).Error
+	})
+}
@@ -0,0 +1,263 @@
+package services
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+var (
+	ErrUnsupportedContainer = errors.New("unsupported video container, upload MP4, MOV, WebM or MKV")
+	ErrUnsupportedCodec     = errors.New("unsupported video codec, use H.264, HEVC, VP8, VP9 or AV1")
+	ErrNoVideoTrack         = errors.New("file has no video track")
+)
+
+// supportedVideoCodecs - кодеки, которые принимает YouTube без перекодирования на нашей стороне
+var supportedVideoCodecs = map[string]bool{"h264": true, "hevc": true, "vp8": true, "vp9": true, "av1": true, "mpeg4": true}
+
+// VideoProbe - контейнер, кодеки и длительность загруженного файла
+type VideoProbe struct {
+	Container       string
+	VideoCodec      string
+	AudioCodec      string
+	DurationSeconds float64
+}
+
+// ProbeVideo - проверка контейнера и кодека видеофайла по его заголовкам
+func ProbeVideo(path string) (VideoProbe, error) {
+	var probe VideoProbe
+	file, err := os.Open(path)
+	if err != nil {
+		return probe, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return probe, err
+	}
+
+	header := make([]byte, 12)
+	if _, err := io.ReadFull(file, header); err != nil {
+		return probe, ErrUnsupportedContainer
+	}
+	switch {
+	case string(header[4:8]) == "ftyp":
+		probe, err = probeISOBMFF(file, info.Size())
+	case bytes.Equal(header[:4], []byte{0x1A, 0x45, 0xDF, 0xA3}):
+		probe, err = probeMatroska(file)
+	default:
+		return probe, ErrUnsupportedContainer
+	}
+	if err != nil {
+		return probe, err
+	}
+
+	if probe.VideoCodec == "" {
+		return probe, ErrNoVideoTrack
+	}
+	if !supportedVideoCodecs[probe.VideoCodec] {
+		return probe, fmt.Errorf("%w (found %s)", ErrUnsupportedCodec, probe.VideoCodec)
+	}
+	return probe, nil
+}
+
+// isoBox - бокс ISO BMFF (MP4/MOV): тип и границы содержимого в файле
+type isoBox struct {
+	kind       string
+	start, end int64 // Содержимое без заголовка
+}
+
+// readISOBoxes - боксы одного уровня в диапазоне [start, end)
+func readISOBoxes(r io.ReaderAt, start, end int64) ([]isoBox, error) {
+	var boxes []isoBox
+	header := make([]byte, 16)
+	for offset := start; offset+8 <= end; {
+		if _, err := r.ReadAt(header[:8], offset); err != nil {
+			return nil, err
+		}
+		size := int64(binary.BigEndian.Uint32(header[:4]))
+		kind := string(header[4:8])
+		headerLen := int64(8)
+		switch size {
+		case 0: // Бокс до конца файла
+			size = end - offset
+		case 1: // 64-битный размер
+			if _, err := r.ReadAt(header[8:16], offset+8); err != nil {
+				return nil, err
+			}
+			size = int64(binary.BigEndian.Uint64(header[8:16]))
+			headerLen = 16
+		}
+		if size < headerLen || offset+size > end {
+			return nil, ErrUnsupportedContainer
+		}
+		boxes = append(boxes, isoBox{kind: kind, start: offset + headerLen, end: offset + size})
+		offset += size
+	}
+	return boxes, nil
+}
+
+// findISOBox - первый вложенный бокс по пути типов, например "mdia", "minf", "stbl"
+func findISOBox(r io.ReaderAt, parent isoBox, path ...string) (isoBox, bool) {
+	current := parent
+	for _, kind := range path {
+		children, err := readISOBoxes(r, current.start, current.end)
+		if err != nil {
+			return current, false
+		}
+		found := false
+		for _, child := range children {
+			if child.kind == kind {
+				current, found = child, true
+				break
+			}
+		}
+		if !found {
+			return current, false
+		}
+	}
+	return current, true
+}
+
+// isoCodecs - названия кодеков по типу записи в stsd
+var isoCodecs = map[string]string{
+	"avc1": "h264", "avc3": "h264", "hvc1": "hevc", "hev1": "hevc", "vp08": "vp8", "vp09": "vp9",
+	"av01": "av1", "mp4v": "mpeg4", "mp4a": "aac", "Opus": "opus", "ac-3": "ac3", "ec-3": "eac3",
+	".mp3": "mp3", "lpcm": "pcm", "sowt": "pcm", "twos": "pcm",
+}
+
+// probeISOBMFF - MP4 и MOV: бренд из ftyp, длительность из mvhd, кодеки из stsd дорожек
+func probeISOBMFF(file *os.File, size int64) (VideoProbe, error) {
+	probe := VideoProbe{Container: "mp4"}
+	boxes, err := readISOBoxes(file, 0, size)
+	if err != nil {
+		return probe, err
+	}
+
+	var moov *isoBox
+	for i := range boxes {
+		switch boxes[i].kind {
+		case "ftyp":
+			brand := make([]byte, 4)
+			if _, err := file.ReadAt(brand, boxes[i].start); err == nil && string(brand) == "qt  " {
+				probe.Container = "mov"
+			}
+		case "moov":
+			moov = &boxes[i]
+		}
+	}
+	if moov == nil {
+		return probe, errors.New("file is incomplete: movie header (moov) not found")
+	}
+
+	if mvhd, ok := findISOBox(file, *moov, "mvhd"); ok {
+		buf := make([]byte, 32)
+		if n, _ := file.ReadAt(buf, mvhd.start); n >= 20 {
+			if buf[0] == 1 && n >= 32 {
+				timescale := binary.BigEndian.Uint32(buf[20:24])
+				duration := binary.BigEndian.Uint64(buf[24:32])
+				if timescale > 0 {
+					probe.DurationSeconds = float64(duration) / float64(timescale)
+				}
+			} else if buf[0] == 0 {
+				timescale := binary.BigEndian.Uint32(buf[12:16])
+				duration := binary.BigEndian.Uint32(buf[16:20])
+				if timescale > 0 {
+					probe.DurationSeconds = float64(duration) / float64(timescale)
+				}
+			}
+		}
+	}
+
+	tracks, err := readISOBoxes(file, moov.start, moov.end)
+	if err != nil {
+		return probe, err
+	}
+	for _, trak := range tracks {
+		if trak.kind != "trak" {
+			continue
+		}
+		hdlr, ok := findISOBox(file, trak, "mdia", "hdlr")
+		if !ok {
+			continue
+		}
+		handler := make([]byte, 4)
+		if _, err := file.ReadAt(handler, hdlr.start+8); err != nil {
+			continue
+		}
+		stsd, ok := findISOBox(file, trak, "mdia", "minf", "stbl", "stsd")
+		if !ok {
+			continue
+		}
+		entry := make([]byte, 8)
+		if _, err := file.ReadAt(entry, stsd.start+8); err != nil {
+			continue
+		}
+		fourcc := string(entry[4:8])
+		codec, known := isoCodecs[fourcc]
+		if !known {
+			codec = fourcc
+		}
+		switch string(handler) {
+		case "vide":
+			if probe.VideoCodec == "" {
+				probe.VideoCodec = codec
+			}
+		case "soun":
+			if probe.AudioCodec == "" {
+				probe.AudioCodec = codec
+			}
+		}
+	}
+	return probe, nil
+}
+
+// matroskaScanBytes - сколько байт от начала файла просматривать в поиске описания дорожек
+const matroskaScanBytes = 4 << 20
+
+// matroskaCodecs - CodecID дорожек Matroska/WebM
+var matroskaCodecs = []struct {
+	id, codec string
+	video     bool
+}{
+	{"V_MPEG4/ISO/AVC", "h264", true}, {"V_MPEGH/ISO/HEVC", "hevc", true}, {"V_VP8", "vp8", true},
+	{"V_VP9", "vp9", true}, {"V_AV1", "av1", true}, {"V_MPEG4/ISO/", "mpeg4", true},
+	{"A_OPUS", "opus", false}, {"A_VORBIS", "vorbis", false}, {"A_AAC", "aac", false}, {"A_MPEG/L3", "mp3", false},
+}
+
+// probeMatroska - WebM и MKV: тип документа из заголовка EBML, кодеки по CodecID в начале файла
+func probeMatroska(file *os.File) (VideoProbe, error) {
+	probe := VideoProbe{Container: "mkv"}
+	buf := make([]byte, matroskaScanBytes)
+	n, err := file.ReadAt(buf, 0)
+	if err != nil && err != io.EOF {
+		return probe, err
+	}
+	buf = buf[:n]
+
+	header := buf
+	if len(header) > 64 {
+		header = header[:64]
+	}
+	if bytes.Contains(header, []byte("webm")) {
+		probe.Container = "webm"
+	} else if !bytes.Contains(header, []byte("matroska")) {
+		return probe, ErrUnsupportedContainer
+	}
+
+	for _, candidate := range matroskaCodecs {
+		if !bytes.Contains(buf, []byte(candidate.id)) {
+			continue
+		}
+		if candidate.video && probe.VideoCodec == "" {
+			probe.VideoCodec = candidate.codec
+		}
+		if !candidate.video && probe.AudioCodec == "" {
+			probe.AudioCodec = candidate.codec
+		}
+	}
+	return probe, nil
+}